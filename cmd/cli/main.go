@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/config"
+	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 	"github.com/baxromumarov/2pc-engine/pkg/transport"
+	twophasecommit "github.com/baxromumarov/2pc-engine/pkg/two_phase_commit"
 )
 
 func main() {
@@ -37,8 +45,32 @@ func main() {
 		addNode()
 	case "remove-node":
 		removeNode()
+	case "sync":
+		syncCluster()
+	case "disable-node":
+		setNodeDisabled(true)
+	case "enable-node":
+		setNodeDisabled(false)
+	case "maintenance":
+		maintenance()
 	case "dashboard":
 		dashboard()
+	case "preflight":
+		preflight()
+	case "state":
+		stateCommand()
+	case "tx-detail":
+		txDetail()
+	case "reconcile":
+		reconcile()
+	case "plan-master":
+		planMaster()
+	case "tail":
+		tail()
+	case "verify-commit-log":
+		verifyCommitLog()
+	case "verify":
+		verifyRow()
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -50,13 +82,13 @@ func printUsage() {
 	fmt.Println("2PC CLI Tool")
 	fmt.Println("")
 	fmt.Println("Usage:")
-	fmt.Println("  cli start-node --addr=<address>")
+	fmt.Println("  cli start-node --addr=<address> [--advertise-addr=<address>]")
 	fmt.Println("      Start a new node on the specified address")
 	fmt.Println("")
-	fmt.Println("  cli start-master --addr=<address> --nodes=<node1,node2,...>")
+	fmt.Println("  cli start-master --addr=<address> --nodes=<node1,node2,...> [--advertise-addr=<address>]")
 	fmt.Println("      Start a master node with the specified slave nodes")
 	fmt.Println("")
-	fmt.Println("  cli commit --master=<address> --payload=<json>")
+	fmt.Println("  cli commit --master=<address> --payload=<json> [--targets=<node1,...>] [--record-on-all] [--partition-key=<key>] [--async] [--ack=all|local|none]")
 	fmt.Println("      Start a distributed transaction via the master")
 	fmt.Println("")
 	fmt.Println("  cli health --addr=<address>")
@@ -65,27 +97,64 @@ func printUsage() {
 	fmt.Println("  cli status --nodes=<node1,node2,...>")
 	fmt.Println("      Check status of all nodes and find the master")
 	fmt.Println("")
-	fmt.Println("  cli add-node --master=<address> --addr=<nodeAddress> [--name=<display>] [--database=<dsn>]")
-	fmt.Println("      Register a new node with the cluster (node must already be running)")
+	fmt.Println("  cli add-node --master=<address> --addr=<nodeAddress> [--name=<display>] [--database=<dsn>] [--labels=k=v,...]")
+	fmt.Println("      Register a new node with the cluster (node must already be running), or update a member's metadata")
 	fmt.Println("")
-	fmt.Println("  cli remove-node --master=<address> --addr=<nodeAddress>")
+	fmt.Println("  cli remove-node --master=<address> --addr=<nodeAddress|name>")
 	fmt.Println("      Remove a node from the cluster membership")
 	fmt.Println("")
-	fmt.Println("  cli dashboard --master=<address>")
+	fmt.Println("  cli sync --master=<address> --config=<json file>")
+	fmt.Println("      Make the membership exactly the nodes in the file ({\"nodes\": [{\"address\": ...}]}), adding and removing as needed")
+	fmt.Println("")
+	fmt.Println("  cli disable-node --master=<address> --addr=<nodeAddress|name>")
+	fmt.Println("  cli enable-node --master=<address> --addr=<nodeAddress|name>")
+	fmt.Println("      Exclude a node from transactions and election (or re-include it) while keeping membership")
+	fmt.Println("")
+	fmt.Println("  cli maintenance --master=<address> --addr=<nodeAddress|name> --on|--off [--drain-timeout=10s]")
+	fmt.Println("      Put a node into maintenance (drain, stop participating, step down if master) or bring it back")
+	fmt.Println("")
+	fmt.Println("  cli dashboard --master=<address> [--user=<user> --pass=<pass>]")
 	fmt.Println("      Show a textual dashboard with health/metrics from the master")
+	fmt.Println("")
+	fmt.Println("  cli preflight --nodes=<node1,node2,...> [--max-skew=2s] [--json]")
+	fmt.Println("      Check reachability, versions, master, databases and clock skew")
+	fmt.Println("")
+	fmt.Println("  cli state export --file=<state file> --key=<key> [--out=<json>]")
+	fmt.Println("  cli state import --file=<state file> --key=<key> [--in=<json>]")
+	fmt.Println("      Back up or migrate the encrypted cluster state as plaintext JSON")
+	fmt.Println("")
+	fmt.Println("  cli tx-detail --master=<address> --id=<transaction id>")
+	fmt.Println("      Show a transaction's status on every node")
+	fmt.Println("")
+	fmt.Println("  cli reconcile --master=<address> --id=<transaction id>")
+	fmt.Println("      Force every node to one outcome for a partially committed transaction")
+	fmt.Println("")
+	fmt.Println("  cli plan-master --master=<address> [--user=<user> --pass=<pass>]")
+	fmt.Println("      Show which node the election would make master, and why, without electing")
+	fmt.Println("")
+	fmt.Println("  cli tail --addr=<address>")
+	fmt.Println("      Follow a node's transactions as they prepare, commit and abort (Ctrl-C to stop)")
+	fmt.Println("")
+	fmt.Println("  cli verify-commit-log --file=<commit log> [--key=<key>]")
+	fmt.Println("      Check the signatures of a coordinator's commit log and report the first tampered entry")
+	fmt.Println("")
+	fmt.Println("  cli verify --master=<address> --table=<table> [--key-col=id] --key=<value> [--user=<user> --pass=<pass>]")
+	fmt.Println("      Compare one row across every node and report the nodes whose copy diverges")
 }
 
 func startNode() {
 	fs := flag.NewFlagSet("start-node", flag.ExitOnError)
 	addr := fs.String("addr", "localhost:8081", "Address for the node")
+	advertise := fs.String("advertise-addr", "", "Address peers use to reach the node when --addr is a wildcard or internal bind address (optional)")
 	nodes := fs.String("nodes", "", "Comma-separated list of node addresses (including this node) for election/failover")
 	heartbeat := fs.String("heartbeat", "5s", "Heartbeat interval (e.g. 5s)")
 	coord := fs.String("coord-timeout", "10s", "2PC coordinator timeout (e.g. 10s)")
 	dsn := fs.String("dsn", "", "Postgres DSN (fallback to POSTGRES_DSN env var if empty)")
 	name := fs.String("name", "", "Display name for this node (optional)")
+	binary := fs.String("binary", "", "Path to the node binary (default: node next to this executable, else go run)")
 	fs.Parse(os.Args[2:])
 
-	args := []string{"run", "./cmd/node", fmt.Sprintf("--addr=%s", *addr)}
+	args := []string{fmt.Sprintf("--addr=%s", *addr)}
 	if *nodes != "" {
 		args = append(args, fmt.Sprintf("--nodes=%s", *nodes))
 	}
@@ -96,9 +165,12 @@ func startNode() {
 	if *name != "" {
 		args = append(args, fmt.Sprintf("--name=%s", *name))
 	}
+	if *advertise != "" {
+		args = append(args, fmt.Sprintf("--advertise-addr=%s", *advertise))
+	}
 
 	fmt.Printf("Starting node %s...\n", *addr)
-	cmd := exec.Command("go", args...)
+	cmd := componentCommand("node", *binary, args)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -109,11 +181,13 @@ func startNode() {
 func startMaster() {
 	fs := flag.NewFlagSet("start-master", flag.ExitOnError)
 	addr := fs.String("addr", "localhost:8080", "Address for the master")
+	advertise := fs.String("advertise-addr", "", "Address peers use to reach the master when --addr is a wildcard or internal bind address (optional)")
 	nodes := fs.String("nodes", "", "Comma-separated list of node addresses")
 	heartbeat := fs.String("heartbeat", "5s", "Heartbeat interval (e.g. 5s)")
 	coord := fs.String("coord-timeout", "10s", "2PC coordinator timeout (e.g. 10s)")
 	dsn := fs.String("dsn", "", "Postgres DSN (fallback to POSTGRES_DSN env var if empty)")
 	name := fs.String("name", "", "Display name for this master (optional)")
+	binary := fs.String("binary", "", "Path to the master binary (default: master next to this executable, else go run)")
 	fs.Parse(os.Args[2:])
 
 	if *nodes == "" {
@@ -122,8 +196,6 @@ func startMaster() {
 	}
 
 	args := []string{
-		"run",
-		"./cmd/master",
 		fmt.Sprintf("--addr=%s", *addr),
 		fmt.Sprintf("--nodes=%s", *nodes),
 		fmt.Sprintf("--heartbeat=%s", *heartbeat),
@@ -135,10 +207,13 @@ func startMaster() {
 	if *name != "" {
 		args = append(args, fmt.Sprintf("--name=%s", *name))
 	}
+	if *advertise != "" {
+		args = append(args, fmt.Sprintf("--advertise-addr=%s", *advertise))
+	}
 
 	fmt.Printf("Starting master on %s...\n", *addr)
 
-	cmd := exec.Command("go", args...)
+	cmd := componentCommand("master", *binary, args)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -146,11 +221,36 @@ func startMaster() {
 	}
 }
 
+// componentCommand builds the command for a cluster component ("node" or "master").
+// It prefers an explicit binary, then a sibling binary next to the CLI executable,
+// and falls back to `go run ./cmd/<component>` for development checkouts.
+func componentCommand(component, binary string, args []string) *exec.Cmd {
+	if binary == "" {
+		if exe, err := os.Executable(); err == nil {
+			candidate := filepath.Join(filepath.Dir(exe), component)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				binary = candidate
+			}
+		}
+	}
+
+	if binary != "" {
+		return exec.Command(binary, args...)
+	}
+
+	return exec.Command("go", append([]string{"run", "./cmd/" + component}, args...)...)
+}
+
 func commit() {
 	fs := flag.NewFlagSet("commit", flag.ExitOnError)
 	master := fs.String("master", "", "Master node address")
 	payload := fs.String("payload", "{}", "Transaction payload as JSON")
 	nodes := fs.String("nodes", "", "Comma-separated list of node addresses to find master")
+	targets := fs.String("targets", "", "Comma-separated subset of node addresses or names to run the transaction on")
+	recordOnAll := fs.Bool("record-on-all", false, "Record an OBSERVED marker on nodes outside --targets")
+	partitionKey := fs.String("partition-key", "", "Route the transaction to the coordinator owning this key (with --coordinators on the cluster)")
+	async := fs.Bool("async", false, "Submit the transaction in the background and poll for its outcome")
+	ack := fs.String("ack", "", "Commit acknowledgements to wait for: all, local or none (default: the coordinator's --commit-ack)")
 	fs.Parse(os.Args[2:])
 
 	client := transport.NewHTTPClient(10 * time.Second)
@@ -173,12 +273,24 @@ func commit() {
 
 	// Send transaction request
 	req := &protocol.TransactionRequest{
-		Payload: payloadData,
+		Payload:      payloadData,
+		RecordOnAll:  *recordOnAll,
+		PartitionKey: *partitionKey,
+		AckPolicy:    *ack,
+	}
+	if *targets != "" {
+		req.Targets = strings.Split(*targets, ",")
 	}
 
 	fmt.Printf("Sending transaction to master at %s...\n", masterAddr)
 
-	resp, err := client.StartTransaction(masterAddr, req)
+	var resp *protocol.TransactionResponse
+	var err error
+	if *async {
+		resp, err = commitAsync(client, masterAddr, req)
+	} else {
+		resp, err = client.StartTransaction(masterAddr, req)
+	}
 	if err != nil {
 		log.Fatalf("Transaction failed: %v", err)
 	}
@@ -189,11 +301,53 @@ func commit() {
 		if resp.Message != "" {
 			fmt.Printf("  Message: %s\n", resp.Message)
 		}
+		for addr, rows := range resp.RowsAffected {
+			fmt.Printf("  Rows affected on %s: %d\n", addr, rows)
+		}
+		for addr, rows := range resp.Returned {
+			fmt.Printf("  Returned by %s: %v\n", addr, rows)
+		}
+		if !resp.Durable {
+			fmt.Printf("  ! Commit not yet acknowledged by: %v\n", resp.PendingNodes)
+		}
 	} else {
 		fmt.Printf("✗ Transaction %s failed\n", resp.TransactionID)
 		if resp.Error != "" {
 			fmt.Printf("  Error: %s\n", resp.Error)
 		}
+		if resp.RetryAfterMs > 0 {
+			fmt.Printf("  Throttled: retry after %dms\n", resp.RetryAfterMs)
+		}
+	}
+	for _, e := range resp.Excluded {
+		fmt.Printf("  Excluded %s: %s\n", e.Address, e.Reason)
+	}
+}
+
+// commitAsync submits req with async=true and polls the accepting node until
+// the transaction is no longer running.
+func commitAsync(client *transport.HTTPClient, masterAddr string, req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+	accepted, err := client.StartTransactionAsync(masterAddr, req)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Accepted transaction %s, waiting for the outcome...\n", accepted.TransactionID)
+
+	for {
+		rec, err := client.GetTransaction(masterAddr, accepted.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			return nil, fmt.Errorf("transaction %s is no longer known to %s", accepted.TransactionID, masterAddr)
+		}
+		switch {
+		case rec.Status == protocol.TxStatusFailed:
+			return nil, fmt.Errorf("transaction %s: coordinator failed, outcome unknown (check cli tx-detail)", accepted.TransactionID)
+		case rec.Result != nil:
+			return rec.Result, nil
+		}
+		time.Sleep(500 * time.Millisecond)
 	}
 }
 
@@ -234,6 +388,8 @@ func clusterStatus() {
 	fmt.Println("Cluster Status:")
 	fmt.Println("---------------")
 
+	var masterAddr string
+
 	for _, addr := range nodeAddrs {
 		addr = strings.TrimSpace(addr)
 		if addr == "" {
@@ -251,15 +407,35 @@ func clusterStatus() {
 			roleEmoji = "👑"
 		}
 		fmt.Printf("  %s %s: %s (%s)\n", roleEmoji, addr, health.Status, health.Role)
+		if health.Role == "MASTER" {
+			masterAddr = addr
+		}
+	}
+
+	if masterAddr == "" {
+		return
+	}
+	// Best-effort: the summary is unavailable when dashboard auth is enabled.
+	if info, err := client.ClusterInfo(masterAddr); err == nil {
+		fmt.Printf("Elections: %s\n", formatElections(info.Elections))
 	}
 }
 
+// formatElections renders election stats as e.g. "3 (last master_died at ...)".
+func formatElections(stats protocol.ElectionStats) string {
+	if stats.Count == 0 || stats.LastAt == nil {
+		return fmt.Sprintf("%d", stats.Count)
+	}
+	return fmt.Sprintf("%d (last %s at %s)", stats.Count, stats.LastReason, stats.LastAt.Format(time.RFC3339))
+}
+
 func addNode() {
 	fs := flag.NewFlagSet("add-node", flag.ExitOnError)
 	master := fs.String("master", "", "Master node address")
 	addr := fs.String("addr", "", "Address of the node to add")
 	name := fs.String("name", "", "Display name for the node (optional)")
 	database := fs.String("database", "", "Database/DSN label for display (optional)")
+	labelsFlag := fs.String("labels", "", "Comma-separated key=value labels, e.g. zone=eu-1,tier=ssd (optional)")
 	fs.Parse(os.Args[2:])
 
 	if *master == "" {
@@ -268,19 +444,29 @@ func addNode() {
 	if *addr == "" {
 		log.Fatal("--addr is required")
 	}
+	labels, err := config.ParseLabels(*labelsFlag)
+	if err != nil {
+		log.Fatalf("Invalid --labels: %v", err)
+	}
 
 	client := transport.NewHTTPClient(5 * time.Second)
 	req := &protocol.AddNodeRequest{
 		Address:  *addr,
 		Name:     *name,
 		Database: *database,
+		Labels:   labels,
 	}
 
-	if _, err := client.AddNode(*master, req); err != nil {
+	resp, err := client.AddNode(*master, req)
+	if err != nil {
 		log.Fatalf("Failed to add node: %v", err)
 	}
 
-	fmt.Printf("✓ Added node %s via master %s\n", *addr, *master)
+	if resp.Updated {
+		fmt.Printf("✓ Updated node %s via master %s (already a member)\n", *addr, *master)
+	} else {
+		fmt.Printf("✓ Added node %s via master %s\n", *addr, *master)
+	}
 	if *name != "" {
 		fmt.Printf("  Name: %s\n", *name)
 	}
@@ -292,7 +478,7 @@ func addNode() {
 func removeNode() {
 	fs := flag.NewFlagSet("remove-node", flag.ExitOnError)
 	master := fs.String("master", "", "Master node address")
-	addr := fs.String("addr", "", "Address of the node to remove")
+	addr := fs.String("addr", "", "Address or name of the node to remove")
 	fs.Parse(os.Args[2:])
 
 	if *master == "" {
@@ -314,16 +500,143 @@ func removeNode() {
 	fmt.Printf("✓ Removed node %s via master %s\n", *addr, *master)
 }
 
+func syncCluster() {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	master := fs.String("master", "", "Master node address")
+	configPath := fs.String("config", "", "JSON file with the desired membership")
+	fs.Parse(os.Args[2:])
+
+	if *master == "" {
+		log.Fatal("--master is required")
+	}
+	if *configPath == "" {
+		log.Fatal("--config is required")
+	}
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to read --config: %v", err)
+	}
+	var req protocol.SyncClusterRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		log.Fatalf("Invalid --config: %v", err)
+	}
+
+	client := transport.NewHTTPClient(5 * time.Second)
+	resp, err := client.SyncCluster(*master, &req)
+	if err != nil {
+		log.Fatalf("Failed to sync cluster: %v", err)
+	}
+
+	if len(resp.Added)+len(resp.Removed)+len(resp.Updated) == 0 {
+		fmt.Printf("✓ Cluster already matches %s (%d nodes)\n", *configPath, len(req.Nodes))
+		return
+	}
+	fmt.Printf("✓ Synced cluster via master %s\n", *master)
+	for _, addr := range resp.Added {
+		fmt.Printf("  + %s\n", addr)
+	}
+	for _, addr := range resp.Removed {
+		fmt.Printf("  - %s\n", addr)
+	}
+	for _, addr := range resp.Updated {
+		fmt.Printf("  ~ %s\n", addr)
+	}
+}
+
+func setNodeDisabled(disabled bool) {
+	command, verb := "enable-node", "Enabled"
+	if disabled {
+		command, verb = "disable-node", "Disabled"
+	}
+
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	master := fs.String("master", "", "Master node address")
+	addr := fs.String("addr", "", "Address or name of the node")
+	fs.Parse(os.Args[2:])
+
+	if *master == "" {
+		log.Fatal("--master is required")
+	}
+	if *addr == "" {
+		log.Fatal("--addr is required")
+	}
+
+	client := transport.NewHTTPClient(5 * time.Second)
+	req := &protocol.DisableNodeRequest{
+		Address: *addr,
+	}
+
+	var err error
+	if disabled {
+		_, err = client.DisableNode(*master, req)
+	} else {
+		_, err = client.EnableNode(*master, req)
+	}
+	if err != nil {
+		log.Fatalf("Failed to %s: %v", command, err)
+	}
+
+	fmt.Printf("✓ %s node %s via master %s\n", verb, *addr, *master)
+}
+
+func maintenance() {
+	fs := flag.NewFlagSet("maintenance", flag.ExitOnError)
+	master := fs.String("master", "", "Master node address")
+	addr := fs.String("addr", "", "Address or name of the node")
+	on := fs.Bool("on", false, "Enter maintenance mode")
+	off := fs.Bool("off", false, "Leave maintenance mode")
+	drain := fs.Duration("drain-timeout", node.DefaultDrainTimeout, "How long to wait for prepared transactions to finish")
+	fs.Parse(os.Args[2:])
+
+	if *master == "" {
+		log.Fatal("--master is required")
+	}
+	if *addr == "" {
+		log.Fatal("--addr is required")
+	}
+	if *on == *off {
+		log.Fatal("exactly one of --on or --off is required")
+	}
+
+	client := transport.NewHTTPClient(*drain + 10*time.Second)
+	resp, err := client.SetMaintenance(*master, &protocol.MaintenanceRequest{
+		Address:        *addr,
+		Enabled:        *on,
+		DrainTimeoutMs: drain.Milliseconds(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to set maintenance: %v", err)
+	}
+
+	if !resp.Enabled {
+		fmt.Printf("✓ Node %s left maintenance via master %s\n", *addr, *master)
+		return
+	}
+	if !resp.Drained {
+		fmt.Printf("! Node %s is in maintenance but %d prepared transactions did not drain within %s\n", *addr, resp.InFlight, *drain)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Node %s is in maintenance and drained via master %s\n", *addr, *master)
+}
+
 func dashboard() {
 	fs := flag.NewFlagSet("dashboard", flag.ExitOnError)
 	master := fs.String("master", "", "Master node address")
+	user := fs.String("user", "", "Dashboard basic-auth user (optional)")
+	pass := fs.String("pass", "", "Dashboard basic-auth password (optional, fallback DASHBOARD_PASS)")
 	fs.Parse(os.Args[2:])
 
 	if *master == "" {
 		log.Fatal("--master is required")
 	}
 
-	client := transport.NewHTTPClient(5 * time.Second)
+	password := *pass
+	if password == "" {
+		password = os.Getenv("DASHBOARD_PASS")
+	}
+
+	client := transport.NewHTTPClient(5*time.Second).WithBasicAuth(*user, password)
 	info, err := client.ClusterInfo(*master)
 	if err != nil {
 		log.Fatalf("Failed to fetch cluster info: %v", err)
@@ -339,6 +652,10 @@ func dashboard() {
 	if !info.Generated.IsZero() {
 		fmt.Printf("Snapshot: %s\n", info.Generated.Format(time.RFC3339))
 	}
+	fmt.Printf("Elections: %s\n", formatElections(info.Elections))
+	if sb := info.SplitBrain; sb != nil && len(sb.Masters) > 1 {
+		fmt.Printf("⚠ SPLIT BRAIN: %d nodes claim MASTER: %s\n", len(sb.Masters), strings.Join(sb.Masters, ", "))
+	}
 	fmt.Println("Nodes:")
 
 	for _, n := range info.Nodes {
@@ -346,10 +663,25 @@ func dashboard() {
 		if n.Alive {
 			status = "UP"
 		}
+		if n.Status != "" {
+			status = n.Status
+		}
 		fmt.Printf("  - %s [%s] (%s)\n", n.Address, n.Role, status)
+		if n.StatusError != "" {
+			fmt.Printf("      Error: %s\n", n.StatusError)
+		}
 		if n.Database != "" {
 			fmt.Printf("      DB: %s\n", n.Database)
 		}
+		if !n.JoinedAt.IsZero() {
+			fmt.Printf("      Uptime: %s | Joined: %s\n",
+				(time.Duration(n.Uptime) * time.Second).String(),
+				n.JoinedAt.Format(time.RFC3339),
+			)
+		}
+		if n.MetricsStale {
+			fmt.Println("      Metrics: unavailable (node did not answer in time)")
+		}
 		fmt.Printf("      Load: %d | Success: %.1f%% | committed=%d aborted=%d failed=%d\n",
 			n.Metrics.InFlight,
 			n.Metrics.SuccessRate,
@@ -357,11 +689,41 @@ func dashboard() {
 			n.Metrics.Aborted,
 			n.Metrics.Failed,
 		)
+		fmt.Printf("      Aborts: voted=%d by-coordinator=%d\n",
+			n.Metrics.AbortedSelf,
+			n.Metrics.AbortedByCoordinator,
+		)
+		if h := n.Metrics.PreparedDuration; h != nil && h.Count > 0 {
+			fmt.Printf("      Prepared: p95=%dms max=%dms (%d resolved)\n",
+				n.Metrics.PreparedDurationP95Ms,
+				h.MaxMs,
+				h.Count,
+			)
+		}
+		if n.Metrics.PreparedXacts > 0 {
+			fmt.Printf("      Postgres prepared xacts: %d (%d stale)\n",
+				n.Metrics.PreparedXacts,
+				n.Metrics.StalePreparedXacts,
+			)
+		}
 	}
 	fmt.Println("")
 }
 
+// findMaster asks the nodes for their view of the master via /cluster/master and
+// falls back to probing each node's role (for nodes without that endpoint).
 func findMaster(client *transport.HTTPClient, nodes []string) string {
+	for _, addr := range nodes {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+
+		if master, err := client.GetMaster(addr); err == nil && master != "" {
+			return master
+		}
+	}
+
 	for _, addr := range nodes {
 		addr = strings.TrimSpace(addr)
 		if addr == "" {
@@ -379,3 +741,202 @@ func findMaster(client *transport.HTTPClient, nodes []string) string {
 	}
 	return ""
 }
+
+func txDetail() {
+	fs := flag.NewFlagSet("tx-detail", flag.ExitOnError)
+	master := fs.String("master", "localhost:8080", "Master address")
+	id := fs.String("id", "", "Transaction ID")
+	fs.Parse(os.Args[2:])
+
+	if *id == "" {
+		log.Fatal("--id is required")
+	}
+
+	client := transport.NewHTTPClient(10 * time.Second)
+	detail, err := client.TransactionDetail(*master, *id)
+	if err != nil {
+		log.Fatalf("Failed to fetch transaction detail: %v", err)
+	}
+
+	addrs := make([]string, 0, len(detail.Nodes))
+	for addr := range detail.Nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	fmt.Printf("Transaction %s\n", detail.TransactionID)
+	fmt.Println("---------------")
+	for _, addr := range addrs {
+		st := detail.Nodes[addr]
+		line := fmt.Sprintf("  %-24s %s", addr, st.Status)
+		if st.Status == "PREPARED" {
+			line += " (in-doubt)"
+		}
+		if st.UpdatedAt != nil {
+			line += " | updated " + st.UpdatedAt.Format(time.RFC3339)
+		}
+		if st.Error != "" {
+			line += " | " + st.Error
+		}
+		fmt.Println(line)
+	}
+}
+
+func reconcile() {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	master := fs.String("master", "localhost:8080", "Master address")
+	id := fs.String("id", "", "Transaction ID")
+	fs.Parse(os.Args[2:])
+
+	if *id == "" {
+		log.Fatal("--id is required")
+	}
+
+	client := transport.NewHTTPClient(10 * time.Second)
+	result, err := client.Reconcile(*master, *id)
+	if err != nil {
+		log.Fatalf("Failed to reconcile transaction: %v", err)
+	}
+
+	addrs := make([]string, 0, len(result.Nodes))
+	for addr := range result.Nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	fmt.Printf("Transaction %s -> %s (%s)\n", result.TransactionID, result.Decision, result.Basis)
+	fmt.Println("---------------")
+	failed := false
+	for _, addr := range addrs {
+		r := result.Nodes[addr]
+		line := fmt.Sprintf("  %-24s %-11s %s", addr, r.Before, r.Action)
+		if r.Error != "" {
+			line += " | " + r.Error
+		}
+		if r.Action == protocol.ReconcileFailed {
+			failed = true
+		}
+		fmt.Println(line)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func planMaster() {
+	fs := flag.NewFlagSet("plan-master", flag.ExitOnError)
+	master := fs.String("master", "localhost:8080", "Master address")
+	user := fs.String("user", "", "Dashboard basic-auth user (optional)")
+	pass := fs.String("pass", "", "Dashboard basic-auth password (optional, fallback DASHBOARD_PASS)")
+	fs.Parse(os.Args[2:])
+
+	password := *pass
+	if password == "" {
+		password = os.Getenv("DASHBOARD_PASS")
+	}
+
+	client := transport.NewHTTPClient(5*time.Second).WithBasicAuth(*user, password)
+	info, err := client.ClusterInfo(*master)
+	if err != nil {
+		log.Fatalf("Failed to fetch cluster info: %v", err)
+	}
+
+	fmt.Print(formatPlan(cluster.PlanElection(info.MasterAddr, cluster.CandidatesFromNodeInfo(info.Nodes))))
+}
+
+// formatPlan renders a simulated election: the outcome, then every candidate
+// in election order with its rank or the reason it is excluded.
+func formatPlan(plan cluster.ElectionPlan) string {
+	var b strings.Builder
+
+	current := plan.Current
+	if current == "" {
+		current = "none"
+	}
+	fmt.Fprintf(&b, "Current master: %s\n", current)
+	if plan.Master == "" {
+		fmt.Fprintf(&b, "Planned master: none (%s)\n", plan.Reason)
+	} else {
+		fmt.Fprintf(&b, "Planned master: %s (%s)\n", plan.Master, plan.Reason)
+	}
+	if plan.Fresh != "" && plan.Fresh != plan.Master {
+		fmt.Fprintf(&b, "A forced election would pick %s instead\n", plan.Fresh)
+	}
+
+	fmt.Fprintln(&b, "---------------")
+	for _, c := range plan.Candidates {
+		status := fmt.Sprintf("#%d", c.Rank)
+		if c.Excluded != "" {
+			status = "excluded: " + c.Excluded
+		}
+		fmt.Fprintf(&b, "  %-24s %-22s in-flight %d\n", c.Address, status, c.InFlight)
+	}
+	fmt.Fprintln(&b, "Priority and load are shown for context; the election ranks eligible nodes by address.")
+
+	return b.String()
+}
+
+func tail() {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	addr := fs.String("addr", "", "Node address to follow")
+	fs.Parse(os.Args[2:])
+
+	if *addr == "" {
+		log.Fatal("--addr is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := transport.NewHTTPClient(5 * time.Second)
+	err := client.StreamTransactions(ctx, *addr, func(ev protocol.TransactionEvent) error {
+		fmt.Println(formatTransactionEvent(ev))
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Fatalf("Transaction stream from %s ended: %v", *addr, err)
+	}
+}
+
+// formatTransactionEvent renders one stream event as a log line.
+func formatTransactionEvent(ev protocol.TransactionEvent) string {
+	line := fmt.Sprintf("%s  %-9s  %s  %s", ev.Time.Format("15:04:05.000"), ev.Status, ev.TxID, ev.Node)
+	if ev.Coordinator != "" {
+		line += "  coordinator=" + ev.Coordinator
+	}
+	if ev.Error != "" {
+		line += "  error=" + ev.Error
+	}
+	return line
+}
+
+func verifyCommitLog() {
+	fs := flag.NewFlagSet("verify-commit-log", flag.ExitOnError)
+	file := fs.String("file", "", "Commit log written by --commit-log")
+	key := fs.String("key", "", "HMAC key the log was written with (fallback COMMIT_LOG_KEY)")
+	fs.Parse(os.Args[2:])
+
+	if *file == "" {
+		log.Fatal("--file is required")
+	}
+	if *key == "" {
+		*key = os.Getenv("COMMIT_LOG_KEY")
+	}
+	if *key == "" {
+		log.Fatal("--key or COMMIT_LOG_KEY is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open commit log: %v", err)
+	}
+	defer f.Close()
+
+	n, err := twophasecommit.VerifyCommitLog(f, []byte(*key))
+	if err != nil {
+		fmt.Printf("✗ %s: %v (%d entries verified before it)\n", *file, err, n)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ %s: %d entries verified\n", *file, n)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+func stubNode(t *testing.T, role, master string) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cluster/master":
+			if master == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(protocol.MasterResponse{MasterAddr: master})
+		case "/role":
+			json.NewEncoder(w).Encode(protocol.RoleResponse{Role: role})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestFindMasterAsksClusterMasterFirst(t *testing.T) {
+	client := transport.NewHTTPClient(time.Second)
+
+	// The first reachable node that knows the master answers in one call.
+	slave := stubNode(t, "SLAVE", "localhost:9000")
+	if got := findMaster(client, []string{"localhost:1", slave}); got != "localhost:9000" {
+		t.Errorf("findMaster = %q, want master reported by /cluster/master", got)
+	}
+
+	// Nodes without a known master fall back to probing roles.
+	unknown := stubNode(t, "SLAVE", "")
+	master := stubNode(t, "MASTER", "")
+	if got := findMaster(client, []string{unknown, master}); got != master {
+		t.Errorf("findMaster = %q, want %q from role probing", got, master)
+	}
+
+	if got := findMaster(client, []string{unknown}); got != "" {
+		t.Errorf("findMaster = %q, want empty when no master is known", got)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// preflightProbe is the raw health data collected from one node.
+type preflightProbe struct {
+	Addr   string
+	Health *protocol.HealthResponse
+	Err    error
+	Skew   time.Duration // node clock minus local clock, corrected for round trip
+}
+
+// preflightCheck is one line of the preflight report.
+type preflightCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// preflightReport is the structured preflight result.
+type preflightReport struct {
+	Passed bool             `json:"passed"`
+	Checks []preflightCheck `json:"checks"`
+}
+
+func preflight() {
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	nodes := fs.String("nodes", "", "Comma-separated list of node addresses")
+	maxSkew := fs.Duration("max-skew", 2*time.Second, "Maximum allowed clock skew between this host and a node")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(os.Args[2:])
+
+	if *nodes == "" {
+		log.Fatal("--nodes is required")
+	}
+
+	client := transport.NewHTTPClient(5 * time.Second)
+
+	var probes []preflightProbe
+	for _, addr := range strings.Split(*nodes, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		probes = append(probes, probeNode(client, addr))
+	}
+
+	report := evaluatePreflight(probes, *maxSkew)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		fmt.Println("Preflight Report:")
+		fmt.Println("-----------------")
+		for _, c := range report.Checks {
+			mark := "✓"
+			if !c.Passed {
+				mark = "✗"
+			}
+			fmt.Printf("  %s %s: %s\n", mark, c.Name, c.Detail)
+		}
+		fmt.Println("")
+		if report.Passed {
+			fmt.Println("PASS")
+		} else {
+			fmt.Println("FAIL")
+		}
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
+
+func probeNode(client *transport.HTTPClient, addr string) preflightProbe {
+	start := time.Now()
+	health, err := client.HealthCheck(addr)
+	rtt := time.Since(start)
+	if err != nil {
+		return preflightProbe{Addr: addr, Err: err}
+	}
+
+	// Assume the server stamped its clock halfway through the round trip.
+	skew := health.Time.Sub(start.Add(rtt / 2))
+	return preflightProbe{Addr: addr, Health: health, Skew: skew}
+}
+
+// evaluatePreflight turns raw probes into pass/fail checks.
+func evaluatePreflight(probes []preflightProbe, maxSkew time.Duration) preflightReport {
+	var checks []preflightCheck
+	var reachable []preflightProbe
+	var masters []string
+
+	for _, p := range probes {
+		if p.Err != nil {
+			checks = append(checks, preflightCheck{
+				Name:   "reachable " + p.Addr,
+				Detail: p.Err.Error(),
+			})
+			continue
+		}
+
+		reachable = append(reachable, p)
+		checks = append(checks, preflightCheck{
+			Name:   "reachable " + p.Addr,
+			Passed: true,
+			Detail: fmt.Sprintf("%s (%s)", p.Health.Status, p.Health.Role),
+		})
+		if p.Health.Role == string(protocol.RoleMaster) {
+			masters = append(masters, p.Addr)
+		}
+	}
+
+	for _, p := range reachable {
+		version := p.Health.Version
+		if version == "" {
+			version = "unknown"
+		}
+		checks = append(checks, preflightCheck{
+			Name:   "version " + p.Addr,
+			Passed: p.Health.Version == protocol.ProtocolVersion,
+			Detail: fmt.Sprintf("protocol %s (expected %s)", version, protocol.ProtocolVersion),
+		})
+	}
+
+	checks = append(checks, preflightCheck{
+		Name:   "single master",
+		Passed: len(masters) == 1,
+		Detail: fmt.Sprintf("%d master(s) %v", len(masters), masters),
+	})
+
+	for _, p := range reachable {
+		db := p.Health.Database
+		if db == "" {
+			db = "unknown"
+		}
+		checks = append(checks, preflightCheck{
+			Name:   "database " + p.Addr,
+			Passed: db == protocol.DatabaseOK || db == protocol.DatabaseNone,
+			Detail: db,
+		})
+	}
+
+	for _, p := range reachable {
+		skew := p.Skew
+		if skew < 0 {
+			skew = -skew
+		}
+		checks = append(checks, preflightCheck{
+			Name:   "clock " + p.Addr,
+			Passed: !p.Health.Time.IsZero() && skew <= maxSkew,
+			Detail: fmt.Sprintf("skew %v (max %v)", p.Skew.Round(time.Millisecond), maxSkew),
+		})
+	}
+
+	passed := len(probes) > 0
+	for _, c := range checks {
+		if !c.Passed {
+			passed = false
+		}
+	}
+
+	return preflightReport{Passed: passed, Checks: checks}
+}
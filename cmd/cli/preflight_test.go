@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func healthyProbe(addr string, role protocol.NodeRole) preflightProbe {
+	return preflightProbe{
+		Addr: addr,
+		Health: &protocol.HealthResponse{
+			Status:   "OK",
+			Address:  addr,
+			Role:     string(role),
+			Version:  protocol.ProtocolVersion,
+			Database: protocol.DatabaseOK,
+			Time:     time.Now(),
+		},
+	}
+}
+
+func failedChecks(report preflightReport) map[string]bool {
+	failed := make(map[string]bool)
+	for _, c := range report.Checks {
+		if !c.Passed {
+			failed[c.Name] = true
+		}
+	}
+	return failed
+}
+
+func TestEvaluatePreflightPasses(t *testing.T) {
+	report := evaluatePreflight([]preflightProbe{
+		healthyProbe("a:1", protocol.RoleMaster),
+		healthyProbe("b:1", protocol.RoleSlave),
+	}, time.Second)
+
+	if !report.Passed {
+		t.Fatalf("Expected preflight to pass, failed checks: %v", failedChecks(report))
+	}
+}
+
+func TestEvaluatePreflightDetectsMisconfiguration(t *testing.T) {
+	secondMaster := healthyProbe("b:1", protocol.RoleMaster)
+	secondMaster.Health.Version = "0"
+	secondMaster.Health.Database = protocol.DatabaseUnreachable
+	secondMaster.Skew = 5 * time.Second
+
+	report := evaluatePreflight([]preflightProbe{
+		healthyProbe("a:1", protocol.RoleMaster),
+		secondMaster,
+		{Addr: "c:1", Err: errors.New("connection refused")},
+	}, time.Second)
+
+	if report.Passed {
+		t.Fatal("Expected preflight to fail")
+	}
+
+	failed := failedChecks(report)
+	for _, name := range []string{"reachable c:1", "version b:1", "single master", "database b:1", "clock b:1"} {
+		if !failed[name] {
+			t.Errorf("Expected check %q to fail, failed checks: %v", name, failed)
+		}
+	}
+	if failed["clock a:1"] || failed["database a:1"] {
+		t.Errorf("Healthy node should pass its checks, failed checks: %v", failed)
+	}
+}
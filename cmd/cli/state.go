@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+)
+
+// stateCommand handles `cli state export|import` for backup and migration.
+func stateCommand() {
+	if len(os.Args) < 3 {
+		log.Fatal("Usage: cli state <export|import> --file=<state file> --key=<key>")
+	}
+
+	sub := os.Args[2]
+	fs := flag.NewFlagSet("state "+sub, flag.ExitOnError)
+	file := fs.String("file", "cluster_state.enc", "Path to the encrypted state file")
+	key := fs.String("key", "", "Encryption key (fallback CLUSTER_STATE_KEY)")
+	in := fs.String("in", "", "Plaintext JSON to import (default stdin)")
+	out := fs.String("out", "", "Write exported JSON here (default stdout)")
+	fs.Parse(os.Args[3:])
+
+	effectiveKey := *key
+	if effectiveKey == "" {
+		effectiveKey = os.Getenv("CLUSTER_STATE_KEY")
+	}
+
+	store := cluster.NewStateStore(*file, effectiveKey)
+	if store == nil {
+		log.Fatal("--file and --key (or CLUSTER_STATE_KEY) are required")
+	}
+
+	switch sub {
+	case "export":
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				log.Fatalf("Failed to open %s: %v", *out, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := store.ExportJSON(w); err != nil {
+			log.Fatalf("Failed to export state: %v", err)
+		}
+	case "import":
+		var r io.Reader = os.Stdin
+		if *in != "" {
+			f, err := os.Open(*in)
+			if err != nil {
+				log.Fatalf("Failed to open %s: %v", *in, err)
+			}
+			defer f.Close()
+			r = f
+		}
+		if err := store.ImportJSON(r); err != nil {
+			log.Fatalf("Failed to import state: %v", err)
+		}
+		fmt.Printf("✓ Imported cluster state into %s\n", *file)
+	default:
+		log.Fatalf("Unknown state command: %s (use export or import)", sub)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// rowCopy is one node's answer to a read by key.
+type rowCopy struct {
+	Addr  string
+	HasDB bool
+	Found bool
+	JSON  string // canonical JSON of the row, "" when not found
+	Err   error
+}
+
+// rowComparison groups the copies of a row by content.
+type rowComparison struct {
+	Reference string    // JSON held by most nodes ("" = the row is missing on most)
+	Agree     []string  // nodes holding the reference
+	Diverge   []rowCopy // nodes holding something else, including no row
+	Failed    []rowCopy // nodes that could not be read
+	Skipped   []string  // nodes without a database
+}
+
+// Consistent reports whether every node that could be read holds the same row.
+func (c rowComparison) Consistent() bool {
+	return len(c.Diverge) == 0 && len(c.Failed) == 0
+}
+
+func verifyRow() {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	master := fs.String("master", "localhost:8080", "Master address, used to list the cluster members")
+	table := fs.String("table", "", "Table holding the row")
+	keyCol := fs.String("key-col", "id", "Column identifying the row")
+	key := fs.String("key", "", "Value of --key-col")
+	user := fs.String("user", "", "Dashboard basic-auth user (optional)")
+	pass := fs.String("pass", "", "Dashboard basic-auth password (optional, fallback DASHBOARD_PASS)")
+	fs.Parse(os.Args[2:])
+
+	if *table == "" || *key == "" {
+		log.Fatal("--table and --key are required")
+	}
+
+	password := *pass
+	if password == "" {
+		password = os.Getenv("DASHBOARD_PASS")
+	}
+
+	client := transport.NewHTTPClient(5*time.Second).WithBasicAuth(*user, password)
+	members, err := client.ClusterNodes(*master)
+	if err != nil {
+		log.Fatalf("Failed to list cluster members: %v", err)
+	}
+
+	addrs := make([]string, 0, len(members.Nodes))
+	for _, n := range members.Nodes {
+		addrs = append(addrs, n.Address)
+	}
+
+	cmp := compareRows(readRowCopies(client, addrs, *table, *keyCol, *key))
+	fmt.Print(formatRowComparison(fmt.Sprintf("%s.%s=%s", *table, *keyCol, *key), cmp))
+	if !cmp.Consistent() {
+		os.Exit(1)
+	}
+}
+
+// readRowCopies reads the row from every node in addrs.
+func readRowCopies(client *transport.HTTPClient, addrs []string, table, keyCol, key string) []rowCopy {
+	copies := make([]rowCopy, len(addrs))
+	for i, addr := range addrs {
+		copies[i] = rowCopy{Addr: addr}
+
+		resp, err := client.ReadRow(addr, table, keyCol, key)
+		if err != nil {
+			copies[i].Err = err
+			continue
+		}
+		copies[i].HasDB, copies[i].Found = resp.HasDB, resp.Found
+		if resp.Found {
+			// encoding/json sorts map keys, so equal rows encode equally.
+			raw, err := json.Marshal(resp.Row)
+			if err != nil {
+				copies[i].Err = err
+				continue
+			}
+			copies[i].JSON = string(raw)
+		}
+	}
+	return copies
+}
+
+// compareRows takes the content held by most nodes as the reference and
+// reports every other node as divergent. Ties go to a present row over a
+// missing one, then to the lowest JSON, so the result is stable.
+func compareRows(copies []rowCopy) rowComparison {
+	var cmp rowComparison
+	holders := make(map[string][]string)
+	for _, c := range copies {
+		switch {
+		case c.Err != nil:
+			cmp.Failed = append(cmp.Failed, c)
+		case !c.HasDB:
+			cmp.Skipped = append(cmp.Skipped, c.Addr)
+		default:
+			holders[c.JSON] = append(holders[c.JSON], c.Addr)
+		}
+	}
+
+	contents := make([]string, 0, len(holders))
+	for content := range holders {
+		contents = append(contents, content)
+	}
+	sort.Slice(contents, func(i, j int) bool {
+		a, b := contents[i], contents[j]
+		if len(holders[a]) != len(holders[b]) {
+			return len(holders[a]) > len(holders[b])
+		}
+		if (a == "") != (b == "") {
+			return a != ""
+		}
+		return a < b
+	})
+	if len(contents) == 0 {
+		return cmp
+	}
+
+	cmp.Reference = contents[0]
+	for _, c := range copies {
+		if c.Err != nil || !c.HasDB {
+			continue
+		}
+		if c.JSON == cmp.Reference {
+			cmp.Agree = append(cmp.Agree, c.Addr)
+		} else {
+			cmp.Diverge = append(cmp.Diverge, c)
+		}
+	}
+	return cmp
+}
+
+// formatRowComparison renders the comparison for the terminal.
+func formatRowComparison(row string, cmp rowComparison) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Row %s\n", row)
+	fmt.Fprintln(&b, "---------------")
+	reference := cmp.Reference
+	if reference == "" {
+		reference = "(no row)"
+	}
+	fmt.Fprintf(&b, "  %d nodes agree: %s\n", len(cmp.Agree), reference)
+	for _, addr := range cmp.Agree {
+		fmt.Fprintf(&b, "    %s\n", addr)
+	}
+	for _, c := range cmp.Diverge {
+		content := c.JSON
+		if content == "" {
+			content = "(no row)"
+		}
+		fmt.Fprintf(&b, "  ✗ %s diverges: %s\n", c.Addr, content)
+	}
+	for _, c := range cmp.Failed {
+		fmt.Fprintf(&b, "  ✗ %s could not be read: %v\n", c.Addr, c.Err)
+	}
+	for _, addr := range cmp.Skipped {
+		fmt.Fprintf(&b, "  - %s has no database\n", addr)
+	}
+
+	if cmp.Consistent() {
+		fmt.Fprintf(&b, "✓ Row is identical on %d nodes\n", len(cmp.Agree))
+	} else {
+		fmt.Fprintf(&b, "✗ %d nodes diverge, %d could not be read\n", len(cmp.Diverge), len(cmp.Failed))
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+func stubRowNode(t *testing.T, resp protocol.RowResponse) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rows" || r.URL.Query().Get("key") != resp.Key {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestVerifyReportsDivergentNode(t *testing.T) {
+	row := func(balance float64) protocol.RowResponse {
+		return protocol.RowResponse{
+			Table: "accounts", KeyColumn: "id", Key: "7",
+			HasDB: true, Found: true,
+			Row: map[string]any{"id": 7.0, "balance": balance},
+		}
+	}
+	a := stubRowNode(t, row(100))
+	b := stubRowNode(t, row(100))
+	divergent := stubRowNode(t, row(90))
+	noDB := stubRowNode(t, protocol.RowResponse{Key: "7"})
+
+	client := transport.NewHTTPClient(time.Second)
+	cmp := compareRows(readRowCopies(client, []string{a, divergent, b, noDB}, "accounts", "id", "7"))
+
+	if cmp.Consistent() {
+		t.Fatal("comparison is consistent, want a divergent node")
+	}
+	if cmp.Reference != `{"balance":100,"id":7}` {
+		t.Errorf("reference = %s, want the majority row", cmp.Reference)
+	}
+	if len(cmp.Agree) != 2 {
+		t.Errorf("agree = %v, want the two matching nodes", cmp.Agree)
+	}
+	if len(cmp.Diverge) != 1 || cmp.Diverge[0].Addr != divergent {
+		t.Errorf("diverge = %+v, want only %s", cmp.Diverge, divergent)
+	}
+	if len(cmp.Skipped) != 1 || cmp.Skipped[0] != noDB {
+		t.Errorf("skipped = %v, want the node without a database", cmp.Skipped)
+	}
+
+	out := formatRowComparison("accounts.id=7", cmp)
+	if !strings.Contains(out, divergent+" diverges") {
+		t.Errorf("output does not name the divergent node:\n%s", out)
+	}
+}
+
+func TestVerifyTreatsMissingRowAsDivergent(t *testing.T) {
+	present := protocol.RowResponse{Key: "7", HasDB: true, Found: true, Row: map[string]any{"id": 7.0}}
+	a := stubRowNode(t, present)
+	b := stubRowNode(t, present)
+	missing := stubRowNode(t, protocol.RowResponse{Key: "7", HasDB: true})
+	down := "localhost:1"
+
+	client := transport.NewHTTPClient(time.Second)
+	cmp := compareRows(readRowCopies(client, []string{a, b, missing, down}, "accounts", "id", "7"))
+
+	if len(cmp.Diverge) != 1 || cmp.Diverge[0].Addr != missing || cmp.Diverge[0].Found {
+		t.Errorf("diverge = %+v, want the node missing the row", cmp.Diverge)
+	}
+	if len(cmp.Failed) != 1 || cmp.Failed[0].Addr != down {
+		t.Errorf("failed = %+v, want the unreachable node", cmp.Failed)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// launchedProcess tracks a node process started by the master.
+type launchedProcess struct {
+	cmd       *exec.Cmd
+	status    protocol.NodeStatus
+	startedAt time.Time
+	exitErr   error
+}
+
+// processTracker remembers auto-started node processes so the cluster summary
+// can report them as STARTING/FAILED while their health check is failing.
+type processTracker struct {
+	mu    sync.Mutex
+	procs map[string]*launchedProcess // address -> process
+}
+
+func newProcessTracker() *processTracker {
+	return &processTracker{
+		procs: make(map[string]*launchedProcess),
+	}
+}
+
+// Start launches cmd for addr and watches it in the background. Any exit of the
+// process is treated as a failure since nodes are expected to run until stopped.
+// Restarting a node replaces its entry, clearing an earlier FAILED status.
+func (t *processTracker) Start(addr string, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		t.mu.Lock()
+		t.procs[addr] = &launchedProcess{
+			cmd:       cmd,
+			status:    protocol.NodeStatusFailed,
+			startedAt: time.Now(),
+			exitErr:   err,
+		}
+		t.mu.Unlock()
+		logging.Warnf("[AutoStart] Node %s failed to start: %v", addr, err)
+		return err
+	}
+
+	proc := &launchedProcess{
+		cmd:       cmd,
+		status:    protocol.NodeStatusStarting,
+		startedAt: time.Now(),
+	}
+
+	t.mu.Lock()
+	t.procs[addr] = proc
+	t.mu.Unlock()
+
+	logging.Infof("[AutoStart] Node %s started (pid %d)", addr, cmd.Process.Pid)
+
+	go func() {
+		err := cmd.Wait()
+		if err == nil {
+			err = fmt.Errorf("process exited")
+		}
+
+		t.mu.Lock()
+		// Only record the exit if this process is still the tracked one.
+		if t.procs[addr] == proc {
+			proc.status = protocol.NodeStatusFailed
+			proc.exitErr = err
+		}
+		t.mu.Unlock()
+
+		logging.Warnf("[AutoStart] Node %s exited after %v: %v", addr, time.Since(proc.startedAt).Round(time.Millisecond), err)
+	}()
+
+	return nil
+}
+
+// Status returns the transient status for addr. A live node reports an empty
+// status, so a node that comes back after a FAILED launch is shown as healthy.
+// Status only reads the tracker; transitions happen in Start and when the
+// process exits.
+func (t *processTracker) Status(addr string, alive bool) (protocol.NodeStatus, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	proc, ok := t.procs[addr]
+	if !ok || alive {
+		return "", ""
+	}
+
+	errMsg := ""
+	if proc.exitErr != nil {
+		errMsg = proc.exitErr.Error()
+	}
+
+	return proc.status, errMsg
+}
+
+// Forget drops tracking for addr (e.g. when the node is removed).
+func (t *processTracker) Forget(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.procs, addr)
+}
+
+// resolveNodeBinary picks the node executable used for auto-start. An explicit
+// path wins; otherwise a "node" binary next to the running master executable is
+// used. An empty result means falling back to `go run ./cmd/node` (dev only).
+func resolveNodeBinary(configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+
+	candidate := filepath.Join(filepath.Dir(exe), "node")
+	if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+		return candidate
+	}
+
+	return ""
+}
+
+// buildNodeCommand assembles the command used to launch a local node process.
+// When binary is empty the node is started via `go run` from the source tree.
+func buildNodeCommand(binary, addr, dsn, name, stateFile, stateKey string, nodes []string) *exec.Cmd {
+	args := []string{fmt.Sprintf("--addr=%s", addr)}
+
+	args = append(args, fmt.Sprintf("--nodes=%s", strings.Join(nodes, ",")))
+	if dsn != "" {
+		args = append(args, fmt.Sprintf("--dsn=%s", dsn))
+	}
+	if name != "" {
+		args = append(args, fmt.Sprintf("--name=%s", name))
+	}
+
+	// Use per-node state file if default.
+	nodeState := stateFile
+	if nodeState == "cluster_state.enc" || nodeState == "" {
+		safeAddr := strings.ReplaceAll(addr, ":", "_")
+		nodeState = fmt.Sprintf("cluster_state_%s.enc", safeAddr)
+	}
+	args = append(args, fmt.Sprintf("--state-file=%s", nodeState))
+	if stateKey != "" {
+		args = append(args, fmt.Sprintf("--state-key=%s", stateKey))
+	}
+
+	var cmd *exec.Cmd
+	if binary != "" {
+		cmd = exec.Command(binary, args...)
+	} else {
+		cmd = exec.Command("go", append([]string{"run", "./cmd/node"}, args...)...)
+	}
+	cmd.Env = append(os.Environ(), fmt.Sprintf("POSTGRES_DSN=%s", dsn))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestProcessTrackerReportsImmediateExit(t *testing.T) {
+	tracker := newProcessTracker()
+
+	// Re-run the test binary without matching any test so it exits right away.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := tracker.Start("localhost:9999", cmd); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, errMsg := tracker.Status("localhost:9999", false)
+		if status == protocol.NodeStatusFailed {
+			if errMsg == "" {
+				t.Error("Expected exit error to be reported")
+			}
+			break
+		}
+		if status != protocol.NodeStatusStarting {
+			t.Fatalf("Unexpected status %q", status)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for process exit to be tracked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A failed launch keeps reporting FAILED until the node actually comes up.
+	if status, _ := tracker.Status("localhost:9999", false); status != protocol.NodeStatusFailed {
+		t.Errorf("Expected FAILED to persist, got %q", status)
+	}
+}
+
+func TestProcessTrackerHidesStatusWhileAlive(t *testing.T) {
+	tracker := newProcessTracker()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	tracker.procs["localhost:9998"] = &launchedProcess{
+		cmd:       cmd,
+		status:    protocol.NodeStatusStarting,
+		startedAt: time.Now(),
+	}
+
+	if status, _ := tracker.Status("localhost:9998", true); status != "" {
+		t.Errorf("Expected no status once alive, got %q", status)
+	}
+	// Reading the status must not change the tracked state.
+	if status, _ := tracker.Status("localhost:9998", false); status != protocol.NodeStatusStarting {
+		t.Errorf("Expected STARTING to be kept, got %q", status)
+	}
+}
+
+func TestProcessTrackerRestartClearsFailed(t *testing.T) {
+	tracker := newProcessTracker()
+
+	if err := tracker.Start("localhost:9996", exec.Command("/nonexistent/2pc-node-binary")); err == nil {
+		t.Fatal("Expected start error for missing binary")
+	}
+	if status, _ := tracker.Status("localhost:9996", false); status != protocol.NodeStatusFailed {
+		t.Fatalf("Expected FAILED status, got %q", status)
+	}
+	if status, _ := tracker.Status("localhost:9996", true); status != "" {
+		t.Errorf("Expected a node that came back to report no status, got %q", status)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProcessTrackerHelperProcess$")
+	cmd.Env = append(os.Environ(), "TWOPC_TRACKER_HELPER=1")
+	if err := tracker.Start("localhost:9996", cmd); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	status, errMsg := tracker.Status("localhost:9996", false)
+	if status != protocol.NodeStatusStarting || errMsg != "" {
+		t.Errorf("Expected restart to reset to STARTING, got %q (%q)", status, errMsg)
+	}
+}
+
+// TestProcessTrackerHelperProcess stands in for a node process that keeps
+// running; it only does so when launched by the tests above.
+func TestProcessTrackerHelperProcess(t *testing.T) {
+	if os.Getenv("TWOPC_TRACKER_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	time.Sleep(time.Minute)
+}
+
+func TestProcessTrackerStartError(t *testing.T) {
+	tracker := newProcessTracker()
+
+	cmd := exec.Command("/nonexistent/2pc-node-binary")
+	if err := tracker.Start("localhost:9997", cmd); err == nil {
+		t.Fatal("Expected start error for missing binary")
+	}
+
+	if status, _ := tracker.Status("localhost:9997", false); status != protocol.NodeStatusFailed {
+		t.Errorf("Expected FAILED status, got %q", status)
+	}
+}
+
+func TestBuildNodeCommandUsesBinary(t *testing.T) {
+	cmd := buildNodeCommand("/opt/2pc/bin/node", "localhost:8083", "postgres://db", "Shard-3", "", "", []string{"localhost:8080", "localhost:8083"})
+
+	if cmd.Path != "/opt/2pc/bin/node" {
+		t.Errorf("Expected binary path /opt/2pc/bin/node, got %s", cmd.Path)
+	}
+	if cmd.Args[0] != "/opt/2pc/bin/node" {
+		t.Errorf("Expected argv[0] to be the binary, got %s", cmd.Args[0])
+	}
+	for _, arg := range cmd.Args {
+		if arg == "run" || arg == "./cmd/node" {
+			t.Errorf("Binary launch should not use go run, got args %v", cmd.Args)
+		}
+	}
+	if cmd.Args[1] != "--addr=localhost:8083" {
+		t.Errorf("Expected first flag to be --addr, got %s", cmd.Args[1])
+	}
+}
+
+func TestBuildNodeCommandFallsBackToGoRun(t *testing.T) {
+	cmd := buildNodeCommand("", "localhost:8083", "", "", "", "", []string{"localhost:8080"})
+
+	if len(cmd.Args) < 3 || cmd.Args[0] != "go" || cmd.Args[1] != "run" || cmd.Args[2] != "./cmd/node" {
+		t.Errorf("Expected go run ./cmd/node fallback, got %v", cmd.Args)
+	}
+}
+
+func TestResolveNodeBinaryPrefersConfigured(t *testing.T) {
+	if got := resolveNodeBinary("/custom/node"); got != "/custom/node" {
+		t.Errorf("Expected configured binary, got %s", got)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+const (
+	joinMinBackoff = 500 * time.Millisecond
+	joinMaxBackoff = 10 * time.Second
+)
+
+// errJoinStopped is returned by Join when the joiner is stopped mid-retry.
+var errJoinStopped = errors.New("join stopped")
+
+// joinClient is the part of transport.HTTPClient the join loop needs.
+type joinClient interface {
+	Join(addr string, req *protocol.JoinRequest) (*protocol.JoinResponse, error)
+	ClusterNodes(addr string) (*protocol.ClusterInfoResponse, error)
+}
+
+// joiner registers this node with the cluster through --join. A join that
+// fails is retried with exponential backoff, and once joined the node checks
+// periodically that the master still lists it, joining again if it was dropped
+// (for instance by a master restarted with stale membership).
+type joiner struct {
+	client  joinClient
+	self    string
+	seed    string        // --join address, used until a master is known
+	timeout time.Duration // give up a join attempt loop after this long (0 = never)
+	check   time.Duration // membership check interval (0 = no checks)
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// onJoined is called with every successful join response.
+	onJoined func(*protocol.JoinResponse)
+	// isMaster reports whether this node is master; a master never rejoins.
+	isMaster func() bool
+
+	mu     sync.Mutex
+	master string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newJoiner(client joinClient, self, seed string, timeout, check time.Duration) *joiner {
+	return &joiner{
+		client:     client,
+		self:       self,
+		seed:       seed,
+		timeout:    timeout,
+		check:      check,
+		minBackoff: joinMinBackoff,
+		maxBackoff: joinMaxBackoff,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// target returns the address to join through: the last known master, or the
+// --join address before one is known.
+func (j *joiner) target() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.master != "" && !cluster.SameAddr(j.master, j.self) {
+		return j.master
+	}
+	return j.seed
+}
+
+func (j *joiner) setMaster(addr string) {
+	j.mu.Lock()
+	j.master = addr
+	j.mu.Unlock()
+}
+
+// Join registers the node, retrying with backoff until a join succeeds, the
+// timeout elapses or the joiner is stopped. A failed attempt through the last
+// known master falls back to the --join address on the next try.
+func (j *joiner) Join() error {
+	var deadline time.Time
+	if j.timeout > 0 {
+		deadline = time.Now().Add(j.timeout)
+	}
+
+	backoff := j.minBackoff
+	for attempt := 1; ; attempt++ {
+		target := j.target()
+		resp, err := j.client.Join(target, &protocol.JoinRequest{Address: j.self})
+		if err == nil {
+			if resp.MasterAddr != "" {
+				j.setMaster(resp.MasterAddr)
+			}
+			if j.onJoined != nil {
+				j.onJoined(resp)
+			}
+			logging.Infof("[Join] Joined the cluster through %s (master %s)", target, resp.MasterAddr)
+			return nil
+		}
+
+		j.setMaster("")
+		if !deadline.IsZero() && time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("join through %s failed after %d attempts: %w", target, attempt, err)
+		}
+		logging.Warnf("[Join] Attempt %d through %s failed: %v; retrying in %v", attempt, target, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-j.stopCh:
+			return errJoinStopped
+		}
+		backoff = min(backoff*2, j.maxBackoff)
+	}
+}
+
+// Start begins the background membership checks
+func (j *joiner) Start() {
+	if j.check <= 0 {
+		return
+	}
+	j.wg.Add(1)
+	go j.run()
+	logging.Infof("[Join] Checking membership every %v", j.check)
+}
+
+// Stop stops the membership checks and any join in progress
+func (j *joiner) Stop() {
+	close(j.stopCh)
+	j.wg.Wait()
+}
+
+func (j *joiner) run() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.check)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.checkOnce()
+		case <-j.stopCh:
+			return
+		}
+	}
+}
+
+// checkOnce joins again if the master's membership no longer lists this node.
+// An unreachable master is left to the heartbeat and election; only a master
+// that answers without this node counts as exclusion.
+func (j *joiner) checkOnce() {
+	if j.isMaster != nil && j.isMaster() {
+		return
+	}
+
+	target := j.target()
+	info, err := j.client.ClusterNodes(target)
+	if err != nil {
+		logging.Debugf("[Join] Membership check through %s failed: %v", target, err)
+		return
+	}
+	if info.MasterAddr != "" {
+		j.setMaster(info.MasterAddr)
+	}
+
+	for _, n := range info.Nodes {
+		if cluster.SameAddr(n.Address, j.self) {
+			return
+		}
+	}
+
+	logging.Warnf("[Join] %s does not list this node as a member; joining again", target)
+	if err := j.Join(); err != nil && !errors.Is(err, errJoinStopped) {
+		logging.Errorf("[Join] Rejoin failed: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// masterStub refuses the first refuse joins, then accepts and records members.
+type masterStub struct {
+	mu      sync.Mutex
+	refuse  int
+	joins   int
+	members []string
+}
+
+func (m *masterStub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cluster/join", func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.JoinRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.joins++
+		if m.joins <= m.refuse {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		m.members = append(m.members, req.Address)
+		json.NewEncoder(w).Encode(protocol.JoinResponse{
+			Success:      true,
+			MasterAddr:   r.Host,
+			ClusterNodes: append([]string{r.Host}, m.members...),
+		})
+	})
+	mux.HandleFunc("/cluster/nodes", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		info := protocol.ClusterInfoResponse{MasterAddr: r.Host}
+		for _, addr := range m.members {
+			info.Nodes = append(info.Nodes, protocol.NodeInfo{Address: addr})
+		}
+		json.NewEncoder(w).Encode(info)
+	})
+	return mux
+}
+
+func (m *masterStub) counts() (joins int, members []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.joins, append([]string(nil), m.members...)
+}
+
+func newTestJoiner(addr string, timeout time.Duration) *joiner {
+	j := newJoiner(transport.NewHTTPClient(time.Second), "localhost:8082", addr, timeout, 0)
+	j.minBackoff = 10 * time.Millisecond
+	j.maxBackoff = 20 * time.Millisecond
+	return j
+}
+
+func TestJoinRetriesUntilMasterAccepts(t *testing.T) {
+	stub := &masterStub{refuse: 3}
+	srv := httptest.NewServer(stub.handler())
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	j := newTestJoiner(addr, 5*time.Second)
+	var joined *protocol.JoinResponse
+	j.onJoined = func(resp *protocol.JoinResponse) { joined = resp }
+
+	if err := j.Join(); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if joins, members := stub.counts(); joins != 4 || len(members) != 1 {
+		t.Errorf("Expected 3 refusals and then one accepted join, got %d attempts and members %v", joins, members)
+	}
+	if joined == nil || joined.MasterAddr != addr {
+		t.Errorf("Expected onJoined with master %s, got %+v", addr, joined)
+	}
+}
+
+func TestJoinGivesUpAfterTimeout(t *testing.T) {
+	stub := &masterStub{refuse: 1 << 30}
+	srv := httptest.NewServer(stub.handler())
+	defer srv.Close()
+
+	j := newTestJoiner(strings.TrimPrefix(srv.URL, "http://"), 100*time.Millisecond)
+	if err := j.Join(); err == nil {
+		t.Fatal("Expected Join to fail once the timeout elapses")
+	}
+	if joins, _ := stub.counts(); joins < 2 {
+		t.Errorf("Expected several attempts before giving up, got %d", joins)
+	}
+}
+
+func TestJoinAgainAfterBeingDropped(t *testing.T) {
+	stub := &masterStub{refuse: 1}
+	srv := httptest.NewServer(stub.handler())
+	defer srv.Close()
+
+	j := newTestJoiner(strings.TrimPrefix(srv.URL, "http://"), 5*time.Second)
+	if err := j.Join(); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	// Still a member: nothing to do.
+	j.checkOnce()
+	if joins, _ := stub.counts(); joins != 2 {
+		t.Fatalf("Expected no rejoin while listed, got %d join attempts", joins)
+	}
+
+	// The master restarts with stale membership and refuses once more.
+	stub.mu.Lock()
+	stub.members = nil
+	stub.refuse = 3
+	stub.mu.Unlock()
+
+	j.checkOnce()
+	if joins, members := stub.counts(); joins != 4 || len(members) != 1 || members[0] != "localhost:8082" {
+		t.Errorf("Expected the dropped node to rejoin after a refusal, got %d attempts and members %v", joins, members)
+	}
+
+	// A master never rejoins anyone.
+	stub.mu.Lock()
+	stub.members = nil
+	stub.mu.Unlock()
+	j.isMaster = func() bool { return true }
+	j.checkOnce()
+	if joins, _ := stub.counts(); joins != 4 {
+		t.Errorf("Expected no rejoin while master, got %d join attempts", joins)
+	}
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+)
+
+// CanonicalAddr normalizes a host:port address so equivalent spellings of the
+// same endpoint compare equal. See node.CanonicalAddr, which nodes use to
+// fence decisions without importing this package.
+func CanonicalAddr(addr string) string {
+	return node.CanonicalAddr(addr)
+}
+
+// SameAddr reports whether a and b name the same endpoint after canonicalization.
+func SameAddr(a, b string) bool {
+	return node.SameAddr(a, b)
+}
+
+// IsWildcardAddr reports whether addr binds every interface (e.g. "0.0.0.0:8081",
+// "[::]:8081" or ":8081") and therefore cannot identify a node to its peers.
+func IsWildcardAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if err != nil {
+		return false
+	}
+	if host == "" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsUnspecified()
+}
+
+// AdvertisedAddr picks the address a node is known by: advertise when set,
+// otherwise the bind address. A wildcard identity is rejected because peers
+// cannot dial it.
+func AdvertisedAddr(bind, advertise string) (string, error) {
+	advertise = strings.TrimSpace(advertise)
+	if advertise == "" {
+		if IsWildcardAddr(bind) {
+			return "", fmt.Errorf("bind address %s is a wildcard; set --advertise-addr to a routable address", bind)
+		}
+		return bind, nil
+	}
+	if IsWildcardAddr(advertise) {
+		return "", fmt.Errorf("advertise address %s is a wildcard; peers cannot reach it", advertise)
+	}
+	return advertise, nil
+}
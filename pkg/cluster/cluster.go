@@ -1,18 +1,45 @@
 package cluster
 
 import (
+	"errors"
+	"maps"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
 
+var (
+	// ErrNodeNotFound means the address is not a cluster member.
+	ErrNodeNotFound error = protocol.NewAPIError(protocol.ErrCodeNodeNotFound, "node not found")
+	// ErrDisableMaster means the current master cannot be disabled.
+	ErrDisableMaster = errors.New("cannot disable the current master")
+)
+
 // Cluster manages a collection of nodes
 type Cluster struct {
 	mu     sync.RWMutex
-	nodes  map[string]*node.Node // address -> node
+	nodes  map[string]*node.Node // canonical address -> node
 	master *node.Node
+
+	// electing is set while an election runs. It is readable without c.mu,
+	// which the election holds.
+	electing atomic.Bool
+
+	elections          int
+	lastElectionAt     time.Time
+	lastElectionReason string
+	masterLostReason   string // why the master was cleared; attributed to the next election
+
+	multipleMasters     int       // times two or more masters were observed
+	multipleMastersAt   time.Time // when that last began
+	multipleMastersSeen []string  // addresses claiming MASTER in the latest scan, if more than one
+
+	keyspace *Keyspace // partition-key ranges per coordinator (nil = the master coordinates everything)
 }
 
 // NewCluster creates a new cluster
@@ -22,11 +49,68 @@ func NewCluster() *Cluster {
 	}
 }
 
-// AddNode adds a node to the cluster
+// AddNode adds a node to the cluster. Addresses are compared in canonical form
+// (see CanonicalAddr); if an equivalent address is already a member, the
+// existing node is kept and n is ignored.
 func (c *Cluster) AddNode(n *node.Node) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.nodes[n.Addr] = n
+
+	key := CanonicalAddr(n.Addr)
+	if existing, ok := c.nodes[key]; ok && existing != n {
+		logging.Warnf("[Cluster] Ignoring %s: already a member as %s", n.Addr, existing.Addr)
+		return
+	}
+
+	n.MarkJoined(time.Now())
+	c.nodes[key] = n
+}
+
+// NodeMeta is the operator-supplied metadata of a member. Empty fields leave
+// the member's current value in place.
+type NodeMeta struct {
+	Name     string
+	Database string
+	Labels   map[string]string
+}
+
+// apply copies the non-empty fields of m onto n and reports whether any of
+// them changed.
+func (m NodeMeta) apply(n *node.Node) bool {
+	changed := false
+	if m.Name != "" && m.Name != n.GetName() {
+		n.SetName(m.Name)
+		changed = true
+	}
+	if m.Database != "" && m.Database != n.GetDatabase() {
+		n.SetDatabase(m.Database)
+		changed = true
+	}
+	if len(m.Labels) > 0 && !maps.Equal(m.Labels, n.GetLabels()) {
+		n.SetLabels(m.Labels)
+		changed = true
+	}
+	return changed
+}
+
+// UpsertNode adds n with meta, or, when its address already belongs to a
+// member, updates that member's metadata in place and leaves its liveness,
+// role, join time and counters untouched. It returns the member and whether
+// n was added.
+func (c *Cluster) UpsertNode(n *node.Node, meta NodeMeta) (*node.Node, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := CanonicalAddr(n.Addr)
+	if existing, ok := c.nodes[key]; ok {
+		meta.apply(existing)
+		return existing, false
+	}
+
+	meta.apply(n)
+	n.MarkJoined(time.Now())
+	c.nodes[key] = n
+	return n, true
 }
 
 // RemoveNode removes a node from the cluster
@@ -34,11 +118,18 @@ func (c *Cluster) RemoveNode(addr string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if n, exists := c.nodes[addr]; exists {
+	c.removeLocked(CanonicalAddr(addr))
+}
+
+// removeLocked removes the member under the canonical address key, clearing
+// the master if it was the one. Callers must hold c.mu.
+func (c *Cluster) removeLocked(key string) {
+	if n, exists := c.nodes[key]; exists {
 		if c.master == n {
 			c.master = nil
+			c.masterLostReason = protocol.ElectionReasonMasterRemoved
 		}
-		delete(c.nodes, addr)
+		delete(c.nodes, key)
 	}
 }
 
@@ -47,7 +138,7 @@ func (c *Cluster) GetNode(addr string) *node.Node {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return c.nodes[addr]
+	return c.nodes[CanonicalAddr(addr)]
 }
 
 // GetNodes returns all nodes in the cluster
@@ -77,14 +168,14 @@ func (c *Cluster) GetAliveNodes() []*node.Node {
 	return nodes
 }
 
-// GetSlaveNodes returns all alive slave nodes
+// GetSlaveNodes returns all eligible (alive, enabled, not in maintenance) slave nodes
 func (c *Cluster) GetSlaveNodes() []*node.Node {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	nodes := make([]*node.Node, 0)
 	for _, n := range c.nodes {
-		if n.GetAlive() && n.GetRole() == protocol.RoleSlave {
+		if n.Selectable() && n.GetRole() == protocol.RoleSlave {
 			nodes = append(nodes, n)
 		}
 	}
@@ -121,8 +212,8 @@ func (c *Cluster) GetNodeAddresses() []string {
 	defer c.mu.RUnlock()
 
 	addrs := make([]string, 0, len(c.nodes))
-	for addr := range c.nodes {
-		addrs = append(addrs, addr)
+	for _, n := range c.nodes {
+		addrs = append(addrs, n.Addr)
 	}
 
 	sort.Strings(addrs)
@@ -155,7 +246,7 @@ func (c *Cluster) SetNodeName(addr, name string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	n, ok := c.nodes[addr]
+	n, ok := c.nodes[CanonicalAddr(addr)]
 	if !ok {
 		return false
 	}
@@ -163,3 +254,35 @@ func (c *Cluster) SetNodeName(addr, name string) bool {
 	n.SetName(name)
 	return true
 }
+
+// DisableNode excludes a member from transactions and election without removing
+// it from the cluster. The current master cannot be disabled.
+func (c *Cluster) DisableNode(addr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.nodes[CanonicalAddr(addr)]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if c.master == n {
+		return ErrDisableMaster
+	}
+
+	n.SetDisabled(true)
+	return nil
+}
+
+// EnableNode makes a disabled member eligible for transactions and election again.
+func (c *Cluster) EnableNode(addr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.nodes[CanonicalAddr(addr)]
+	if !ok {
+		return ErrNodeNotFound
+	}
+
+	n.SetDisabled(false)
+	return nil
+}
@@ -1,10 +1,16 @@
 package cluster
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
 )
 
 func TestClusterAddRemoveNode(t *testing.T) {
@@ -180,3 +186,527 @@ func TestGetSlaveNodes(t *testing.T) {
 		}
 	}
 }
+
+func TestAddNodeRecordsJoinTime(t *testing.T) {
+	c := NewCluster()
+
+	n := node.NewNode("localhost:8081", protocol.RoleSlave)
+	if !n.GetJoinedAt().IsZero() {
+		t.Fatal("Expected join time to be unset before AddNode")
+	}
+
+	c.AddNode(n)
+	joined := n.GetJoinedAt()
+	if joined.IsZero() {
+		t.Fatal("Expected AddNode to record join time")
+	}
+
+	// Re-adding the same node keeps the original join time.
+	c.AddNode(n)
+	if !n.GetJoinedAt().Equal(joined) {
+		t.Error("Expected join time to be preserved on re-add")
+	}
+}
+
+func TestDisableNodeExcludesFromTransactionsAndElection(t *testing.T) {
+	c := NewCluster()
+
+	n1 := node.NewNode("localhost:8081", protocol.RoleMaster)
+	n2 := node.NewNode("localhost:8082", protocol.RoleSlave)
+	n3 := node.NewNode("localhost:8083", protocol.RoleSlave)
+	c.AddNode(n1)
+	c.AddNode(n2)
+	c.AddNode(n3)
+	c.SetMaster(n1)
+
+	if err := c.DisableNode(n1.Addr); !errors.Is(err, ErrDisableMaster) {
+		t.Errorf("Expected ErrDisableMaster, got %v", err)
+	}
+	if err := c.DisableNode("localhost:9999"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+
+	if err := c.DisableNode(n2.Addr); err != nil {
+		t.Fatalf("DisableNode failed: %v", err)
+	}
+
+	if c.Size() != 3 || c.GetNode(n2.Addr) == nil {
+		t.Error("Expected disabled node to remain a member")
+	}
+	slaves := c.GetSlaveNodes()
+	if len(slaves) != 1 || slaves[0].Addr != n3.Addr {
+		t.Errorf("Expected only %s as slave, got %v", n3.Addr, slaves)
+	}
+
+	// With the master gone the disabled node must be skipped in election.
+	n1.SetAlive(false)
+	c.CheckAndElect()
+	if master := c.GetMaster(); master == nil || master.Addr != n3.Addr {
+		t.Errorf("Expected %s to be elected over disabled node, got %v", n3.Addr, master)
+	}
+	if c.ShouldBeMaster(n2.Addr) {
+		t.Error("Disabled node should not be master")
+	}
+
+	if err := c.EnableNode(n2.Addr); err != nil {
+		t.Fatalf("EnableNode failed: %v", err)
+	}
+	if !c.ShouldBeMaster(n2.Addr) {
+		t.Error("Expected re-enabled node to be eligible for election again")
+	}
+}
+
+func TestCanonicalAddr(t *testing.T) {
+	tests := map[string]string{
+		"localhost:8081":          "localhost:8081",
+		" LocalHost:8081 ":        "localhost:8081",
+		"127.0.0.1:8081":          "localhost:8081",
+		"127.0.0.2:8081":          "127.0.0.2:8081",
+		"[::ffff:127.0.0.1]:8081": "localhost:8081",
+		"[::1]:8081":              "localhost:8081",
+		"[::ffff:10.0.0.5]:8081":  "10.0.0.5:8081",
+		"[2001:DB8::0001]:8081":   "[2001:db8::1]:8081",
+		"Node-1.Example.COM.:443": "node-1.example.com:443",
+		"no-port":                 "no-port",
+		"http://127.0.0.1:8081/":  "localhost:8081",
+	}
+
+	for in, want := range tests {
+		if got := CanonicalAddr(in); got != want {
+			t.Errorf("CanonicalAddr(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if !SameAddr("127.0.0.1:8081", "localhost:8081") || SameAddr("localhost:8081", "localhost:8082") {
+		t.Error("SameAddr must match loopback aliases on the same port only")
+	}
+	if SameAddr("127.0.0.2:8081", "localhost:8081") || SameAddr("127.0.0.2:8081", "127.0.0.3:8081") {
+		t.Error("SameAddr must keep other 127.0.0.0/8 addresses apart")
+	}
+}
+
+func TestAddNodeDedupesEquivalentAddresses(t *testing.T) {
+	c := NewCluster()
+
+	local := node.NewNode("localhost:8081", protocol.RoleMaster)
+	c.AddNode(local)
+	c.AddNode(node.NewNode("127.0.0.1:8081", protocol.RoleSlave))
+	c.AddNode(node.NewNode("[::1]:8081", protocol.RoleSlave))
+	c.AddNode(node.NewNode("localhost:8082", protocol.RoleSlave))
+
+	if c.Size() != 2 {
+		t.Fatalf("Expected equivalent addresses to collapse into 2 members, got %d: %v", c.Size(), c.GetNodeAddresses())
+	}
+	if got := c.GetNode("127.0.0.1:8081"); got != local {
+		t.Error("Expected lookup by equivalent address to return the first-added node")
+	}
+
+	c.SetMaster(local)
+	if slaves := c.GetSlaveNodes(); len(slaves) != 1 || slaves[0].Addr != "localhost:8082" {
+		t.Errorf("Expected a single slave localhost:8082, got %v", slaves)
+	}
+
+	c.RemoveNode("127.0.0.1:8081")
+	if c.GetNode("localhost:8081") != nil {
+		t.Error("Expected removal by equivalent address to remove the member")
+	}
+}
+
+func TestUpsertNodeUpdatesMetadataInPlace(t *testing.T) {
+	c := NewCluster()
+
+	n := node.NewNode("localhost:8082", protocol.RoleSlave)
+	member, added := c.UpsertNode(n, NodeMeta{Name: "db-a", Database: "pg-1"})
+	if !added || member != n {
+		t.Fatal("Expected the first upsert to add the node")
+	}
+	joined := n.GetJoinedAt()
+	n.SetAlive(false)
+	n.SetDisabled(true)
+	n.SetMaxPrepared(7)
+
+	// Re-adding by an equivalent address updates the existing member only.
+	member, added = c.UpsertNode(node.NewNode("127.0.0.1:8082", protocol.RoleSlave), NodeMeta{
+		Database: "pg-2",
+		Labels:   map[string]string{"zone": "eu-1"},
+	})
+	if added || member != n {
+		t.Fatal("Expected re-adding to update the existing member, not replace it")
+	}
+	if c.Size() != 1 {
+		t.Fatalf("Expected one member, got %d", c.Size())
+	}
+
+	if n.GetName() != "db-a" || n.GetDatabase() != "pg-2" || n.GetLabels()["zone"] != "eu-1" {
+		t.Errorf("Unexpected metadata: name=%q database=%q labels=%v", n.GetName(), n.GetDatabase(), n.GetLabels())
+	}
+	if n.GetAlive() || !n.GetDisabled() {
+		t.Error("Expected liveness and disabled state to survive re-adding")
+	}
+	if got := n.Metrics().MaxPrepared; got != 7 {
+		t.Errorf("Expected metrics to survive re-adding, max_prepared = %d", got)
+	}
+	if !n.GetJoinedAt().Equal(joined) {
+		t.Error("Expected the join time to survive re-adding")
+	}
+}
+
+func TestElectionInProgress(t *testing.T) {
+	c := NewCluster()
+	if c.ElectionInProgress() {
+		t.Error("Expected no election in an empty cluster")
+	}
+
+	a := node.NewNode("localhost:8081", protocol.RoleSlave)
+	b := node.NewNode("localhost:8082", protocol.RoleSlave)
+	c.AddNode(a)
+	c.AddNode(b)
+	if !c.ElectionInProgress() {
+		t.Error("Expected a masterless cluster with alive nodes to report an election")
+	}
+
+	c.CheckAndElect()
+	if c.ElectionInProgress() {
+		t.Error("Expected no election once a master is elected")
+	}
+
+	a.SetAlive(false)
+	c.EvictMaster()
+	if !c.ElectionInProgress() {
+		t.Error("Expected an election after the master was evicted")
+	}
+	c.CheckAndElect()
+	if c.ElectionInProgress() || c.GetMaster() != b {
+		t.Errorf("Expected %s elected and the election finished, got %v", b.Addr, c.GetMaster())
+	}
+
+	b.SetAlive(false)
+	c.CheckAndElect()
+	if c.ElectionInProgress() {
+		t.Error("Expected no election when no node is alive")
+	}
+}
+
+func TestElectionStatsCountFailovers(t *testing.T) {
+	c := NewCluster()
+	nodes := []*node.Node{
+		node.NewNode("localhost:8081", protocol.RoleSlave),
+		node.NewNode("localhost:8082", protocol.RoleSlave),
+		node.NewNode("localhost:8083", protocol.RoleSlave),
+	}
+	for _, n := range nodes {
+		c.AddNode(n)
+	}
+
+	if stats := c.ElectionStats(); stats.Count != 0 || stats.LastAt != nil {
+		t.Fatalf("Expected no elections yet, got %+v", stats)
+	}
+
+	c.CheckAndElect()
+	if stats := c.ElectionStats(); stats.Count != 1 || stats.LastReason != protocol.ElectionReasonInitial || stats.LastAt == nil {
+		t.Fatalf("Expected one initial election, got %+v", stats)
+	}
+
+	// A healthy master is kept and does not count as an election.
+	c.CheckAndElect()
+	if got := c.ElectionStats().Count; got != 1 {
+		t.Fatalf("Expected no election while the master is alive, got %d", got)
+	}
+
+	nodes[0].SetAlive(false)
+	c.CheckAndElect()
+	if stats := c.ElectionStats(); stats.Count != 2 || stats.LastReason != protocol.ElectionReasonMasterDied {
+		t.Fatalf("Expected a failover after the master died, got %+v", stats)
+	}
+
+	c.RemoveNode(nodes[1].Addr)
+	c.CheckAndElect()
+	stats := c.ElectionStats()
+	if stats.Count != 3 || stats.LastReason != protocol.ElectionReasonMasterRemoved {
+		t.Fatalf("Expected a failover after the master was removed, got %+v", stats)
+	}
+	if m := c.GetMaster(); m != nodes[2] {
+		t.Errorf("Expected %s as master, got %v", nodes[2].Addr, m)
+	}
+
+	c.ElectMaster()
+	if stats := c.ElectionStats(); stats.Count != 4 || stats.LastReason != protocol.ElectionReasonManual {
+		t.Errorf("Expected a manual election, got %+v", stats)
+	}
+}
+
+func TestAdvertisedAddr(t *testing.T) {
+	tests := []struct {
+		bind, advertise string
+		want            string
+		wantErr         bool
+	}{
+		{bind: "localhost:8081", want: "localhost:8081"},
+		{bind: "0.0.0.0:8081", advertise: "10.0.0.5:8081", want: "10.0.0.5:8081"},
+		{bind: "[::]:8081", advertise: " node-1:8081 ", want: "node-1:8081"},
+		{bind: ":8081", advertise: "10.0.0.5:8081", want: "10.0.0.5:8081"},
+		{bind: "0.0.0.0:8081", wantErr: true},
+		{bind: ":8081", wantErr: true},
+		{bind: "10.0.0.5:8081", advertise: "0.0.0.0:8081", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		got, err := AdvertisedAddr(tc.bind, tc.advertise)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("AdvertisedAddr(%q, %q) = %q, want error", tc.bind, tc.advertise, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("AdvertisedAddr(%q, %q) = %q, %v; want %q", tc.bind, tc.advertise, got, err, tc.want)
+		}
+	}
+}
+
+func TestNodeDetailLocalAndRemote(t *testing.T) {
+	local := node.NewNode("localhost:8080", protocol.RoleMaster)
+	local.SetLabels(map[string]string{"zone": "a"})
+	local.SetPriority(3)
+	if ready, err := local.Prepare("tx-in-doubt", map[string]any{"k": "v"}); !ready || err != nil {
+		t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+	}
+
+	remoteNode := node.NewNode("placeholder", protocol.RoleSlave)
+	srv := httptest.NewServer(transport.NewHTTPServer(remoteNode).Handler())
+	defer srv.Close()
+	remoteNode.Addr = srv.Listener.Addr().String()
+
+	c := NewCluster()
+	c.AddNode(local)
+	c.AddNode(node.NewNode(remoteNode.Addr, protocol.RoleSlave))
+	client := transport.NewHTTPClient(time.Second)
+
+	d := c.NodeDetail(local.Addr, local, client)
+	if d == nil || d.Priority != 3 || d.Labels["zone"] != "a" || d.InDoubt == nil || *d.InDoubt != 1 {
+		t.Errorf("Unexpected local detail: %+v", d)
+	}
+
+	d = c.NodeDetail(remoteNode.Addr, local, client)
+	if d == nil || d.Version != protocol.ProtocolVersion || d.ClockSkewMs == nil || d.InDoubt == nil || *d.InDoubt != 0 {
+		t.Errorf("Unexpected remote detail: %+v", d)
+	}
+
+	if d := c.NodeDetail("localhost:9999", local, client); d != nil {
+		t.Errorf("Expected nil detail for a non-member, got %+v", d)
+	}
+}
+
+func TestMaintenanceExcludesNodeAndStepsDownMaster(t *testing.T) {
+	c := NewCluster()
+
+	n1 := node.NewNode("localhost:8081", protocol.RoleMaster)
+	n2 := node.NewNode("localhost:8082", protocol.RoleSlave)
+	n3 := node.NewNode("localhost:8083", protocol.RoleSlave)
+	c.AddNode(n1)
+	c.AddNode(n2)
+	c.AddNode(n3)
+	c.SetMaster(n1)
+
+	n2.SetMaintenance(true)
+	slaves := c.GetSlaveNodes()
+	if len(slaves) != 1 || slaves[0].Addr != n3.Addr {
+		t.Errorf("Expected only %s as slave, got %v", n3.Addr, slaves)
+	}
+
+	// The master in maintenance hands over to the next eligible node, skipping n2.
+	n1.SetMaintenance(true)
+	if !c.CheckAndElect() {
+		t.Fatal("Expected a master in maintenance to step down")
+	}
+	if master := c.GetMaster(); master == nil || master.Addr != n3.Addr {
+		t.Errorf("Expected %s to take over, got %v", n3.Addr, master)
+	}
+	if stats := c.ElectionStats(); stats.LastReason != protocol.ElectionReasonMaintenance {
+		t.Errorf("LastReason = %q, want %q", stats.LastReason, protocol.ElectionReasonMaintenance)
+	}
+	if c.Size() != 3 || !n1.GetAlive() {
+		t.Error("Expected nodes in maintenance to stay alive members")
+	}
+
+	// With nobody left to take over, the last master stays.
+	n3.SetMaintenance(true)
+	if c.CheckAndElect() {
+		t.Error("Expected no election without an eligible successor")
+	}
+	if master := c.GetMaster(); master == nil || master.Addr != n3.Addr {
+		t.Errorf("Expected %s to remain master, got %v", n3.Addr, master)
+	}
+}
+
+func TestDrainingNodeIsNotElected(t *testing.T) {
+	c := NewCluster()
+
+	n1 := node.NewNode("localhost:8081", protocol.RoleMaster)
+	n2 := node.NewNode("localhost:8082", protocol.RoleSlave)
+	n3 := node.NewNode("localhost:8083", protocol.RoleSlave)
+	c.AddNode(n1)
+	c.AddNode(n2)
+	c.AddNode(n3)
+	c.SetMaster(n1)
+
+	// The draining master steps down, and the draining n2 is passed over.
+	n2.SetDraining(true)
+	n1.SetDraining(true)
+	if !c.CheckAndElect() {
+		t.Fatal("Expected a draining master to step down")
+	}
+	if master := c.GetMaster(); master == nil || master.Addr != n3.Addr {
+		t.Errorf("Expected %s to take over, got %v", n3.Addr, master)
+	}
+	if c.ShouldBeMaster(n2.Addr) || !c.ShouldBeMaster(n3.Addr) {
+		t.Error("Expected ShouldBeMaster to skip draining nodes")
+	}
+}
+
+func TestSetMaintenanceForwardsToRemoteNode(t *testing.T) {
+	local := node.NewNode("localhost:8080", protocol.RoleMaster)
+
+	remoteNode := node.NewNode("placeholder", protocol.RoleSlave)
+	srv := httptest.NewServer(transport.NewHTTPServer(remoteNode).Handler())
+	defer srv.Close()
+	remoteNode.Addr = srv.Listener.Addr().String()
+
+	c := NewCluster()
+	c.AddNode(local)
+	view := node.NewNode(remoteNode.Addr, protocol.RoleSlave)
+	c.AddNode(view)
+	c.SetMaster(local)
+
+	resp, err := c.SetMaintenance(&protocol.MaintenanceRequest{Address: remoteNode.Addr, Enabled: true, DrainTimeoutMs: 200}, local)
+	if err != nil {
+		t.Fatalf("SetMaintenance failed: %v", err)
+	}
+	if !resp.Enabled || !resp.Drained {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if !remoteNode.GetMaintenance() || !view.GetMaintenance() {
+		t.Error("Expected the node and the master's view to be in maintenance")
+	}
+
+	if _, err := c.SetMaintenance(&protocol.MaintenanceRequest{Address: remoteNode.Addr}, local); err != nil {
+		t.Fatalf("SetMaintenance off failed: %v", err)
+	}
+	if remoteNode.GetMaintenance() || view.GetMaintenance() {
+		t.Error("Expected maintenance to be cleared on the node and the view")
+	}
+
+	if _, err := c.SetMaintenance(&protocol.MaintenanceRequest{Address: "localhost:9999", Enabled: true}, local); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestCollectMetricsBoundedBySlowNodes(t *testing.T) {
+	local := node.NewNode("localhost:8080", protocol.RoleMaster)
+
+	fastNode := node.NewNode("placeholder", protocol.RoleSlave)
+	fast := httptest.NewServer(transport.NewHTTPServer(fastNode).Handler())
+	defer fast.Close()
+	fastNode.Addr = fast.Listener.Addr().String()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	slowAddr := slow.Listener.Addr().String()
+
+	c := NewCluster()
+	c.AddNode(local)
+	c.AddNode(node.NewNode(fastNode.Addr, protocol.RoleSlave))
+	c.AddNode(node.NewNode(slowAddr, protocol.RoleSlave))
+
+	start := time.Now()
+	got := c.CollectMetrics(local, transport.NewHTTPClient(10*time.Second), 200*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CollectMetrics took %v, want it bounded by the 200ms deadline", elapsed)
+	}
+
+	if _, ok := got[local.Addr]; !ok {
+		t.Error("Expected local metrics")
+	}
+	if _, ok := got[fastNode.Addr]; !ok {
+		t.Error("Expected metrics from the responsive node")
+	}
+	if _, ok := got[slowAddr]; ok {
+		t.Error("Expected no metrics from the node that missed the deadline")
+	}
+}
+
+func TestResolveNode(t *testing.T) {
+	c := NewCluster()
+
+	n1 := node.NewNode("localhost:8081", protocol.RoleMaster)
+	n2 := node.NewNode("localhost:8082", protocol.RoleSlave)
+	n3 := node.NewNode("localhost:8083", protocol.RoleSlave)
+	n1.SetName("orders-a")
+	n2.SetName("replica")
+	n3.SetName("replica")
+	c.AddNode(n1)
+	c.AddNode(n2)
+	c.AddNode(n3)
+
+	for ref, want := range map[string]string{
+		"localhost:8081": "localhost:8081",
+		"127.0.0.1:8082": "localhost:8082",
+		"orders-a":       "localhost:8081",
+		" orders-a ":     "localhost:8081",
+	} {
+		got, err := c.ResolveNode(ref)
+		if err != nil || got != want {
+			t.Errorf("ResolveNode(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+
+	_, err := c.ResolveNode("replica")
+	if !errors.Is(err, ErrAmbiguousNode) {
+		t.Errorf("Expected ErrAmbiguousNode for a shared name, got %v", err)
+	} else if !strings.Contains(err.Error(), "localhost:8082, localhost:8083") {
+		t.Errorf("Expected the ambiguous error to list the candidates, got %v", err)
+	}
+
+	if _, err := c.ResolveNode("orders-b"); !errors.Is(err, ErrUnknownNode) {
+		t.Errorf("Expected ErrUnknownNode, got %v", err)
+	}
+
+	// An address wins over a node that is named like another member's address.
+	n3.SetName("localhost:8081")
+	if got, err := c.ResolveNode("localhost:8081"); err != nil || got != "localhost:8081" {
+		t.Errorf("Expected the address to win, got %q, %v", got, err)
+	}
+}
+
+func TestAuthorizeTakeover(t *testing.T) {
+	c := NewCluster()
+	master := node.NewNode("node-b:8080", protocol.RoleMaster)
+	master.SetAlive(true)
+	oldMaster := node.NewNode("node-a:8080", protocol.RoleSlave)
+	c.AddNode(oldMaster)
+	c.AddNode(master)
+	c.SetMaster(master)
+
+	if err := c.AuthorizeTakeover("http://node-b:8080", "node-a:8080"); err != nil {
+		t.Errorf("Expected the current master to take over from a dead owner, got %v", err)
+	}
+	if err := c.AuthorizeTakeover("node-c:8080", "node-a:8080"); err == nil || !strings.Contains(err.Error(), "not the current master") {
+		t.Errorf("Expected a takeover by a non-master to be refused, got %v", err)
+	}
+
+	// An owner this view still sees alive as master keeps its transaction.
+	oldMaster.SetAlive(true)
+	oldMaster.SetRole(protocol.RoleMaster)
+	if err := c.AuthorizeTakeover("node-b:8080", "node-a:8080"); err == nil || !strings.Contains(err.Error(), "still master") {
+		t.Errorf("Expected a takeover from a live master to be refused, got %v", err)
+	}
+
+	c.SetMaster(nil)
+	if err := c.AuthorizeTakeover("node-b:8080", "node-a:8080"); err == nil {
+		t.Error("Expected takeovers to be refused while there is no master")
+	}
+}
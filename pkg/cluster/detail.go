@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// NodeDetail assembles the extended view of member addr. The local node is
+// read directly; a remote node is asked for /health (version, clock) and
+// /metrics (in-doubt count), and whatever it cannot answer is left empty.
+// It returns nil if addr is not a member.
+func (c *Cluster) NodeDetail(addr string, local *node.Node, client *transport.HTTPClient) *protocol.NodeDetail {
+	n := c.GetNode(addr)
+	if n == nil {
+		return nil
+	}
+
+	detail := &protocol.NodeDetail{
+		Labels:   n.GetLabels(),
+		Priority: n.GetPriority(),
+	}
+
+	if local != nil && SameAddr(addr, local.Addr) {
+		var skew int64
+		inDoubt := len(local.GetPendingTransactions())
+		detail.Version = protocol.ProtocolVersion
+		detail.ClockSkewMs = &skew
+		detail.InDoubt = &inDoubt
+		return detail
+	}
+
+	if health, err := client.HealthCheck(n.Addr); err == nil {
+		detail.Version = health.Version
+		if !health.Time.IsZero() {
+			skew := health.Time.Sub(time.Now()).Milliseconds()
+			detail.ClockSkewMs = &skew
+		}
+	}
+	if metrics, err := client.GetMetrics(n.Addr); err == nil {
+		inDoubt := metrics.InFlight
+		detail.InDoubt = &inDoubt
+	}
+
+	return detail
+}
@@ -1,19 +1,36 @@
 package cluster
 
 import (
-	"log"
 	"sort"
+	"time"
 
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
 
 // ElectMaster performs a deterministic master election
-// The alive node with the lowest lexicographical address becomes master
+// The eligible node with the lowest lexicographical canonical address becomes master
 func (c *Cluster) ElectMaster() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.electMasterLocked()
+	c.electing.Store(true)
+	defer c.electing.Store(false)
+
+	c.electMasterLocked(c.electionReasonLocked(protocol.ElectionReasonManual))
+}
+
+// ElectionInProgress reports whether an election is running or the cluster is
+// momentarily masterless while alive nodes could still be elected.
+func (c *Cluster) ElectionInProgress() bool {
+	if c.electing.Load() {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.master == nil && c.lowestAliveAddrLocked() != ""
 }
 
 // EvictMaster removes the current master (usually after detecting it's dead)
@@ -22,9 +39,10 @@ func (c *Cluster) EvictMaster() {
 	defer c.mu.Unlock()
 
 	if c.master != nil {
-		log.Printf("[Election] Evicting master: %s", c.master.Addr)
+		logging.Warnf("[Election] Evicting master: %s", c.master.Addr)
 		c.master.SetRole(protocol.RoleSlave)
 		c.master = nil
+		c.masterLostReason = protocol.ElectionReasonMasterDied
 	}
 }
 
@@ -35,15 +53,37 @@ func (c *Cluster) CheckAndElect() bool {
 	defer c.mu.Unlock()
 
 	// If current master exists and is alive, keep it to avoid churn when new nodes join.
-	if c.master != nil && c.master.GetAlive() {
+	if c.master != nil && c.master.GetAlive() && !c.master.GetMaintenance() && !c.master.GetDraining() {
 		return false
 	}
 
+	// A master in maintenance or draining steps down, but only once someone
+	// can take over.
+	if c.master != nil && c.master.GetAlive() {
+		successor := c.lowestAliveAddrLocked()
+		if successor == "" {
+			return false
+		}
+
+		c.electing.Store(true)
+		defer c.electing.Store(false)
+
+		logging.Warnf("[Election] Master %s is %s, stepping down", c.master.Addr, c.master.State())
+		c.master.SetRole(protocol.RoleSlave)
+		c.master = nil
+		c.masterLostReason = protocol.ElectionReasonMaintenance
+		return c.electMasterLocked(c.electionReasonLocked(protocol.ElectionReasonMaintenance))
+	}
+
+	c.electing.Store(true)
+	defer c.electing.Store(false)
+
 	// If master exists but is dead, evict and elect.
 	if c.master != nil && !c.master.GetAlive() {
-		log.Printf("[Election] Master %s is dead, triggering election", c.master.Addr)
+		logging.Warnf("[Election] Master %s is dead, triggering election", c.master.Addr)
 		c.master.SetRole(protocol.RoleSlave)
 		c.master = nil
+		c.masterLostReason = protocol.ElectionReasonMasterDied
 	}
 
 	lowestAlive := c.lowestAliveAddrLocked()
@@ -51,19 +91,37 @@ func (c *Cluster) CheckAndElect() bool {
 		return false
 	}
 
-	changed := c.electMasterLocked()
+	changed := c.electMasterLocked(c.electionReasonLocked(protocol.ElectionReasonMasterDied))
 	return changed
 }
 
+// ElectionStats returns how many elections this cluster view has run and
+// when and why the last one happened.
+func (c *Cluster) ElectionStats() protocol.ElectionStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := protocol.ElectionStats{
+		Count:      c.elections,
+		LastReason: c.lastElectionReason,
+	}
+	if !c.lastElectionAt.IsZero() {
+		at := c.lastElectionAt
+		stats.LastAt = &at
+	}
+
+	return stats
+}
+
 // ShouldBeMaster checks if a given address should be the master based on election rules
 func (c *Cluster) ShouldBeMaster(addr string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	// Get all alive nodes sorted by address
+	// Get all eligible nodes sorted by address
 	var aliveAddrs []string
 	for nodeAddr, n := range c.nodes {
-		if n.GetAlive() {
+		if n.Eligible() {
 			aliveAddrs = append(aliveAddrs, nodeAddr)
 		}
 	}
@@ -74,34 +132,41 @@ func (c *Cluster) ShouldBeMaster(addr string) bool {
 
 	sort.Strings(aliveAddrs)
 
-	return aliveAddrs[0] == addr
+	return aliveAddrs[0] == CanonicalAddr(addr)
 }
 
-// lowestAliveAddrLocked returns the lexicographically smallest alive node address.
+// lowestAliveAddrLocked returns the lexicographically smallest eligible node
+// address, the winner of a fresh election as PlanElection ranks it.
 // Caller must hold c.mu.
 func (c *Cluster) lowestAliveAddrLocked() string {
-	var aliveAddrs []string
+	candidates := make([]ElectionCandidate, 0, len(c.nodes))
 	for addr, n := range c.nodes {
-		if n.GetAlive() {
-			aliveAddrs = append(aliveAddrs, addr)
-		}
+		candidates = append(candidates, candidateFromNode(addr, n))
 	}
 
-	if len(aliveAddrs) == 0 {
-		return ""
-	}
-
-	sort.Strings(aliveAddrs)
-
-	return aliveAddrs[0]
+	return PlanElection("", candidates).Fresh
 }
 
-// electMasterLocked elects a master based on current alive nodes.
+// electionReasonLocked explains the next election: why the master was lost if
+// known, "initial" for the first election, otherwise fallback.
 // Caller must hold c.mu.
-func (c *Cluster) electMasterLocked() bool {
+func (c *Cluster) electionReasonLocked(fallback string) string {
+	switch {
+	case c.masterLostReason != "":
+		return c.masterLostReason
+	case c.elections == 0:
+		return protocol.ElectionReasonInitial
+	default:
+		return fallback
+	}
+}
+
+// electMasterLocked elects a master based on current alive nodes and records
+// the election under reason. Caller must hold c.mu.
+func (c *Cluster) electMasterLocked(reason string) bool {
 	lowestAlive := c.lowestAliveAddrLocked()
 	if lowestAlive == "" {
-		log.Println("[Election] No alive nodes, no master elected")
+		logging.Warnf("[Election] No alive nodes, no master elected")
 		c.master = nil
 		return false
 	}
@@ -115,7 +180,12 @@ func (c *Cluster) electMasterLocked() bool {
 	newMaster.SetRole(protocol.RoleMaster)
 	c.master = newMaster
 
-	log.Printf("[Election] Elected new master: %s", lowestAlive)
+	c.elections++
+	c.lastElectionAt = time.Now()
+	c.lastElectionReason = reason
+	c.masterLostReason = ""
+
+	logging.Warnf("[Election] Elected new master: %s (%s, election #%d)", lowestAlive, reason, c.elections)
 
 	return true
 }
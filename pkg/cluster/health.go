@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// Health score rubric. The score starts at 100 and each check subtracts its
+// penalty; the overall status is the worst status of any check.
+//
+//	master     no alive master                          critical, -50
+//	quorum     half or fewer of the members alive       critical, -30
+//	alive      any member down                          degraded, -40 x fraction down
+//	in_doubt   decisions not acknowledged by a node     degraded, -5 per transaction, at most -20
+//	abort_rate share of the last interval not committed degraded from 10%, critical from 50%, -0.4 per percent, at most -20
+//
+// Disabled members and members in maintenance are out on purpose and are not
+// counted as members.
+const (
+	healthMasterPenalty    = 50
+	healthQuorumPenalty    = 30
+	healthAlivePenalty     = 40
+	healthInDoubtPenalty   = 5
+	healthInDoubtMax       = 20
+	healthAbortRateMax     = 20
+	healthAbortDegradedPct = 10.0
+	healthAbortCriticalPct = 50.0
+)
+
+// HealthInputs are the signals besides membership that the health score uses.
+type HealthInputs struct {
+	// InDoubt counts transactions whose decision some node has not yet
+	// acknowledged, e.g. the coordinator's recovery queue.
+	InDoubt int
+
+	// AbortRate is the percentage of recently finished transactions that did
+	// not commit; nil when unknown or nothing finished.
+	AbortRate *float64
+}
+
+// Health scores the cluster as this node sees it, see the rubric above.
+func (c *Cluster) Health(in HealthInputs) *protocol.ClusterHealthResponse {
+	c.mu.RLock()
+	masterAlive := c.master != nil && c.master.GetAlive()
+	members, alive := 0, 0
+	for _, n := range c.nodes {
+		if n.GetDisabled() || n.GetMaintenance() {
+			continue
+		}
+		members++
+		if n.GetAlive() {
+			alive++
+		}
+	}
+	c.mu.RUnlock()
+
+	var checks []protocol.ClusterHealthCheck
+	add := func(name, status string, penalty int, detail string) {
+		checks = append(checks, protocol.ClusterHealthCheck{Name: name, Status: status, Penalty: penalty, Detail: detail})
+	}
+
+	if masterAlive {
+		add("master", protocol.ClusterHealthy, 0, "master is alive")
+	} else {
+		add("master", protocol.ClusterCritical, healthMasterPenalty, "no alive master")
+	}
+
+	if alive*2 > members {
+		add("quorum", protocol.ClusterHealthy, 0, fmt.Sprintf("%d of %d members alive", alive, members))
+	} else {
+		add("quorum", protocol.ClusterCritical, healthQuorumPenalty, fmt.Sprintf("only %d of %d members alive", alive, members))
+	}
+
+	if down := members - alive; down > 0 {
+		penalty := (healthAlivePenalty*down + members - 1) / members // rounded up
+		add("alive", protocol.ClusterDegraded, penalty, fmt.Sprintf("%d of %d members down", down, members))
+	} else {
+		add("alive", protocol.ClusterHealthy, 0, "all members alive")
+	}
+
+	if in.InDoubt > 0 {
+		add("in_doubt", protocol.ClusterDegraded, min(in.InDoubt*healthInDoubtPenalty, healthInDoubtMax),
+			fmt.Sprintf("%d transactions awaiting acknowledgement", in.InDoubt))
+	} else {
+		add("in_doubt", protocol.ClusterHealthy, 0, "no transactions in doubt")
+	}
+
+	if rate := in.AbortRate; rate == nil {
+		add("abort_rate", protocol.ClusterHealthy, 0, "no recent transactions")
+	} else {
+		status, penalty := protocol.ClusterHealthy, 0
+		if *rate >= healthAbortDegradedPct {
+			status, penalty = protocol.ClusterDegraded, min(int(*rate*0.4+0.5), healthAbortRateMax)
+		}
+		if *rate >= healthAbortCriticalPct {
+			status = protocol.ClusterCritical
+		}
+		add("abort_rate", status, penalty, fmt.Sprintf("%.1f%% of recent transactions aborted", *rate))
+	}
+
+	resp := &protocol.ClusterHealthResponse{
+		Status:    protocol.ClusterHealthy,
+		Score:     100,
+		Checks:    checks,
+		Generated: time.Now(),
+	}
+	for _, check := range checks {
+		resp.Score -= check.Penalty
+		if healthRank(check.Status) > healthRank(resp.Status) {
+			resp.Status = check.Status
+		}
+	}
+	resp.Score = max(resp.Score, 0)
+	return resp
+}
+
+// healthRank orders health statuses from best to worst.
+func healthRank(status string) int {
+	switch status {
+	case protocol.ClusterCritical:
+		return 2
+	case protocol.ClusterDegraded:
+		return 1
+	default:
+		return 0
+	}
+}
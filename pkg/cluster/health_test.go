@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// healthCluster builds a cluster of alive members with the first as master.
+func healthCluster(addrs ...string) (*Cluster, []*node.Node) {
+	c := NewCluster()
+	var nodes []*node.Node
+	for _, addr := range addrs {
+		n := node.NewNode(addr, protocol.RoleSlave)
+		c.AddNode(n)
+		nodes = append(nodes, n)
+	}
+	c.SetMaster(nodes[0])
+	return c, nodes
+}
+
+func healthCheck(t *testing.T, resp *protocol.ClusterHealthResponse, name string) protocol.ClusterHealthCheck {
+	t.Helper()
+	for _, check := range resp.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("No %q check in %+v", name, resp.Checks)
+	return protocol.ClusterHealthCheck{}
+}
+
+func TestHealthAllMembersAlive(t *testing.T) {
+	c, _ := healthCluster("localhost:8081", "localhost:8082", "localhost:8083")
+
+	rate := 2.0
+	resp := c.Health(HealthInputs{AbortRate: &rate})
+	if resp.Status != protocol.ClusterHealthy || resp.Score != 100 {
+		t.Errorf("Expected healthy with score 100, got %s %d: %+v", resp.Status, resp.Score, resp.Checks)
+	}
+	if len(resp.Checks) != 5 {
+		t.Errorf("Expected every rubric check reported, got %+v", resp.Checks)
+	}
+}
+
+func TestHealthDegradedWithOneNodeDown(t *testing.T) {
+	c, nodes := healthCluster("localhost:8081", "localhost:8082", "localhost:8083", "localhost:8084")
+	nodes[3].SetAlive(false)
+
+	resp := c.Health(HealthInputs{InDoubt: 1})
+	if resp.Status != protocol.ClusterDegraded {
+		t.Fatalf("Expected degraded, got %s: %+v", resp.Status, resp.Checks)
+	}
+	// A quarter of the members down costs 10, one in-doubt transaction 5.
+	if resp.Score != 85 {
+		t.Errorf("Expected score 85, got %d: %+v", resp.Score, resp.Checks)
+	}
+	if check := healthCheck(t, resp, "quorum"); check.Status != protocol.ClusterHealthy {
+		t.Errorf("Expected quorum kept with 3 of 4 alive, got %+v", check)
+	}
+
+	// A disabled member is out on purpose and does not count as down.
+	nodes[3].SetDisabled(true)
+	if check := healthCheck(t, c.Health(HealthInputs{}), "alive"); check.Status != protocol.ClusterHealthy {
+		t.Errorf("Expected a disabled member not to count as down, got %+v", check)
+	}
+}
+
+func TestHealthCriticalWithoutMaster(t *testing.T) {
+	c, nodes := healthCluster("localhost:8081", "localhost:8082", "localhost:8083")
+	nodes[0].SetAlive(false)
+
+	resp := c.Health(HealthInputs{})
+	if resp.Status != protocol.ClusterCritical {
+		t.Fatalf("Expected critical, got %s: %+v", resp.Status, resp.Checks)
+	}
+	if check := healthCheck(t, resp, "master"); check.Status != protocol.ClusterCritical {
+		t.Errorf("Expected the master check critical, got %+v", check)
+	}
+	if resp.Score >= 50 {
+		t.Errorf("Expected a score below 50 without a master, got %d", resp.Score)
+	}
+}
+
+func TestHealthAbortRateThresholds(t *testing.T) {
+	c, _ := healthCluster("localhost:8081")
+
+	tests := []struct {
+		rate   float64
+		status string
+	}{
+		{5, protocol.ClusterHealthy},
+		{20, protocol.ClusterDegraded},
+		{75, protocol.ClusterCritical},
+	}
+	for _, tt := range tests {
+		rate := tt.rate
+		if check := healthCheck(t, c.Health(HealthInputs{AbortRate: &rate}), "abort_rate"); check.Status != tt.status {
+			t.Errorf("Abort rate %.0f%%: got %+v, want %s", tt.rate, check, tt.status)
+		}
+	}
+}
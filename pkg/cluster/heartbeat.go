@@ -1,20 +1,68 @@
 package cluster
 
 import (
-	"log"
+	"fmt"
+	"math/rand/v2"
 	"sync"
 	"time"
 
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 	"github.com/baxromumarov/2pc-engine/pkg/transport"
 )
 
+// HeartbeatProbe selects what the heartbeat checks besides liveness.
+type HeartbeatProbe string
+
+const (
+	// ProbeHealth checks /health only: a live node is a candidate participant.
+	ProbeHealth HeartbeatProbe = "health"
+	// ProbeReady also checks /ready on live nodes. A node that is up but not
+	// ready (draining, database down) stays alive for election but is not
+	// selected as a participant.
+	ProbeReady HeartbeatProbe = "ready"
+)
+
+// ParseHeartbeatProbe validates a heartbeat probe name.
+func ParseHeartbeatProbe(s string) (HeartbeatProbe, error) {
+	switch probe := HeartbeatProbe(s); probe {
+	case ProbeHealth, ProbeReady:
+		return probe, nil
+	default:
+		return "", fmt.Errorf("unknown heartbeat probe %q (want %s or %s)", s, ProbeHealth, ProbeReady)
+	}
+}
+
+// Bounds SetInterval accepts; startup validates --heartbeat against the same
+// range. Below the minimum the heartbeat spins, above the maximum a dead node
+// goes unnoticed for too long.
+const (
+	MinHeartbeatInterval = 100 * time.Millisecond
+	MaxHeartbeatInterval = 5 * time.Minute
+)
+
 // HeartbeatManager handles periodic health checks of all nodes
 type HeartbeatManager struct {
 	cluster  *Cluster
 	client   *transport.HTTPClient
 	interval time.Duration
+	resetCh  chan time.Duration // delivers interval changes to the running loop
 	stopCh   chan struct{}
+	readyCh  chan struct{} // closed once the first full scan has been applied
 	wg       sync.WaitGroup
+	mu       sync.Mutex
+	scanMu   sync.Mutex // serializes applying liveness results and electing
+	probe    HeartbeatProbe
+
+	// A failed liveness probe is repeated up to healthRetries times,
+	// retrySpacing apart, before the node is marked dead.
+	healthRetries int
+	retrySpacing  time.Duration
+
+	// The first scan waits a random fraction (up to startJitter) of the
+	// interval so nodes started together do not probe each other in lockstep.
+	startJitter float64
+	rng         *rand.Rand
 }
 
 // NewHeartbeatManager creates a new heartbeat manager
@@ -23,37 +71,159 @@ func NewHeartbeatManager(cluster *Cluster, interval time.Duration) *HeartbeatMan
 		cluster:  cluster,
 		client:   transport.NewHTTPClient(2*time.Second).WithRetry(1, 100*time.Millisecond),
 		interval: interval,
+		resetCh:  make(chan time.Duration, 1),
 		stopCh:   make(chan struct{}),
+		readyCh:  make(chan struct{}),
+		probe:    ProbeHealth,
+
+		retrySpacing: 100 * time.Millisecond,
+
+		startJitter: 1,
+		rng:         rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+// WithStartJitter sets the maximum fraction of the interval (clamped to [0, 1])
+// to wait before the first scan and seeds the random source, so tests get a
+// reproducible delay. Zero disables the jitter. Must be called before Start.
+func (h *HeartbeatManager) WithStartJitter(maxFraction float64, seed uint64) *HeartbeatManager {
+	h.startJitter = min(max(maxFraction, 0), 1)
+	h.rng = rand.New(rand.NewPCG(seed, seed))
+	return h
+}
+
+// WithProbe selects whether the heartbeat also tracks readiness. Must be
+// called before Start.
+func (h *HeartbeatManager) WithProbe(probe HeartbeatProbe) *HeartbeatManager {
+	h.probe = probe
+	return h
+}
+
+// WithHealthRetries repeats a failed liveness probe up to retries times,
+// spacing apart, before the node is marked dead, so a single dropped probe
+// does not trigger an election. Zero (the default) marks the node dead on the
+// first failure. Must be called before Start.
+func (h *HeartbeatManager) WithHealthRetries(retries int, spacing time.Duration) *HeartbeatManager {
+	h.healthRetries = max(retries, 0)
+	h.retrySpacing = max(spacing, 0)
+	return h
+}
+
+// WithHTTPClient replaces the client used for health and readiness probes,
+// by default one with a 2s timeout and a single retry. A nil client is
+// ignored. Must be called before Start.
+func (h *HeartbeatManager) WithHTTPClient(client *transport.HTTPClient) *HeartbeatManager {
+	if client != nil {
+		h.client = client
 	}
+	return h
+}
+
+// startDelay picks the wait before the first scan.
+func (h *HeartbeatManager) startDelay() time.Duration {
+	if h.startJitter <= 0 {
+		return 0
+	}
+	return time.Duration(h.rng.Float64() * h.startJitter * float64(h.Interval()))
 }
 
 // Start begins the heartbeat checking loop
 func (h *HeartbeatManager) Start() {
 	h.wg.Add(1)
 	go h.run()
-	log.Printf("[Heartbeat] Started with interval %v", h.interval)
+	logging.Infof("[Heartbeat] Started with interval %v", h.Interval())
+}
+
+// Interval returns the current heartbeat interval.
+func (h *HeartbeatManager) Interval() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.interval
+}
+
+// SetInterval changes the heartbeat interval without restarting the manager.
+// The running loop picks up the new interval immediately. Intervals outside
+// MinHeartbeatInterval..MaxHeartbeatInterval are rejected.
+func (h *HeartbeatManager) SetInterval(d time.Duration) error {
+	switch {
+	case d <= 0:
+		return fmt.Errorf("heartbeat interval must be positive, got %v", d)
+	case d < MinHeartbeatInterval:
+		return fmt.Errorf("heartbeat interval must be at least %v, got %v", MinHeartbeatInterval, d)
+	case d > MaxHeartbeatInterval:
+		return fmt.Errorf("heartbeat interval must be at most %v, got %v", MaxHeartbeatInterval, d)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.interval = d
+	// Replace any pending change so the loop always sees the latest value.
+	select {
+	case <-h.resetCh:
+	default:
+	}
+	h.resetCh <- d
+
+	logging.Infof("[Heartbeat] Interval changed to %v", d)
+	return nil
+}
+
+// Ready reports whether the first full heartbeat round has completed, i.e.
+// liveness of every member has been verified at least once.
+func (h *HeartbeatManager) Ready() bool {
+	select {
+	case <-h.readyCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadyOrGrace returns a readiness check that passes once the first heartbeat
+// round completes or grace has elapsed since the call, whichever is first.
+// A non-positive grace waits for the heartbeat only.
+func (h *HeartbeatManager) ReadyOrGrace(grace time.Duration) func() bool {
+	deadline := time.Now().Add(grace)
+	return func() bool {
+		return h.Ready() || (grace > 0 && !time.Now().Before(deadline))
+	}
 }
 
 // Stop stops the heartbeat manager
 func (h *HeartbeatManager) Stop() {
 	close(h.stopCh)
 	h.wg.Wait()
-	log.Println("[Heartbeat] Stopped")
+	logging.Infof("[Heartbeat] Stopped")
 }
 
 func (h *HeartbeatManager) run() {
 	defer h.wg.Done()
 
-	ticker := time.NewTicker(h.interval)
-	defer ticker.Stop()
+	if delay := h.startDelay(); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-h.stopCh:
+			timer.Stop()
+			return
+		}
+	}
+
+	ticker := time.NewTicker(h.Interval())
+	defer func() { ticker.Stop() }()
 
 	// Initial check
 	h.checkAllNodes()
+	close(h.readyCh)
 
 	for {
 		select {
 		case <-ticker.C:
 			h.checkAllNodes()
+		case d := <-h.resetCh:
+			ticker.Stop()
+			ticker = time.NewTicker(d)
 		case <-h.stopCh:
 			return
 		}
@@ -67,25 +237,110 @@ func (h *HeartbeatManager) checkAllNodes() {
 		return
 	}
 
+	// Probe in parallel without touching cluster state so an election can
+	// never observe a half-applied scan.
+	results := make([]probeResult, len(nodes))
 	var wg sync.WaitGroup
 	wg.Add(len(nodes))
 
-	for _, n := range nodes {
-		node := n // capture for goroutine
+	for i, n := range nodes {
+		idx := i // capture for goroutine
+		addr := n.Addr
 		go func() {
 			defer wg.Done()
-			h.checkNode(node.Addr)
+			results[idx] = h.probeNode(addr)
 		}()
 	}
 
 	wg.Wait()
 
+	h.scanMu.Lock()
+	defer h.scanMu.Unlock()
+
+	var masters []string
+	for i, n := range nodes {
+		h.applyResult(n.Addr, results[i])
+		if results[i].err == nil && results[i].role == protocol.RoleMaster {
+			masters = append(masters, n.Addr)
+		}
+	}
+	h.cluster.observeMasters(masters, time.Now())
+
 	// After health checks, check if we need to elect a new master
 	h.cluster.CheckAndElect()
 }
 
-// checkNode performs a health check on a single node
+// checkNode probes a single node, records its liveness and re-runs the election.
 func (h *HeartbeatManager) checkNode(addr string) {
+	if h.cluster.GetNode(addr) == nil {
+		return
+	}
+
+	result := h.probeNode(addr)
+
+	h.scanMu.Lock()
+	defer h.scanMu.Unlock()
+
+	h.applyResult(addr, result)
+	h.cluster.CheckAndElect()
+}
+
+// probeResult is what one heartbeat learned about a node.
+type probeResult struct {
+	err         error // liveness probe failure; nil means alive
+	maintenance bool
+	draining    bool
+	ready       bool
+	role        protocol.NodeRole // role the node reports on /role; "" if unknown
+}
+
+// probeNode checks a node's liveness, retrying a failed check healthRetries
+// times, then asks a live node for its role and, with ProbeReady, its
+// readiness. Without the readiness probe every live node is ready.
+func (h *HeartbeatManager) probeNode(addr string) probeResult {
+	var res probeResult
+	res.maintenance, res.draining, res.err = h.ping(addr)
+	for attempt := 1; res.err != nil && attempt <= h.healthRetries; attempt++ {
+		logging.Debugf("[Heartbeat] Health check of %s failed (retry %d/%d): %v", addr, attempt, h.healthRetries, res.err)
+		select {
+		case <-time.After(h.retrySpacing):
+		case <-h.stopCh:
+			return probeResult{err: res.err, ready: true}
+		}
+		res.maintenance, res.draining, res.err = h.ping(addr)
+	}
+	if res.err != nil {
+		res.ready = true
+		return res
+	}
+
+	if role, err := h.client.GetRole(addr); err != nil {
+		logging.Debugf("[Heartbeat] Role check of %s failed: %v", addr, err)
+	} else {
+		res.role = protocol.NodeRole(role.Role)
+	}
+	if h.probe != ProbeReady {
+		res.ready = true
+		return res
+	}
+
+	ready, err := h.client.Ready(addr)
+	if err != nil {
+		logging.Debugf("[Heartbeat] Readiness probe of %s failed: %v", addr, err)
+	}
+	res.ready = ready
+	return res
+}
+
+// ping runs one liveness probe.
+func (h *HeartbeatManager) ping(addr string) (maintenance, draining bool, err error) {
+	status, err := h.client.PingStatus(addr)
+	return status.Maintenance, status.Draining, err
+}
+
+// applyResult records a health check result, including the maintenance mode,
+// draining and readiness a reachable node reports. Callers must hold scanMu.
+func (h *HeartbeatManager) applyResult(addr string, res probeResult) {
 	node := h.cluster.GetNode(addr)
 	if node == nil {
 		return
@@ -93,21 +348,34 @@ func (h *HeartbeatManager) checkNode(addr string) {
 
 	wasAlive := node.GetAlive()
 
-	_, err := h.client.HealthCheck(addr)
-	if err != nil {
+	if res.err != nil {
 		node.SetAlive(false)
 		if wasAlive {
-			log.Printf("[Heartbeat] Node %s is now DEAD: %v", addr, err)
+			logging.Warnf("[Heartbeat] Node %s is now DEAD: %v", addr, res.err)
 		}
 	} else {
 		node.SetAlive(true)
 		if !wasAlive {
-			log.Printf("[Heartbeat] Node %s is now ALIVE", addr)
+			logging.Infof("[Heartbeat] Node %s is now ALIVE", addr)
+		}
+		if node.GetMaintenance() != res.maintenance {
+			node.SetMaintenance(res.maintenance)
+			logging.Infof("[Heartbeat] Node %s maintenance=%t", addr, res.maintenance)
+		}
+		if node.GetDraining() != res.draining {
+			node.SetDraining(res.draining)
+			logging.Infof("[Heartbeat] Node %s draining=%t", addr, res.draining)
+		}
+		if node.GetReady() != res.ready {
+			node.SetReady(res.ready)
+			logging.Infof("[Heartbeat] Node %s ready=%t", addr, res.ready)
 		}
 	}
 }
 
-// CheckNode performs a single health check on a specific node (exposed for manual checks)
+// CheckNode performs a single health check on a specific node (exposed for manual checks).
+// The result is applied and the election re-evaluated atomically with respect to
+// the periodic scan.
 func (h *HeartbeatManager) CheckNode(addr string) bool {
 	h.checkNode(addr)
 	node := h.cluster.GetNode(addr)
@@ -120,7 +388,7 @@ func (h *HeartbeatManager) CheckNode(addr string) bool {
 
 // IsNodeAlive checks if a specific node is alive
 func (h *HeartbeatManager) IsNodeAlive(addr string) bool {
-	_, err := h.client.HealthCheck(addr)
+	err := h.client.Ping(addr)
 
 	return err == nil
 }
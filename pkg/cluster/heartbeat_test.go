@@ -0,0 +1,491 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func healthServer(t *testing.T, hits *int32) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			atomic.AddInt32(hits, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestHeartbeatSetIntervalChangesCadence(t *testing.T) {
+	var hits int32
+	c := NewCluster()
+	c.AddNode(node.NewNode(healthServer(t, &hits), protocol.RoleSlave))
+
+	h := NewHeartbeatManager(c, time.Hour).WithStartJitter(0, 0)
+	h.Start()
+	defer h.Stop()
+
+	// Only the initial check runs with a one-hour interval.
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("Expected 1 initial health check, got %d", got)
+	}
+
+	if err := h.SetInterval(MinHeartbeatInterval); err != nil {
+		t.Fatalf("SetInterval failed: %v", err)
+	}
+	if h.Interval() != MinHeartbeatInterval {
+		t.Errorf("Expected interval %v, got %v", MinHeartbeatInterval, h.Interval())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&hits) < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected faster cadence after SetInterval, got %d checks", atomic.LoadInt32(&hits))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHeartbeatSetIntervalRejectsOutOfBounds(t *testing.T) {
+	h := NewHeartbeatManager(NewCluster(), time.Second)
+
+	for _, d := range []time.Duration{0, MinHeartbeatInterval - time.Nanosecond, MaxHeartbeatInterval + time.Nanosecond} {
+		if err := h.SetInterval(d); err == nil {
+			t.Errorf("Expected error for interval %v", d)
+		}
+		if h.Interval() != time.Second {
+			t.Errorf("Expected interval unchanged after %v, got %v", d, h.Interval())
+		}
+	}
+
+	// Both bounds themselves are accepted.
+	for _, d := range []time.Duration{MinHeartbeatInterval, MaxHeartbeatInterval} {
+		if err := h.SetInterval(d); err != nil || h.Interval() != d {
+			t.Errorf("SetInterval(%v) = %v, interval %v", d, err, h.Interval())
+		}
+	}
+}
+
+func TestHeartbeatProbesWithHead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewCluster()
+	n := node.NewNode(strings.TrimPrefix(srv.URL, "http://"), protocol.RoleSlave)
+	c.AddNode(n)
+
+	h := NewHeartbeatManager(c, time.Hour)
+	if !h.CheckNode(n.Addr) {
+		t.Error("Expected liveness probe to use HEAD /health")
+	}
+}
+
+func TestHeartbeatHealthRetriesRideOutFailedProbe(t *testing.T) {
+	var hits, failures int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewCluster()
+	n := node.NewNode(strings.TrimPrefix(srv.URL, "http://"), protocol.RoleSlave)
+	n.SetAlive(true)
+	c.AddNode(n)
+
+	h := NewHeartbeatManager(c, time.Hour).WithHealthRetries(2, time.Millisecond)
+
+	// One of the three probes fails: the node stays alive.
+	atomic.StoreInt32(&failures, 1)
+	if !h.CheckNode(n.Addr) {
+		t.Fatal("Expected a single failed probe to be retried, node marked dead")
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("Expected 2 probes (1 failed, 1 retry), got %d", got)
+	}
+
+	// All three fail: the node is dead.
+	atomic.StoreInt32(&hits, 0)
+	atomic.StoreInt32(&failures, 3)
+	if h.CheckNode(n.Addr) {
+		t.Fatal("Expected node to be dead after every retry failed")
+	}
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("Expected 3 probes, got %d", got)
+	}
+}
+
+func TestHeartbeatCheckNodeTriggersElection(t *testing.T) {
+	var hits int32
+	c := NewCluster()
+
+	// "0.0.0.0:1" sorts before the test server and refuses connections.
+	dead := node.NewNode("0.0.0.0:1", protocol.RoleSlave)
+	dead.SetAlive(true)
+	alive := node.NewNode(healthServer(t, &hits), protocol.RoleSlave)
+	alive.SetAlive(true)
+	c.AddNode(dead)
+	c.AddNode(alive)
+	c.ElectMaster()
+
+	if m := c.GetMaster(); m == nil || m.Addr != dead.Addr {
+		t.Fatalf("Expected %s to be master before the check", dead.Addr)
+	}
+
+	h := NewHeartbeatManager(c, time.Hour)
+	if h.CheckNode(dead.Addr) {
+		t.Fatal("Expected CheckNode to report the unreachable node as dead")
+	}
+
+	if m := c.GetMaster(); m == nil || m.Addr != alive.Addr {
+		t.Errorf("Expected CheckNode to elect %s after master died, got %v", alive.Addr, m)
+	}
+}
+
+func TestHeartbeatConcurrentChecksKeepElectionConsistent(t *testing.T) {
+	var hitsA, hitsB int32
+	c := NewCluster()
+
+	addrA := healthServer(t, &hitsA)
+	addrB := healthServer(t, &hitsB)
+	deadAddr := "0.0.0.0:1"
+	for _, addr := range []string{addrA, addrB, deadAddr} {
+		n := node.NewNode(addr, protocol.RoleSlave)
+		n.SetAlive(true)
+		c.AddNode(n)
+	}
+
+	h := NewHeartbeatManager(c, time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.checkAllNodes()
+		}()
+		go func(addr string) {
+			defer wg.Done()
+			h.CheckNode(addr)
+		}([]string{addrA, addrB, deadAddr}[i%3])
+	}
+	wg.Wait()
+
+	if c.GetNode(deadAddr).GetAlive() {
+		t.Error("Expected unreachable node to be marked dead")
+	}
+
+	expected := addrA
+	if addrB < addrA {
+		expected = addrB
+	}
+	master := c.GetMaster()
+	if master == nil || master.Addr != expected {
+		t.Fatalf("Expected master %s, got %v", expected, master)
+	}
+
+	masters := 0
+	for _, n := range c.GetNodes() {
+		if n.GetRole() == protocol.RoleMaster {
+			masters++
+		}
+	}
+	if masters != 1 {
+		t.Errorf("Expected exactly one node with master role, got %d", masters)
+	}
+}
+
+func TestHeartbeatReadyAfterFirstRound(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // hold the first round open
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	defer srv.Close()
+
+	c := NewCluster()
+	c.AddNode(node.NewNode(srv.Listener.Addr().String(), protocol.RoleSlave))
+
+	h := NewHeartbeatManager(c, time.Hour).WithStartJitter(0, 0)
+	waitForHeartbeat := h.ReadyOrGrace(0)
+	withGrace := h.ReadyOrGrace(50 * time.Millisecond)
+
+	h.Start()
+	defer h.Stop()
+
+	if h.Ready() || waitForHeartbeat() || withGrace() {
+		t.Fatal("Expected not ready before the first round completes")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !withGrace() {
+		t.Error("Expected grace period to open the gate")
+	}
+	if waitForHeartbeat() {
+		t.Error("Expected zero grace to keep waiting for the heartbeat")
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for !h.Ready() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !h.Ready() || !waitForHeartbeat() {
+		t.Fatal("Expected ready after the first heartbeat round")
+	}
+}
+
+func TestHeartbeatStartJitterDelaysFirstCheck(t *testing.T) {
+	const (
+		interval = 400 * time.Millisecond
+		fraction = 0.5
+		seed     = 42
+	)
+
+	firstHit := make(chan time.Time, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case firstHit <- time.Now():
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	// The same seed yields the same delay, so a twin manager predicts it.
+	want := NewHeartbeatManager(NewCluster(), interval).WithStartJitter(fraction, seed).startDelay()
+	if want < 0 || want > time.Duration(fraction*float64(interval)) {
+		t.Fatalf("Expected delay within [0, %v], got %v", time.Duration(fraction*float64(interval)), want)
+	}
+
+	c := NewCluster()
+	c.AddNode(node.NewNode(srv.Listener.Addr().String(), protocol.RoleSlave))
+	h := NewHeartbeatManager(c, interval).WithStartJitter(fraction, seed)
+
+	start := time.Now()
+	h.Start()
+	defer h.Stop()
+
+	select {
+	case hit := <-firstHit:
+		elapsed := hit.Sub(start)
+		if elapsed < want {
+			t.Errorf("First check after %v, want at least the jitter %v", elapsed, want)
+		}
+		if elapsed > want+interval/2 {
+			t.Errorf("First check after %v, want close to the jitter %v", elapsed, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a first health check")
+	}
+}
+
+func TestHeartbeatStopDuringStartJitter(t *testing.T) {
+	h := NewHeartbeatManager(NewCluster(), time.Hour).WithStartJitter(1, 1)
+	h.Start()
+
+	done := make(chan struct{})
+	go func() {
+		h.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Stop to interrupt the start jitter")
+	}
+	if h.Ready() {
+		t.Error("Expected not ready when stopped before the first scan")
+	}
+}
+
+func TestHeartbeatLearnsMaintenanceFromHealthHeader(t *testing.T) {
+	var maintenance atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maintenance.Load() {
+			w.Header().Set(protocol.MaintenanceHeader, "true")
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewCluster()
+	n := node.NewNode(strings.TrimPrefix(srv.URL, "http://"), protocol.RoleSlave)
+	c.AddNode(n)
+	h := NewHeartbeatManager(c, time.Hour)
+
+	maintenance.Store(true)
+	if !h.CheckNode(n.Addr) || !n.GetMaintenance() {
+		t.Fatal("Expected the node to be alive and in maintenance")
+	}
+
+	maintenance.Store(false)
+	if !h.CheckNode(n.Addr) || n.GetMaintenance() {
+		t.Error("Expected maintenance to clear once the node stops reporting it")
+	}
+}
+
+func TestHeartbeatLearnsDrainingFromHealthHeader(t *testing.T) {
+	var draining atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			w.Header().Set(protocol.StateHeader, string(protocol.NodeStateDraining))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewCluster()
+	n := node.NewNode(strings.TrimPrefix(srv.URL, "http://"), protocol.RoleSlave)
+	c.AddNode(n)
+	h := NewHeartbeatManager(c, time.Hour)
+
+	draining.Store(true)
+	if !h.CheckNode(n.Addr) || !n.GetDraining() {
+		t.Fatal("Expected the node to be alive and draining")
+	}
+	if n.Selectable() {
+		t.Error("Expected a draining node not to be selectable for new transactions")
+	}
+	if got := c.GetSlaveNodes(); len(got) != 0 {
+		t.Errorf("Expected no selectable slaves while draining, got %v", got)
+	}
+
+	draining.Store(false)
+	if !h.CheckNode(n.Addr) || n.GetDraining() {
+		t.Error("Expected draining to clear once the node stops reporting it")
+	}
+}
+
+func TestHeartbeatReadyProbeSeparatesReadinessFromLiveness(t *testing.T) {
+	// Up, but not ready: /health answers 200 and /ready 503.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewCluster()
+	n := node.NewNode(strings.TrimPrefix(srv.URL, "http://"), protocol.RoleSlave)
+	c.AddNode(n)
+
+	// The health probe alone selects the node.
+	NewHeartbeatManager(c, time.Hour).checkAllNodes()
+	if !n.GetAlive() || !n.GetReady() {
+		t.Fatalf("Expected an alive, ready node with the health probe, got alive=%v ready=%v", n.GetAlive(), n.GetReady())
+	}
+
+	// Start over without a master.
+	n.SetRole(protocol.RoleSlave)
+	c.SetMaster(nil)
+	NewHeartbeatManager(c, time.Hour).WithProbe(ProbeReady).checkAllNodes()
+	if !n.GetAlive() || n.GetReady() {
+		t.Fatalf("Expected an alive but unready node with the ready probe, got alive=%v ready=%v", n.GetAlive(), n.GetReady())
+	}
+
+	// Liveness still drives the election; readiness only participant selection.
+	if m := c.GetMaster(); m == nil || m.Addr != n.Addr {
+		t.Errorf("Expected the unready node to stay electable, got master %v", m)
+	}
+	n.SetRole(protocol.RoleSlave) // GetSlaveNodes leaves out the master
+	if slaves := c.GetSlaveNodes(); len(slaves) != 0 {
+		t.Errorf("Expected the unready node not to be selected, got %d participants", len(slaves))
+	}
+
+	n.SetReady(true)
+	if slaves := c.GetSlaveNodes(); len(slaves) != 1 {
+		t.Errorf("Expected a ready node to be selected, got %d participants", len(slaves))
+	}
+}
+
+func TestParseHeartbeatProbe(t *testing.T) {
+	for _, probe := range []HeartbeatProbe{ProbeHealth, ProbeReady} {
+		if got, err := ParseHeartbeatProbe(string(probe)); err != nil || got != probe {
+			t.Errorf("ParseHeartbeatProbe(%q) = %q, %v", probe, got, err)
+		}
+	}
+	if _, err := ParseHeartbeatProbe("live"); err == nil {
+		t.Error("Expected an unknown probe to be rejected")
+	}
+}
+
+// roleServer answers /health and reports role on /role.
+func roleServer(t *testing.T, role *atomic.Value) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/role" {
+			json.NewEncoder(w).Encode(protocol.RoleResponse{Role: role.Load().(string)})
+			return
+		}
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestHeartbeatDetectsMultipleMasters(t *testing.T) {
+	var roleA, roleB atomic.Value
+	roleA.Store(string(protocol.RoleMaster))
+	roleB.Store(string(protocol.RoleMaster))
+
+	c := NewCluster()
+	a := roleServer(t, &roleA)
+	b := roleServer(t, &roleB)
+	c.AddNode(node.NewNode(a, protocol.RoleSlave))
+	c.AddNode(node.NewNode(b, protocol.RoleSlave))
+
+	h := NewHeartbeatManager(c, time.Hour)
+	h.checkAllNodes()
+
+	stats := c.SplitBrainStats()
+	if stats == nil || stats.MultipleMastersDetected != 1 || len(stats.Masters) != 2 || stats.LastAt == nil {
+		t.Fatalf("Expected one detection of both masters, got %+v", stats)
+	}
+
+	// The same condition seen again is not a new detection.
+	h.checkAllNodes()
+	if got := c.SplitBrainStats().MultipleMastersDetected; got != 1 {
+		t.Errorf("Expected a persisting split brain to be counted once, got %d", got)
+	}
+
+	// Once one steps down the condition clears but the count remains.
+	roleB.Store(string(protocol.RoleSlave))
+	h.checkAllNodes()
+	stats = c.SplitBrainStats()
+	if stats.MultipleMastersDetected != 1 || len(stats.Masters) != 0 {
+		t.Errorf("Expected the split brain resolved with its count kept, got %+v", stats)
+	}
+}
+
+func TestSplitBrainStatsNilWithSingleMaster(t *testing.T) {
+	c := NewCluster()
+	c.observeMasters([]string{"localhost:8081"}, time.Now())
+	if stats := c.SplitBrainStats(); stats != nil {
+		t.Errorf("Expected no split brain stats with one master, got %+v", stats)
+	}
+}
@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// Keyspace splits the partition-key hash space into contiguous ranges, each
+// owned by one coordinator, so several masters can coordinate disjoint sets
+// of transactions. Keys are hashed with 32-bit FNV-1a (see PartitionHash).
+type Keyspace struct {
+	partitions []protocol.KeyspacePartition
+}
+
+// NewKeyspace splits the hash space evenly between coordinators. They are
+// canonicalized and sorted first, so every node given the same list builds the
+// same map regardless of order or spelling.
+func NewKeyspace(coordinators []string) (*Keyspace, error) {
+	seen := make(map[string]bool, len(coordinators))
+	var owners []string
+	for _, addr := range coordinators {
+		key := CanonicalAddr(addr)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		owners = append(owners, key)
+	}
+	if len(owners) == 0 {
+		return nil, errors.New("keyspace needs at least one coordinator")
+	}
+	sort.Strings(owners)
+
+	size := (uint64(math.MaxUint32) + 1) / uint64(len(owners))
+	partitions := make([]protocol.KeyspacePartition, len(owners))
+	for i, owner := range owners {
+		start := uint64(i) * size
+		end := start + size - 1
+		if i == len(owners)-1 {
+			end = math.MaxUint32
+		}
+		partitions[i] = protocol.KeyspacePartition{Start: uint32(start), End: uint32(end), Coordinator: owner}
+	}
+
+	return &Keyspace{partitions: partitions}, nil
+}
+
+// NewKeyspaceFromPartitions builds a keyspace from explicit ranges. They must
+// be sorted by Start and cover the whole hash space without gaps or overlaps.
+func NewKeyspaceFromPartitions(partitions []protocol.KeyspacePartition) (*Keyspace, error) {
+	if len(partitions) == 0 {
+		return nil, errors.New("keyspace needs at least one partition")
+	}
+
+	var next uint64
+	for i, p := range partitions {
+		if p.Coordinator == "" {
+			return nil, fmt.Errorf("partition %d has no coordinator", i)
+		}
+		if uint64(p.Start) != next || p.End < p.Start {
+			return nil, fmt.Errorf("partition %d [%d, %d] does not start at %d", i, p.Start, p.End, next)
+		}
+		next = uint64(p.End) + 1
+	}
+	if next != uint64(math.MaxUint32)+1 {
+		return nil, fmt.Errorf("partitions end at %d, not %d", next-1, uint64(math.MaxUint32))
+	}
+
+	out := make([]protocol.KeyspacePartition, len(partitions))
+	for i, p := range partitions {
+		p.Coordinator = CanonicalAddr(p.Coordinator)
+		out[i] = p
+	}
+	return &Keyspace{partitions: out}, nil
+}
+
+// PartitionHash maps a partition key to its position in the hash space.
+func PartitionHash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// Owner returns the coordinator owning key's partition.
+func (k *Keyspace) Owner(key string) string {
+	h := PartitionHash(key)
+	i := sort.Search(len(k.partitions), func(i int) bool { return k.partitions[i].End >= h })
+	return k.partitions[i].Coordinator
+}
+
+// Partitions returns a copy of the ranges, in hash order.
+func (k *Keyspace) Partitions() []protocol.KeyspacePartition {
+	return append([]protocol.KeyspacePartition(nil), k.partitions...)
+}
+
+// SetKeyspace partitions transactions between coordinators by partition key.
+// Nil turns partitioning off, leaving every transaction to the master.
+func (c *Cluster) SetKeyspace(k *Keyspace) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keyspace = k
+}
+
+// GetKeyspace returns the keyspace, or nil when transactions are not partitioned.
+func (c *Cluster) GetKeyspace() *Keyspace {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.keyspace
+}
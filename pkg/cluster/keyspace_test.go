@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestNewKeyspaceSplitsEvenly(t *testing.T) {
+	ks, err := NewKeyspace([]string{"node-c:8080", "node-a:8080", "NODE-B:8080", "node-a:8080"})
+	if err != nil {
+		t.Fatalf("NewKeyspace failed: %v", err)
+	}
+
+	want := []protocol.KeyspacePartition{
+		{Start: 0, End: 1431655764, Coordinator: "node-a:8080"},
+		{Start: 1431655765, End: 2863311529, Coordinator: "node-b:8080"},
+		{Start: 2863311530, End: math.MaxUint32, Coordinator: "node-c:8080"},
+	}
+	if got := ks.Partitions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Partitions = %+v, want %+v", got, want)
+	}
+
+	// Every node must build the same map from the same set of coordinators.
+	other, _ := NewKeyspace([]string{"node-b:8080", "node-c:8080", "node-a:8080"})
+	if !reflect.DeepEqual(other.Partitions(), want) {
+		t.Errorf("Expected the order of coordinators not to matter, got %+v", other.Partitions())
+	}
+
+	if _, err := NewKeyspace([]string{" ", ""}); err == nil {
+		t.Error("Expected a keyspace without coordinators to be rejected")
+	}
+}
+
+func TestKeyspaceOwnerFollowsHashRanges(t *testing.T) {
+	ks, _ := NewKeyspace([]string{"node-a:8080", "node-b:8080"})
+
+	owned := map[string]int{}
+	for i := range 1000 {
+		key := fmt.Sprintf("customer-%d", i)
+		owner := ks.Owner(key)
+		if ks.Owner(key) != owner {
+			t.Fatalf("Owner(%q) is not stable", key)
+		}
+
+		wantOwner := "node-a:8080"
+		if PartitionHash(key) > 1<<31-1 {
+			wantOwner = "node-b:8080"
+		}
+		if owner != wantOwner {
+			t.Fatalf("Owner(%q) = %s, want %s (hash %d)", key, owner, wantOwner, PartitionHash(key))
+		}
+		owned[owner]++
+	}
+
+	if owned["node-a:8080"] < 400 || owned["node-b:8080"] < 400 {
+		t.Errorf("Expected keys to spread over both coordinators, got %v", owned)
+	}
+}
+
+func TestNewKeyspaceFromPartitionsValidatesCoverage(t *testing.T) {
+	valid := []protocol.KeyspacePartition{
+		{Start: 0, End: 99, Coordinator: "node-a:8080"},
+		{Start: 100, End: math.MaxUint32, Coordinator: "node-b:8080"},
+	}
+	ks, err := NewKeyspaceFromPartitions(valid)
+	if err != nil {
+		t.Fatalf("NewKeyspaceFromPartitions failed: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		want := "node-b:8080"
+		if PartitionHash(key) <= 99 {
+			want = "node-a:8080"
+		}
+		if got := ks.Owner(key); got != want {
+			t.Errorf("Owner(%q) = %s, want %s", key, got, want)
+		}
+	}
+
+	for name, parts := range map[string][]protocol.KeyspacePartition{
+		"empty":          nil,
+		"gap":            {{Start: 0, End: 99, Coordinator: "a:1"}, {Start: 101, End: math.MaxUint32, Coordinator: "b:1"}},
+		"overlap":        {{Start: 0, End: 99, Coordinator: "a:1"}, {Start: 50, End: math.MaxUint32, Coordinator: "b:1"}},
+		"short":          {{Start: 0, End: 99, Coordinator: "a:1"}},
+		"not from zero":  {{Start: 1, End: math.MaxUint32, Coordinator: "a:1"}},
+		"no coordinator": {{Start: 0, End: math.MaxUint32}},
+	} {
+		if _, err := NewKeyspaceFromPartitions(parts); err == nil {
+			t.Errorf("%s: expected the partitions to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// SetMaintenance moves member req.Address into or out of maintenance mode.
+// Entering, the member is first dropped from this view's participants, then
+// told to refuse new prepares and drain the ones it holds; a master steps down
+// once the drain returns. Leaving, the member is told first and rejoins this
+// view afterwards. The local node is switched directly, others over HTTP.
+func (c *Cluster) SetMaintenance(req *protocol.MaintenanceRequest, local *node.Node) (*protocol.MaintenanceResponse, error) {
+	n := c.GetNode(req.Address)
+	if n == nil {
+		return nil, fmt.Errorf("node %s: %w", req.Address, ErrNodeNotFound)
+	}
+
+	if req.Enabled {
+		n.SetMaintenance(true)
+	}
+
+	drainTimeout := time.Duration(req.DrainTimeoutMs) * time.Millisecond
+	if drainTimeout <= 0 {
+		drainTimeout = node.DefaultDrainTimeout
+	}
+
+	var resp *protocol.MaintenanceResponse
+	if local != nil && SameAddr(n.Addr, local.Addr) {
+		resp = local.ApplyMaintenance(req.Enabled, drainTimeout)
+	} else {
+		// The node answers only after draining, so allow for the full drain.
+		client := transport.NewHTTPClient(drainTimeout + 5*time.Second)
+		var err error
+		resp, err = client.SetNodeMaintenance(n.Addr, &protocol.MaintenanceRequest{
+			Address:        n.Addr,
+			Enabled:        req.Enabled,
+			DrainTimeoutMs: drainTimeout.Milliseconds(),
+		})
+		if err != nil {
+			if req.Enabled {
+				n.SetMaintenance(false)
+			}
+			return nil, fmt.Errorf("node %s: %w", n.Addr, err)
+		}
+	}
+
+	if !req.Enabled {
+		n.SetMaintenance(false)
+	}
+	c.CheckAndElect()
+
+	return resp, nil
+}
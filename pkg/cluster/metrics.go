@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// DefaultMetricsDeadline bounds how long a cluster summary waits for members'
+// metrics, so a few dead nodes cannot stall the dashboard.
+const DefaultMetricsDeadline = 2 * time.Second
+
+type metricsResult struct {
+	addr    string
+	metrics *protocol.NodeMetrics
+}
+
+// CollectMetrics fetches every member's metrics in parallel, keyed by node
+// address. The local node is read directly. Members that fail or do not answer
+// within deadline are missing from the result; their late answers are dropped.
+func (c *Cluster) CollectMetrics(local *node.Node, client *transport.HTTPClient, deadline time.Duration) map[string]protocol.NodeMetrics {
+	nodes := c.GetNodes()
+	out := make(map[string]protocol.NodeMetrics, len(nodes))
+
+	// Buffered so fetches finishing after the deadline never block.
+	results := make(chan metricsResult, len(nodes))
+	pending := 0
+
+	for _, n := range nodes {
+		if local != nil && SameAddr(n.Addr, local.Addr) {
+			out[n.Addr] = local.Metrics()
+			continue
+		}
+
+		pending++
+		addr := n.Addr
+		go func() {
+			m, err := client.GetMetrics(addr)
+			if err != nil {
+				m = nil
+			}
+			results <- metricsResult{addr: addr, metrics: m}
+		}()
+	}
+
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.metrics != nil {
+				out[r.addr] = *r.metrics
+			}
+		case <-timer.C:
+			return out
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,151 @@
+package cluster
+
+import (
+	"sort"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// ElectionCandidate is what an election looks at for one member.
+type ElectionCandidate struct {
+	Address     string
+	Alive       bool
+	Disabled    bool
+	Maintenance bool
+	Draining    bool
+
+	// Informational only: the election does not weigh them.
+	Priority int
+	InFlight int
+}
+
+// candidateFromNode builds a candidate for member n listed under key. Elections
+// call it under the cluster lock, so the in-flight count is read without
+// taking the node's lock.
+func candidateFromNode(key string, n *node.Node) ElectionCandidate {
+	return ElectionCandidate{
+		Address:     key,
+		Alive:       n.GetAlive(),
+		Disabled:    n.GetDisabled(),
+		Maintenance: n.GetMaintenance(),
+		Draining:    n.GetDraining(),
+		Priority:    n.GetPriority(),
+		InFlight:    n.PendingCount(),
+	}
+}
+
+// candidateFromNodeInfo builds a candidate from a cluster summary entry.
+func candidateFromNodeInfo(info protocol.NodeInfo) ElectionCandidate {
+	c := ElectionCandidate{
+		Address:     info.Address,
+		Alive:       info.Alive,
+		Disabled:    info.Disabled,
+		Maintenance: info.Maintenance,
+		Draining:    info.Draining,
+		InFlight:    info.Metrics.InFlight,
+	}
+	if info.Detail != nil {
+		c.Priority = info.Detail.Priority
+	}
+	return c
+}
+
+// CandidatesFromNodeInfo turns a cluster summary into election candidates.
+func CandidatesFromNodeInfo(nodes []protocol.NodeInfo) []ElectionCandidate {
+	out := make([]ElectionCandidate, len(nodes))
+	for i, info := range nodes {
+		out[i] = candidateFromNodeInfo(info)
+	}
+	return out
+}
+
+// excludedBecause returns why the election skips c, or "" if c is eligible.
+// It mirrors node.Eligible.
+func (c ElectionCandidate) excludedBecause() string {
+	switch {
+	case c.Disabled:
+		return protocol.ExcludeDisabled
+	case c.Draining:
+		return protocol.ExcludeDraining
+	case c.Maintenance:
+		return protocol.ExcludeMaintenance
+	case !c.Alive:
+		return protocol.ExcludeDead
+	default:
+		return ""
+	}
+}
+
+// Reasons given by an ElectionPlan for its outcome.
+const (
+	PlanKeepMaster    = "current master is alive and neither in maintenance nor draining, so elections keep it"
+	PlanNoSuccessor   = "current master is in maintenance or draining, but no other node can take over"
+	PlanLowestAddress = "lowest address among eligible nodes"
+	PlanNoEligible    = "no eligible node"
+)
+
+// PlannedCandidate is one member's place in a simulated election.
+type PlannedCandidate struct {
+	ElectionCandidate
+	Excluded string // why it cannot be elected (protocol.Exclude*), "" if eligible
+	Rank     int    // 1 for the node a fresh election picks, 0 when excluded
+}
+
+// ElectionPlan is the outcome of a simulated election.
+type ElectionPlan struct {
+	Current    string // master at the time of the plan, "" if none
+	Master     string // node that would be master after the next election check
+	Fresh      string // node a forced election (ElectMaster) would pick
+	Reason     string // why Master is the outcome (one of the Plan* reasons)
+	Candidates []PlannedCandidate
+}
+
+// PlanElection simulates the election over candidates without changing
+// anything, using the same rules as the cluster: an alive master that is
+// neither in maintenance nor draining is kept (CheckAndElect), otherwise the eligible node with the
+// lowest canonical address wins. Priority and load are reported but do not
+// influence the outcome.
+func PlanElection(current string, candidates []ElectionCandidate) ElectionPlan {
+	plan := ElectionPlan{Current: current}
+
+	planned := make([]PlannedCandidate, len(candidates))
+	var eligible []int
+	var incumbent *ElectionCandidate
+	for i, c := range candidates {
+		planned[i] = PlannedCandidate{ElectionCandidate: c, Excluded: c.excludedBecause()}
+		if planned[i].Excluded == "" {
+			eligible = append(eligible, i)
+		}
+		if current != "" && SameAddr(c.Address, current) {
+			incumbent = &candidates[i]
+		}
+	}
+
+	sort.SliceStable(eligible, func(a, b int) bool {
+		return CanonicalAddr(planned[eligible[a]].Address) < CanonicalAddr(planned[eligible[b]].Address)
+	})
+	for rank, i := range eligible {
+		planned[i].Rank = rank + 1
+	}
+	if len(eligible) > 0 {
+		plan.Fresh = planned[eligible[0]].Address
+	}
+
+	switch {
+	case incumbent != nil && incumbent.Alive && !incumbent.Maintenance && !incumbent.Draining:
+		plan.Master, plan.Reason = incumbent.Address, PlanKeepMaster
+	case incumbent != nil && incumbent.Alive && plan.Fresh == "":
+		plan.Master, plan.Reason = incumbent.Address, PlanNoSuccessor
+	case plan.Fresh != "":
+		plan.Master, plan.Reason = plan.Fresh, PlanLowestAddress
+	default:
+		plan.Reason = PlanNoEligible
+	}
+
+	sort.SliceStable(planned, func(a, b int) bool {
+		return CanonicalAddr(planned[a].Address) < CanonicalAddr(planned[b].Address)
+	})
+	plan.Candidates = planned
+	return plan
+}
@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestPlanElection(t *testing.T) {
+	alive := func(addr string) ElectionCandidate { return ElectionCandidate{Address: addr, Alive: true} }
+	dead := ElectionCandidate{Address: "node-a:8080"}
+	disabled := ElectionCandidate{Address: "node-b:8080", Alive: true, Disabled: true}
+	maint := ElectionCandidate{Address: "node-c:8080", Alive: true, Maintenance: true}
+	draining := ElectionCandidate{Address: "node-b:9090", Alive: true, Draining: true}
+
+	tests := []struct {
+		name       string
+		current    string
+		candidates []ElectionCandidate
+		master     string
+		fresh      string
+		reason     string
+	}{
+		{
+			name:       "keeps healthy incumbent over lower address",
+			current:    "node-z:8080",
+			candidates: []ElectionCandidate{alive("node-z:8080"), alive("node-d:8080")},
+			master:     "node-z:8080",
+			fresh:      "node-d:8080",
+			reason:     PlanKeepMaster,
+		},
+		{
+			name:       "replaces incumbent in maintenance",
+			current:    "node-c:8080",
+			candidates: []ElectionCandidate{maint, alive("node-e:8080")},
+			master:     "node-e:8080",
+			fresh:      "node-e:8080",
+			reason:     PlanLowestAddress,
+		},
+		{
+			name:       "replaces draining incumbent",
+			current:    "node-b:9090",
+			candidates: []ElectionCandidate{draining, alive("node-e:8080")},
+			master:     "node-e:8080",
+			fresh:      "node-e:8080",
+			reason:     PlanLowestAddress,
+		},
+		{
+			name:       "keeps incumbent in maintenance without successor",
+			current:    "node-c:8080",
+			candidates: []ElectionCandidate{maint, dead, disabled},
+			master:     "node-c:8080",
+			reason:     PlanNoSuccessor,
+		},
+		{
+			name:       "skips excluded nodes",
+			candidates: []ElectionCandidate{dead, disabled, maint, draining, alive("node-f:8080"), alive("node-e:8080")},
+			master:     "node-e:8080",
+			fresh:      "node-e:8080",
+			reason:     PlanLowestAddress,
+		},
+		{
+			name:       "ranks by canonical address",
+			current:    "node-a:8080",
+			candidates: []ElectionCandidate{dead, alive("localhost:9000"), alive("127.0.0.1:8081")},
+			master:     "127.0.0.1:8081",
+			fresh:      "127.0.0.1:8081",
+			reason:     PlanLowestAddress,
+		},
+		{
+			name:       "no eligible node",
+			candidates: []ElectionCandidate{dead, disabled, maint},
+			reason:     PlanNoEligible,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanElection(tt.current, tt.candidates)
+			if plan.Master != tt.master || plan.Fresh != tt.fresh || plan.Reason != tt.reason {
+				t.Fatalf("plan = master %q fresh %q (%s), want master %q fresh %q (%s)",
+					plan.Master, plan.Fresh, plan.Reason, tt.master, tt.fresh, tt.reason)
+			}
+			if len(plan.Candidates) != len(tt.candidates) {
+				t.Fatalf("expected %d planned candidates, got %d", len(tt.candidates), len(plan.Candidates))
+			}
+		})
+	}
+}
+
+func TestPlanElectionExplainsEachCandidate(t *testing.T) {
+	plan := PlanElection("", []ElectionCandidate{
+		{Address: "node-d:8080", Alive: true, Priority: 9, InFlight: 40},
+		{Address: "node-a:8080"},
+		{Address: "node-b:8080", Alive: true, Disabled: true},
+		{Address: "node-c:8080", Alive: true, Maintenance: true},
+		{Address: "node-e:8080", Alive: true},
+		{Address: "node-f:8080", Alive: true, Draining: true},
+	})
+
+	want := []struct {
+		addr     string
+		excluded string
+		rank     int
+	}{
+		{"node-a:8080", protocol.ExcludeDead, 0},
+		{"node-b:8080", protocol.ExcludeDisabled, 0},
+		{"node-c:8080", protocol.ExcludeMaintenance, 0},
+		{"node-d:8080", "", 1},
+		{"node-e:8080", "", 2},
+		{"node-f:8080", protocol.ExcludeDraining, 0},
+	}
+	for i, w := range want {
+		got := plan.Candidates[i]
+		if got.Address != w.addr || got.Excluded != w.excluded || got.Rank != w.rank {
+			t.Errorf("candidate %d = %s excluded %q rank %d, want %s excluded %q rank %d",
+				i, got.Address, got.Excluded, got.Rank, w.addr, w.excluded, w.rank)
+		}
+	}
+	if plan.Candidates[3].Priority != 9 || plan.Candidates[3].InFlight != 40 {
+		t.Errorf("priority and load must be carried through, got %+v", plan.Candidates[3])
+	}
+}
+
+func TestPlanElectionMatchesElectMaster(t *testing.T) {
+	c := NewCluster()
+	for _, addr := range []string{"node-c:8080", "node-a:8080", "node-b:8080"} {
+		n := node.NewNode(addr, protocol.RoleSlave)
+		n.SetAlive(true)
+		c.AddNode(n)
+	}
+	c.GetNode("node-a:8080").SetMaintenance(true)
+	c.GetNode("node-b:8080").SetDraining(true)
+
+	var candidates []ElectionCandidate
+	for _, n := range c.GetNodes() {
+		candidates = append(candidates, candidateFromNode(n.Addr, n))
+	}
+	plan := PlanElection("", candidates)
+
+	c.ElectMaster()
+	if got := c.GetMaster(); got == nil || got.Addr != plan.Fresh {
+		t.Fatalf("plan picked %q but ElectMaster picked %v", plan.Fresh, got)
+	}
+}
@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// ErrUnknownNode is returned when a node reference matches no member's address or name.
+var ErrUnknownNode error = protocol.NewAPIError(protocol.ErrCodeNodeNotFound, "unknown node")
+
+// ErrAmbiguousNode is returned when a display name is shared by several members.
+var ErrAmbiguousNode error = protocol.NewAPIError(protocol.ErrCodeAmbiguousNode, "node name is ambiguous")
+
+// ResolveNodeRef finds the node ref refers to among nodes. ref is matched as an
+// address first (after canonicalization) and then as a display name, so an
+// address always wins over a node that happens to be named like it.
+func ResolveNodeRef(ref string, nodes []*node.Node) (*node.Node, error) {
+	ref = strings.TrimSpace(ref)
+
+	for _, n := range nodes {
+		if SameAddr(n.Addr, ref) {
+			return n, nil
+		}
+	}
+
+	var matches []*node.Node
+	for _, n := range nodes {
+		if n.GetName() == ref {
+			matches = append(matches, n)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w %q: no member has that address or name", ErrUnknownNode, ref)
+	case 1:
+		return matches[0], nil
+	default:
+		addrs := make([]string, len(matches))
+		for i, n := range matches {
+			addrs[i] = n.Addr
+		}
+		sort.Strings(addrs)
+		return nil, fmt.Errorf("%w: %q is used by %s; use an address", ErrAmbiguousNode, ref, strings.Join(addrs, ", "))
+	}
+}
+
+// ResolveNode returns the address of the member ref refers to, by address or
+// display name.
+func (c *Cluster) ResolveNode(ref string) (string, error) {
+	n, err := ResolveNodeRef(ref, c.GetNodes())
+	if err != nil {
+		return "", err
+	}
+	return n.Addr, nil
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// observeMasters records the nodes that claimed the MASTER role in one
+// heartbeat scan. Two or more is a split brain: it is logged loudly and
+// counted each time it begins or the set of masters changes.
+func (c *Cluster) observeMasters(masters []string, now time.Time) {
+	sort.Strings(masters)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(masters) < 2 {
+		if c.multipleMastersSeen != nil {
+			logging.Infof("[Cluster] Split brain resolved: %d node(s) now claim MASTER", len(masters))
+			c.multipleMastersSeen = nil
+		}
+		return
+	}
+	if slices.Equal(masters, c.multipleMastersSeen) {
+		return
+	}
+
+	c.multipleMasters++
+	c.multipleMastersAt = now
+	c.multipleMastersSeen = masters
+	logging.Warnf("[Cluster] SPLIT BRAIN: %d nodes claim MASTER: %v. Transactions may be coordinated twice; intervene now", len(masters), masters)
+}
+
+// SplitBrainStats reports how often more than one master was observed and
+// which nodes claim the role now. It returns nil if that never happened.
+func (c *Cluster) SplitBrainStats() *protocol.SplitBrainStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.multipleMasters == 0 {
+		return nil
+	}
+	at := c.multipleMastersAt
+	return &protocol.SplitBrainStats{
+		MultipleMastersDetected: c.multipleMasters,
+		LastAt:                  &at,
+		Masters:                 slices.Clone(c.multipleMastersSeen),
+	}
+}
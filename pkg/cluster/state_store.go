@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -17,6 +18,13 @@ import (
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
 
+var (
+	// ErrStateKeyMismatch means the state file exists but the configured key cannot decrypt it.
+	ErrStateKeyMismatch = errors.New("state file cannot be decrypted with the configured key")
+	// ErrStateCorrupt means the state file exists but is not a valid encrypted state blob.
+	ErrStateCorrupt = errors.New("state file is corrupt")
+)
+
 // ClusterState holds the minimal data we persist for names and membership.
 type ClusterState struct {
 	Nodes     []StoredNode `json:"nodes"`
@@ -28,6 +36,10 @@ type StoredNode struct {
 	Address  string `json:"address"`
 	Name     string `json:"name,omitempty"`
 	Database string `json:"database,omitempty"`
+	// Priority and Labels were added later; older state files omit them.
+	Priority int               `json:"priority,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
 }
 
 // StateStore handles encrypted persistence of cluster state.
@@ -48,7 +60,15 @@ func NewStateStore(path, key string) *StateStore {
 	}
 }
 
-// SaveCluster captures the current cluster nodes (names + DB labels) and writes them encrypted.
+// Path returns the location of the state file.
+func (s *StateStore) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
+
+// SaveCluster captures the current cluster nodes (names, DB labels, priorities, labels) and writes them encrypted.
 func (s *StateStore) SaveCluster(c *Cluster) error {
 	if s == nil {
 		return nil
@@ -68,6 +88,9 @@ func (s *StateStore) SaveCluster(c *Cluster) error {
 			Address:  n.Addr,
 			Name:     n.GetName(),
 			Database: n.GetDatabase(),
+			Priority: n.GetPriority(),
+			Labels:   n.GetLabels(),
+			Disabled: n.GetDisabled(),
 		})
 	}
 
@@ -110,7 +133,8 @@ func (s *StateStore) Save(state *ClusterState) error {
 	return os.WriteFile(s.path, []byte(encoded), 0o600)
 }
 
-// Load reads and decrypts cluster state from disk.
+// Load reads and decrypts cluster state from disk. A missing file yields (nil, nil);
+// a file that exists but cannot be read back returns ErrStateKeyMismatch or ErrStateCorrupt.
 func (s *StateStore) Load() (*ClusterState, error) {
 	if s == nil {
 		return nil, nil
@@ -126,7 +150,7 @@ func (s *StateStore) Load() (*ClusterState, error) {
 
 	raw, err := base64.StdEncoding.DecodeString(string(content))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrStateCorrupt, err)
 	}
 
 	block, err := aes.NewCipher(s.key)
@@ -141,7 +165,7 @@ func (s *StateStore) Load() (*ClusterState, error) {
 
 	nonceSize := gcm.NonceSize()
 	if len(raw) < nonceSize {
-		return nil, errors.New("invalid ciphertext")
+		return nil, fmt.Errorf("%w: invalid ciphertext", ErrStateCorrupt)
 	}
 
 	nonce := raw[:nonceSize]
@@ -149,18 +173,52 @@ func (s *StateStore) Load() (*ClusterState, error) {
 
 	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", ErrStateKeyMismatch, s.path)
 	}
 
 	var state ClusterState
 	if err := json.Unmarshal(plain, &state); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrStateCorrupt, err)
 	}
 
 	return &state, nil
 }
 
-// ApplyState merges persisted nodes back into the cluster, updating names and DB labels.
+// ExportJSON decrypts the state file and writes it as indented plaintext JSON.
+func (s *StateStore) ExportJSON(w io.Writer) error {
+	if s == nil {
+		return errors.New("state store not configured")
+	}
+
+	state, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no state file at %s", s.path)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(state)
+}
+
+// ImportJSON reads plaintext ClusterState JSON and writes it encrypted to the state file.
+func (s *StateStore) ImportJSON(r io.Reader) error {
+	if s == nil {
+		return errors.New("state store not configured")
+	}
+
+	var state ClusterState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("invalid cluster state JSON: %w", err)
+	}
+
+	return s.Save(&state)
+}
+
+// ApplyState merges persisted nodes back into the cluster, updating names, DB labels,
+// priorities and labels. Fields missing from older state files leave node defaults intact.
 func ApplyState(c *Cluster, state *ClusterState, local *node.Node) {
 	if c == nil || state == nil {
 		return
@@ -172,7 +230,7 @@ func ApplyState(c *Cluster, state *ClusterState, local *node.Node) {
 		}
 
 		// Update local node metadata if present.
-		if local != nil && sn.Address == local.Addr {
+		if local != nil && SameAddr(sn.Address, local.Addr) {
 			if sn.Name != "" {
 				local.SetName(sn.Name)
 			}
@@ -180,12 +238,20 @@ func ApplyState(c *Cluster, state *ClusterState, local *node.Node) {
 			if sn.Database != "" {
 				local.SetDatabase(sn.Database)
 			}
+
+			if sn.Priority != 0 {
+				local.SetPriority(sn.Priority)
+			}
+
+			if len(sn.Labels) > 0 {
+				local.SetLabels(sn.Labels)
+			}
 		}
 
 		n := c.GetNode(sn.Address)
 		if n == nil {
 			role := protocol.RoleSlave
-			if local != nil && sn.Address == local.Addr {
+			if local != nil && SameAddr(sn.Address, local.Addr) {
 				role = local.GetRole()
 			}
 			n = node.NewNode(sn.Address, role)
@@ -199,6 +265,16 @@ func ApplyState(c *Cluster, state *ClusterState, local *node.Node) {
 		if sn.Database != "" {
 			n.SetDatabase(sn.Database)
 		}
+
+		if sn.Priority != 0 {
+			n.SetPriority(sn.Priority)
+		}
+
+		if len(sn.Labels) > 0 {
+			n.SetLabels(sn.Labels)
+		}
+
+		n.SetDisabled(sn.Disabled)
 		n.SetAlive(true)
 	}
 }
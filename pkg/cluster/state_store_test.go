@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestStateStoreLoadMissingFile(t *testing.T) {
+	store := NewStateStore(filepath.Join(t.TempDir(), "state.enc"), "key")
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Expected no error for missing file, got %v", err)
+	}
+	if state != nil {
+		t.Errorf("Expected nil state for missing file, got %+v", state)
+	}
+}
+
+func TestStateStoreLoadWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.enc")
+
+	if err := NewStateStore(path, "right-key").Save(&ClusterState{
+		Nodes: []StoredNode{{Address: "localhost:8081", Name: "Shard-1"}},
+	}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	state, err := NewStateStore(path, "wrong-key").Load()
+	if !errors.Is(err, ErrStateKeyMismatch) {
+		t.Fatalf("Expected ErrStateKeyMismatch, got %v", err)
+	}
+	if state != nil {
+		t.Errorf("Expected nil state on key mismatch, got %+v", state)
+	}
+
+	state, err = NewStateStore(path, "right-key").Load()
+	if err != nil {
+		t.Fatalf("Load with the right key failed: %v", err)
+	}
+	if len(state.Nodes) != 1 || state.Nodes[0].Name != "Shard-1" {
+		t.Errorf("Unexpected state after reload: %+v", state)
+	}
+}
+
+func TestStateStoreLoadCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.enc")
+	if err := os.WriteFile(path, []byte("not base64!"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	_, err := NewStateStore(path, "key").Load()
+	if !errors.Is(err, ErrStateCorrupt) {
+		t.Fatalf("Expected ErrStateCorrupt, got %v", err)
+	}
+}
+
+func TestStateStoreExportImportRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := NewStateStore(filepath.Join(dir, "src.enc"), "source-key")
+
+	original := &ClusterState{
+		Nodes: []StoredNode{
+			{Address: "localhost:8080", Name: "Master", Database: "postgres://****@db1/app"},
+			{Address: "localhost:8081", Name: "Shard-1"},
+		},
+	}
+	if err := src.Save(original); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportJSON(&buf); err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"Shard-1"`) {
+		t.Errorf("Expected plaintext export, got %s", buf.String())
+	}
+
+	// Import into a store with a different key, as in a cross-environment migration.
+	dst := NewStateStore(filepath.Join(dir, "dst.enc"), "target-key")
+	if err := dst.ImportJSON(&buf); err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+
+	restored, err := dst.Load()
+	if err != nil {
+		t.Fatalf("Load after import failed: %v", err)
+	}
+	if len(restored.Nodes) != len(original.Nodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(original.Nodes), len(restored.Nodes))
+	}
+	for i, n := range original.Nodes {
+		if !reflect.DeepEqual(restored.Nodes[i], n) {
+			t.Errorf("Node %d = %+v, want %+v", i, restored.Nodes[i], n)
+		}
+	}
+}
+
+func TestStateStoreImportRejectsInvalidJSON(t *testing.T) {
+	store := NewStateStore(filepath.Join(t.TempDir(), "state.enc"), "key")
+	if err := store.ImportJSON(strings.NewReader("{not json")); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestStateStorePersistsPriorityAndLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.enc")
+	store := NewStateStore(path, "key")
+
+	c := NewCluster()
+	n := node.NewNode("localhost:8081", protocol.RoleSlave)
+	n.SetPriority(10)
+	n.SetLabels(map[string]string{"zone": "eu-1", "tier": "ssd"})
+	c.AddNode(n)
+	c.AddNode(node.NewNode("localhost:8082", protocol.RoleSlave))
+
+	if err := store.SaveCluster(c); err != nil {
+		t.Fatalf("SaveCluster failed: %v", err)
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	restored := NewCluster()
+	ApplyState(restored, state, nil)
+
+	got := restored.GetNode("localhost:8081")
+	if got == nil {
+		t.Fatal("Expected node to be reconstructed")
+	}
+	if got.GetPriority() != 10 {
+		t.Errorf("Priority = %d, want 10", got.GetPriority())
+	}
+	if labels := got.GetLabels(); labels["zone"] != "eu-1" || labels["tier"] != "ssd" {
+		t.Errorf("Labels = %v, want zone=eu-1 tier=ssd", labels)
+	}
+
+	plain := restored.GetNode("localhost:8082")
+	if plain.GetPriority() != 0 || len(plain.GetLabels()) != 0 {
+		t.Errorf("Expected defaults for node without metadata, got priority=%d labels=%v", plain.GetPriority(), plain.GetLabels())
+	}
+}
+
+func TestApplyStateLegacyFileWithoutPriorityOrLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.enc")
+	store := NewStateStore(path, "key")
+
+	// State written before priorities/labels existed.
+	legacy := `{"nodes":[{"address":"localhost:8081","name":"Shard-1"}],"generated_at":"2024-01-01T00:00:00Z"}`
+	if err := store.ImportJSON(strings.NewReader(legacy)); err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	c := NewCluster()
+	existing := node.NewNode("localhost:8081", protocol.RoleSlave)
+	existing.SetPriority(5)
+	c.AddNode(existing)
+	ApplyState(c, state, nil)
+
+	if existing.GetName() != "Shard-1" {
+		t.Errorf("Name = %q, want Shard-1", existing.GetName())
+	}
+	if existing.GetPriority() != 5 {
+		t.Errorf("Legacy state must not reset priority, got %d", existing.GetPriority())
+	}
+	if existing.GetLabels() != nil {
+		t.Errorf("Expected no labels, got %v", existing.GetLabels())
+	}
+}
+
+func TestApplyStateMatchesLocalNodeByLoopbackAlias(t *testing.T) {
+	c := NewCluster()
+	local := node.NewNode("localhost:8081", protocol.RoleMaster)
+	c.AddNode(local)
+
+	state := &ClusterState{Nodes: []StoredNode{{Address: "127.0.0.1:8081", Name: "Primary", Priority: 3}}}
+	ApplyState(c, state, local)
+
+	if c.Size() != 1 {
+		t.Fatalf("Expected loopback alias to map onto the local node, got members %v", c.GetNodeAddresses())
+	}
+	if local.GetName() != "Primary" || local.GetPriority() != 3 {
+		t.Errorf("Expected local node metadata from state, got name=%q priority=%d", local.GetName(), local.GetPriority())
+	}
+	if local.GetRole() != protocol.RoleMaster {
+		t.Errorf("Local role = %v, want master", local.GetRole())
+	}
+}
@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// MemberSpec is one member of a desired membership.
+type MemberSpec struct {
+	Addr string
+	Meta NodeMeta
+}
+
+// SyncResult lists, by address, what Sync changed. Each list is sorted.
+type SyncResult struct {
+	Added   []string
+	Removed []string
+	Updated []string // members whose metadata changed
+}
+
+// Sync makes the membership equal to desired in one step, under the cluster
+// lock: members missing from the cluster are created with newNode and added,
+// members not in desired are removed, and the others get their metadata
+// updated as by UpsertNode. keep is the address of the local node, which must
+// be listed, since a node cannot remove itself. If desired is invalid,
+// nothing changes. Syncing to the current membership changes nothing.
+func (c *Cluster) Sync(desired []MemberSpec, keep string, newNode func(addr string) *node.Node) (SyncResult, error) {
+	want := make(map[string]MemberSpec, len(desired))
+	for _, spec := range desired {
+		if spec.Addr == "" {
+			return SyncResult{}, protocol.NewAPIError(protocol.ErrCodeInvalidRequest, "every member needs an address")
+		}
+		key := CanonicalAddr(spec.Addr)
+		if prev, dup := want[key]; dup {
+			return SyncResult{}, protocol.NewAPIError(protocol.ErrCodeInvalidRequest,
+				fmt.Sprintf("%s and %s are the same member", prev.Addr, spec.Addr))
+		}
+		want[key] = spec
+	}
+	if keep != "" {
+		if _, ok := want[CanonicalAddr(keep)]; !ok {
+			return SyncResult{}, protocol.NewAPIError(protocol.ErrCodeInvalidRequest,
+				fmt.Sprintf("desired membership must include %s, the node applying it", keep))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var res SyncResult
+	for key, n := range c.nodes {
+		if _, ok := want[key]; !ok {
+			res.Removed = append(res.Removed, n.Addr)
+			c.removeLocked(key)
+		}
+	}
+	for key, spec := range want {
+		if n, ok := c.nodes[key]; ok {
+			if spec.Meta.apply(n) {
+				res.Updated = append(res.Updated, n.Addr)
+			}
+			continue
+		}
+		n := newNode(spec.Addr)
+		spec.Meta.apply(n)
+		n.MarkJoined(time.Now())
+		c.nodes[key] = n
+		res.Added = append(res.Added, n.Addr)
+	}
+
+	sort.Strings(res.Added)
+	sort.Strings(res.Removed)
+	sort.Strings(res.Updated)
+	return res, nil
+}
+
+// MemberSpecs converts the nodes of a sync request into member specs.
+func MemberSpecs(nodes []protocol.AddNodeRequest) []MemberSpec {
+	specs := make([]MemberSpec, 0, len(nodes))
+	for _, n := range nodes {
+		specs = append(specs, MemberSpec{
+			Addr: n.Address,
+			Meta: NodeMeta{Name: n.Name, Database: n.Database, Labels: n.Labels},
+		})
+	}
+	return specs
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func newSlave(addr string) *node.Node {
+	return node.NewNode(addr, protocol.RoleSlave)
+}
+
+func TestSyncAddsRemovesAndUpdates(t *testing.T) {
+	c := NewCluster()
+	local := newSlave("localhost:8081")
+	stale := newSlave("localhost:8082")
+	kept := newSlave("localhost:8083")
+	c.AddNode(local)
+	c.AddNode(stale)
+	c.AddNode(kept)
+	c.SetMaster(stale)
+
+	res, err := c.Sync([]MemberSpec{
+		{Addr: "localhost:8081"},
+		{Addr: "127.0.0.1:8083", Meta: NodeMeta{Name: "db-c"}},
+		{Addr: "localhost:8084", Meta: NodeMeta{Database: "pg-4"}},
+	}, local.Addr, newSlave)
+	if err != nil {
+		t.Fatalf("Sync() returned error: %v", err)
+	}
+
+	want := SyncResult{
+		Added:   []string{"localhost:8084"},
+		Removed: []string{"localhost:8082"},
+		Updated: []string{"localhost:8083"},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Sync() = %+v, want %+v", res, want)
+	}
+	if c.Size() != 3 || c.GetNode("localhost:8082") != nil {
+		t.Errorf("Expected the stale member removed, got %v", c.GetNodeAddresses())
+	}
+	if c.GetMaster() != nil {
+		t.Error("Expected removing the master to clear it")
+	}
+	if c.GetNode("localhost:8083") != kept || kept.GetName() != "db-c" {
+		t.Error("Expected the kept member updated in place")
+	}
+	added := c.GetNode("localhost:8084")
+	if added == nil || added.GetDatabase() != "pg-4" || added.GetJoinedAt().IsZero() {
+		t.Errorf("Expected the new member added with its metadata and join time, got %+v", added)
+	}
+}
+
+func TestSyncToCurrentMembershipIsNoOp(t *testing.T) {
+	c := NewCluster()
+	c.AddNode(newSlave("localhost:8081"))
+	desired := []MemberSpec{
+		{Addr: "localhost:8081"},
+		{Addr: "localhost:8082", Meta: NodeMeta{Name: "db-b", Labels: map[string]string{"zone": "eu-1"}}},
+	}
+
+	if _, err := c.Sync(desired, "localhost:8081", newSlave); err != nil {
+		t.Fatalf("First Sync() returned error: %v", err)
+	}
+	res, err := c.Sync(desired, "localhost:8081", newSlave)
+	if err != nil {
+		t.Fatalf("Second Sync() returned error: %v", err)
+	}
+	if len(res.Added)+len(res.Removed)+len(res.Updated) != 0 {
+		t.Errorf("Expected syncing again to change nothing, got %+v", res)
+	}
+}
+
+func TestSyncRejectsInvalidDesiredSet(t *testing.T) {
+	c := NewCluster()
+	c.AddNode(newSlave("localhost:8081"))
+	c.AddNode(newSlave("localhost:8082"))
+
+	tests := map[string][]MemberSpec{
+		"duplicate":     {{Addr: "localhost:8081"}, {Addr: "127.0.0.1:8081"}},
+		"missing local": {{Addr: "localhost:8082"}},
+		"empty address": {{Addr: "localhost:8081"}, {Addr: ""}},
+	}
+	for name, desired := range tests {
+		_, err := c.Sync(desired, "localhost:8081", newSlave)
+		var apiErr *protocol.APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != protocol.ErrCodeInvalidRequest {
+			t.Errorf("%s: expected an invalid_request error, got %v", name, err)
+		}
+	}
+	if c.Size() != 2 {
+		t.Errorf("Expected a rejected sync to change nothing, got %v", c.GetNodeAddresses())
+	}
+}
@@ -0,0 +1,28 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// AuthorizeTakeover is a node.TakeoverGuard: it lets coordinator finish a
+// transaction owner prepared only when coordinator is the master this view
+// recognizes and owner is gone or no longer master. A stale master on the
+// other side of a split brain is refused even when it asks for a takeover.
+func (c *Cluster) AuthorizeTakeover(coordinator, owner string) error {
+	master := c.GetMaster()
+	if master == nil || !SameAddr(master.Addr, coordinator) {
+		current := "none"
+		if master != nil {
+			current = master.Addr
+		}
+		return fmt.Errorf("coordinator %s is not the current master (master: %s)", coordinator, current)
+	}
+
+	if prev := c.GetNode(owner); prev != nil && prev.GetAlive() && prev.GetRole() == protocol.RoleMaster {
+		return fmt.Errorf("owner %s is alive and still master", owner)
+	}
+
+	return nil
+}
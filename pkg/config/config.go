@@ -0,0 +1,215 @@
+// Package config loads master/node settings from a YAML or JSON file and merges
+// them with command-line flags.
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a parsed config file. Keys are flag names (e.g. "addr", "heartbeat")
+// and values are in the same textual form the flag accepts.
+type File map[string]string
+
+// EnvFallbacks are the env vars the binaries consult when the matching flag is empty.
+var EnvFallbacks = map[string]string{
+	"dsn":            "POSTGRES_DSN",
+	"state-key":      "CLUSTER_STATE_KEY",
+	"dashboard-pass": "DASHBOARD_PASS",
+}
+
+// Load reads a YAML (.yaml/.yml) or JSON (.json) config file. Lists are joined
+// with commas and maps become comma-separated key=value pairs, matching the
+// corresponding flags (e.g. nodes, labels).
+func Load(path string) (File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &doc)
+	case ".json":
+		err = json.Unmarshal(raw, &doc)
+	default:
+		return nil, fmt.Errorf("config %s: unsupported extension %q (want .yaml, .yml or .json)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	file := make(File, len(doc))
+	for key, value := range doc {
+		s, err := stringify(value)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: key %q: %w", path, key, err)
+		}
+		file[key] = s
+	}
+
+	return file, nil
+}
+
+// Apply sets every flag in fs that was not given on the command line from file.
+// A flag whose env fallback (envFallbacks maps flag name to env var) is set in
+// the environment is left alone so the binary's env lookup takes over. The
+// resulting precedence is flags > env > file > defaults. Unknown keys are errors.
+func Apply(fs *flag.FlagSet, file File, envFallbacks map[string]string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	keys := make([]string, 0, len(file))
+	for key := range file {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("unknown config key %q", key)
+		}
+		if explicit[key] {
+			continue
+		}
+		if env := envFallbacks[key]; env != "" && os.Getenv(env) != "" {
+			continue
+		}
+		if err := fs.Set(key, file[key]); err != nil {
+			return fmt.Errorf("config key %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// LoadAndApply loads path and applies it to fs. An empty path is a no-op.
+func LoadAndApply(fs *flag.FlagSet, path string, envFallbacks map[string]string) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	return Apply(fs, file, envFallbacks)
+}
+
+// EnvPrefix prefixes the environment variable bound to every flag.
+const EnvPrefix = "TWOPC_"
+
+// EnvName returns the environment variable bound to a flag,
+// e.g. "coord-timeout" -> "TWOPC_COORD_TIMEOUT".
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// ApplyEnv sets every flag not given on the command line from its TWOPC_*
+// variable. Empty variables are ignored.
+func ApplyEnv(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(EnvName(f.Name))
+		if !ok || value == "" {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", EnvName(f.Name), setErr)
+		}
+	})
+
+	return err
+}
+
+// Resolve fills flags not given on the command line, first from TWOPC_* env
+// vars and then from the --config file (itself settable via TWOPC_CONFIG), so
+// the precedence is flags > env > file > defaults.
+func Resolve(fs *flag.FlagSet) error {
+	if err := ApplyEnv(fs); err != nil {
+		return err
+	}
+
+	var path string
+	if f := fs.Lookup("config"); f != nil {
+		path = f.Value.String()
+	}
+
+	return LoadAndApply(fs, path, EnvFallbacks)
+}
+
+func stringify(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := stringify(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(v))
+		for _, k := range keys {
+			s, err := stringify(v[k])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, k+"="+s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// ParseLabels parses comma-separated key=value pairs (the --labels format).
+func ParseLabels(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q (want key=value)", pair)
+		}
+		labels[key] = value
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testFlags struct {
+	fs        *flag.FlagSet
+	addr      *string
+	nodes     *string
+	heartbeat *time.Duration
+	dsn       *string
+	priority  *int
+	labels    *string
+	chaos     *bool
+}
+
+func newTestFlags() *testFlags {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	return &testFlags{
+		fs:        fs,
+		addr:      fs.String("addr", "localhost:8081", ""),
+		nodes:     fs.String("nodes", "", ""),
+		heartbeat: fs.Duration("heartbeat", 5*time.Second, ""),
+		dsn:       fs.String("dsn", "", ""),
+		priority:  fs.Int("priority", 0, ""),
+		labels:    fs.String("labels", "", ""),
+		chaos:     fs.Bool("chaos", false, ""),
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadYAMLAndJSON(t *testing.T) {
+	want := File{
+		"addr":      "localhost:9000",
+		"nodes":     "localhost:9000,localhost:9001",
+		"heartbeat": "2s",
+		"priority":  "3",
+		"labels":    "tier=db,zone=a",
+		"chaos":     "true",
+	}
+
+	yamlPath := writeFile(t, "config.yaml", `
+addr: localhost:9000
+nodes: [localhost:9000, localhost:9001]
+heartbeat: 2s
+priority: 3
+labels:
+  zone: a
+  tier: db
+chaos: true
+`)
+	jsonPath := writeFile(t, "config.json", `{
+  "addr": "localhost:9000",
+  "nodes": ["localhost:9000", "localhost:9001"],
+  "heartbeat": "2s",
+  "priority": 3,
+  "labels": {"zone": "a", "tier": "db"},
+  "chaos": true
+}`)
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		got, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %v", path, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load(%s) = %v, want %v", filepath.Base(path), got, want)
+		}
+	}
+
+	if _, err := Load(writeFile(t, "config.toml", "addr = 1")); err == nil {
+		t.Error("Expected unsupported extension to fail")
+	}
+}
+
+func TestApplyPrecedence(t *testing.T) {
+	f := newTestFlags()
+	if err := f.fs.Parse([]string{"--addr=cli:1"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	t.Setenv("TEST_DSN", "postgres://env")
+
+	file := File{
+		"addr":      "file:1", // overridden by the flag
+		"heartbeat": "750ms",  // fills an unset flag
+		"dsn":       "postgres://file",
+		"labels":    "zone=a",
+	}
+	if err := Apply(f.fs, file, map[string]string{"dsn": "TEST_DSN"}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if *f.addr != "cli:1" {
+		t.Errorf("addr = %q, want explicit flag to win", *f.addr)
+	}
+	if *f.heartbeat != 750*time.Millisecond {
+		t.Errorf("heartbeat = %v, want file value", *f.heartbeat)
+	}
+	if *f.dsn != "" {
+		t.Errorf("dsn = %q, want empty so the env fallback wins over the file", *f.dsn)
+	}
+	if *f.labels != "zone=a" {
+		t.Errorf("labels = %q, want file value", *f.labels)
+	}
+	if *f.priority != 0 || *f.chaos {
+		t.Errorf("Expected defaults for keys missing from file, got priority=%d chaos=%v", *f.priority, *f.chaos)
+	}
+}
+
+func TestApplyUsesFileWhenEnvUnset(t *testing.T) {
+	f := newTestFlags()
+	_ = f.fs.Parse(nil)
+
+	t.Setenv("TEST_DSN", "")
+	if err := Apply(f.fs, File{"dsn": "postgres://file"}, map[string]string{"dsn": "TEST_DSN"}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if *f.dsn != "postgres://file" {
+		t.Errorf("dsn = %q, want file value", *f.dsn)
+	}
+}
+
+func TestApplyRejectsUnknownAndInvalidKeys(t *testing.T) {
+	f := newTestFlags()
+	_ = f.fs.Parse(nil)
+
+	if err := Apply(f.fs, File{"adr": "typo:1"}, nil); err == nil || !strings.Contains(err.Error(), `unknown config key "adr"`) {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+	if err := Apply(f.fs, File{"heartbeat": "soon"}, nil); err == nil || !strings.Contains(err.Error(), "heartbeat") {
+		t.Errorf("Expected invalid duration error, got %v", err)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	got, err := ParseLabels("zone=a, tier=db")
+	if err != nil {
+		t.Fatalf("ParseLabels failed: %v", err)
+	}
+	if want := map[string]string{"zone": "a", "tier": "db"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLabels = %v, want %v", got, want)
+	}
+
+	if got, err := ParseLabels(""); err != nil || got != nil {
+		t.Errorf("ParseLabels(\"\") = %v, %v; want nil, nil", got, err)
+	}
+	if _, err := ParseLabels("zone"); err == nil {
+		t.Error("Expected error for label without '='")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for flagName, want := range map[string]string{
+		"addr":              "TWOPC_ADDR",
+		"coord-timeout":     "TWOPC_COORD_TIMEOUT",
+		"max-payload-bytes": "TWOPC_MAX_PAYLOAD_BYTES",
+	} {
+		if got := EnvName(flagName); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", flagName, got, want)
+		}
+	}
+}
+
+func TestApplyEnvPicksUpUnsetFlags(t *testing.T) {
+	f := newTestFlags()
+	if err := f.fs.Parse([]string{"--addr=cli:1"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	t.Setenv("TWOPC_ADDR", "env:1")
+	t.Setenv("TWOPC_NODES", "a:1,b:2")
+	t.Setenv("TWOPC_HEARTBEAT", "300ms")
+	t.Setenv("TWOPC_PRIORITY", "7")
+	t.Setenv("TWOPC_CHAOS", "true")
+	t.Setenv("TWOPC_LABELS", "") // empty values are ignored
+
+	if err := ApplyEnv(f.fs); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+
+	if *f.addr != "cli:1" {
+		t.Errorf("addr = %q, want explicit flag to win over env", *f.addr)
+	}
+	if *f.nodes != "a:1,b:2" || *f.heartbeat != 300*time.Millisecond || *f.priority != 7 || !*f.chaos {
+		t.Errorf("Expected env values, got nodes=%q heartbeat=%v priority=%d chaos=%v", *f.nodes, *f.heartbeat, *f.priority, *f.chaos)
+	}
+	if *f.labels != "" {
+		t.Errorf("labels = %q, want default for empty env var", *f.labels)
+	}
+}
+
+func TestApplyEnvRejectsInvalidValue(t *testing.T) {
+	f := newTestFlags()
+	_ = f.fs.Parse(nil)
+
+	t.Setenv("TWOPC_HEARTBEAT", "often")
+	if err := ApplyEnv(f.fs); err == nil || !strings.Contains(err.Error(), "TWOPC_HEARTBEAT") {
+		t.Errorf("Expected error naming TWOPC_HEARTBEAT, got %v", err)
+	}
+}
+
+func TestResolveEnvBeatsFile(t *testing.T) {
+	f := newTestFlags()
+	f.fs.String("config", "", "")
+	_ = f.fs.Parse(nil)
+
+	path := writeFile(t, "config.yaml", "addr: file:1\nheartbeat: 2s\n")
+	t.Setenv("TWOPC_CONFIG", path)
+	t.Setenv("TWOPC_ADDR", "env:1")
+
+	if err := Resolve(f.fs); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if *f.addr != "env:1" {
+		t.Errorf("addr = %q, want env to win over file", *f.addr)
+	}
+	if *f.heartbeat != 2*time.Second {
+		t.Errorf("heartbeat = %v, want file value", *f.heartbeat)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DBPool holds the database/sql connection pool settings the binaries expose as
+// --db-max-open-conns, --db-max-idle-conns and --db-conn-max-lifetime.
+type DBPool struct {
+	MaxOpenConns    int           // 0 = unlimited
+	MaxIdleConns    int           // idle connections kept for reuse (database/sql default: 2)
+	ConnMaxLifetime time.Duration // 0 = connections are reused forever
+}
+
+// DefaultDBPool matches database/sql's own defaults.
+var DefaultDBPool = DBPool{MaxIdleConns: 2}
+
+// PoolSetter is the part of *sql.DB that DBPool configures.
+type PoolSetter interface {
+	SetMaxOpenConns(n int)
+	SetMaxIdleConns(n int)
+	SetConnMaxLifetime(d time.Duration)
+}
+
+// Validate rejects negative settings and more idle than open connections,
+// which database/sql would otherwise lower silently.
+func (p DBPool) Validate() error {
+	if p.MaxOpenConns < 0 {
+		return errors.New("db-max-open-conns must not be negative")
+	}
+	if p.MaxIdleConns < 0 {
+		return errors.New("db-max-idle-conns must not be negative")
+	}
+	if p.ConnMaxLifetime < 0 {
+		return errors.New("db-conn-max-lifetime must not be negative")
+	}
+	if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		return fmt.Errorf("db-max-idle-conns (%d) exceeds db-max-open-conns (%d)", p.MaxIdleConns, p.MaxOpenConns)
+	}
+	return nil
+}
+
+// Apply validates the settings and applies them to db.
+func (p DBPool) Apply(db PoolSetter) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	return nil
+}
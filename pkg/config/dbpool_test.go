@@ -0,0 +1,60 @@
+package config
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+var _ PoolSetter = (*sql.DB)(nil)
+
+type fakePool struct {
+	maxOpen, maxIdle int
+	lifetime         time.Duration
+	applied          bool
+}
+
+func (f *fakePool) SetMaxOpenConns(n int)              { f.maxOpen, f.applied = n, true }
+func (f *fakePool) SetMaxIdleConns(n int)              { f.maxIdle, f.applied = n, true }
+func (f *fakePool) SetConnMaxLifetime(d time.Duration) { f.lifetime, f.applied = d, true }
+
+func TestDBPoolApply(t *testing.T) {
+	var db fakePool
+	pool := DBPool{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetime: 30 * time.Minute}
+	if err := pool.Apply(&db); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if db.maxOpen != 20 || db.maxIdle != 5 || db.lifetime != 30*time.Minute {
+		t.Errorf("Unexpected pool settings: %+v", db)
+	}
+
+	db = fakePool{}
+	if err := DefaultDBPool.Apply(&db); err != nil {
+		t.Fatalf("Apply(DefaultDBPool) failed: %v", err)
+	}
+	if db.maxOpen != 0 || db.maxIdle != 2 || db.lifetime != 0 {
+		t.Errorf("Expected database/sql defaults, got %+v", db)
+	}
+}
+
+func TestDBPoolApplyRejectsInvalidSettings(t *testing.T) {
+	for _, pool := range []DBPool{
+		{MaxOpenConns: -1},
+		{MaxIdleConns: -1},
+		{ConnMaxLifetime: -time.Second},
+		{MaxOpenConns: 4, MaxIdleConns: 8},
+	} {
+		var db fakePool
+		if err := pool.Apply(&db); err == nil {
+			t.Errorf("Expected %+v to be rejected", pool)
+		}
+		if db.applied {
+			t.Errorf("Expected nothing applied for %+v, got %+v", pool, db)
+		}
+	}
+
+	// Unlimited open connections allow any number idle.
+	if err := (DBPool{MaxIdleConns: 50}).Validate(); err != nil {
+		t.Errorf("Expected idle conns without an open limit to be valid, got %v", err)
+	}
+}
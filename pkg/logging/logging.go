@@ -0,0 +1,93 @@
+// Package logging gates the engine's log lines by level on top of the standard
+// logger. Per-transaction chatter is logged at debug, lifecycle events at info
+// and failures, elections and degraded modes at warn or error.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is a log verbosity level; higher levels are less verbose.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel parses a --log-level value (debug, info, warn or error; case-insensitive).
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+}
+
+var current atomic.Int32
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// SetLevel changes the minimum level that is written. The default is LevelInfo.
+func SetLevel(l Level) {
+	current.Store(int32(l))
+}
+
+// GetLevel returns the minimum level that is written.
+func GetLevel() Level {
+	return Level(current.Load())
+}
+
+// Enabled reports whether lines at l are written.
+func Enabled(l Level) bool {
+	return l >= GetLevel()
+}
+
+// Debugf logs per-transaction and per-request detail.
+func Debugf(format string, args ...any) { output(LevelDebug, format, args...) }
+
+// Infof logs lifecycle events such as startup and membership changes.
+func Infof(format string, args ...any) { output(LevelInfo, format, args...) }
+
+// Warnf logs conditions an operator should look at: failed RPCs, dead nodes, elections.
+func Warnf(format string, args ...any) { output(LevelWarn, format, args...) }
+
+// Errorf logs failures that lose work or leave state inconsistent.
+func Errorf(format string, args ...any) { output(LevelError, format, args...) }
+
+func output(l Level, format string, args ...any) {
+	if !Enabled(l) {
+		return
+	}
+	// Calldepth 3 attributes the line to the caller of Debugf/Infof/... when
+	// the standard logger is configured with Lshortfile.
+	_ = log.Output(3, strings.ToUpper(l.String())+" "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prevOut, prevFlags, prevLevel := log.Writer(), log.Flags(), GetLevel()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(prevOut)
+		log.SetFlags(prevFlags)
+		SetLevel(prevLevel)
+	})
+
+	return &buf
+}
+
+func TestDebugSuppressedAtInfo(t *testing.T) {
+	buf := captureLog(t)
+	SetLevel(LevelInfo)
+
+	Debugf("[Node %s] Prepared transaction %s", "a:1", "tx-1")
+	Infof("[Heartbeat] Started")
+	Warnf("[Heartbeat] Node %s is now DEAD", "a:1")
+
+	out := buf.String()
+	if strings.Contains(out, "Prepared transaction") {
+		t.Errorf("Expected debug line to be suppressed at info, got:\n%s", out)
+	}
+	if !strings.Contains(out, "INFO [Heartbeat] Started") || !strings.Contains(out, "WARN [Heartbeat] Node a:1 is now DEAD") {
+		t.Errorf("Expected info and warn lines, got:\n%s", out)
+	}
+
+	buf.Reset()
+	SetLevel(LevelDebug)
+	Debugf("[Node %s] Prepared transaction %s", "a:1", "tx-1")
+	if !strings.Contains(buf.String(), "DEBUG [Node a:1] Prepared transaction tx-1") {
+		t.Errorf("Expected debug line at debug level, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	SetLevel(LevelError)
+	Warnf("dropped")
+	Errorf("kept")
+	if got := buf.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "ERROR kept") {
+		t.Errorf("Expected only error lines at error level, got:\n%s", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]Level{
+		"debug": LevelDebug,
+		"INFO":  LevelInfo,
+		"":      LevelInfo,
+		"warn":  LevelWarn,
+		"Error": LevelError,
+	} {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected unknown level to be rejected")
+	}
+}
@@ -0,0 +1,45 @@
+package node
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ipv4Localhost is the IPv4 address "localhost" resolves to.
+var ipv4Localhost = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+
+// CanonicalAddr normalizes a host:port address so equivalent spellings of the
+// same endpoint compare equal: an http(s):// scheme and trailing slash are
+// dropped, hosts are lower-cased, IP literals are printed in their standard
+// form and 127.0.0.1 and ::1, the addresses "localhost" resolves to, become
+// "localhost". Other loopback addresses such as 127.0.0.2 are distinct
+// endpoints and keep their IP. Addresses without a port are only trimmed and
+// lower-cased.
+func CanonicalAddr(addr string) string {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "http://"), "https://")
+	addr = strings.TrimSuffix(addr, "/")
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	host = strings.TrimSuffix(host, ".")
+	if ip, err := netip.ParseAddr(host); err == nil {
+		ip = ip.Unmap()
+		if ip == ipv4Localhost || ip == netip.IPv6Loopback() {
+			host = "localhost"
+		} else {
+			host = ip.String()
+		}
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// SameAddr reports whether a and b name the same endpoint after canonicalization.
+func SameAddr(a, b string) bool {
+	return CanonicalAddr(a) == CanonicalAddr(b)
+}
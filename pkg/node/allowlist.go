@@ -0,0 +1,52 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTableNotPermitted is returned by prepare when the payload targets a
+// table outside the node's allowlist (see SetAllowedTables).
+var ErrTableNotPermitted = errors.New("table not permitted")
+
+// SetAllowedTables limits the tables transactions may touch on this node, so
+// a client of a shared cluster cannot write to arbitrary tables. Prepares of
+// any other table vote ABORT with ErrTableNotPermitted. Names are matched
+// case-insensitively, as the SQL builder lowercases identifiers. An empty
+// list removes the limit.
+func (n *Node) SetAllowedTables(tables []string) error {
+	allowed := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		name, err := safeIdent(strings.TrimSpace(table))
+		if err != nil {
+			return fmt.Errorf("allowed table %q: %w", table, err)
+		}
+		allowed[name] = true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(allowed) == 0 {
+		allowed = nil
+	}
+	n.allowedTables = allowed
+	return nil
+}
+
+// checkTableAllowed returns ErrTableNotPermitted when allowed is set and does
+// not list the action's table.
+func checkTableAllowed(allowed map[string]bool, action *SQLAction) error {
+	if allowed == nil {
+		return nil
+	}
+
+	name, err := safeIdent(action.Table)
+	if err != nil {
+		return fmt.Errorf("table %q: %w", action.Table, err)
+	}
+	if !allowed[name] {
+		return fmt.Errorf("%w: %q", ErrTableNotPermitted, action.Table)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+)
+
+// DefaultDBMonitorMaxBackoff caps the wait between pings of a database that is down.
+const DefaultDBMonitorMaxBackoff = 30 * time.Second
+
+// DBMonitor pings the node's database every interval and marks the node
+// degraded while it is unreachable, so /health and /ready report the outage
+// before a prepare runs into it. database/sql reconnects on its own; the
+// monitor only notices when it has. While the database is down the pings back
+// off exponentially up to maxBackoff to spare a recovering server.
+type DBMonitor struct {
+	node       *Node
+	interval   time.Duration
+	maxBackoff time.Duration
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewDBMonitor creates a monitor that pings n's database every interval.
+func NewDBMonitor(n *Node, interval time.Duration) *DBMonitor {
+	return &DBMonitor{
+		node:       n,
+		interval:   interval,
+		maxBackoff: max(DefaultDBMonitorMaxBackoff, interval),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// WithMaxBackoff caps the wait between pings while the database is down.
+// Values below the interval are raised to it. Must be called before Start.
+func (m *DBMonitor) WithMaxBackoff(d time.Duration) *DBMonitor {
+	m.maxBackoff = max(d, m.interval)
+	return m
+}
+
+// Start begins the background ping loop
+func (m *DBMonitor) Start() {
+	m.wg.Add(1)
+	go m.run()
+	logging.Infof("[DBMonitor] Started with interval %v", m.interval)
+}
+
+// Stop stops the ping loop
+func (m *DBMonitor) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+	logging.Infof("[DBMonitor] Stopped")
+}
+
+func (m *DBMonitor) run() {
+	defer m.wg.Done()
+
+	failures := 0
+	for {
+		timer := time.NewTimer(dbCheckDelay(m.interval, m.maxBackoff, failures))
+		select {
+		case <-timer.C:
+		case <-m.stopCh:
+			timer.Stop()
+			return
+		}
+
+		if m.check() {
+			failures = 0
+		} else {
+			failures++
+		}
+	}
+}
+
+// check pings the database once and updates the degraded flag. It reports
+// whether the database answered.
+func (m *DBMonitor) check() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), min(m.interval, 2*time.Second))
+	defer cancel()
+
+	err := m.node.PingDB(ctx)
+	m.node.setDBDegraded(err)
+	return err == nil
+}
+
+// dbCheckDelay is the wait before the next ping after failures consecutive
+// failed ones: interval, doubling per failure up to maxBackoff.
+func dbCheckDelay(interval, maxBackoff time.Duration, failures int) time.Duration {
+	delay := interval
+	for range failures {
+		if delay >= maxBackoff/2 {
+			return maxBackoff
+		}
+		delay *= 2
+	}
+	return delay
+}
+
+// DBDegraded reports whether the database monitor last found the database
+// unreachable. It is always false without a running monitor.
+func (n *Node) DBDegraded() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.dbDegraded
+}
+
+// setDBDegraded records the result of a database ping, logging transitions.
+func (n *Node) setDBDegraded(err error) {
+	n.mu.Lock()
+	was := n.dbDegraded
+	n.dbDegraded = err != nil
+	n.mu.Unlock()
+
+	switch {
+	case err != nil && !was:
+		logging.Warnf("[Node %s] Database unreachable, marking node degraded: %v", n.Addr, err)
+	case err == nil && was:
+		logging.Infof("[Node %s] Database reachable again, clearing degraded state", n.Addr)
+	}
+}
@@ -0,0 +1,49 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestDBMonitorTracksDatabaseOutage(t *testing.T) {
+	db, drv := newRecordingDB(t)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+
+	m := NewDBMonitor(n, 5*time.Millisecond).WithMaxBackoff(20 * time.Millisecond)
+	m.Start()
+	defer m.Stop()
+
+	waitFor := func(degraded bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for n.DBDegraded() != degraded {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for DBDegraded() = %v", degraded)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if n.DBDegraded() {
+		t.Fatal("Expected a reachable database not to degrade the node")
+	}
+
+	drv.down.Store(true)
+	waitFor(true)
+
+	drv.down.Store(false)
+	waitFor(false)
+}
+
+func TestDBCheckDelayBacksOff(t *testing.T) {
+	for failures, want := range []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+	} {
+		if got := dbCheckDelay(time.Second, 10*time.Second, failures); got != want {
+			t.Errorf("dbCheckDelay after %d failures = %v, want %v", failures, got, want)
+		}
+	}
+}
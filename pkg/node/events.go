@@ -0,0 +1,70 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// eventBuffer is how far a subscriber may fall behind before it misses events.
+const eventBuffer = 64
+
+// eventHub fans local transaction events out to subscribers. It has its own
+// lock so events can be published while n.mu is held.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan protocol.TransactionEvent]struct{}
+}
+
+// SubscribeTransactions returns a channel of this node's transaction state
+// changes and a function that ends the subscription. A subscriber that falls
+// more than eventBuffer events behind misses events instead of slowing down
+// transactions.
+func (n *Node) SubscribeTransactions() (<-chan protocol.TransactionEvent, func()) {
+	ch := make(chan protocol.TransactionEvent, eventBuffer)
+
+	n.events.mu.Lock()
+	if n.events.subs == nil {
+		n.events.subs = make(map[chan protocol.TransactionEvent]struct{})
+	}
+	n.events.subs[ch] = struct{}{}
+	n.events.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			n.events.mu.Lock()
+			delete(n.events.subs, ch)
+			n.events.mu.Unlock()
+		})
+	}
+}
+
+// publishTransaction sends a state change of txID to every subscriber.
+func (n *Node) publishTransaction(txID, status, coordinator string, err error) {
+	n.events.mu.Lock()
+	defer n.events.mu.Unlock()
+
+	if len(n.events.subs) == 0 {
+		return
+	}
+
+	ev := protocol.TransactionEvent{
+		TxID:        txID,
+		Node:        n.Addr,
+		Status:      status,
+		Coordinator: coordinator,
+		Time:        time.Now(),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+
+	for ch := range n.events.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// recordingDriver is a minimal database/sql driver that accepts every statement,
+// reports one affected row and records the SQL it was given.
+type recordingDriver struct {
+	mu      sync.Mutex
+	queries []string
+
+	// answer, when set, supplies the rows of a query; returning nil falls back
+	// to the default single-row answer.
+	answer func(query string) [][]driver.Value
+
+	// down simulates a stopped server: new connections fail and pooled ones
+	// report themselves broken on ping.
+	down atomic.Bool
+}
+
+var fakeDriverSeq atomic.Int64
+
+// newRecordingDB returns a *sql.DB backed by a fresh recordingDriver.
+func newRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+
+	d := &recordingDriver{}
+	name := fmt.Sprintf("recording-%d", fakeDriverSeq.Add(1))
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db, d
+}
+
+func (d *recordingDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, strings.Join(strings.Fields(query), " "))
+}
+
+// Queries returns the normalized SQL statements seen so far.
+func (d *recordingDriver) Queries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) {
+	if d.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return &recordingConn{d: d}, nil
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Ping(context.Context) error {
+	if c.d.down.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+func (c *recordingConn) Begin() (driver.Tx, error) { return recordingTx{d: c.d}, nil }
+
+func (c *recordingConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return recordingTx{d: c.d}, nil
+}
+
+type recordingTx struct{ d *recordingDriver }
+
+func (t recordingTx) Commit() error   { t.d.record("COMMIT"); return nil }
+func (t recordingTx) Rollback() error { t.d.record("ROLLBACK"); return nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.record(s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.record(s.query)
+	if s.d.answer != nil {
+		if rows := s.d.answer(s.query); rows != nil {
+			return &recordingRows{rows: rows}, nil
+		}
+	}
+	// Every other query in the node is a single-row lookup; answering
+	// to_regclass with a non-NULL value makes ensureSchema treat the table as
+	// present.
+	return &recordingRows{rows: [][]driver.Value{{distTx}}}, nil
+}
+
+type recordingRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (r *recordingRows) Columns() []string { return []string{"value"} }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// fakeExecer records the statement and arguments handed to the execer seam, so
+// SQL generation can be asserted without going through a driver.
+type fakeExecer struct {
+	query string
+	args  []any
+	err   error
+}
+
+func (f *fakeExecer) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	f.query, f.args = query, args
+	if f.err != nil {
+		return nil, f.err
+	}
+	return driver.RowsAffected(1), nil
+}
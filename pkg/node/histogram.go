@@ -0,0 +1,82 @@
+package node
+
+import (
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// preparedBucketsMs are the upper bounds of the prepared-duration buckets. A
+// healthy coordinator resolves in milliseconds; the tail buckets catch ones that
+// prepare and then stall.
+var preparedBucketsMs = []int64{1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000}
+
+// durationHistogram counts durations into fixed buckets. The last count holds
+// observations above the largest bound. It is not safe for concurrent use.
+type durationHistogram struct {
+	boundsMs []int64
+	counts   []uint64
+	total    uint64
+	sumMs    int64
+	maxMs    int64
+}
+
+func newDurationHistogram(boundsMs []int64) *durationHistogram {
+	return &durationHistogram{
+		boundsMs: boundsMs,
+		counts:   make([]uint64, len(boundsMs)+1),
+	}
+}
+
+func (h *durationHistogram) observe(d time.Duration) {
+	ms := d.Milliseconds()
+
+	i := 0
+	for i < len(h.boundsMs) && ms > h.boundsMs[i] {
+		i++
+	}
+	h.counts[i]++
+	h.total++
+	h.sumMs += ms
+	h.maxMs = max(h.maxMs, ms)
+}
+
+// quantileMs returns the upper bound of the bucket holding quantile q, or the
+// largest observation when that falls past the last bound. Zero if empty.
+func (h *durationHistogram) quantileMs(q float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(h.total))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, c := range h.counts[:len(h.boundsMs)] {
+		seen += c
+		if seen >= rank {
+			return min(h.boundsMs[i], h.maxMs)
+		}
+	}
+	return h.maxMs
+}
+
+// snapshot returns the histogram with cumulative bucket counts.
+func (h *durationHistogram) snapshot() *protocol.DurationHistogram {
+	out := &protocol.DurationHistogram{
+		Buckets: make([]protocol.DurationBucket, len(h.boundsMs)),
+		Count:   h.total,
+		SumMs:   h.sumMs,
+		MaxMs:   h.maxMs,
+	}
+
+	var cumulative uint64
+	for i, bound := range h.boundsMs {
+		cumulative += h.counts[i]
+		out.Buckets[i] = protocol.DurationBucket{LeMs: bound, Count: cumulative}
+	}
+
+	return out
+}
@@ -0,0 +1,155 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+const (
+	// DefaultMetricsHistoryInterval is how often the sampler takes a sample.
+	DefaultMetricsHistoryInterval = time.Minute
+	// DefaultMetricsHistoryWindow is how many samples the sampler keeps: an
+	// hour at the default interval.
+	DefaultMetricsHistoryWindow = 60
+)
+
+// MetricsSampler keeps a short in-memory history of a node's metrics: every
+// interval it records the transactions finished since the previous sample,
+// keeping the last window samples in a ring buffer. The history is lost on
+// restart.
+type MetricsSampler struct {
+	node     *Node
+	interval time.Duration
+	read     func() protocol.NodeMetrics // n.Metrics, replaced in tests
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	samples []protocol.MetricsSample // ring buffer of len window
+	next    int                      // slot the next sample goes into
+	count   int                      // samples held, up to window
+	last    *protocol.NodeMetrics    // cumulative counters at the previous sample
+}
+
+// NewMetricsSampler creates a sampler that samples n's metrics every interval
+// and keeps the last window samples. A window below one keeps one sample.
+func NewMetricsSampler(n *Node, interval time.Duration, window int) *MetricsSampler {
+	return &MetricsSampler{
+		node:     n,
+		interval: interval,
+		read:     n.Metrics,
+		stopCh:   make(chan struct{}),
+		samples:  make([]protocol.MetricsSample, max(window, 1)),
+	}
+}
+
+// Start takes the baseline the first interval is measured from and begins
+// the sampling loop.
+func (m *MetricsSampler) Start() {
+	m.sample(time.Now())
+	m.wg.Add(1)
+	go m.run()
+	logging.Infof("[Metrics] Sampling history every %v, keeping %d samples", m.interval, len(m.samples))
+}
+
+// Stop stops the sampling loop. The history stays readable.
+func (m *MetricsSampler) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+}
+
+func (m *MetricsSampler) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			m.sample(now)
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// sample reads the node's metrics and records the interval since the
+// previous call. The first call only sets the baseline.
+func (m *MetricsSampler) sample(now time.Time) {
+	cur := m.read()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev := m.last
+	m.last = &cur
+	if prev == nil {
+		return
+	}
+
+	s := protocol.MetricsSample{
+		Time:      now,
+		Committed: counterDelta(prev.Committed, cur.Committed),
+		Aborted:   counterDelta(prev.Aborted, cur.Aborted),
+		Failed:    counterDelta(prev.Failed, cur.Failed),
+		InFlight:  cur.InFlight,
+	}
+	if total := s.Committed + s.Aborted + s.Failed; total > 0 {
+		rate := float64(s.Committed) / float64(total) * 100
+		s.SuccessRate = &rate
+	}
+
+	m.samples[m.next] = s
+	m.next = (m.next + 1) % len(m.samples)
+	m.count = min(m.count+1, len(m.samples))
+}
+
+// counterDelta is how much a cumulative counter grew. A counter that went
+// down was reset, e.g. the database was swapped, so it grew by its new value.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// History returns the samples held, oldest first.
+func (m *MetricsSampler) History() *protocol.MetricsHistoryResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	samples := make([]protocol.MetricsSample, 0, m.count)
+	start := (m.next - m.count + len(m.samples)) % len(m.samples)
+	for i := range m.count {
+		samples = append(samples, m.samples[(start+i)%len(m.samples)])
+	}
+
+	return &protocol.MetricsHistoryResponse{
+		Node:            m.node.Addr,
+		IntervalSeconds: int64(m.interval / time.Second),
+		Window:          len(m.samples),
+		Samples:         samples,
+	}
+}
+
+// RecentAbortRate is the percentage of the transactions finished in the
+// latest interval that did not commit. It is nil before the first interval
+// ends and after an interval in which nothing finished.
+func (m *MetricsSampler) RecentAbortRate() *float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.count == 0 {
+		return nil
+	}
+	latest := m.samples[(m.next-1+len(m.samples))%len(m.samples)]
+	if latest.SuccessRate == nil {
+		return nil
+	}
+	rate := 100 - *latest.SuccessRate
+	return &rate
+}
@@ -0,0 +1,91 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestMetricsSamplerAccumulatesAndCapsAtWindow(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	sampler := NewMetricsSampler(n, time.Minute, 3)
+
+	// Each read reports 10 more commits and 1 more abort than the last.
+	var reads uint64
+	sampler.read = func() protocol.NodeMetrics {
+		reads++
+		return protocol.NodeMetrics{Committed: reads * 10, Aborted: reads, InFlight: int(reads)}
+	}
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sampler.sample(base) // baseline only
+	if got := sampler.History().Samples; len(got) != 0 {
+		t.Fatalf("Expected no samples after the baseline, got %d", len(got))
+	}
+
+	for i := 1; i <= 5; i++ {
+		sampler.sample(base.Add(time.Duration(i) * time.Minute))
+		if got, want := len(sampler.History().Samples), min(i, 3); got != want {
+			t.Fatalf("After %d samples: held %d, want %d", i, got, want)
+		}
+	}
+
+	history := sampler.History()
+	if history.Window != 3 || history.IntervalSeconds != 60 || history.Node != n.Addr {
+		t.Errorf("Unexpected history header: %+v", history)
+	}
+	for i, s := range history.Samples {
+		// The last three of five samples, oldest first.
+		if want := base.Add(time.Duration(i+3) * time.Minute); !s.Time.Equal(want) {
+			t.Errorf("Sample %d at %v, want %v", i, s.Time, want)
+		}
+		if s.Committed != 10 || s.Aborted != 1 {
+			t.Errorf("Sample %d counts %d/%d, want the per-interval 10/1", i, s.Committed, s.Aborted)
+		}
+		if s.SuccessRate == nil || *s.SuccessRate < 90.9 || *s.SuccessRate > 91 {
+			t.Errorf("Sample %d success rate %v, want ~90.9", i, s.SuccessRate)
+		}
+	}
+}
+
+func TestMetricsSamplerIdleIntervalHasNoSuccessRate(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	sampler := NewMetricsSampler(n, time.Minute, 5)
+	sampler.read = func() protocol.NodeMetrics { return protocol.NodeMetrics{Committed: 4} }
+
+	sampler.sample(time.Now())
+	sampler.sample(time.Now())
+
+	samples := sampler.History().Samples
+	if len(samples) != 1 || samples[0].Committed != 0 || samples[0].SuccessRate != nil {
+		t.Errorf("Expected one idle sample without a success rate, got %+v", samples)
+	}
+}
+
+func TestCounterDeltaHandlesReset(t *testing.T) {
+	if got := counterDelta(10, 15); got != 5 {
+		t.Errorf("counterDelta(10, 15) = %d, want 5", got)
+	}
+	if got := counterDelta(10, 3); got != 3 {
+		t.Errorf("counterDelta(10, 3) = %d, want 3 after a reset", got)
+	}
+}
+
+func TestMetricsSamplerRecentAbortRate(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	sampler := NewMetricsSampler(n, time.Minute, 5)
+	var committed, aborted uint64
+	sampler.read = func() protocol.NodeMetrics { return protocol.NodeMetrics{Committed: committed, Aborted: aborted} }
+
+	sampler.sample(time.Now())
+	if rate := sampler.RecentAbortRate(); rate != nil {
+		t.Fatalf("Expected no rate before the first interval, got %v", *rate)
+	}
+
+	committed, aborted = 3, 1
+	sampler.sample(time.Now())
+	if rate := sampler.RecentAbortRate(); rate == nil || *rate != 25 {
+		t.Errorf("Expected a 25%% abort rate, got %v", rate)
+	}
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// PayloadLimits bounds the size and complexity of a transaction payload so a
+// single request cannot fan out huge SQL statements to every participant.
+// A zero value for any field disables that limit.
+type PayloadLimits struct {
+	MaxColumns int // columns of the action (values + where)
+	MaxBytes   int // JSON-encoded payload size
+}
+
+// DefaultPayloadLimits are applied by the coordinator unless overridden.
+var DefaultPayloadLimits = PayloadLimits{
+	MaxColumns: 64,
+	MaxBytes:   1 << 20,
+}
+
+// ValidatePayload checks a transaction payload against the limits. It only
+// enforces size bounds and the single-object shape participants accept;
+// participants still validate the action itself.
+func ValidatePayload(payload any, limits PayloadLimits) error {
+	raw, err := payloadJSON(payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	if limits.MaxBytes > 0 && len(raw) > limits.MaxBytes {
+		return fmt.Errorf("payload is %d bytes, exceeds limit of %d", len(raw), limits.MaxBytes)
+	}
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		// Participants take one action object per transaction and reject
+		// arrays at prepare; refuse them here with the same error.
+		return fmt.Errorf("payload is not a SQL action object: got %s", jsonKind(trimmed[0]))
+	}
+
+	var action SQLAction
+	// Non-action payloads carry no columns; leave their validation to participants.
+	_ = json.Unmarshal(trimmed, &action)
+
+	if cols := len(action.Values) + len(action.Where); limits.MaxColumns > 0 && cols > limits.MaxColumns {
+		return fmt.Errorf("action has %d columns, exceeds limit of %d", cols, limits.MaxColumns)
+	}
+
+	return nil
+}
+
+func payloadJSON(payload any) ([]byte, error) {
+	switch v := payload.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
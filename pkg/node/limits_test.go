@@ -0,0 +1,81 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func wideValues(n int) map[string]any {
+	values := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		values[fmt.Sprintf("col_%d", i)] = i
+	}
+	return values
+}
+
+func TestValidatePayload(t *testing.T) {
+	limits := PayloadLimits{MaxColumns: 4, MaxBytes: 512}
+
+	tests := []struct {
+		name    string
+		payload any
+		limits  PayloadLimits
+		wantErr string
+	}{
+		{
+			name:    "within limits",
+			payload: SQLAction{Table: "users", Values: wideValues(2), Where: map[string]any{"id": 1}},
+			limits:  limits,
+		},
+		{
+			name:    "too many columns",
+			payload: SQLAction{Table: "users", Values: wideValues(5)},
+			limits:  limits,
+			wantErr: "5 columns, exceeds limit of 4",
+		},
+		{
+			name:    "where counts toward columns",
+			payload: SQLAction{Table: "users", Values: wideValues(3), Where: map[string]any{"id": 1, "tenant": 2}},
+			limits:  limits,
+			wantErr: "5 columns",
+		},
+		{
+			name:    "array payload rejected",
+			payload: `[{"table":"a","values":{"x":1}},{"table":"b","values":{"x":1}},{"table":"c","values":{"x":1}}]`,
+			limits:  limits,
+			wantErr: "not a SQL action object: got an array",
+		},
+		{
+			name:    "too many bytes",
+			payload: SQLAction{Table: "users", Values: map[string]any{"bio": strings.Repeat("x", 600)}},
+			limits:  limits,
+			wantErr: "exceeds limit of 512",
+		},
+		{
+			name:    "zero limits disable checks",
+			payload: SQLAction{Table: "users", Values: wideValues(500)},
+			limits:  PayloadLimits{},
+		},
+		{
+			name:    "non-action payload",
+			payload: map[string]string{"test": "data"},
+			limits:  limits,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePayload(tt.payload, tt.limits)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidatePayload() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidatePayload() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package node
+
+import (
+	"context"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// DefaultDrainTimeout bounds how long entering maintenance waits for prepared
+// transactions to be committed or aborted.
+const DefaultDrainTimeout = 10 * time.Second
+
+// drainPoll is how often Drain rechecks the pending transactions.
+var drainPoll = 50 * time.Millisecond
+
+// SetMaintenance puts the node into (or out of) maintenance. In maintenance it
+// votes ABORT on every new prepare, while transactions it already prepared can
+// still be committed or aborted and its database stays connected.
+func (n *Node) SetMaintenance(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Maintenance = enabled
+}
+
+// GetMaintenance returns whether the node reports itself in maintenance.
+func (n *Node) GetMaintenance() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.Maintenance
+}
+
+// Eligible reports whether the node may take part in transactions and
+// election: alive, not disabled by the cluster, not in maintenance and not
+// shutting down.
+func (n *Node) Eligible() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.IsAlive && !n.Disabled && !n.Maintenance && !n.Draining
+}
+
+// SetReady records the result of the node's last readiness probe.
+func (n *Node) SetReady(ready bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.NotReady = !ready
+}
+
+// GetReady reports whether the node passed its last readiness probe. Nodes
+// nobody probes for readiness are always ready.
+func (n *Node) GetReady() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return !n.NotReady
+}
+
+// SetDraining records that the node is shutting down. A draining node still
+// commits or aborts what it prepared but takes no new transactions.
+func (n *Node) SetDraining(draining bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Draining = draining
+}
+
+// GetDraining reports whether the node is shutting down.
+func (n *Node) GetDraining() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.Draining
+}
+
+// State returns the node's lifecycle state. Draining wins over maintenance,
+// since a node that is shutting down will not come back from maintenance.
+func (n *Node) State() protocol.NodeState {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	switch {
+	case n.Draining:
+		return protocol.NodeStateDraining
+	case n.Maintenance:
+		return protocol.NodeStateMaintenance
+	default:
+		return protocol.NodeStateRunning
+	}
+}
+
+// Selectable reports whether a coordinator may pick the node as a participant
+// of a new transaction: eligible, ready and not draining.
+func (n *Node) Selectable() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.IsAlive && !n.Disabled && !n.Maintenance && !n.NotReady && !n.Draining
+}
+
+// Drain waits until the node holds no prepared transactions or ctx is done.
+// It returns how many are still pending.
+func (n *Node) Drain(ctx context.Context) int {
+	ticker := time.NewTicker(drainPoll)
+	defer ticker.Stop()
+
+	for {
+		pending := len(n.GetPendingTransactions())
+		if pending == 0 {
+			return 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return pending
+		case <-ticker.C:
+		}
+	}
+}
+
+// ApplyMaintenance switches maintenance on or off. Turning it on drains
+// prepared transactions for up to drainTimeout (DefaultDrainTimeout when zero);
+// the node stays in maintenance even if the drain times out.
+func (n *Node) ApplyMaintenance(enabled bool, drainTimeout time.Duration) *protocol.MaintenanceResponse {
+	n.SetMaintenance(enabled)
+
+	resp := &protocol.MaintenanceResponse{
+		Success: true,
+		Address: n.Addr,
+		Enabled: enabled,
+		Drained: true,
+	}
+	if !enabled {
+		logging.Infof("[Node %s] Left maintenance mode", n.Addr)
+		return resp
+	}
+
+	if drainTimeout <= 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+	logging.Infof("[Node %s] Entering maintenance mode, draining for up to %v", n.Addr, drainTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if pending := n.Drain(ctx); pending > 0 {
+		logging.Warnf("[Node %s] Maintenance drain timed out with %d prepared transactions", n.Addr, pending)
+		resp.Drained = false
+		resp.InFlight = pending
+	}
+
+	return resp
+}
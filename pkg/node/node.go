@@ -1,18 +1,20 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
 
@@ -21,32 +23,128 @@ const ddl = `
 				tx_id TEXT PRIMARY KEY,
 				payload JSONB NOT NULL,
 				status TEXT NOT NULL,
+				coordinator_addr TEXT,
 				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
 				updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 			);`
 
 const distTx = "distributed_tx"
 
+// migrations bring a distributed_tx created by an older version up to date.
+// Each statement is idempotent; they run in order once per process.
+var migrations = []string{
+	// coordinator that prepared the transaction (NULL when unfenced or observed)
+	`ALTER TABLE distributed_tx ADD COLUMN IF NOT EXISTS coordinator_addr TEXT`,
+}
+
+// ErrForeignCoordinator is returned when a commit or abort comes from a
+// coordinator other than the one that prepared the transaction.
+var ErrForeignCoordinator = errors.New("transaction was prepared by a different coordinator")
+
+// ErrTakeoverRefused is returned when a coordinator asks to take over a
+// transaction another coordinator prepared and the takeover guard refuses it.
+var ErrTakeoverRefused = errors.New("takeover refused")
+
+// ErrReadCheckNoDatabase is returned by prepare for a READ_CHECK on a node
+// without a database, which could not hold the checked rows until commit.
+var ErrReadCheckNoDatabase = errors.New("read check needs a database")
+
+// ErrCoordinateOnly is returned by prepare on a node that coordinates without
+// a database of its own (see SetCoordinateOnly).
+var ErrCoordinateOnly = errors.New("node is coordinate-only and holds no database")
+
+// ErrMaintenance is returned by prepare while the node is in maintenance mode.
+var ErrMaintenance = errors.New("node is in maintenance mode")
+
+// ErrAtCapacity is returned by prepare when the node already holds its maximum
+// number of prepared transactions (see SetMaxPrepared).
+var ErrAtCapacity = errors.New("node at capacity")
+
 // Node represents a single node in the distributed system
 type Node struct {
-	Addr     string            // address of the node (e.g., "localhost:8081")
-	Name     string            // display name for UI
-	Role     protocol.NodeRole // MASTER or SLAVE
-	IsAlive  bool              // health status
-	TxState  protocol.TxState  // current transaction state
-	Database string            // optional metadata about backing DB (for dashboards)
+	Addr        string            // advertised address peers use to reach the node (e.g., "localhost:8081")
+	BindAddr    string            // listen address when it differs from Addr (e.g., "0.0.0.0:8081"); empty means Addr
+	Name        string            // display name for UI
+	Role        protocol.NodeRole // MASTER or SLAVE
+	IsAlive     bool              // health status
+	Disabled    bool              // excluded from transactions and election, kept in membership
+	Maintenance bool              // self-reported: refuses new prepares and stays out of election
+	NotReady    bool              // failed its last /ready probe: kept out of new transactions, still counts for election
+	Draining    bool              // self-reported: shutting down, kept out of new transactions
+	TxState     protocol.TxState  // current transaction state
+	Database    string            // optional metadata about backing DB (for dashboards)
+	Priority    int               // operator-assigned preference; reported only, elections do not weigh it
+	Labels      map[string]string // free-form metadata (zone, tier, ...)
+
+	JoinedAt        time.Time // when the node became a cluster member
+	LastBecameAlive time.Time // last dead -> alive transition (uptime start)
 
 	// Transaction management
-	pendingTx   map[string]*sql.Tx // map of transaction_id -> pending transaction
-	pendingData map[string]any     // simulated data storage for transactions
-	mu          sync.RWMutex
+	pendingTx    map[string]*sql.Tx          // map of transaction_id -> pending transaction
+	pendingData  map[string]any              // simulated data storage for transactions
+	pendingRows  map[string]int64            // rows affected by the prepared statement per transaction
+	returned     map[string][]map[string]any // RETURNING values of the prepared INSERT per transaction
+	pendingOwner map[string]string           // coordinator that prepared each transaction ("" = unfenced)
+	preparedAt   map[string]time.Time        // when each pending transaction was prepared
+	pendingCount atomic.Int64                // len(pendingData), readable without n.mu
+	mu           sync.RWMutex
+	events       eventHub // subscribers to transaction state changes
+
+	// Abort counters since process start (guarded by mu)
+	abortedSelf          uint64 // voted ABORT during prepare
+	abortedByCoordinator uint64 // prepared, then aborted by the coordinator
+	rejectedAtCapacity   uint64 // prepares refused by the maxPrepared limit
+	rejectedForPressure  uint64 // prepares refused by the resource thresholds
+
+	// Time spent PREPARED by transactions that were since committed or aborted (guarded by mu)
+	preparedDurations *durationHistogram
+
+	// Business rules evaluated before a transaction is applied (optional)
+	preparePolicy PreparePolicy
+
+	// takeoverGuard authorizes finishing a transaction another coordinator
+	// prepared; nil refuses every takeover
+	takeoverGuard TakeoverGuard
+
+	// maxPrepared caps the transactions held prepared at once, each of which
+	// pins a database connection (0 = unlimited)
+	maxPrepared int
+
+	// resourceThresholds refuse prepares while the connection pool is saturated
+	resourceThresholds ResourceThresholds
+
+	// allowedTables lists the (lowercased) tables payloads may target; nil allows any
+	allowedTables map[string]bool
+
+	// coordinateOnly refuses every prepare: the node runs without a database
+	// and would otherwise vote READY and commit only in memory
+	coordinateOnly bool
+
+	// durablePrepare forces synchronous_commit=on for transactions this node prepares
+	durablePrepare bool
+
+	// preparedTxns ends prepare with PREPARE TRANSACTION so the prepared state
+	// is durable in Postgres rather than held in an open sql.Tx
+	preparedTxns bool
+	// stalePreparedAfter is the age at which a prepared transaction is reported
+	// stale (0 = DefaultStalePreparedAfter)
+	stalePreparedAfter time.Duration
 
 	// Database connection (optional, for real DB integration)
 	db         *sql.DB
+	dbDegraded bool // last DBMonitor ping failed (guarded by mu)
 	schemaOnce sync.Once
 	schemaErr  error
 }
 
+// PreparePolicy vetoes a transaction at prepare time. Returning an error makes
+// the node vote ABORT with that error as the reason.
+type PreparePolicy func(txID string, action *SQLAction) error
+
+// TakeoverGuard decides whether coordinator may finish a transaction that
+// owner prepared. Returning an error refuses the takeover with that reason.
+type TakeoverGuard func(coordinator, owner string) error
+
 // NodeStats tracks lightweight telemetry for operational visibility.
 type NodeStats struct {
 	Prepared    uint64
@@ -60,14 +158,29 @@ type NodeStats struct {
 // NewNode creates a new node instance
 func NewNode(addr string, role protocol.NodeRole) *Node {
 	return &Node{
-		Addr:        addr,
-		Name:        addr,
-		Role:        role,
-		IsAlive:     true,
-		TxState:     protocol.StateInit,
-		pendingTx:   make(map[string]*sql.Tx),
-		pendingData: make(map[string]any),
+		Addr:            addr,
+		Name:            addr,
+		Role:            role,
+		IsAlive:         true,
+		LastBecameAlive: time.Now(),
+		TxState:         protocol.StateInit,
+		pendingTx:       make(map[string]*sql.Tx),
+		pendingData:     make(map[string]any),
+		pendingRows:     make(map[string]int64),
+		returned:        make(map[string][]map[string]any),
+		pendingOwner:    make(map[string]string),
+		preparedAt:      make(map[string]time.Time),
+
+		preparedDurations: newDurationHistogram(preparedBucketsMs),
+	}
+}
+
+// ListenAddr returns the address the node's HTTP server binds to.
+func (n *Node) ListenAddr() string {
+	if n.BindAddr != "" {
+		return n.BindAddr
 	}
+	return n.Addr
 }
 
 // NewNodeWithDB creates a new node with database connection
@@ -84,6 +197,13 @@ func NewNodeWithDB(addr string, role protocol.NodeRole, db *sql.DB) *Node {
 func (n *Node) Metrics() protocol.NodeMetrics {
 	n.mu.RLock()
 	inFlight := len(n.pendingData)
+	abortedSelf := n.abortedSelf
+	abortedByCoordinator := n.abortedByCoordinator
+	maxPrepared := n.maxPrepared
+	rejectedAtCapacity := n.rejectedAtCapacity
+	rejectedForPressure := n.rejectedForPressure
+	preparedP95 := n.preparedDurations.quantileMs(0.95)
+	preparedDuration := n.preparedDurations.snapshot()
 	n.mu.RUnlock()
 
 	var committed uint64
@@ -96,6 +216,8 @@ func (n *Node) Metrics() protocol.NodeMetrics {
 		failed = dbFailed
 	}
 
+	preparedXacts, stalePreparedXacts, _ := n.preparedXactCounts()
+
 	totalAttempts := committed + aborted + failed
 	successRate := 0.0
 	if totalAttempts > 0 {
@@ -108,7 +230,79 @@ func (n *Node) Metrics() protocol.NodeMetrics {
 		Failed:      failed,
 		InFlight:    inFlight,
 		SuccessRate: successRate,
+
+		MaxPrepared:        maxPrepared,
+		RejectedAtCapacity: rejectedAtCapacity,
+
+		RejectedForPressure: rejectedForPressure,
+
+		AbortedSelf:          abortedSelf,
+		AbortedByCoordinator: abortedByCoordinator,
+
+		PreparedDurationP95Ms: preparedP95,
+		PreparedDuration:      preparedDuration,
+
+		PreparedXacts:      preparedXacts,
+		StalePreparedXacts: stalePreparedXacts,
+	}
+}
+
+// PingDB verifies the database connection. It returns nil when the node has no database.
+func (n *Node) PingDB(ctx context.Context) error {
+	n.mu.RLock()
+	db := n.db
+	n.mu.RUnlock()
+
+	if db == nil {
+		return nil
 	}
+	return db.PingContext(ctx)
+}
+
+// SetPreparePolicy installs a business-rule hook evaluated before the SQL action
+// is applied. Pass nil to remove it.
+func (n *Node) SetPreparePolicy(policy PreparePolicy) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.preparePolicy = policy
+}
+
+// SetCoordinateOnly makes the node refuse every prepare with ErrCoordinateOnly.
+// A coordinate-only master has no database, so a prepare sent by another
+// coordinator would vote READY and its writes would be lost.
+func (n *Node) SetCoordinateOnly(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.coordinateOnly = enabled
+}
+
+// SetTakeoverGuard installs the check CommitTakeover and AbortTakeover run
+// before finishing a transaction another coordinator prepared. Without one,
+// such takeovers are refused. Pass nil to remove it.
+func (n *Node) SetTakeoverGuard(guard TakeoverGuard) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.takeoverGuard = guard
+}
+
+// SetMaxPrepared limits how many transactions the node holds prepared at once.
+// Every prepared transaction keeps a database connection open until commit or
+// abort, so the limit protects the connection pool: prepares beyond it vote
+// ABORT with ErrAtCapacity instead of queueing for a connection. Zero or less
+// removes the limit.
+func (n *Node) SetMaxPrepared(limit int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.maxPrepared = max(limit, 0)
+}
+
+// SetDurablePrepare makes Prepare issue SET LOCAL synchronous_commit = on so the
+// transaction's commit waits for the WAL flush (and synchronous standbys, if any)
+// regardless of the server default. This trades commit latency for durability.
+func (n *Node) SetDurablePrepare(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.durablePrepare = enabled
 }
 
 // HasDB indicates if this node was started with a real database.
@@ -136,7 +330,7 @@ func (n *Node) fetchDBCounters() (
 	defer cancel()
 
 	if err := n.ensureSchema(ctx); err != nil {
-		log.Printf("[Node %s] fetchDBCounters ensureSchema error: %v", n.Addr, err)
+		logging.Warnf("[Node %s] fetchDBCounters ensureSchema error: %v", n.Addr, err)
 		return 0, 0, 0, false
 	}
 
@@ -144,22 +338,156 @@ func (n *Node) fetchDBCounters() (
 		SELECT
 			COALESCE(COUNT(*) FILTER (WHERE status='COMMITTED'), 0) AS committed,
 			COALESCE(COUNT(*) FILTER (WHERE status='ABORTED'), 0)   AS aborted,
-			COALESCE(COUNT(*) FILTER (WHERE status NOT IN ('COMMITTED','ABORTED','PREPARED')), 0) AS failed
+			COALESCE(COUNT(*) FILTER (WHERE status NOT IN ('COMMITTED','ABORTED','PREPARED','OBSERVED')), 0) AS failed
 		FROM distributed_tx`).Scan(
 		&committed,
 		&aborted,
 		&failed,
 	); err != nil {
 
-		log.Printf("[Node %s] fetchDBCounters scan error: %v", n.Addr, err)
+		logging.Warnf("[Node %s] fetchDBCounters scan error: %v", n.Addr, err)
 		return 0, 0, 0, false
 	}
 
 	return committed, aborted, failed, true
 }
 
+// RecordObserved inserts an OBSERVED marker for a transaction this node did not
+// participate in. It is a no-op without a database and never overwrites an existing row.
+func (n *Node) RecordObserved(ctx context.Context, txID string, payload any) error {
+	n.mu.RLock()
+	db := n.db
+	n.mu.RUnlock()
+
+	if db == nil {
+		return nil
+	}
+
+	if err := n.ensureSchema(ctx); err != nil {
+		return err
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO distributed_tx (
+			tx_id,
+			payload,
+			status
+			) VALUES ($1, $2::jsonb, 'OBSERVED')
+		ON CONFLICT (tx_id) DO NOTHING`,
+		txID, string(payloadBytes),
+	)
+	return err
+}
+
+// GetTransaction returns the stored record for txID, or nil if this node has none.
+// Without a database only in-memory prepared transactions are visible.
+func (n *Node) GetTransaction(ctx context.Context, txID string) (*protocol.TransactionRecord, error) {
+	n.mu.RLock()
+	db := n.db
+	payload, pending := n.pendingData[txID]
+	coordinator := n.pendingOwner[txID]
+	preparedTxns := n.preparedTxns
+	n.mu.RUnlock()
+
+	if db == nil {
+		if !pending {
+			return nil, nil
+		}
+		return &protocol.TransactionRecord{
+			TxID:            txID,
+			Status:          "PREPARED",
+			Payload:         payload,
+			CoordinatorAddr: coordinator,
+		}, nil
+	}
+
+	if err := n.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	var rec protocol.TransactionRecord
+	var payloadRaw []byte
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			tx_id,
+			status,
+			payload,
+			COALESCE(coordinator_addr, ''),
+			created_at,
+			updated_at
+		FROM
+			distributed_tx
+		WHERE tx_id = $1`,
+		txID,
+	).Scan(
+		&rec.TxID,
+		&rec.Status,
+		&payloadRaw,
+		&rec.CoordinatorAddr,
+		&rec.CreatedAt,
+		&rec.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A prepared transaction's row is not visible until it is resolved.
+		if !pending && preparedTxns {
+			gid, err := findPreparedGID(ctx, db, txID)
+			if err != nil {
+				return nil, err
+			}
+			if pending = gid != ""; pending {
+				_, coordinator = parsePreparedGID(gid)
+			}
+		}
+		if pending {
+			return &protocol.TransactionRecord{
+				TxID:            txID,
+				Status:          "PREPARED",
+				Payload:         payload,
+				CoordinatorAddr: coordinator,
+			}, nil
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payloadRaw) > 0 {
+		_ = json.Unmarshal(payloadRaw, &rec.Payload)
+	}
+
+	return &rec, nil
+}
+
+// ErrInvalidStatus is returned for a transaction status filter outside the stored statuses.
+var ErrInvalidStatus = errors.New("invalid transaction status")
+
+// NormalizeTxStatus upper-cases and validates a transaction status filter.
+// Empty means no filter.
+func NormalizeTxStatus(status string) (string, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	switch status {
+	case "", protocol.TxStatusPrepared, protocol.TxStatusCommitted, protocol.TxStatusAborted, protocol.TxStatusObserved:
+		return status, nil
+	default:
+		return "", fmt.Errorf("%w %q (want PREPARED, COMMITTED, ABORTED or OBSERVED)", ErrInvalidStatus, status)
+	}
+}
+
 // ListTransactions returns paginated distributed_tx entries when a DB is configured.
+// status filters by stored status (case-insensitive); unknown values are rejected
+// with ErrInvalidStatus.
 func (n *Node) ListTransactions(ctx context.Context, page, limit int, status string) ([]protocol.TransactionRecord, int, error) {
+	status, err := NormalizeTxStatus(status)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	n.mu.RLock()
 	db := n.db
 	n.mu.RUnlock()
@@ -210,6 +538,7 @@ func (n *Node) ListTransactions(ctx context.Context, page, limit int, status str
 				tx_id, 
 				status, 
 				payload, 
+				COALESCE(coordinator_addr, ''),
 				created_at, 
 				updated_at
 			FROM 
@@ -242,6 +571,7 @@ func (n *Node) ListTransactions(ctx context.Context, page, limit int, status str
 			&rec.TxID,
 			&rec.Status,
 			&payloadRaw,
+			&rec.CoordinatorAddr,
 			&rec.CreatedAt,
 			&rec.UpdatedAt,
 		); err != nil {
@@ -271,50 +601,75 @@ func (n *Node) ensureSchema(ctx context.Context) error {
 	return n.schemaErr
 }
 
-// ensureSchemaLocked performs a robust create-if-missing with a post-check to tolerate races.
+// ensureSchemaLocked performs a robust create-if-missing with a post-check to
+// tolerate races, then applies the migrations.
 func (n *Node) ensureSchemaLocked(ctx context.Context) error {
 
-	exists, err := n.tableExists(ctx, distTx)
+	exists, err := tableExists(ctx, n.db, distTx)
 	if err != nil {
 		return err
 	}
 
-	if exists {
-		return nil
-	}
+	if !exists {
+		if _, err := n.db.ExecContext(ctx, ddl); err != nil {
+			// If we raced with another node, re-check: if the table now exists, ignore the error.
+			ok, chkErr := tableExists(ctx, n.db, distTx)
+			if chkErr != nil {
+				return chkErr
+			}
 
-	if _, err := n.db.ExecContext(ctx, ddl); err != nil {
-		// If we raced with another node, re-check: if the table now exists, ignore the error.
-		ok, chkErr := n.tableExists(ctx, distTx)
-		if chkErr != nil {
-			return chkErr
+			if !ok {
+				return err
+			}
 		}
+	}
 
-		if ok {
-			return nil
+	for _, stmt := range migrations {
+		if _, err := n.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrate %s: %w", distTx, err)
 		}
-
-		return err
 	}
 	return nil
 }
 
-func (n *Node) tableExists(ctx context.Context, name string) (bool, error) {
+func tableExists(ctx context.Context, q querier, name string) (bool, error) {
 	var regclass *string
-	if err := n.db.QueryRowContext(ctx, `SELECT to_regclass($1)`, name).Scan(&regclass); err != nil {
+	if err := q.QueryRowContext(ctx, `SELECT to_regclass($1)`, name).Scan(&regclass); err != nil {
 		return false, err
 	}
 	return regclass != nil, nil
 }
 
-// SQLAction describes a simple insert/update request
+// SQLAction describes a simple insert/update request, or a read-only check
 type SQLAction struct {
 	Table     string         `json:"table"`
-	Operation string         `json:"operation"` // INSERT or UPDATE (case-insensitive); default INSERT
+	Operation string         `json:"operation"` // INSERT, UPDATE, UPSERT or READ_CHECK (case-insensitive); default INSERT
 	Values    map[string]any `json:"values"`
-	Where     map[string]any `json:"where,omitempty"` // required for UPDATE
+	Where     map[string]any `json:"where,omitempty"` // required for UPDATE and READ_CHECK
+
+	// Conflict lists the unique key columns of an UPSERT (required, each must be in Values).
+	// UpdateColumns are overwritten on conflict; they default to every non-key column in Values.
+	Conflict      []string `json:"conflict,omitempty"`
+	UpdateColumns []string `json:"update_columns,omitempty"`
+
+	// ExpectRowsAffected optionally guards the statement result, e.g. ">=1" or "1".
+	// Prepare votes ABORT when the affected row count does not satisfy it.
+	ExpectRowsAffected string `json:"expect_rows_affected,omitempty"`
+
+	// Returning lists columns of the inserted row to read back with INSERT ...
+	// RETURNING, such as a serial primary key. Only INSERT supports it.
+	Returning []string `json:"returning,omitempty"`
+
+	// Expect is what a READ_CHECK requires of the rows matching Where:
+	// "exists" (the default) or a count, e.g. "0", "1" or ">=2". Prepare runs
+	// it as a SELECT ... FOR SHARE without writing anything, keeping the rows
+	// locked until the decision, and votes ABORT when it is not met.
+	Expect string `json:"expect,omitempty"`
 }
 
+// parseSQLAction converts a transaction payload into a validated SQLAction.
+// JSON payloads must be a single object using only SQLAction's keys, so a
+// typo such as "tabel" is rejected instead of surfacing as a missing table.
 func parseSQLAction(payload any) (*SQLAction, error) {
 	var action SQLAction
 
@@ -329,19 +684,19 @@ func parseSQLAction(payload any) (*SQLAction, error) {
 		}
 		action = *v
 	case []byte:
-		if err := json.Unmarshal(v, &action); err != nil {
+		if err := decodeSQLAction(v, &action); err != nil {
 			return nil, err
 		}
 	case string:
-		if err := json.Unmarshal([]byte(v), &action); err != nil {
+		if err := decodeSQLAction([]byte(v), &action); err != nil {
 			return nil, err
 		}
 	default:
-		bytes, err := json.Marshal(v)
+		raw, err := json.Marshal(v)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("payload is not a SQL action object: %w", err)
 		}
-		if err := json.Unmarshal(bytes, &action); err != nil {
+		if err := decodeSQLAction(raw, &action); err != nil {
 			return nil, err
 		}
 	}
@@ -360,15 +715,68 @@ func parseSQLAction(payload any) (*SQLAction, error) {
 	return &action, nil
 }
 
+// decodeSQLAction strictly decodes one JSON object into action.
+func decodeSQLAction(raw []byte, action *SQLAction) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return errors.New("payload is required")
+	}
+	if !json.Valid(raw) {
+		return errors.New("payload is not a SQL action object: invalid JSON")
+	}
+	if raw[0] != '{' {
+		return fmt.Errorf("payload is not a SQL action object: got %s", jsonKind(raw[0]))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(action); err != nil {
+		return fmt.Errorf("invalid SQL action: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	return nil
+}
+
+// jsonKind names the JSON type starting with c, for error messages.
+func jsonKind(c byte) string {
+	switch c {
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "a boolean"
+	case 'n':
+		return "null"
+	default:
+		return "a number"
+	}
+}
+
 func validateSQLAction(action *SQLAction) error {
 	if action.Table == "" {
 		return errors.New("table is required")
 	}
 
+	if err := validateReturning(action); err != nil {
+		return err
+	}
+
+	if action.Operation == "READ_CHECK" {
+		return validateReadCheck(action)
+	}
+	if action.Expect != "" {
+		return errors.New("expect only applies to READ_CHECK")
+	}
+
 	if len(action.Values) == 0 {
 		return errors.New("values are required")
 	}
 
+	if _, _, err := parseRowsExpectation(action.ExpectRowsAffected); err != nil {
+		return err
+	}
+
 	switch action.Operation {
 	case "INSERT":
 		return nil
@@ -377,47 +785,212 @@ func validateSQLAction(action *SQLAction) error {
 			return errors.New("where is required for UPDATE")
 		}
 		return nil
+	case "UPSERT":
+		return validateUpsert(action)
 	default:
 		return errors.New("unsupported operation: " + action.Operation)
 	}
 }
 
-func (n *Node) applySQLAction(ctx context.Context, tx *sql.Tx, action *SQLAction) error {
+// validateReturning checks that only an INSERT asks for RETURNING columns,
+// and that they are valid identifiers.
+func validateReturning(action *SQLAction) error {
+	if len(action.Returning) == 0 {
+		return nil
+	}
+	if action.Operation != "INSERT" {
+		return errors.New("returning only applies to INSERT")
+	}
+	for _, c := range action.Returning {
+		if _, err := safeIdent(c); err != nil {
+			return fmt.Errorf("returning column %q: %w", c, err)
+		}
+	}
+	return nil
+}
+
+// validateReadCheck checks that a READ_CHECK has a condition and a valid
+// expectation, and nothing to write.
+func validateReadCheck(action *SQLAction) error {
+	if len(action.Where) == 0 {
+		return errors.New("where is required for READ_CHECK")
+	}
+	if len(action.Values) > 0 {
+		return errors.New("values are not allowed for READ_CHECK")
+	}
+	if action.ExpectRowsAffected != "" {
+		return errors.New("expect_rows_affected does not apply to READ_CHECK; use expect")
+	}
+
+	_, _, err := parseReadExpectation(action.Expect)
+	return err
+}
+
+// validateUpsert checks that the conflict key and update columns are valid
+// identifiers taken from Values, and that no key column is also updated.
+func validateUpsert(action *SQLAction) error {
+	if len(action.Conflict) == 0 {
+		return errors.New("conflict columns are required for UPSERT")
+	}
+
+	conflict := make(map[string]bool, len(action.Conflict))
+	for _, c := range action.Conflict {
+		if _, err := safeIdent(c); err != nil {
+			return fmt.Errorf("conflict column %q: %w", c, err)
+		}
+		if _, ok := action.Values[c]; !ok {
+			return fmt.Errorf("conflict column %q must be present in values", c)
+		}
+		conflict[c] = true
+	}
+
+	for _, c := range action.UpdateColumns {
+		if _, ok := action.Values[c]; !ok {
+			return fmt.Errorf("update column %q must be present in values", c)
+		}
+		if conflict[c] {
+			return fmt.Errorf("update column %q is part of the conflict key", c)
+		}
+	}
+
+	return nil
+}
+
+// parseRowsExpectation parses an expect_rows_affected value into an operator
+// (">=" or "=") and a count. An empty expectation returns an empty operator.
+func parseRowsExpectation(expect string) (string, int64, error) {
+	expect = strings.TrimSpace(expect)
+	if expect == "" {
+		return "", 0, nil
+	}
+
+	op := "="
+	switch {
+	case strings.HasPrefix(expect, ">="):
+		op = ">="
+		expect = expect[2:]
+	case strings.HasPrefix(expect, "="):
+		expect = expect[1:]
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(expect), 10, 64)
+	if err != nil || count < 0 {
+		return "", 0, errors.New("invalid expect_rows_affected: use N or >=N")
+	}
+
+	return op, count, nil
+}
+
+// checkRowsAffected verifies the affected row count against the action's expectation.
+func checkRowsAffected(expect string, affected int64) error {
+	op, count, err := parseRowsExpectation(expect)
+	if err != nil || op == "" {
+		return err
+	}
+
+	if (op == ">=" && affected < count) || (op == "=" && affected != count) {
+		return fmt.Errorf("rows affected %d does not satisfy expectation %s%d", affected, op, count)
+	}
+
+	return nil
+}
+
+// parseReadExpectation parses a READ_CHECK expect value into an operator and
+// a count like parseRowsExpectation. "exists" and "" mean ">=1".
+func parseReadExpectation(expect string) (string, int64, error) {
+	expect = strings.TrimSpace(expect)
+	if expect == "" || strings.EqualFold(expect, "exists") {
+		return ">=", 1, nil
+	}
+
+	op, count, err := parseRowsExpectation(expect)
+	if err != nil {
+		return "", 0, errors.New("invalid expect: use exists, N or >=N")
+	}
+	return op, count, nil
+}
+
+// readCheck counts the rows of action.Table matching action.Where and returns
+// an error when the count does not meet action.Expect.
+func readCheck(ctx context.Context, q querier, action *SQLAction) error {
 	table, err := safeIdent(action.Table)
 	if err != nil {
 		return err
 	}
 
-	switch action.Operation {
-	case "INSERT":
-		cols := sortedKeys(action.Values)
-		colIdents := make([]string, len(cols))
-		args := make([]any, len(cols))
-		placeholders := make([]string, len(cols))
-
-		for i, c := range cols {
-			ident, err := safeIdent(c)
-			if err != nil {
-				return err
-			}
+	op, want, err := parseReadExpectation(action.Expect)
+	if err != nil {
+		return err
+	}
 
-			colIdents[i] = `"` + ident + `"`
-			args[i] = action.Values[c]
-			placeholders[i] = placeholder(i + 1)
+	whereCols := sortedKeys(action.Where)
+	whereParts := make([]string, len(whereCols))
+	args := make([]any, len(whereCols))
+	for i, c := range whereCols {
+		ident, err := safeIdent(c)
+		if err != nil {
+			return err
 		}
+		whereParts[i] = `"` + ident + `"=` + placeholder(i+1)
+		args[i] = action.Where[c]
+	}
 
-		stmt := "INSERT INTO \"" + table + "\" (" + strings.Join(colIdents, ",") + ") VALUES (" + strings.Join(placeholders, ",") + ")"
-
-		_, err = tx.ExecContext(ctx, stmt, args...)
+	// FOR SHARE is not allowed with an aggregate, so lock the rows in a subquery.
+	stmt := "SELECT count(*) FROM (SELECT 1 FROM \"" + table + "\" WHERE " + strings.Join(whereParts, " AND ") + " FOR SHARE) AS matched"
 
+	var count int64
+	if err := q.QueryRowContext(ctx, stmt, args...).Scan(&count); err != nil {
 		return err
+	}
+
+	if (op == ">=" && count < want) || (op == "=" && count != want) {
+		return fmt.Errorf("read check failed: %d rows match, expected %s%d", count, op, want)
+	}
+
+	return nil
+}
+
+// execer is the write side of *sql.DB, *sql.Tx and *sql.Conn. SQL generation
+// only depends on it, so tests can assert the exact statements with a fake or
+// sqlmock instead of a live Postgres.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// querier is the read side of *sql.DB, *sql.Tx and *sql.Conn.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// applySQLAction executes the action through ex (the prepare transaction) and
+// returns the number of rows affected.
+func (n *Node) applySQLAction(ctx context.Context, ex execer, action *SQLAction) (int64, error) {
+	table, err := safeIdent(action.Table)
+	if err != nil {
+		return 0, err
+	}
+
+	switch action.Operation {
+	case "INSERT":
+		stmt, args, err := insertStatement(table, action.Values)
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := ex.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return 0, err
+		}
+
+		return res.RowsAffected()
 
 	case "UPDATE":
 		setCols := sortedKeys(action.Values)
 		whereCols := sortedKeys(action.Where)
 
 		if len(whereCols) == 0 {
-			return errors.New("where is required for UPDATE")
+			return 0, errors.New("where is required for UPDATE")
 		}
 
 		setParts := make([]string, len(setCols))
@@ -428,7 +1001,7 @@ func (n *Node) applySQLAction(ctx context.Context, tx *sql.Tx, action *SQLAction
 		for i, c := range setCols {
 			ident, err := safeIdent(c)
 			if err != nil {
-				return err
+				return 0, err
 			}
 
 			setParts[i] = `"` + ident + `"=` + placeholder(idx)
@@ -440,7 +1013,7 @@ func (n *Node) applySQLAction(ctx context.Context, tx *sql.Tx, action *SQLAction
 		for i, c := range whereCols {
 			ident, err := safeIdent(c)
 			if err != nil {
-				return err
+				return 0, err
 			}
 			whereParts[i] = `"` + ident + `"=` + placeholder(idx)
 			args = append(args, action.Where[c])
@@ -449,28 +1022,176 @@ func (n *Node) applySQLAction(ctx context.Context, tx *sql.Tx, action *SQLAction
 
 		stmt := "UPDATE \"" + table + "\" SET " + strings.Join(setParts, ",") + " WHERE " + strings.Join(whereParts, " AND ")
 
-		_, err := tx.ExecContext(ctx, stmt, args...)
+		res, err := ex.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return 0, err
+		}
 
-		return err
+		return res.RowsAffected()
+
+	case "UPSERT":
+		stmt, args, err := upsertStatement(table, action)
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := ex.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return 0, err
+		}
+
+		return res.RowsAffected()
 	default:
-		return errors.New("unsupported operation: " + action.Operation)
+		return 0, errors.New("unsupported operation: " + action.Operation)
 	}
 }
 
-func sortedKeys(m map[string]any) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
+// insertReturning runs an INSERT ... RETURNING through q and returns the
+// affected row count with the requested columns of each inserted row.
+func insertReturning(ctx context.Context, q querier, action *SQLAction) (int64, []map[string]any, error) {
+	table, err := safeIdent(action.Table)
+	if err != nil {
+		return 0, nil, err
 	}
 
-	sort.Strings(keys)
+	stmt, args, err := insertStatement(table, action.Values)
+	if err != nil {
+		return 0, nil, err
+	}
 
-	return keys
-}
+	cols := make([]string, len(action.Returning))
+	idents := make([]string, len(action.Returning))
+	for i, c := range action.Returning {
+		ident, err := safeIdent(c)
+		if err != nil {
+			return 0, nil, err
+		}
+		cols[i] = ident
+		idents[i] = `"` + ident + `"`
+	}
+	stmt += " RETURNING " + strings.Join(idents, ",")
 
-func placeholder(idx int) string {
-	return "$" + strconv.Itoa(idx)
-}
+	rows, err := q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var returned []map[string]any
+	for rows.Next() {
+		row, err := scanRow(rows, cols)
+		if err != nil {
+			return 0, nil, err
+		}
+		returned = append(returned, row)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return int64(len(returned)), returned, nil
+}
+
+// applyPrepared applies action inside the prepare transaction, reading back
+// the RETURNING columns when the action asks for them. A READ_CHECK writes
+// nothing but share-locks the rows it counted for the rest of the transaction.
+func (n *Node) applyPrepared(ctx context.Context, ex interface {
+	execer
+	querier
+}, action *SQLAction) (int64, []map[string]any, error) {
+	if action.Operation == "READ_CHECK" {
+		return 0, nil, readCheck(ctx, ex, action)
+	}
+	if len(action.Returning) > 0 {
+		return insertReturning(ctx, ex, action)
+	}
+
+	affected, err := n.applySQLAction(ctx, ex, action)
+	return affected, nil, err
+}
+
+// insertStatement builds a parameterized INSERT of values into table.
+func insertStatement(table string, values map[string]any) (string, []any, error) {
+	cols := sortedKeys(values)
+	colIdents := make([]string, len(cols))
+	args := make([]any, len(cols))
+	placeholders := make([]string, len(cols))
+
+	for i, c := range cols {
+		ident, err := safeIdent(c)
+		if err != nil {
+			return "", nil, err
+		}
+
+		colIdents[i] = `"` + ident + `"`
+		args[i] = values[c]
+		placeholders[i] = placeholder(i + 1)
+	}
+
+	stmt := "INSERT INTO \"" + table + "\" (" + strings.Join(colIdents, ",") + ") VALUES (" + strings.Join(placeholders, ",") + ")"
+
+	return stmt, args, nil
+}
+
+// upsertStatement builds INSERT ... ON CONFLICT (key) DO UPDATE SET col=EXCLUDED.col.
+// With nothing to update the conflicting row is left as is (DO NOTHING).
+func upsertStatement(table string, action *SQLAction) (string, []any, error) {
+	stmt, args, err := insertStatement(table, action.Values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conflict := make(map[string]bool, len(action.Conflict))
+	keyIdents := make([]string, len(action.Conflict))
+	for i, c := range action.Conflict {
+		ident, err := safeIdent(c)
+		if err != nil {
+			return "", nil, err
+		}
+		keyIdents[i] = `"` + ident + `"`
+		conflict[c] = true
+	}
+
+	updateCols := action.UpdateColumns
+	if len(updateCols) == 0 {
+		for _, c := range sortedKeys(action.Values) {
+			if !conflict[c] {
+				updateCols = append(updateCols, c)
+			}
+		}
+	}
+
+	stmt += " ON CONFLICT (" + strings.Join(keyIdents, ",") + ")"
+	if len(updateCols) == 0 {
+		return stmt + " DO NOTHING", args, nil
+	}
+
+	setParts := make([]string, len(updateCols))
+	for i, c := range updateCols {
+		ident, err := safeIdent(c)
+		if err != nil {
+			return "", nil, err
+		}
+		setParts[i] = `"` + ident + `"=EXCLUDED."` + ident + `"`
+	}
+
+	return stmt + " DO UPDATE SET " + strings.Join(setParts, ","), args, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func placeholder(idx int) string {
+	return "$" + strconv.Itoa(idx)
+}
 
 func safeIdent(id string) (string, error) {
 	if id == "" {
@@ -504,6 +1225,10 @@ func isAlreadyFinishedErr(err error) bool {
 func (n *Node) SetAlive(alive bool) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
+
+	if alive && !n.IsAlive {
+		n.LastBecameAlive = time.Now()
+	}
 	n.IsAlive = alive
 }
 
@@ -514,6 +1239,49 @@ func (n *Node) GetAlive() bool {
 	return n.IsAlive
 }
 
+// SetDisabled marks the node as temporarily excluded from transactions and election.
+func (n *Node) SetDisabled(disabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Disabled = disabled
+}
+
+// GetDisabled returns whether the node is administratively disabled.
+func (n *Node) GetDisabled() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.Disabled
+}
+
+// MarkJoined records the time the node joined the cluster (first call wins).
+func (n *Node) MarkJoined(at time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.JoinedAt.IsZero() {
+		n.JoinedAt = at
+	}
+}
+
+// GetJoinedAt returns when the node joined the cluster (zero if never added).
+func (n *Node) GetJoinedAt() time.Time {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.JoinedAt
+}
+
+// Uptime returns how long the node has been continuously alive (0 when dead).
+func (n *Node) Uptime() time.Duration {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if !n.IsAlive || n.LastBecameAlive.IsZero() {
+		return 0
+	}
+	return time.Since(n.LastBecameAlive)
+}
+
 // SetRole updates the node's role
 func (n *Node) SetRole(role protocol.NodeRole) {
 	n.mu.Lock()
@@ -530,6 +1298,14 @@ func (n *Node) GetRole() protocol.NodeRole {
 	return n.Role
 }
 
+// GetTxState returns the state of the node's most recent transaction.
+func (n *Node) GetTxState() protocol.TxState {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.TxState
+}
+
 // SetDatabase sets a display-friendly database label/DSN for dashboards.
 func (n *Node) SetDatabase(db string) {
 	n.mu.Lock()
@@ -546,6 +1322,51 @@ func (n *Node) GetDatabase() string {
 	return n.Database
 }
 
+// SetPriority sets the node's priority label. Elections report it but do not
+// weigh it.
+func (n *Node) SetPriority(priority int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.Priority = priority
+}
+
+// GetPriority returns the node's priority label.
+func (n *Node) GetPriority() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.Priority
+}
+
+// SetLabels replaces the node's labels with a copy of labels.
+func (n *Node) SetLabels(labels map[string]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.Labels = copyLabels(labels)
+}
+
+// GetLabels returns a copy of the node's labels.
+func (n *Node) GetLabels() map[string]string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return copyLabels(n.Labels)
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
 // SetName sets the display name for the node.
 func (n *Node) SetName(name string) {
 	n.mu.Lock()
@@ -569,27 +1390,132 @@ func (n *Node) GetName() string {
 	return n.Name
 }
 
+// isRetryLocked reports whether a prepare of the pending txID repeats the
+// original one: the same coordinator and a payload that encodes to the same
+// JSON. A transaction recovered from Postgres has no payload to compare, so
+// it never matches. Callers must hold n.mu.
+func (n *Node) isRetryLocked(coordinator, txID string, payload any) bool {
+	prev := n.pendingData[txID]
+	if prev == nil || !SameAddr(n.pendingOwner[txID], coordinator) {
+		return false
+	}
+
+	prevJSON, err := json.Marshal(prev)
+	if err != nil {
+		return false
+	}
+	retryJSON, err := json.Marshal(payload)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(prevJSON, retryJSON)
+}
+
 // Prepare handles the prepare phase of 2PC
 // Returns true if ready to commit, false otherwise
-func (n *Node) Prepare(txID string, payload any) (bool, error) {
+func (n *Node) Prepare(txID string, payload any) (ready bool, err error) {
+	return n.PrepareFor("", txID, payload)
+}
+
+// PrepareFor prepares txID on behalf of coordinator. Once prepared, only the
+// same coordinator may commit or abort it, fencing off a second master in a
+// split brain. An empty coordinator leaves the transaction unfenced.
+func (n *Node) PrepareFor(coordinator, txID string, payload any) (ready bool, err error) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	// A coordinator retrying a prepare whose response was lost gets its
+	// READY vote again; the transaction stays prepared once.
+	if _, exists := n.pendingData[txID]; exists && n.isRetryLocked(coordinator, txID, payload) {
+		logging.Debugf("[Node %s] Transaction %s is already prepared with the same payload", n.Addr, txID)
+		return true, nil
+	}
+
+	defer func() {
+		if !ready {
+			n.abortedSelf++
+			n.publishTransaction(txID, "ABORTED", coordinator, err)
+			return
+		}
+		n.publishTransaction(txID, "PREPARED", coordinator, nil)
+	}()
+
+	if n.coordinateOnly {
+		return false, ErrCoordinateOnly
+	}
+
+	if n.Maintenance {
+		return false, ErrMaintenance
+	}
+
 	// Check if we already have a pending transaction with this ID
 	if _, exists := n.pendingData[txID]; exists {
-		err := errors.New("transaction already in progress")
+		err := errors.New("transaction already in progress with a different payload or coordinator")
 		return false, err
 	}
 
-	// If we have a real database connection, start a transaction and persist the payload
+	if n.maxPrepared > 0 && len(n.pendingData) >= n.maxPrepared {
+		n.rejectedAtCapacity++
+		logging.Warnf("[Node %s] Rejecting transaction %s: %d transactions already prepared", n.Addr, txID, len(n.pendingData))
+		return false, fmt.Errorf("%w: %d of %d prepared transactions in flight", ErrAtCapacity, len(n.pendingData), n.maxPrepared)
+	}
+
 	if n.db != nil {
+		if err := n.resourceThresholds.check(n.db.Stats()); err != nil {
+			n.rejectedForPressure++
+			logging.Warnf("[Node %s] Rejecting transaction %s: %v", n.Addr, txID, err)
+			return false, err
+		}
+	}
+
+	if n.allowedTables != nil {
+		action, err := parseSQLAction(payload)
+		if err != nil {
+			return false, err
+		}
+		if err := checkTableAllowed(n.allowedTables, action); err != nil {
+			logging.Warnf("[Node %s] Rejecting transaction %s: %v", n.Addr, txID, err)
+			return false, err
+		}
+	}
+
+	if n.preparePolicy != nil {
+		action, err := parseSQLAction(payload)
+		if err != nil {
+			return false, err
+		}
+		if err := n.preparePolicy(txID, action); err != nil {
+			logging.Warnf("[Node %s] Prepare policy rejected transaction %s: %v", n.Addr, txID, err)
+			return false, fmt.Errorf("rejected by prepare policy: %w", err)
+		}
+	}
+
+	// A read check must hold the rows it counted until the decision; a node
+	// without a database has nothing to hold them with.
+	if n.db == nil {
+		if action, err := parseSQLAction(payload); err == nil && action.Operation == "READ_CHECK" {
+			return false, ErrReadCheckNoDatabase
+		}
+	}
+
+	// If we have a real database connection, start a transaction and persist the payload.
+	if n.db != nil && n.preparedTxns {
+		affected, returned, err := n.prepareTransactionLocked(coordinator, txID, payload)
+		if err != nil {
+			return false, err
+		}
+		n.pendingRows[txID] = affected
+		if returned != nil {
+			n.returned[txID] = returned
+		}
+	} else if n.db != nil {
 		// Use a timeout context for schema operations but NOT for the transaction itself
 		// because cancelling the context would rollback the transaction
 		schemaCtx, schemaCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer schemaCancel()
 
 		if err := n.ensureSchema(schemaCtx); err != nil {
-			log.Printf("[Node %s] Failed to ensure schema: %v", n.Addr, err)
+			logging.Errorf("[Node %s] Failed to ensure schema: %v", n.Addr, err)
 			return false, err
 		}
 
@@ -597,22 +1523,37 @@ func (n *Node) Prepare(txID string, payload any) (bool, error) {
 		// The transaction will be committed or rolled back later in Commit/Abort
 		tx, err := n.db.BeginTx(context.Background(), nil)
 		if err != nil {
-			log.Printf("[Node %s] Failed to begin transaction: %v", n.Addr, err)
+			logging.Errorf("[Node %s] Failed to begin transaction: %v", n.Addr, err)
 			return false, err
 		}
 
+		// Use a timeout context for SQL operations within the transaction
+		opCtx, opCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer opCancel()
+
+		if n.durablePrepare {
+			if _, err := tx.ExecContext(opCtx, `SET LOCAL synchronous_commit = on`); err != nil {
+				_ = tx.Rollback()
+				logging.Warnf("[Node %s] Failed to enable synchronous_commit for %s: %v", n.Addr, txID, err)
+				return false, err
+			}
+		}
+
 		action, err := parseSQLAction(payload)
 		if err != nil {
 			_ = tx.Rollback()
 			return false, err
 		}
 
-		// Use a timeout context for SQL operations within the transaction
-		opCtx, opCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer opCancel()
+		affected, returned, err := n.applyPrepared(opCtx, tx, action)
+		if err != nil {
+			_ = tx.Rollback()
+			return false, err
+		}
 
-		if err := n.applySQLAction(opCtx, tx, action); err != nil {
+		if err := checkRowsAffected(action.ExpectRowsAffected, affected); err != nil {
 			_ = tx.Rollback()
+			logging.Warnf("[Node %s] Rejecting transaction %s: %v", n.Addr, txID, err)
 			return false, err
 		}
 
@@ -626,9 +1567,10 @@ func (n *Node) Prepare(txID string, payload any) (bool, error) {
 			`INSERT INTO distributed_tx (
 				tx_id, 
 				payload, 
-				status
-				) VALUES ($1, $2::jsonb, 'PREPARED')`,
-			txID, string(payloadBytes),
+				status,
+				coordinator_addr
+				) VALUES ($1, $2::jsonb, 'PREPARED', NULLIF($3, ''))`,
+			txID, string(payloadBytes), coordinator,
 		)
 		if err != nil {
 			_ = tx.Rollback()
@@ -648,6 +1590,10 @@ func (n *Node) Prepare(txID string, payload any) (bool, error) {
 		}
 
 		n.pendingTx[txID] = tx
+		n.pendingRows[txID] = affected
+		if returned != nil {
+			n.returned[txID] = returned
+		}
 	} else {
 		// Store the payload for simulated transaction
 		n.pendingData[txID] = payload
@@ -657,18 +1603,152 @@ func (n *Node) Prepare(txID string, payload any) (bool, error) {
 	if n.db != nil {
 		n.pendingData[txID] = payload
 	}
+	n.pendingCount.Store(int64(len(n.pendingData)))
+
+	if coordinator != "" {
+		n.pendingOwner[txID] = coordinator
+	}
+	n.preparedAt[txID] = time.Now()
 
 	n.TxState = protocol.StateReady
-	log.Printf("[Node %s] Prepared transaction %s", n.Addr, txID)
+	logging.Debugf("[Node %s] Prepared transaction %s", n.Addr, txID)
 
 	return true, nil
 }
 
 // Commit commits the prepared transaction
 func (n *Node) Commit(txID string) error {
+	return n.CommitFor("", txID)
+}
+
+// CommitFor commits txID on behalf of coordinator, rejecting it with
+// ErrForeignCoordinator if another coordinator prepared the transaction.
+func (n *Node) CommitFor(coordinator, txID string) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	return n.commitLocked(coordinator, txID)
+}
+
+// CommitForChanges commits txID like CommitFor and returns the change it
+// applied, for change-data-capture. The result is empty when the transaction
+// was already finished or its payload is not an SQLAction.
+func (n *Node) CommitForChanges(coordinator, txID string) ([]protocol.AppliedChange, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	payload, pending := n.pendingData[txID]
+	rows := n.pendingRows[txID]
+
+	if err := n.commitLocked(coordinator, txID); err != nil {
+		return nil, err
+	}
+	if !pending {
+		return nil, nil
+	}
+
+	action, err := parseSQLAction(payload)
+	if err != nil || action.Operation == "READ_CHECK" {
+		return nil, nil
+	}
+
+	change := protocol.AppliedChange{
+		Table:        action.Table,
+		Operation:    action.Operation,
+		Values:       action.Values,
+		RowsAffected: rows,
+	}
+	if len(action.Where) > 0 {
+		change.Where = action.Where
+	}
+	change.Keys = n.changeKeysLocked(action)
+
+	return []protocol.AppliedChange{change}, nil
+}
+
+// changeKeysLocked returns the primary-key values identifying the row an action
+// touched: the WHERE of an UPDATE, the conflict key of an UPSERT, and for an
+// INSERT the table's primary-key columns found in its values. Keys the node
+// cannot know, such as a generated id, are left out.
+func (n *Node) changeKeysLocked(action *SQLAction) map[string]any {
+	var cols []string
+
+	switch action.Operation {
+	case "UPDATE":
+		return action.Where
+	case "UPSERT":
+		cols = action.Conflict
+	case "INSERT":
+		if n.db == nil {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		pk, err := primaryKeyColumns(ctx, n.db, action.Table)
+		if err != nil {
+			logging.Warnf("[Node %s] Failed to look up the primary key of %s: %v", n.Addr, action.Table, err)
+			return nil
+		}
+		cols = pk
+	}
+
+	keys := make(map[string]any, len(cols))
+	for _, c := range cols {
+		if v, ok := action.Values[c]; ok {
+			keys[c] = v
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return keys
+}
+
+// primaryKeyColumns lists the primary-key columns of table, in column order.
+// A table without a primary key, or one that does not exist, has none.
+func primaryKeyColumns(ctx context.Context, q querier, table string) ([]string, error) {
+	ident, err := safeIdent(table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT a.attname
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = to_regclass($1) AND i.indisprimary
+		ORDER BY a.attnum`,
+		ident,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+
+	return cols, rows.Err()
+}
+
+// commitLocked commits txID. The caller must hold n.mu.
+func (n *Node) commitLocked(coordinator, txID string) error {
+	if err := n.checkOwnerLocked(coordinator, txID); err != nil {
+		return err
+	}
+
+	_, prepared := n.pendingData[txID]
+	owner := n.pendingOwner[txID]
+
 	// If we have a real transaction, commit it
 	if tx, exists := n.pendingTx[txID]; exists {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -687,14 +1767,14 @@ func (n *Node) Commit(txID string) error {
 		); err != nil {
 			if !isAlreadyFinishedErr(err) {
 				_ = tx.Rollback()
-				log.Printf("[Node %s] Failed to update status for %s: %v", n.Addr, txID, err)
+				logging.Errorf("[Node %s] Failed to update status for %s: %v", n.Addr, txID, err)
 				return err
 			}
 		}
 
 		if err := tx.Commit(); err != nil {
 			if !isAlreadyFinishedErr(err) {
-				log.Printf("[Node %s] Failed to commit transaction %s: %v", n.Addr, txID, err)
+				logging.Errorf("[Node %s] Failed to commit transaction %s: %v", n.Addr, txID, err)
 				return err
 			}
 		}
@@ -702,6 +1782,11 @@ func (n *Node) Commit(txID string) error {
 		// Ensure we only drop it once; committing again should be idempotent
 		delete(n.pendingTx, txID)
 
+	} else if n.db != nil && n.preparedTxns {
+		if err := n.finishPreparedLocked(txID, true); err != nil {
+			return err
+		}
+
 	} else if n.db != nil {
 		// Idempotent handling: mark as committed even if we already applied it
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -716,34 +1801,67 @@ func (n *Node) Commit(txID string) error {
 			WHERE tx_id=$1`,
 			txID,
 		); err != nil {
-			log.Printf("[Node %s] Idempotent commit update failed for %s: %v", n.Addr, txID, err)
+			logging.Warnf("[Node %s] Idempotent commit update failed for %s: %v", n.Addr, txID, err)
 			return err
 		}
 	}
 
 	// Clean up simulated data
+	n.observePreparedLocked(txID)
 	delete(n.pendingData, txID)
+	n.pendingCount.Store(int64(len(n.pendingData)))
+	delete(n.pendingRows, txID)
+	delete(n.returned, txID)
+	delete(n.pendingOwner, txID)
 	n.TxState = protocol.StateCommit
+	if prepared {
+		n.publishTransaction(txID, "COMMITTED", owner, nil)
+	}
 
-	log.Printf("[Node %s] Committed transaction %s", n.Addr, txID)
+	logging.Debugf("[Node %s] Committed transaction %s", n.Addr, txID)
 	return nil
 }
 
 // Abort rolls back the prepared transaction
 func (n *Node) Abort(txID string) error {
+	return n.AbortFor("", txID)
+}
+
+// AbortFor aborts txID on behalf of coordinator, rejecting it with
+// ErrForeignCoordinator if another coordinator prepared the transaction.
+func (n *Node) AbortFor(coordinator, txID string) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	return n.abortLocked(coordinator, txID)
+}
+
+// abortLocked rolls back txID. The caller must hold n.mu.
+func (n *Node) abortLocked(coordinator, txID string) error {
+	if err := n.checkOwnerLocked(coordinator, txID); err != nil {
+		return err
+	}
+
+	// Only a transaction this node prepared counts as aborted by the coordinator;
+	// aborts for transactions it refused were already counted as self-aborts.
+	_, prepared := n.pendingData[txID]
+	owner := n.pendingOwner[txID]
+
 	// If we have a real transaction, rollback
 	if tx, exists := n.pendingTx[txID]; exists {
 		if err := tx.Rollback(); err != nil {
 			if !isAlreadyFinishedErr(err) {
-				log.Printf("[Node %s] Failed to rollback transaction %s: %v", n.Addr, txID, err)
+				logging.Errorf("[Node %s] Failed to rollback transaction %s: %v", n.Addr, txID, err)
 				return err
 			}
 		}
 		delete(n.pendingTx, txID)
 
+	} else if n.db != nil && n.preparedTxns {
+		if err := n.finishPreparedLocked(txID, false); err != nil {
+			return err
+		}
+
 	} else if n.db != nil {
 		// Idempotent rollback path when the tx was already committed/rolled back
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -759,19 +1877,123 @@ func (n *Node) Abort(txID string) error {
 				tx_id=$1`,
 			txID,
 		); err != nil {
-			log.Printf("[Node %s] Idempotent abort update failed for %s: %v", n.Addr, txID, err)
+			logging.Warnf("[Node %s] Idempotent abort update failed for %s: %v", n.Addr, txID, err)
 			return err
 		}
 	}
 
 	// Clean up simulated data
+	n.observePreparedLocked(txID)
 	delete(n.pendingData, txID)
+	n.pendingCount.Store(int64(len(n.pendingData)))
+	delete(n.pendingRows, txID)
+	delete(n.returned, txID)
+	delete(n.pendingOwner, txID)
 	n.TxState = protocol.StateAbort
+	if prepared {
+		n.abortedByCoordinator++
+		n.publishTransaction(txID, "ABORTED", owner, nil)
+	}
 
-	log.Printf("[Node %s] Aborted transaction %s", n.Addr, txID)
+	logging.Debugf("[Node %s] Aborted transaction %s", n.Addr, txID)
 	return nil
 }
 
+// CommitTakeover commits txID on behalf of coordinator even when another
+// coordinator prepared it. Reconciliation uses it to finish a transaction
+// whose coordinator is gone after a failover; a plain CommitFor from a stale
+// coordinator is still rejected. The takeover guard must allow it.
+func (n *Node) CommitTakeover(coordinator, txID string) error {
+	owner, err := n.authorizeTakeover(coordinator, txID)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.commitLocked(owner, txID)
+}
+
+// AbortTakeover aborts txID on behalf of coordinator even when another
+// coordinator prepared it. See CommitTakeover.
+func (n *Node) AbortTakeover(coordinator, txID string) error {
+	owner, err := n.authorizeTakeover(coordinator, txID)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.abortLocked(owner, txID)
+}
+
+// authorizeTakeover runs the takeover guard when coordinator is not the one
+// that prepared txID and returns the identity that passes the owner check.
+// The guard runs without n.mu held, since it may read this node's state; an
+// owner that changes before the decision is applied fails the owner check.
+func (n *Node) authorizeTakeover(coordinator, txID string) (string, error) {
+	n.mu.RLock()
+	owner := n.pendingOwner[txID]
+	guard := n.takeoverGuard
+	n.mu.RUnlock()
+
+	if owner == "" || SameAddr(owner, coordinator) {
+		return owner, nil
+	}
+	if guard == nil {
+		return "", fmt.Errorf("%w: coordinator %q cannot finish transaction %s prepared by %s", ErrTakeoverRefused, coordinator, txID, owner)
+	}
+	if err := guard(coordinator, owner); err != nil {
+		logging.Warnf("[Node %s] Refusing takeover of transaction %s by %q from %s: %v", n.Addr, txID, coordinator, owner, err)
+		return "", fmt.Errorf("%w: %v", ErrTakeoverRefused, err)
+	}
+
+	logging.Warnf("[Node %s] Coordinator %q takes over transaction %s from %s", n.Addr, coordinator, txID, owner)
+	return owner, nil
+}
+
+// observePreparedLocked records how long txID stayed prepared, if this node
+// prepared it. Caller must hold n.mu.
+func (n *Node) observePreparedLocked(txID string) {
+	if at, ok := n.preparedAt[txID]; ok {
+		n.preparedDurations.observe(time.Since(at))
+		delete(n.preparedAt, txID)
+	}
+}
+
+// checkOwnerLocked rejects a commit/abort from a coordinator other than the
+// one that prepared txID. Unfenced transactions accept any caller.
+// Caller must hold n.mu.
+func (n *Node) checkOwnerLocked(coordinator, txID string) error {
+	owner := n.pendingOwner[txID]
+	if owner == "" || SameAddr(owner, coordinator) {
+		return nil
+	}
+
+	logging.Warnf("[Node %s] Rejecting decision for transaction %s from %q: prepared by %s", n.Addr, txID, coordinator, owner)
+	return fmt.Errorf("%w (owner %s)", ErrForeignCoordinator, owner)
+}
+
+// RowsAffected returns the number of rows the prepared statement for txID modified.
+// Simulated (DB-less) transactions always report zero.
+func (n *Node) RowsAffected(txID string) int64 {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.pendingRows[txID]
+}
+
+// Returned returns the RETURNING values read back by the prepared INSERT for
+// txID, or nil when it asked for none.
+func (n *Node) Returned(txID string) []map[string]any {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.returned[txID]
+}
+
 // HasPendingTransaction checks if a transaction is pending
 func (n *Node) HasPendingTransaction(txID string) bool {
 	n.mu.RLock()
@@ -793,3 +2015,10 @@ func (n *Node) GetPendingTransactions() []string {
 
 	return txIDs
 }
+
+// PendingCount returns how many transactions the node holds prepared. Unlike
+// GetPendingTransactions it does not take the node's lock, which a prepare
+// holds across database I/O, so elections can read it without waiting.
+func (n *Node) PendingCount() int {
+	return int(n.pendingCount.Load())
+}
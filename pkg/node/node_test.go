@@ -1,7 +1,18 @@
 package node
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
@@ -115,18 +126,188 @@ func TestNodeDuplicatePrepare(t *testing.T) {
 	payload := map[string]string{"key": "value"}
 
 	// First prepare should succeed
-	ready, err := n.Prepare(txID, payload)
+	ready, err := n.PrepareFor("master-a:8080", txID, payload)
 	if err != nil || !ready {
 		t.Fatal("First prepare should succeed")
 	}
 
-	// Second prepare with same ID should fail
-	ready, err = n.Prepare(txID, payload)
-	if ready {
-		t.Error("Duplicate prepare should fail")
+	// A retry with the same payload, e.g. after a lost response, is READY again
+	ready, err = n.PrepareFor("master-a:8080", txID, map[string]string{"key": "value"})
+	if err != nil || !ready {
+		t.Fatalf("Expected an identical retry to be READY, got %v, %v", ready, err)
 	}
-	if err == nil {
-		t.Error("Expected error for duplicate prepare")
+	if got := n.Metrics().InFlight; got != 1 {
+		t.Errorf("Expected the retry to keep one prepared transaction, got %d", got)
+	}
+
+	// The same ID with a different payload, or from another coordinator, is refused
+	ready, err = n.PrepareFor("master-a:8080", txID, map[string]string{"key": "other"})
+	if ready || err == nil || !strings.Contains(err.Error(), "already in progress") {
+		t.Errorf("Expected a conflicting payload to be refused, got %v, %v", ready, err)
+	}
+	ready, err = n.PrepareFor("master-b:8080", txID, payload)
+	if ready || err == nil {
+		t.Errorf("Expected a prepare from another coordinator to be refused, got %v, %v", ready, err)
+	}
+
+	// Refused retries leave the original prepared
+	if err := n.CommitFor("master-a:8080", txID); err != nil {
+		t.Errorf("Expected the original transaction to commit, got %v", err)
+	}
+}
+
+func TestNodeFencesDecisionsToPreparingCoordinator(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	payload := map[string]string{"key": "value"}
+
+	if ready, err := n.PrepareFor("master-a:8080", "tx-fenced", payload); !ready || err != nil {
+		t.Fatalf("PrepareFor failed: ready=%v err=%v", ready, err)
+	}
+
+	if err := n.CommitFor("master-b:8080", "tx-fenced"); !errors.Is(err, ErrForeignCoordinator) {
+		t.Fatalf("Expected foreign commit to be rejected, got %v", err)
+	}
+	if err := n.AbortFor("", "tx-fenced"); !errors.Is(err, ErrForeignCoordinator) {
+		t.Fatalf("Expected anonymous abort to be rejected, got %v", err)
+	}
+	if !n.HasPendingTransaction("tx-fenced") {
+		t.Fatal("Rejected decisions must leave the transaction prepared")
+	}
+
+	if err := n.CommitFor("master-a:8080", "tx-fenced"); err != nil {
+		t.Fatalf("Expected owner commit to succeed, got %v", err)
+	}
+	if n.HasPendingTransaction("tx-fenced") {
+		t.Error("Expected transaction to be committed and removed")
+	}
+
+	// The owner may spell its address differently than when it prepared.
+	if ready, err := n.PrepareFor("127.0.0.1:8080", "tx-alias", payload); !ready || err != nil {
+		t.Fatalf("PrepareFor failed: ready=%v err=%v", ready, err)
+	}
+	if err := n.CommitFor("http://localhost:8080", "tx-alias"); err != nil {
+		t.Fatalf("Expected commit from an alias of the owner to succeed, got %v", err)
+	}
+
+	// A successor resolving the transaction after a failover takes it over,
+	// but only once the takeover guard accepts it.
+	if _, err := n.PrepareFor("master-a:8080", "tx-takeover", payload); err != nil {
+		t.Fatalf("PrepareFor failed: %v", err)
+	}
+	if err := n.AbortTakeover("master-b:8080", "tx-takeover"); !errors.Is(err, ErrTakeoverRefused) {
+		t.Fatalf("Expected takeover without a guard to be refused, got %v", err)
+	}
+	n.SetTakeoverGuard(func(coordinator, owner string) error {
+		if coordinator != "master-b:8080" || owner != "master-a:8080" {
+			return fmt.Errorf("%s is not the master", coordinator)
+		}
+		return nil
+	})
+	if err := n.CommitTakeover("stale-master:8080", "tx-takeover"); !errors.Is(err, ErrTakeoverRefused) {
+		t.Fatalf("Expected takeover by a stale master to be refused, got %v", err)
+	}
+	if !n.HasPendingTransaction("tx-takeover") {
+		t.Fatal("Refused takeovers must leave the transaction prepared")
+	}
+	if err := n.AbortTakeover("master-b:8080", "tx-takeover"); err != nil {
+		t.Fatalf("Expected takeover abort to succeed, got %v", err)
+	}
+	if n.HasPendingTransaction("tx-takeover") {
+		t.Error("Expected the taken over transaction to be aborted")
+	}
+
+	// Unfenced prepares accept a decision from any coordinator.
+	if _, err := n.Prepare("tx-open", payload); err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if err := n.AbortFor("master-b:8080", "tx-open"); err != nil {
+		t.Errorf("Expected unfenced abort to succeed, got %v", err)
+	}
+}
+
+func TestNodeRecordsPreparingCoordinator(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	if _, err := n.PrepareFor("master-a:8080", "tx-owned", map[string]string{"key": "value"}); err != nil {
+		t.Fatalf("PrepareFor failed: %v", err)
+	}
+
+	rec, err := n.GetTransaction(context.Background(), "tx-owned")
+	if err != nil || rec == nil {
+		t.Fatalf("GetTransaction failed: %+v, %v", rec, err)
+	}
+	if rec.CoordinatorAddr != "master-a:8080" {
+		t.Errorf("Expected coordinator master-a:8080, got %q", rec.CoordinatorAddr)
+	}
+
+	db, queries := newRecordingDB(t)
+	n = NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	if _, err := n.PrepareFor("master-a:8080", "tx-owned", SQLAction{Table: "users", Values: map[string]any{"name": "Alice"}}); err != nil {
+		t.Fatalf("PrepareFor failed: %v", err)
+	}
+
+	var migrated, inserted bool
+	for _, q := range queries.Queries() {
+		migrated = migrated || strings.Contains(q, "ADD COLUMN IF NOT EXISTS coordinator_addr")
+		inserted = inserted || strings.HasPrefix(q, "INSERT INTO distributed_tx") && strings.Contains(q, "coordinator_addr")
+	}
+	if !migrated || !inserted {
+		t.Errorf("Expected the coordinator_addr migration and insert, got %v", queries.Queries())
+	}
+}
+
+// TestCoordinatorAddrRoundTrips runs against a real Postgres. Set
+// TWOPC_TEST_POSTGRES_DSN to enable it.
+func TestCoordinatorAddrRoundTrips(t *testing.T) {
+	dsn := os.Getenv("TWOPC_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TWOPC_TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS coordinator_addr_test (name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `DELETE FROM distributed_tx WHERE tx_id IN ('tx-coord-owned', 'tx-coord-open')`)
+		_, _ = db.ExecContext(ctx, `DROP TABLE IF EXISTS coordinator_addr_test`)
+	})
+
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	action := SQLAction{Table: "coordinator_addr_test", Values: map[string]any{"name": "Alice"}}
+	for _, tc := range []struct{ txID, coordinator string }{
+		{"tx-coord-owned", "master-a:8080"},
+		{"tx-coord-open", ""},
+	} {
+		if _, err := n.PrepareFor(tc.coordinator, tc.txID, action); err != nil {
+			t.Fatalf("PrepareFor(%s) failed: %v", tc.txID, err)
+		}
+		if err := n.CommitFor(tc.coordinator, tc.txID); err != nil {
+			t.Fatalf("CommitFor(%s) failed: %v", tc.txID, err)
+		}
+
+		rec, err := n.GetTransaction(ctx, tc.txID)
+		if err != nil || rec == nil {
+			t.Fatalf("GetTransaction(%s) failed: %+v, %v", tc.txID, rec, err)
+		}
+		if rec.CoordinatorAddr != tc.coordinator {
+			t.Errorf("%s: coordinator_addr = %q, want %q", tc.txID, rec.CoordinatorAddr, tc.coordinator)
+		}
+	}
+
+	records, _, err := n.ListTransactions(ctx, 1, 100, "")
+	if err != nil {
+		t.Fatalf("ListTransactions failed: %v", err)
+	}
+	for _, rec := range records {
+		if rec.TxID == "tx-coord-owned" && rec.CoordinatorAddr != "master-a:8080" {
+			t.Errorf("Expected the listing to carry the coordinator, got %+v", rec)
+		}
 	}
 }
 
@@ -151,3 +332,917 @@ func TestNodeGetPendingTransactions(t *testing.T) {
 		t.Errorf("Expected 2 pending transactions after commit, got %d", len(pending))
 	}
 }
+
+func TestNodeUptimeResetsOnAliveFlip(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+
+	firstAlive := n.LastBecameAlive
+	if firstAlive.IsZero() {
+		t.Fatal("Expected new node to record when it became alive")
+	}
+
+	// Staying alive must not reset the uptime start.
+	n.SetAlive(true)
+	if !n.LastBecameAlive.Equal(firstAlive) {
+		t.Error("Expected uptime start to be unchanged while alive")
+	}
+
+	n.SetAlive(false)
+	if n.Uptime() != 0 {
+		t.Errorf("Expected zero uptime for dead node, got %v", n.Uptime())
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	n.SetAlive(true)
+	if !n.LastBecameAlive.After(firstAlive) {
+		t.Error("Expected uptime start to reset after dead -> alive transition")
+	}
+	if n.Uptime() <= 0 {
+		t.Error("Expected positive uptime once alive again")
+	}
+}
+
+func TestCheckRowsAffected(t *testing.T) {
+	tests := []struct {
+		expect   string
+		affected int64
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{">=1", 0, true},
+		{">=1", 3, false},
+		{"1", 1, false},
+		{"=1", 2, true},
+		{"0", 0, false},
+	}
+
+	for _, tt := range tests {
+		err := checkRowsAffected(tt.expect, tt.affected)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkRowsAffected(%q, %d) error = %v, wantErr %v", tt.expect, tt.affected, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSQLActionRejectsBadRowsExpectation(t *testing.T) {
+	_, err := parseSQLAction(map[string]any{
+		"table":                "users",
+		"operation":            "update",
+		"values":               map[string]any{"name": "Alice"},
+		"where":                map[string]any{"id": 1},
+		"expect_rows_affected": "at least one",
+	})
+	if err == nil {
+		t.Fatal("Expected invalid expect_rows_affected to be rejected")
+	}
+
+	action, err := parseSQLAction(map[string]any{
+		"table":                "users",
+		"operation":            "update",
+		"values":               map[string]any{"name": "Alice"},
+		"where":                map[string]any{"id": 1},
+		"expect_rows_affected": ">=1",
+	})
+	if err != nil {
+		t.Fatalf("Expected valid expectation to parse, got %v", err)
+	}
+	if action.ExpectRowsAffected != ">=1" {
+		t.Errorf("Expected expectation to be preserved, got %q", action.ExpectRowsAffected)
+	}
+}
+
+func TestParseSQLActionValidatesReadCheck(t *testing.T) {
+	bad := []map[string]any{
+		{"table": "users", "operation": "read_check"},
+		{"table": "users", "operation": "read_check", "where": map[string]any{"id": 1}, "values": map[string]any{"name": "Alice"}},
+		{"table": "users", "operation": "read_check", "where": map[string]any{"id": 1}, "expect": "some"},
+		{"table": "users", "operation": "read_check", "where": map[string]any{"id": 1}, "expect_rows_affected": "1"},
+		{"table": "users", "values": map[string]any{"name": "Alice"}, "expect": "exists"},
+	}
+	for _, payload := range bad {
+		if _, err := parseSQLAction(payload); err == nil {
+			t.Errorf("Expected %v to be rejected", payload)
+		}
+	}
+
+	for _, expect := range []string{"", "exists", "EXISTS", "0", ">=2"} {
+		action, err := parseSQLAction(map[string]any{
+			"table":     "users",
+			"operation": "read_check",
+			"where":     map[string]any{"id": 1, "version": 3},
+			"expect":    expect,
+		})
+		if err != nil {
+			t.Errorf("Expected expect %q to parse, got %v", expect, err)
+		} else if action.Operation != "READ_CHECK" {
+			t.Errorf("Expected READ_CHECK, got %q", action.Operation)
+		}
+	}
+}
+
+func TestNodeReadCheckVotesOnPrecondition(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	matching := int64(1)
+	rec.answer = func(query string) [][]driver.Value {
+		if strings.HasPrefix(query, "SELECT count(*)") {
+			return [][]driver.Value{{matching}}
+		}
+		return nil
+	}
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+
+	check := SQLAction{
+		Table:     "accounts",
+		Operation: "READ_CHECK",
+		Where:     map[string]any{"id": 7, "version": 3},
+	}
+
+	ready, err := n.Prepare("tx-check-ok", check)
+	if err != nil || !ready {
+		t.Fatalf("Expected precondition to hold, ready=%v err=%v", ready, err)
+	}
+	if !n.HasPendingTransaction("tx-check-ok") {
+		t.Error("Expected the read check to be pending until the decision")
+	}
+	if err := n.Commit("tx-check-ok"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	matching = 0
+	ready, err = n.Prepare("tx-check-missing", check)
+	if ready || err == nil || !strings.Contains(err.Error(), "read check failed") {
+		t.Fatalf("Expected ABORT vote when the row is missing, ready=%v err=%v", ready, err)
+	}
+
+	check.Expect = "0"
+	if ready, err := n.Prepare("tx-check-absent", check); err != nil || !ready {
+		t.Fatalf("Expected expect=0 to hold, ready=%v err=%v", ready, err)
+	}
+	if err := n.Abort("tx-check-absent"); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+
+	// The count runs inside the prepare transaction and share-locks the
+	// matching rows, so they cannot change before the decision.
+	want := `SELECT count(*) FROM (SELECT 1 FROM "accounts" WHERE "id"=$1 AND "version"=$2 FOR SHARE) AS matched`
+	inTx, checks := false, 0
+	for _, q := range rec.Queries() {
+		switch {
+		case q == "BEGIN":
+			inTx = true
+		case q == "COMMIT" || q == "ROLLBACK":
+			inTx = false
+		case q == want:
+			checks++
+			if !inTx {
+				t.Errorf("Read check ran outside the prepare transaction")
+			}
+		case strings.Contains(q, `"accounts"`):
+			t.Errorf("Expected the read check to write nothing, got %q", q)
+		}
+	}
+	if checks != 3 {
+		t.Errorf("Expected 3 read-check queries, got %d in %q", checks, rec.Queries())
+	}
+}
+
+func TestNodeWithoutDatabaseRefusesReadCheck(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	check := SQLAction{Table: "accounts", Operation: "READ_CHECK", Where: map[string]any{"id": 7}}
+
+	if ready, err := n.Prepare("tx-check", check); ready || !errors.Is(err, ErrReadCheckNoDatabase) {
+		t.Fatalf("Prepare = %v, %v; want refusal with ErrReadCheckNoDatabase", ready, err)
+	}
+	if n.HasPendingTransaction("tx-check") {
+		t.Error("A refused read check must not be pending")
+	}
+}
+
+func TestParseSQLActionDiagnosesPayloadShape(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload any
+		want    string
+	}{
+		{"bare string", "hello", "not a SQL action object: invalid JSON"},
+		{"JSON string", `"users"`, "not a SQL action object: got a string"},
+		{"number", 42, "not a SQL action object: got a number"},
+		{"array", []any{map[string]any{"table": "users"}}, "not a SQL action object: got an array"},
+		{"bytes null", []byte("null"), "not a SQL action object: got null"},
+		{"empty string", "  ", "payload is required"},
+		{"typo'd key", map[string]any{"tabel": "users", "values": map[string]any{"x": 1}}, `unknown field "tabel"`},
+		{"unrelated object", map[string]any{"foo": "bar"}, `unknown field "foo"`},
+		{"wrong field type", `{"table": 1, "values": {"x": 1}}`, "invalid SQL action"},
+		{"missing table", map[string]any{"values": map[string]any{"x": 1}}, "table is required"},
+		{"missing values", `{"table": "users"}`, "values are required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSQLAction(tt.payload)
+			if err == nil {
+				t.Fatal("Expected payload to be rejected")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTxStatus(t *testing.T) {
+	for in, want := range map[string]string{
+		"":           "",
+		"COMMITTED":  "COMMITTED",
+		"committed":  "COMMITTED",
+		" Prepared ": "PREPARED",
+		"aborted":    "ABORTED",
+		"observed":   "OBSERVED",
+	} {
+		got, err := NormalizeTxStatus(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeTxStatus(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	for _, bogus := range []string{"dropped", "COMMITTED' OR '1'='1", "commit"} {
+		if _, err := NormalizeTxStatus(bogus); !errors.Is(err, ErrInvalidStatus) {
+			t.Errorf("NormalizeTxStatus(%q) error = %v, want ErrInvalidStatus", bogus, err)
+		}
+	}
+
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	if _, _, err := n.ListTransactions(context.Background(), 1, 20, "dropped"); !errors.Is(err, ErrInvalidStatus) {
+		t.Errorf("ListTransactions with bogus status error = %v, want ErrInvalidStatus", err)
+	}
+}
+
+func TestNodePreparePolicyVetoesTables(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+
+	errMaintenance := errors.New("table billing is in a maintenance window")
+	n.SetPreparePolicy(func(txID string, action *SQLAction) error {
+		if action.Table == "billing" {
+			return errMaintenance
+		}
+		return nil
+	})
+
+	ready, err := n.Prepare("tx-veto", SQLAction{
+		Table:  "billing",
+		Values: map[string]any{"amount": 10},
+	})
+	if ready {
+		t.Error("Expected prepare to be vetoed")
+	}
+	if !errors.Is(err, errMaintenance) {
+		t.Errorf("Expected policy reason in error, got %v", err)
+	}
+	if n.HasPendingTransaction("tx-veto") {
+		t.Error("Vetoed transaction must not be pending")
+	}
+
+	ready, err = n.Prepare("tx-allowed", SQLAction{
+		Table:  "users",
+		Values: map[string]any{"name": "Alice"},
+	})
+	if err != nil || !ready {
+		t.Fatalf("Expected prepare on users to succeed, got ready=%v err=%v", ready, err)
+	}
+	if !n.HasPendingTransaction("tx-allowed") {
+		t.Error("Expected allowed transaction to be pending")
+	}
+}
+
+func TestNodeAbortMetricsDistinguishReason(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	n.SetPreparePolicy(func(txID string, action *SQLAction) error {
+		if action.Table == "locked" {
+			return errors.New("table locked")
+		}
+		return nil
+	})
+
+	// This node votes ABORT itself.
+	if ready, _ := n.Prepare("tx-self", SQLAction{Table: "locked", Values: map[string]any{"x": 1}}); ready {
+		t.Fatal("Expected prepare to be vetoed")
+	}
+	// The coordinator still aborts it; that must not count as a coordinator abort.
+	if err := n.Abort("tx-self"); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+
+	// This node prepares, then the coordinator aborts because another node failed.
+	if ready, err := n.Prepare("tx-coord", SQLAction{Table: "users", Values: map[string]any{"x": 1}}); !ready || err != nil {
+		t.Fatalf("Expected prepare to succeed, got ready=%v err=%v", ready, err)
+	}
+	if err := n.Abort("tx-coord"); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+
+	m := n.Metrics()
+	if m.AbortedSelf != 1 {
+		t.Errorf("AbortedSelf = %d, want 1", m.AbortedSelf)
+	}
+	if m.AbortedByCoordinator != 1 {
+		t.Errorf("AbortedByCoordinator = %d, want 1", m.AbortedByCoordinator)
+	}
+}
+
+func TestNodeRecordsPreparedDuration(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+
+	if ready, err := n.Prepare("tx-slow", map[string]any{"x": 1}); !ready || err != nil {
+		t.Fatalf("Expected prepare to succeed, got ready=%v err=%v", ready, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := n.Commit("tx-slow"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// A commit for a transaction this node never prepared is not a sample.
+	if err := n.Commit("tx-unknown"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	m := n.Metrics()
+	h := m.PreparedDuration
+	if h == nil || h.Count != 1 {
+		t.Fatalf("Expected one prepared-duration sample, got %+v", h)
+	}
+	if h.MaxMs < 20 {
+		t.Errorf("MaxMs = %d, want at least the 20ms delay", h.MaxMs)
+	}
+	if m.PreparedDurationP95Ms < 20 || m.PreparedDurationP95Ms > h.MaxMs {
+		t.Errorf("PreparedDurationP95Ms = %d, want between 20 and %d", m.PreparedDurationP95Ms, h.MaxMs)
+	}
+	for _, b := range h.Buckets {
+		if b.LeMs < 20 && b.Count != 0 {
+			t.Errorf("Bucket le=%d count = %d, want 0", b.LeMs, b.Count)
+		}
+	}
+	if last := h.Buckets[len(h.Buckets)-1]; last.Count != 1 {
+		t.Errorf("Last cumulative bucket = %d, want 1", last.Count)
+	}
+}
+
+func TestNodeMaintenanceRefusesPrepareAndDrains(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+
+	if ready, err := n.Prepare("tx-held", map[string]any{"x": 1}); !ready || err != nil {
+		t.Fatalf("Expected prepare to succeed, got ready=%v err=%v", ready, err)
+	}
+
+	done := make(chan *protocol.MaintenanceResponse)
+	go func() { done <- n.ApplyMaintenance(true, 2*time.Second) }()
+
+	// Wait for maintenance to take effect, then check new work is refused.
+	for !n.GetMaintenance() {
+		time.Sleep(time.Millisecond)
+	}
+	if ready, err := n.Prepare("tx-new", map[string]any{"x": 2}); ready || !errors.Is(err, ErrMaintenance) {
+		t.Errorf("Expected ErrMaintenance, got ready=%v err=%v", ready, err)
+	}
+
+	select {
+	case resp := <-done:
+		t.Fatalf("Expected drain to wait for the prepared transaction, got %+v", resp)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := n.Commit("tx-held"); err != nil {
+		t.Fatalf("Commit during maintenance failed: %v", err)
+	}
+	if resp := <-done; !resp.Success || !resp.Drained || resp.InFlight != 0 {
+		t.Errorf("Expected a complete drain, got %+v", resp)
+	}
+}
+
+func TestNodeMaintenanceDrainTimeout(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	if ready, err := n.Prepare("tx-stuck", map[string]any{"x": 1}); !ready || err != nil {
+		t.Fatalf("Expected prepare to succeed, got ready=%v err=%v", ready, err)
+	}
+
+	resp := n.ApplyMaintenance(true, 60*time.Millisecond)
+	if resp.Drained || resp.InFlight != 1 || !n.GetMaintenance() {
+		t.Errorf("Expected an undrained maintenance with 1 in flight, got %+v", resp)
+	}
+
+	if resp := n.ApplyMaintenance(false, 0); resp.Enabled || n.GetMaintenance() || !n.Eligible() {
+		t.Errorf("Expected node to leave maintenance, got %+v", resp)
+	}
+
+	n.SetDraining(true)
+	if n.Eligible() {
+		t.Error("Expected a draining node to be ineligible")
+	}
+}
+
+func TestPendingCountReadsWithoutNodeLock(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	for _, txID := range []string{"tx-1", "tx-2"} {
+		if ready, err := n.Prepare(txID, map[string]any{"x": 1}); !ready || err != nil {
+			t.Fatalf("Prepare(%s) failed: ready=%v err=%v", txID, ready, err)
+		}
+	}
+	if err := n.Commit("tx-1"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if got := n.PendingCount(); got != 1 {
+		t.Fatalf("PendingCount = %d, want 1", got)
+	}
+
+	// A prepare holds the node's lock across database I/O; the count must
+	// stay readable meanwhile.
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	done := make(chan int, 1)
+	go func() { done <- n.PendingCount() }()
+	select {
+	case got := <-done:
+		if got != 1 {
+			t.Errorf("PendingCount = %d, want 1", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PendingCount blocked on the node lock")
+	}
+}
+
+func TestNodeMaxPreparedRejectsAtCapacity(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	n.SetMaxPrepared(2)
+
+	for _, txID := range []string{"tx-1", "tx-2"} {
+		if ready, err := n.Prepare(txID, map[string]any{"x": 1}); !ready || err != nil {
+			t.Fatalf("Prepare(%s) failed: ready=%v err=%v", txID, ready, err)
+		}
+	}
+
+	ready, err := n.Prepare("tx-3", map[string]any{"x": 1})
+	if ready || !errors.Is(err, ErrAtCapacity) {
+		t.Fatalf("Expected ErrAtCapacity beyond the cap, got ready=%v err=%v", ready, err)
+	}
+	if n.HasPendingTransaction("tx-3") {
+		t.Error("Expected the refused transaction not to be pending")
+	}
+
+	m := n.Metrics()
+	if m.InFlight != 2 || m.MaxPrepared != 2 || m.RejectedAtCapacity != 1 || m.AbortedSelf != 1 {
+		t.Errorf("Unexpected metrics at capacity: %+v", m)
+	}
+
+	// Finishing a transaction frees a slot.
+	if err := n.Commit("tx-1"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if ready, err := n.Prepare("tx-3", map[string]any{"x": 1}); !ready || err != nil {
+		t.Errorf("Expected a prepare to succeed once a slot is free, got ready=%v err=%v", ready, err)
+	}
+}
+
+func TestNodeResourceThresholdsRejectSaturatedPool(t *testing.T) {
+	db, _ := newRecordingDB(t)
+	db.SetMaxOpenConns(1)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	n.SetResourceThresholds(ResourceThresholds{MaxPoolInUse: 1})
+
+	action := SQLAction{Table: "users", Values: map[string]any{"name": "Alice"}}
+	if ready, err := n.Prepare("tx-held", action); !ready || err != nil {
+		t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+	}
+
+	// tx-held keeps the only connection, so the pool is saturated. Without the
+	// check this prepare would queue for the connection instead.
+	ready, err := n.Prepare("tx-pressed", action)
+	if ready || !errors.Is(err, ErrResourcePressure) {
+		t.Fatalf("Expected ErrResourcePressure on a saturated pool, got ready=%v err=%v", ready, err)
+	}
+	if m := n.Metrics(); m.RejectedForPressure != 1 || m.AbortedSelf != 1 {
+		t.Errorf("Unexpected metrics under pressure: %+v", m)
+	}
+
+	if err := n.Commit("tx-held"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if ready, err := n.Prepare("tx-pressed", action); !ready || err != nil {
+		t.Errorf("Expected a prepare to succeed once the connection is free, got ready=%v err=%v", ready, err)
+	}
+
+	for _, bad := range []ResourceThresholds{{MaxPoolInUse: 1.5}, {MaxPoolInUse: -0.1}, {MaxInUseConns: -1}} {
+		if bad.Validate() == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestNodeDurablePrepareSetsSynchronousCommit(t *testing.T) {
+	for _, durable := range []bool{true, false} {
+		db, rec := newRecordingDB(t)
+		n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+		n.SetDurablePrepare(durable)
+
+		ready, err := n.Prepare("tx-durable", SQLAction{Table: "users", Values: map[string]any{"name": "Alice"}})
+		if err != nil || !ready {
+			t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+		}
+
+		issued := false
+		for i, q := range rec.Queries() {
+			if q == "SET LOCAL synchronous_commit = on" {
+				issued = true
+				if i == 0 || rec.Queries()[i-1] != "BEGIN" {
+					t.Errorf("Expected SET LOCAL right after BEGIN, got %v", rec.Queries())
+				}
+			}
+		}
+		if issued != durable {
+			t.Errorf("durable=%v: SET LOCAL issued=%v, queries: %v", durable, issued, rec.Queries())
+		}
+
+		if err := n.Commit("tx-durable"); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+}
+
+func TestListenAddrDefaultsToAddr(t *testing.T) {
+	n := NewNode("10.0.0.5:8081", protocol.RoleSlave)
+	if got := n.ListenAddr(); got != "10.0.0.5:8081" {
+		t.Errorf("ListenAddr = %q, want Addr", got)
+	}
+
+	n.BindAddr = "0.0.0.0:8081"
+	if got := n.ListenAddr(); got != "0.0.0.0:8081" {
+		t.Errorf("ListenAddr = %q, want BindAddr", got)
+	}
+}
+
+func TestNodeUpsertBuildsOnConflictUpdate(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+
+	action, err := parseSQLAction(map[string]any{
+		"table":     "users",
+		"operation": "upsert",
+		"values":    map[string]any{"id": 1, "name": "Alice", "email": "a@example.com"},
+		"conflict":  []any{"id"},
+	})
+	if err != nil {
+		t.Fatalf("parseSQLAction failed: %v", err)
+	}
+
+	// The first upsert inserts, the second hits the conflict and updates.
+	for _, txID := range []string{"tx-upsert-1", "tx-upsert-2"} {
+		if ready, err := n.Prepare(txID, *action); err != nil || !ready {
+			t.Fatalf("Prepare(%s) failed: ready=%v err=%v", txID, ready, err)
+		}
+		if err := n.Commit(txID); err != nil {
+			t.Fatalf("Commit(%s) failed: %v", txID, err)
+		}
+	}
+
+	want := `INSERT INTO "users" ("email","id","name") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "email"=EXCLUDED."email","name"=EXCLUDED."name"`
+	count := 0
+	for _, q := range rec.Queries() {
+		if q == want {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected upsert statement twice, got queries: %v", rec.Queries())
+	}
+
+	stmt, _, err := upsertStatement("users", &SQLAction{
+		Values:        map[string]any{"id": 1, "name": "Alice", "email": "a@example.com"},
+		Conflict:      []string{"id"},
+		UpdateColumns: []string{"name"},
+	})
+	if err != nil {
+		t.Fatalf("upsertStatement failed: %v", err)
+	}
+	if want := `ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`; !strings.HasSuffix(stmt, want) {
+		t.Errorf("Expected explicit update columns only, got %s", stmt)
+	}
+
+	stmt, _, err = upsertStatement("users", &SQLAction{
+		Values:   map[string]any{"id": 1},
+		Conflict: []string{"id"},
+	})
+	if err != nil {
+		t.Fatalf("upsertStatement failed: %v", err)
+	}
+	if !strings.HasSuffix(stmt, `ON CONFLICT ("id") DO NOTHING`) {
+		t.Errorf("Expected DO NOTHING when only key columns are given, got %s", stmt)
+	}
+}
+
+func TestParseSQLActionValidatesUpsertColumns(t *testing.T) {
+	values := map[string]any{"id": 1, "name": "Alice"}
+	tests := []struct {
+		name    string
+		payload map[string]any
+	}{
+		{"missing conflict", map[string]any{"table": "users", "operation": "upsert", "values": values}},
+		{"bad identifier", map[string]any{"table": "users", "operation": "upsert", "values": values, "conflict": []any{"id;drop"}}},
+		{"conflict not in values", map[string]any{"table": "users", "operation": "upsert", "values": values, "conflict": []any{"email"}}},
+		{"update not in values", map[string]any{"table": "users", "operation": "upsert", "values": values, "conflict": []any{"id"}, "update_columns": []any{"email"}}},
+		{"update of key column", map[string]any{"table": "users", "operation": "upsert", "values": values, "conflict": []any{"id"}, "update_columns": []any{"id"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseSQLAction(tt.payload); err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+}
+
+func TestApplySQLActionInsert(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	ex := &fakeExecer{}
+
+	affected, err := n.applySQLAction(context.Background(), ex, &SQLAction{
+		Table:     "Users",
+		Operation: "INSERT",
+		Values:    map[string]any{"name": "Alice", "id": 1, "email": "a@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("applySQLAction failed: %v", err)
+	}
+	if affected != 1 {
+		t.Errorf("affected = %d, want 1", affected)
+	}
+
+	// Columns are sorted and placeholders numbered in the same order.
+	if want := `INSERT INTO "users" ("email","id","name") VALUES ($1,$2,$3)`; ex.query != want {
+		t.Errorf("query = %s, want %s", ex.query, want)
+	}
+	if want := []any{"a@example.com", 1, "Alice"}; !reflect.DeepEqual(ex.args, want) {
+		t.Errorf("args = %v, want %v", ex.args, want)
+	}
+}
+
+func TestApplySQLActionUpdate(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	ex := &fakeExecer{}
+
+	_, err := n.applySQLAction(context.Background(), ex, &SQLAction{
+		Table:     "users",
+		Operation: "UPDATE",
+		Values:    map[string]any{"name": "Bob", "email": "b@example.com"},
+		Where:     map[string]any{"tenant": "t1", "id": 7},
+	})
+	if err != nil {
+		t.Fatalf("applySQLAction failed: %v", err)
+	}
+
+	// SET placeholders come first, WHERE placeholders continue the numbering.
+	if want := `UPDATE "users" SET "email"=$1,"name"=$2 WHERE "id"=$3 AND "tenant"=$4`; ex.query != want {
+		t.Errorf("query = %s, want %s", ex.query, want)
+	}
+	if want := []any{"b@example.com", "Bob", 7, "t1"}; !reflect.DeepEqual(ex.args, want) {
+		t.Errorf("args = %v, want %v", ex.args, want)
+	}
+
+	ex = &fakeExecer{}
+	if _, err := n.applySQLAction(context.Background(), ex, &SQLAction{
+		Table:     "users",
+		Operation: "UPDATE",
+		Values:    map[string]any{"bad col": 1},
+		Where:     map[string]any{"id": 1},
+	}); err == nil {
+		t.Error("Expected unsafe column identifier to be rejected")
+	}
+	if ex.query != "" {
+		t.Errorf("Expected no statement for a rejected action, got %s", ex.query)
+	}
+
+	ex = &fakeExecer{err: errors.New("connection reset")}
+	if _, err := n.applySQLAction(context.Background(), ex, &SQLAction{
+		Table:     "users",
+		Operation: "UPDATE",
+		Values:    map[string]any{"name": "Bob"},
+		Where:     map[string]any{"id": 1},
+	}); err == nil || err.Error() != "connection reset" {
+		t.Errorf("Expected exec error to propagate, got %v", err)
+	}
+}
+
+func TestCommitForChangesEchoesAppliedAction(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	rec.answer = func(query string) [][]driver.Value {
+		if strings.Contains(query, "pg_index") {
+			return [][]driver.Value{{"id"}}
+		}
+		return nil
+	}
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+
+	tests := []struct {
+		name    string
+		payload map[string]any
+		want    protocol.AppliedChange
+	}{
+		{
+			name:    "insert keys from the primary key",
+			payload: map[string]any{"table": "users", "operation": "insert", "values": map[string]any{"id": 1, "name": "Alice"}},
+			want: protocol.AppliedChange{
+				Table: "users", Operation: "INSERT",
+				Values:       map[string]any{"id": 1, "name": "Alice"},
+				Keys:         map[string]any{"id": 1},
+				RowsAffected: 1,
+			},
+		},
+		{
+			name:    "update keys from where",
+			payload: map[string]any{"table": "users", "operation": "update", "values": map[string]any{"name": "Bob"}, "where": map[string]any{"id": 2}},
+			want: protocol.AppliedChange{
+				Table: "users", Operation: "UPDATE",
+				Values:       map[string]any{"name": "Bob"},
+				Where:        map[string]any{"id": 2},
+				Keys:         map[string]any{"id": 2},
+				RowsAffected: 1,
+			},
+		},
+		{
+			name:    "upsert keys from the conflict columns",
+			payload: map[string]any{"table": "users", "operation": "upsert", "values": map[string]any{"email": "c@example.com", "name": "Carol"}, "conflict": []any{"email"}},
+			want: protocol.AppliedChange{
+				Table: "users", Operation: "UPSERT",
+				Values:       map[string]any{"email": "c@example.com", "name": "Carol"},
+				Keys:         map[string]any{"email": "c@example.com"},
+				RowsAffected: 1,
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txID := "tx-cdc-" + string(rune('a'+i))
+			if ready, err := n.Prepare(txID, tt.payload); err != nil || !ready {
+				t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+			}
+
+			changes, err := n.CommitForChanges("", txID)
+			if err != nil {
+				t.Fatalf("CommitForChanges failed: %v", err)
+			}
+			// Payload numbers are decoded as json.Number, so compare the wire form.
+			got, _ := json.Marshal(changes)
+			want, _ := json.Marshal([]protocol.AppliedChange{tt.want})
+			if string(got) != string(want) {
+				t.Errorf("changes = %s, want %s", got, want)
+			}
+		})
+	}
+
+	// Committing again is idempotent and has nothing left to echo.
+	if changes, err := n.CommitForChanges("", "tx-cdc-a"); err != nil || len(changes) != 0 {
+		t.Errorf("Expected a repeated commit to echo nothing, got %+v, %v", changes, err)
+	}
+}
+
+func TestNodePublishesTransactionEvents(t *testing.T) {
+	n := NewNode("localhost:8081", protocol.RoleSlave)
+	events, cancel := n.SubscribeTransactions()
+	defer cancel()
+
+	payload := map[string]any{"table": "users", "values": map[string]any{"name": "Alice"}}
+	n.PrepareFor("master-a:8080", "tx-1", payload)
+	n.CommitFor("master-a:8080", "tx-1")
+	n.Prepare("tx-2", payload)
+	n.Prepare("tx-2", map[string]any{"table": "users"}) // refused: already in progress with another payload
+	n.Abort("tx-2")
+	n.Commit("tx-unknown") // nothing was prepared, so nothing changes state
+
+	want := []struct{ txID, status, coordinator string }{
+		{"tx-1", "PREPARED", "master-a:8080"},
+		{"tx-1", "COMMITTED", "master-a:8080"},
+		{"tx-2", "PREPARED", ""},
+		{"tx-2", "ABORTED", ""},
+		{"tx-2", "ABORTED", ""},
+	}
+	for i, w := range want {
+		select {
+		case ev := <-events:
+			if ev.TxID != w.txID || ev.Status != w.status || ev.Coordinator != w.coordinator || ev.Node != n.Addr {
+				t.Errorf("event %d = %+v, want %s %s by %q", i, ev, w.txID, w.status, w.coordinator)
+			}
+			if i == 3 && !strings.Contains(ev.Error, "already in progress") {
+				t.Errorf("Expected the refused prepare to carry its reason, got %q", ev.Error)
+			}
+		default:
+			t.Fatalf("Missing event %d (%s %s)", i, w.txID, w.status)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("Unexpected event %+v", ev)
+	default:
+	}
+
+	cancel()
+	n.Prepare("tx-3", payload)
+	if len(events) != 0 {
+		t.Error("Expected no events after the subscription ended")
+	}
+}
+
+func TestNodeAllowedTablesRejectsOtherTables(t *testing.T) {
+	db, drv := newRecordingDB(t)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	if err := n.SetAllowedTables([]string{"Users", " orders"}); err != nil {
+		t.Fatalf("SetAllowedTables failed: %v", err)
+	}
+
+	for _, table := range []string{"users", "USERS", "orders"} {
+		action := SQLAction{Table: table, Values: map[string]any{"name": "Alice"}}
+		if ready, err := n.Prepare("tx-"+table, action); !ready || err != nil {
+			t.Errorf("Prepare on permitted table %q failed: ready=%v err=%v", table, ready, err)
+		}
+	}
+
+	before := len(drv.Queries())
+	for _, action := range []SQLAction{
+		{Table: "payments", Values: map[string]any{"amount": 10}},
+		{Operation: "READ_CHECK", Table: "secrets", Where: map[string]any{"id": 1}},
+	} {
+		ready, err := n.Prepare("tx-"+action.Table, action)
+		if ready || !errors.Is(err, ErrTableNotPermitted) {
+			t.Errorf("Expected ErrTableNotPermitted for %q, got ready=%v err=%v", action.Table, ready, err)
+		}
+	}
+	if got := drv.Queries()[before:]; len(got) != 0 {
+		t.Errorf("Expected denied tables to reach no SQL, got %v", got)
+	}
+
+	if err := n.SetAllowedTables([]string{"bad;table"}); err == nil {
+		t.Error("Expected an invalid table name to be refused")
+	}
+	if err := n.SetAllowedTables(nil); err != nil {
+		t.Fatalf("SetAllowedTables(nil) failed: %v", err)
+	}
+	if ready, err := n.Prepare("tx-any", SQLAction{Table: "payments", Values: map[string]any{"amount": 10}}); !ready || err != nil {
+		t.Errorf("Expected an empty allowlist to permit any table, got ready=%v err=%v", ready, err)
+	}
+}
+
+func TestNodeInsertReturningHoldsGeneratedKey(t *testing.T) {
+	db, drv := newRecordingDB(t)
+	drv.answer = func(query string) [][]driver.Value {
+		if strings.Contains(query, "RETURNING") {
+			return [][]driver.Value{{int64(42)}}
+		}
+		return nil
+	}
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+
+	action := map[string]any{"table": "users", "values": map[string]any{"name": "Alice"}, "returning": []any{"ID"}}
+	if ready, err := n.Prepare("tx-returning", action); !ready || err != nil {
+		t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+	}
+
+	want := `INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`
+	if !slices.Contains(drv.Queries(), want) {
+		t.Errorf("Expected %s, got queries: %v", want, drv.Queries())
+	}
+	if got := n.Returned("tx-returning"); !reflect.DeepEqual(got, []map[string]any{{"id": int64(42)}}) {
+		t.Errorf("Returned = %v, want the generated id", got)
+	}
+	if rows := n.RowsAffected("tx-returning"); rows != 1 {
+		t.Errorf("RowsAffected = %d, want 1", rows)
+	}
+
+	if err := n.Commit("tx-returning"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if got := n.Returned("tx-returning"); got != nil {
+		t.Errorf("Expected the returned values to be released on commit, got %v", got)
+	}
+
+	for _, payload := range []map[string]any{
+		{"table": "users", "operation": "update", "values": map[string]any{"name": "Bob"}, "where": map[string]any{"id": 1}, "returning": []any{"id"}},
+		{"table": "users", "values": map[string]any{"name": "Bob"}, "returning": []any{"id;drop"}},
+	} {
+		if _, err := parseSQLAction(payload); err == nil {
+			t.Errorf("Expected %v to be rejected", payload)
+		}
+	}
+}
+
+func TestCoordinateOnlyNodeRefusesPrepare(t *testing.T) {
+	n := NewNode("master:8080", protocol.RoleMaster)
+	n.SetCoordinateOnly(true)
+
+	if ready, err := n.PrepareFor("other:8080", "tx-1", map[string]string{"key": "value"}); ready || !errors.Is(err, ErrCoordinateOnly) {
+		t.Fatalf("PrepareFor = %v, %v; want refusal with ErrCoordinateOnly", ready, err)
+	}
+	if n.HasPendingTransaction("tx-1") {
+		t.Error("A refused prepare must not leave the transaction pending")
+	}
+
+	n.SetCoordinateOnly(false)
+	if ready, err := n.PrepareFor("other:8080", "tx-1", map[string]string{"key": "value"}); !ready || err != nil {
+		t.Fatalf("PrepareFor after clearing coordinate-only = %v, %v; want READY", ready, err)
+	}
+}
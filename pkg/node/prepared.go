@@ -0,0 +1,375 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// preparedGIDPrefix namespaces the Postgres global transaction identifiers this
+// engine creates, so recovery never touches prepared transactions owned by
+// another application on the same server.
+const preparedGIDPrefix = "2pc-engine:"
+
+// DefaultStalePreparedAfter is how long a prepared transaction may sit in
+// Postgres before it is reported stale. It is well past any coordinator
+// timeout, so a stale gid has lost its coordinator.
+const DefaultStalePreparedAfter = time.Minute
+
+// SetPreparedTransactions makes Prepare end with PREPARE TRANSACTION instead of
+// holding an open sql.Tx, and Commit/Abort issue COMMIT PREPARED/ROLLBACK
+// PREPARED. The prepared state then lives in Postgres and survives a crash of
+// this process; RecoverPrepared picks it up again on restart. The server needs
+// max_prepared_transactions > 0.
+func (n *Node) SetPreparedTransactions(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.preparedTxns = enabled
+}
+
+// SetStalePreparedAfter sets the age at which a prepared transaction counts as
+// stale. A non-positive value restores DefaultStalePreparedAfter.
+func (n *Node) SetStalePreparedAfter(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stalePreparedAfter = d
+}
+
+// stalePreparedAfterLocked returns the effective stale threshold. Caller must hold n.mu.
+func (n *Node) stalePreparedAfterLocked() time.Duration {
+	if n.stalePreparedAfter <= 0 {
+		return DefaultStalePreparedAfter
+	}
+	return n.stalePreparedAfter
+}
+
+// preparedGIDOwnerSep separates the transaction ID from the coordinator that
+// prepared it inside a gid. The distributed_tx row holding coordinator_addr
+// stays invisible while the transaction is prepared, so the gid is the only
+// place the owner survives a restart.
+const preparedGIDOwnerSep = "@"
+
+// preparedGID returns the Postgres global transaction identifier for txID,
+// naming coordinator when the transaction is fenced to one.
+func preparedGID(coordinator, txID string) string {
+	if coordinator == "" {
+		return preparedGIDPrefix + txID
+	}
+	return preparedGIDPrefix + txID + preparedGIDOwnerSep + coordinator
+}
+
+// parsePreparedGID splits a gid built by preparedGID into the transaction ID
+// and the coordinator that prepared it.
+func parsePreparedGID(gid string) (txID, coordinator string) {
+	rest := strings.TrimPrefix(gid, preparedGIDPrefix)
+	if i := strings.LastIndex(rest, preparedGIDOwnerSep); i >= 0 {
+		return rest[:i], rest[i+len(preparedGIDOwnerSep):]
+	}
+	return rest, ""
+}
+
+// quoteLiteral renders s as a SQL string literal. PREPARE TRANSACTION and its
+// COMMIT/ROLLBACK counterparts do not accept bind parameters.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// prepareTransactionLocked applies payload on a dedicated connection and ends
+// the transaction with PREPARE TRANSACTION, returning the rows the action
+// affected and any RETURNING values. The connection goes back to the pool afterwards; the prepared
+// transaction is no longer tied to it. Caller must hold n.mu.
+func (n *Node) prepareTransactionLocked(coordinator, txID string, payload any) (int64, []map[string]any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := n.ensureSchema(ctx); err != nil {
+		logging.Errorf("[Node %s] Failed to ensure schema: %v", n.Addr, err)
+		return 0, nil, err
+	}
+
+	conn, err := n.db.Conn(ctx)
+	if err != nil {
+		logging.Errorf("[Node %s] Failed to get connection for %s: %v", n.Addr, txID, err)
+		return 0, nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `BEGIN`); err != nil {
+		logging.Errorf("[Node %s] Failed to begin transaction: %v", n.Addr, err)
+		return 0, nil, err
+	}
+
+	rollback := func() {
+		if _, err := conn.ExecContext(context.Background(), `ROLLBACK`); err != nil {
+			logging.Warnf("[Node %s] Failed to roll back %s: %v", n.Addr, txID, err)
+		}
+	}
+
+	if n.durablePrepare {
+		if _, err := conn.ExecContext(ctx, `SET LOCAL synchronous_commit = on`); err != nil {
+			rollback()
+			logging.Warnf("[Node %s] Failed to enable synchronous_commit for %s: %v", n.Addr, txID, err)
+			return 0, nil, err
+		}
+	}
+
+	action, err := parseSQLAction(payload)
+	if err != nil {
+		rollback()
+		return 0, nil, err
+	}
+
+	affected, returned, err := n.applyPrepared(ctx, conn, action)
+	if err != nil {
+		rollback()
+		return 0, nil, err
+	}
+
+	if err := checkRowsAffected(action.ExpectRowsAffected, affected); err != nil {
+		rollback()
+		logging.Warnf("[Node %s] Rejecting transaction %s: %v", n.Addr, txID, err)
+		return 0, nil, err
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		rollback()
+		return 0, nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx,
+		`INSERT INTO distributed_tx (
+			tx_id,
+			payload,
+			status,
+			coordinator_addr
+			) VALUES ($1, $2::jsonb, 'PREPARED', NULLIF($3, ''))`,
+		txID, string(payloadBytes), coordinator,
+	); err != nil {
+		rollback()
+		return 0, nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, `PREPARE TRANSACTION `+quoteLiteral(preparedGID(coordinator, txID))); err != nil {
+		rollback()
+		logging.Errorf("[Node %s] PREPARE TRANSACTION failed for %s: %v", n.Addr, txID, err)
+		return 0, nil, err
+	}
+
+	return affected, returned, nil
+}
+
+// finishPreparedLocked resolves the prepared transaction for txID with
+// COMMIT PREPARED (commit=true) or ROLLBACK PREPARED and records the outcome in
+// distributed_tx. A gid that no longer exists was already resolved, so the call
+// is idempotent. Caller must hold n.mu.
+func (n *Node) finishPreparedLocked(txID string, commit bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	verb, status := "ROLLBACK PREPARED ", "ABORTED"
+	if commit {
+		verb, status = "COMMIT PREPARED ", "COMMITTED"
+	}
+
+	gid := preparedGID(n.pendingOwner[txID], txID)
+	if _, pending := n.pendingData[txID]; !pending {
+		// Not recovered into memory, so the owner in the gid is unknown.
+		found, err := findPreparedGID(ctx, n.db, txID)
+		if err != nil {
+			logging.Errorf("[Node %s] Failed to look up prepared transaction %s: %v", n.Addr, txID, err)
+			return err
+		}
+		if found != "" {
+			gid = found
+		}
+	}
+
+	if _, err := n.db.ExecContext(ctx, verb+quoteLiteral(gid)); err != nil {
+		if !isUnknownPreparedErr(err) {
+			logging.Errorf("[Node %s] %sfailed for %s: %v", n.Addr, verb, txID, err)
+			return err
+		}
+		logging.Debugf("[Node %s] No prepared transaction for %s; already resolved", n.Addr, txID)
+	}
+
+	// After ROLLBACK PREPARED the PREPARED row is gone, so this only matches
+	// when an earlier attempt got further.
+	if _, err := n.db.ExecContext(ctx,
+		`UPDATE
+			distributed_tx
+		SET
+			status=$2,
+			updated_at=NOW()
+		WHERE
+			tx_id=$1`,
+		txID, status,
+	); err != nil {
+		logging.Warnf("[Node %s] Failed to mark %s as %s: %v", n.Addr, txID, status, err)
+		return err
+	}
+
+	return nil
+}
+
+// isUnknownPreparedErr reports whether err says the prepared transaction does
+// not exist (SQLSTATE 42704), i.e. it was already committed or rolled back.
+func isUnknownPreparedErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "prepared transaction with identifier") &&
+		strings.Contains(msg, "does not exist")
+}
+
+// ListPreparedXacts returns the prepared transactions this engine holds in
+// Postgres (pg_prepared_xacts rows with its gid prefix), oldest first, marking
+// those older than the stale threshold. Without a database it returns nothing.
+func (n *Node) ListPreparedXacts(ctx context.Context) ([]protocol.PreparedXact, error) {
+	n.mu.RLock()
+	db := n.db
+	staleAfter := n.stalePreparedAfterLocked()
+	n.mu.RUnlock()
+
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT
+			gid,
+			prepared
+		FROM
+			pg_prepared_xacts
+		WHERE
+			database = current_database()
+			AND starts_with(gid, $1)
+		ORDER BY prepared`,
+		preparedGIDPrefix,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list prepared transactions: %w", err)
+	}
+	defer rows.Close()
+
+	xacts := []protocol.PreparedXact{}
+	now := time.Now()
+	for rows.Next() {
+		var x protocol.PreparedXact
+		if err := rows.Scan(&x.GID, &x.PreparedAt); err != nil {
+			return nil, fmt.Errorf("scan prepared transaction: %w", err)
+		}
+		x.TransactionID, x.Coordinator = parsePreparedGID(x.GID)
+		x.Stale = now.Sub(x.PreparedAt) >= staleAfter
+		xacts = append(xacts, x)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list prepared transactions: %w", err)
+	}
+
+	return xacts, nil
+}
+
+// preparedXactCounts returns how many prepared transactions the node holds and
+// how many are stale. ok is false when prepared transactions are off or the
+// query failed.
+func (n *Node) preparedXactCounts() (total, stale int, ok bool) {
+	n.mu.RLock()
+	enabled := n.preparedTxns && n.db != nil
+	n.mu.RUnlock()
+
+	if !enabled {
+		return 0, 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	xacts, err := n.ListPreparedXacts(ctx)
+	if err != nil {
+		logging.Warnf("[Node %s] Failed to count prepared transactions: %v", n.Addr, err)
+		return 0, 0, false
+	}
+
+	for _, x := range xacts {
+		if x.Stale {
+			stale++
+		}
+	}
+	return len(xacts), stale, true
+}
+
+// findPreparedGID returns the gid under which Postgres still holds txID as a
+// prepared transaction, or "" if it holds none. Its distributed_tx row is
+// invisible until it is resolved.
+func findPreparedGID(ctx context.Context, db *sql.DB, txID string) (string, error) {
+	var gid string
+	err := db.QueryRowContext(ctx,
+		`SELECT
+			gid
+		FROM
+			pg_prepared_xacts
+		WHERE
+			database = current_database()
+			AND (gid = $1 OR starts_with(gid, $2))
+		LIMIT 1`,
+		preparedGID("", txID),
+		preparedGID("", txID)+preparedGIDOwnerSep,
+	).Scan(&gid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return gid, err
+}
+
+// RecoverPrepared re-registers transactions this engine left prepared in
+// Postgres, e.g. before a crash, so they show up as pending and the coordinator
+// can commit or abort them. Each stays fenced to the coordinator named in its
+// gid. It returns the recovered transaction IDs.
+func (n *Node) RecoverPrepared(ctx context.Context) ([]string, error) {
+	if !n.HasDB() {
+		return nil, errors.New("no database configured")
+	}
+
+	xacts, err := n.ListPreparedXacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var recovered []string
+	for _, x := range xacts {
+		if _, exists := n.pendingData[x.TransactionID]; exists {
+			continue
+		}
+
+		// The payload sits inside the prepared transaction and cannot be read
+		// until it is resolved.
+		n.pendingData[x.TransactionID] = nil
+		n.preparedAt[x.TransactionID] = x.PreparedAt
+		if x.Coordinator != "" {
+			n.pendingOwner[x.TransactionID] = x.Coordinator
+		}
+		recovered = append(recovered, x.TransactionID)
+	}
+	n.pendingCount.Store(int64(len(n.pendingData)))
+
+	if len(recovered) > 0 {
+		n.TxState = protocol.StateReady
+		logging.Warnf("[Node %s] Recovered %d prepared transaction(s) awaiting a decision: %v", n.Addr, len(recovered), recovered)
+	}
+
+	return recovered, nil
+}
@@ -0,0 +1,171 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestPreparedTransactionsIssuePrepareAndCommitPrepared(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	n.SetPreparedTransactions(true)
+
+	action := SQLAction{Table: "users", Values: map[string]any{"name": "Alice"}}
+	for _, tc := range []struct {
+		txID    string
+		resolve func(string) error
+		verb    string
+	}{
+		{"tx-commit", n.Commit, "COMMIT PREPARED"},
+		{"tx-abort", n.Abort, "ROLLBACK PREPARED"},
+	} {
+		if ready, err := n.Prepare(tc.txID, action); err != nil || !ready {
+			t.Fatalf("Prepare(%s) failed: ready=%v err=%v", tc.txID, ready, err)
+		}
+
+		queries := rec.Queries()
+		prepare := "PREPARE TRANSACTION '2pc-engine:" + tc.txID + "'"
+		if queries[len(queries)-1] != prepare {
+			t.Fatalf("Expected prepare to end with %q, got %v", prepare, queries)
+		}
+		if !n.HasPendingTransaction(tc.txID) {
+			t.Fatalf("Expected %s to be pending after prepare", tc.txID)
+		}
+
+		if err := tc.resolve(tc.txID); err != nil {
+			t.Fatalf("%s(%s) failed: %v", tc.verb, tc.txID, err)
+		}
+		resolve := tc.verb + " '2pc-engine:" + tc.txID + "'"
+		if !slices.Contains(rec.Queries(), resolve) {
+			t.Errorf("Expected %q, got %v", resolve, rec.Queries())
+		}
+		if n.HasPendingTransaction(tc.txID) {
+			t.Errorf("Expected %s to be resolved", tc.txID)
+		}
+	}
+
+	if slices.Contains(rec.Queries(), "COMMIT") {
+		t.Errorf("Prepared transactions must not use a plain COMMIT: %v", rec.Queries())
+	}
+}
+
+func TestPreparedGIDNamesCoordinator(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	n.SetPreparedTransactions(true)
+
+	action := SQLAction{Table: "users", Values: map[string]any{"name": "Alice"}}
+	if ready, err := n.PrepareFor("master-a:8080", "tx-owned", action); err != nil || !ready {
+		t.Fatalf("PrepareFor failed: ready=%v err=%v", ready, err)
+	}
+	if err := n.CommitFor("master-a:8080", "tx-owned"); err != nil {
+		t.Fatalf("CommitFor failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"PREPARE TRANSACTION '2pc-engine:tx-owned@master-a:8080'",
+		"COMMIT PREPARED '2pc-engine:tx-owned@master-a:8080'",
+	} {
+		if !slices.Contains(rec.Queries(), want) {
+			t.Errorf("Expected %q, got %v", want, rec.Queries())
+		}
+	}
+
+	for gid, want := range map[string][2]string{
+		"2pc-engine:tx-owned@master-a:8080": {"tx-owned", "master-a:8080"},
+		"2pc-engine:tx-open":                {"tx-open", ""},
+	} {
+		if txID, coordinator := parsePreparedGID(gid); txID != want[0] || coordinator != want[1] {
+			t.Errorf("parsePreparedGID(%q) = %q, %q, want %q, %q", gid, txID, coordinator, want[0], want[1])
+		}
+	}
+}
+
+func TestQuoteLiteralEscapesQuotes(t *testing.T) {
+	if got := quoteLiteral("2pc-engine:it's"); got != "'2pc-engine:it''s'" {
+		t.Errorf("quoteLiteral = %s", got)
+	}
+}
+
+// TestPreparedTransactionSurvivesRestart runs against a real Postgres with
+// max_prepared_transactions > 0. Set TWOPC_TEST_POSTGRES_DSN to enable it.
+func TestPreparedTransactionSurvivesRestart(t *testing.T) {
+	dsn := os.Getenv("TWOPC_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TWOPC_TEST_POSTGRES_DSN not set")
+	}
+
+	open := func() *sql.DB {
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			t.Fatalf("sql.Open failed: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	ctx := context.Background()
+	admin := open()
+	if _, err := admin.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS prepared_recovery_test (name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.ExecContext(ctx, `ROLLBACK PREPARED '2pc-engine:tx-recover@master-a:8080'`)
+		_, _ = admin.ExecContext(ctx, `DELETE FROM distributed_tx WHERE tx_id = 'tx-recover'`)
+		_, _ = admin.ExecContext(ctx, `DROP TABLE IF EXISTS prepared_recovery_test`)
+	})
+
+	before := NewNodeWithDB("localhost:8081", protocol.RoleSlave, open())
+	before.SetPreparedTransactions(true)
+	action := SQLAction{Table: "prepared_recovery_test", Values: map[string]any{"name": "Alice"}}
+	if ready, err := before.PrepareFor("master-a:8080", "tx-recover", action); err != nil || !ready {
+		t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+	}
+
+	// A fresh node on a fresh pool stands in for the restarted process.
+	after := NewNodeWithDB("localhost:8081", protocol.RoleSlave, open())
+	after.SetPreparedTransactions(true)
+	recovered, err := after.RecoverPrepared(ctx)
+	if err != nil {
+		t.Fatalf("RecoverPrepared failed: %v", err)
+	}
+	if !slices.Contains(recovered, "tx-recover") || !after.HasPendingTransaction("tx-recover") {
+		t.Fatalf("Expected tx-recover to be recovered, got %v", recovered)
+	}
+
+	if err := after.CommitFor("master-b:8080", "tx-recover"); !errors.Is(err, ErrForeignCoordinator) {
+		t.Fatalf("Expected the recovered transaction to stay fenced to master-a, got %v", err)
+	}
+	if err := after.CommitFor("master-a:8080", "tx-recover"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var rows int
+	if err := admin.QueryRowContext(ctx, `SELECT COUNT(*) FROM prepared_recovery_test`).Scan(&rows); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("Expected the recovered transaction's row to be committed, got %d rows", rows)
+	}
+
+	rec, err := after.GetTransaction(ctx, "tx-recover")
+	if err != nil {
+		t.Fatalf("GetTransaction failed: %v", err)
+	}
+	if rec == nil || rec.Status != protocol.TxStatusCommitted {
+		t.Errorf("Expected COMMITTED record, got %+v", rec)
+	}
+
+	// Resolving it again is a no-op.
+	if err := after.Commit("tx-recover"); err != nil {
+		t.Errorf("Second commit failed: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package node
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrResourcePressure is returned by prepare when the node's database
+// connection pool is busier than its ResourceThresholds allow.
+var ErrResourcePressure = errors.New("resource pressure")
+
+// ResourceThresholds make prepare vote ABORT while the database connection
+// pool is saturated, so the node refuses work it could not finish instead of
+// queueing for a connection. They are checked against sql.DBStats before each
+// prepare. A zero value for any field disables that check.
+type ResourceThresholds struct {
+	// MaxPoolInUse is the fraction (0, 1] of the pool's MaxOpenConnections in
+	// use at which prepares are refused. It needs a pool limit to apply.
+	MaxPoolInUse float64
+	// MaxInUseConns is the number of connections in use at which prepares are
+	// refused, for pools without a limit.
+	MaxInUseConns int
+}
+
+// Validate reports thresholds that cannot be met.
+func (t ResourceThresholds) Validate() error {
+	if t.MaxPoolInUse < 0 || t.MaxPoolInUse > 1 {
+		return fmt.Errorf("pool in-use threshold must be between 0 and 1, got %v", t.MaxPoolInUse)
+	}
+	if t.MaxInUseConns < 0 {
+		return fmt.Errorf("in-use connection threshold must not be negative, got %d", t.MaxInUseConns)
+	}
+	return nil
+}
+
+// check returns ErrResourcePressure when stats cross a threshold.
+func (t ResourceThresholds) check(stats sql.DBStats) error {
+	if t.MaxInUseConns > 0 && stats.InUse >= t.MaxInUseConns {
+		return fmt.Errorf("%w: %d database connections in use, threshold %d", ErrResourcePressure, stats.InUse, t.MaxInUseConns)
+	}
+	if t.MaxPoolInUse > 0 && stats.MaxOpenConnections > 0 &&
+		float64(stats.InUse) >= t.MaxPoolInUse*float64(stats.MaxOpenConnections) {
+		return fmt.Errorf("%w: %d of %d database connections in use", ErrResourcePressure, stats.InUse, stats.MaxOpenConnections)
+	}
+	return nil
+}
+
+// SetResourceThresholds makes prepare vote ABORT with ErrResourcePressure
+// while the connection pool crosses thresholds. The zero value turns the
+// check off. Nodes without a database are never under pressure.
+func (n *Node) SetResourceThresholds(t ResourceThresholds) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.resourceThresholds = t
+}
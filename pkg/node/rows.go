@@ -0,0 +1,102 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// ErrAmbiguousKey is returned by ReadRow when the key column matches more
+// than one row, so there is no single row to compare.
+var ErrAmbiguousKey = errors.New("key matches more than one row")
+
+// ReadRow returns the row of table whose keyCol equals key, with column
+// names as keys, or nil when no row matches. The key is sent as text and
+// converted by Postgres to the column's type. It is meant for consistency
+// checks across nodes of application tables, so it is refused without a table
+// allowlist and for system catalogs, which an unqualified name could still
+// reach through search_path. hasDB is false for nodes without a database,
+// which hold no rows.
+func (n *Node) ReadRow(ctx context.Context, table, keyCol, key string) (row map[string]any, hasDB bool, err error) {
+	n.mu.RLock()
+	db := n.db
+	allowed := n.allowedTables
+	n.mu.RUnlock()
+
+	if db == nil {
+		return nil, false, nil
+	}
+
+	tableIdent, err := safeIdent(table)
+	if err != nil {
+		return nil, true, protocol.NewAPIError(protocol.ErrCodeInvalidRequest, fmt.Sprintf("table %q: %v", table, err))
+	}
+	keyIdent, err := safeIdent(keyCol)
+	if err != nil {
+		return nil, true, protocol.NewAPIError(protocol.ErrCodeInvalidRequest, fmt.Sprintf("key column %q: %v", keyCol, err))
+	}
+	if isSystemIdent(tableIdent) {
+		return nil, true, protocol.NewAPIError(protocol.ErrCodeInvalidRequest, fmt.Sprintf("table %q: system catalogs cannot be read", table))
+	}
+	if allowed == nil {
+		return nil, true, fmt.Errorf("%w: reading rows requires a table allowlist", ErrTableNotPermitted)
+	}
+	if err := checkTableAllowed(allowed, &SQLAction{Table: table}); err != nil {
+		return nil, true, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT * FROM "`+tableIdent+`" WHERE "`+keyIdent+`" = $1 LIMIT 2`, key)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, true, err
+	}
+
+	for rows.Next() {
+		if row != nil {
+			return nil, true, fmt.Errorf("%w: %s.%s = %s", ErrAmbiguousKey, tableIdent, keyIdent, key)
+		}
+
+		if row, err = scanRow(rows, cols); err != nil {
+			return nil, true, err
+		}
+	}
+
+	return row, true, rows.Err()
+}
+
+// isSystemIdent reports whether the lowercased identifier names a Postgres
+// system catalog or view.
+func isSystemIdent(ident string) bool {
+	return strings.HasPrefix(ident, "pg_") || ident == "information_schema"
+}
+
+// scanRow reads the current row into a map keyed by cols. Byte slices become
+// strings so the row marshals as readable JSON.
+func scanRow(rows *sql.Rows, cols []string) (map[string]any, error) {
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]any, len(cols))
+	for i, c := range cols {
+		if b, ok := vals[i].([]byte); ok {
+			vals[i] = string(b)
+		}
+		row[c] = vals[i]
+	}
+	return row, nil
+}
@@ -0,0 +1,39 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestReadRowRequiresAllowlistAndRejectsCatalogs(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	n := NewNodeWithDB("localhost:8081", protocol.RoleSlave, db)
+	ctx := context.Background()
+
+	if _, _, err := n.ReadRow(ctx, "users", "id", "1"); !errors.Is(err, ErrTableNotPermitted) {
+		t.Fatalf("Expected reads to be refused without an allowlist, got %v", err)
+	}
+
+	if err := n.SetAllowedTables([]string{"users", "pg_authid"}); err != nil {
+		t.Fatalf("SetAllowedTables failed: %v", err)
+	}
+	for _, table := range []string{"pg_authid", "PG_Shadow", "information_schema"} {
+		_, _, err := n.ReadRow(ctx, table, "id", "1")
+		if protocol.ErrorCode(err, "") != protocol.ErrCodeInvalidRequest {
+			t.Errorf("Expected %s to be rejected as a system catalog, got %v", table, err)
+		}
+	}
+	if _, _, err := n.ReadRow(ctx, "orders", "id", "1"); !errors.Is(err, ErrTableNotPermitted) {
+		t.Errorf("Expected a table outside the allowlist to be refused, got %v", err)
+	}
+	if got := rec.Queries(); len(got) != 0 {
+		t.Fatalf("Refused reads must not reach the database, got %v", got)
+	}
+
+	if _, hasDB, err := n.ReadRow(ctx, "users", "id", "1"); err != nil || !hasDB {
+		t.Fatalf("Expected an allowlisted read to succeed, got hasDB=%v err=%v", hasDB, err)
+	}
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import "errors"
+
+// Stable, machine-readable codes carried in the error envelope. Clients should
+// branch on these rather than on messages, which may change.
+const (
+	ErrCodeInvalidRequest      = "invalid_request"       // malformed body, missing field or bad query parameter
+	ErrCodeInvalidPayload      = "invalid_payload"       // the transaction payload was rejected
+	ErrCodeMethodNotAllowed    = "method_not_allowed"    // wrong HTTP method; see the Allow header
+	ErrCodeNotFound            = "not_found"             // no such endpoint
+	ErrCodeUnauthorized        = "unauthorized"          // dashboard credentials missing or wrong
+	ErrCodeOriginNotAllowed    = "origin_not_allowed"    // CORS preflight from an origin not on the allowlist
+	ErrCodeNotMaster           = "not_master"            // the request must go to the master
+	ErrCodeWrongCoordinator    = "wrong_coordinator"     // a forwarded transaction reached a node that does not own its partition
+	ErrCodeNoMaster            = "no_master"             // this node knows of no master
+	ErrCodeNotReady            = "not_ready"             // the master has not finished starting up
+	ErrCodeElectionInProgress  = "election_in_progress"  // a master election is running
+	ErrCodeNodeNotFound        = "node_not_found"        // no member has that address or name
+	ErrCodeAmbiguousNode       = "ambiguous_node"        // a display name shared by several members
+	ErrCodeTransactionNotFound = "transaction_not_found" // this node has no record of the transaction
+	ErrCodeTargetsUnavailable  = "targets_unavailable"   // a requested target is unknown, dead or excluded
+	ErrCodeNoParticipants      = "no_participants"       // nobody was available to prepare
+	ErrCodeTooManyParticipants = "too_many_participants" // over the coordinator's participant limit
+	ErrCodeLocalNoDatabase     = "local_no_database"     // the node has no database to prepare on and refuses
+	ErrCodeRateLimited         = "rate_limited"          // over the coordinator's rate limit; see retry_after_ms
+	ErrCodeAtCapacity          = "at_capacity"           // the node holds its maximum of prepared transactions
+	ErrCodeResourcePressure    = "resource_pressure"     // the node's connection pool is saturated
+	ErrCodeTableNotPermitted   = "table_not_permitted"   // the payload targets a table outside the node's allowlist
+	ErrCodeTransactionAborted  = "transaction_aborted"   // a participant did not prepare, so the transaction aborted
+	ErrCodeConflict            = "conflict"              // the request conflicts with the transaction's state
+	ErrCodeUnavailable         = "unavailable"           // a dependency such as the cluster view is not available
+	ErrCodeNotConfigured       = "not_configured"        // the node has no handler for this request
+	ErrCodeInternal            = "internal_error"        // anything else
+)
+
+// APIError is the body of a failed request: a stable code and a message for
+// humans. It implements error so handlers can return it, or wrap it, to choose
+// the code clients see.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewAPIError creates an APIError.
+func NewAPIError(code, message string) *APIError {
+	return &APIError{Code: code, Message: message}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// ErrorResponse is the envelope every API endpoint uses for failures:
+// {"error": {"code": "...", "message": "..."}}. Failed transactions also carry
+// their ID, when throttled how long to wait before retrying, and with verbose
+// responses the members left out.
+type ErrorResponse struct {
+	Error         APIError       `json:"error"`
+	TransactionID string         `json:"transaction_id,omitempty"`
+	RetryAfterMs  int64          `json:"retry_after_ms,omitempty"`
+	Excluded      []ExcludedNode `json:"excluded,omitempty"`
+}
+
+// ErrorCode returns the code of the first APIError in err's chain, or fallback
+// when there is none.
+func ErrorCode(err error, fallback string) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return fallback
+}
@@ -6,28 +6,55 @@ import "time"
 type PrepareRequest struct {
 	TransactionID string `json:"transaction_id"`
 	Payload       any    `json:"payload"`
+	Coordinator   string `json:"coordinator,omitempty"` // coordinator identity; only it may commit/abort
 }
 
 // PrepareResponse is returned by participants
 type PrepareResponse struct {
-	Status PrepareStatus `json:"status"` // READY or ABORT
-	Error  string        `json:"error,omitempty"`
+	Status       PrepareStatus `json:"status"` // READY or ABORT
+	Error        string        `json:"error,omitempty"`
+	RowsAffected int64         `json:"rows_affected"`  // rows modified by the prepared statement
+	Code         string        `json:"code,omitempty"` // why the vote is ABORT, when it is a known reason (ErrCodeAtCapacity, ErrCodeResourcePressure)
+	// Returned holds the RETURNING columns of each row the prepared INSERT
+	// added, when the action asked for them.
+	Returned []map[string]any `json:"returned,omitempty"`
 }
 
 // CommitRequest is sent by coordinator to commit
 type CommitRequest struct {
 	TransactionID string `json:"transaction_id"`
+	Coordinator   string `json:"coordinator,omitempty"` // must match the coordinator that prepared
+	// Takeover lets Coordinator finish a transaction another coordinator
+	// prepared, e.g. when reconciling after a failover.
+	Takeover bool `json:"takeover,omitempty"`
+	// IncludeChanges asks the participant to echo what it committed in Changes.
+	IncludeChanges bool `json:"include_changes,omitempty"`
 }
 
 // CommitResponse is returned by participants
 type CommitResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
+	Changes []AppliedChange `json:"changes,omitempty"` // only with CommitRequest.IncludeChanges
+}
+
+// AppliedChange is a statement a participant committed, echoed back so a
+// change-data-capture consumer can build a change feed without re-querying.
+type AppliedChange struct {
+	Table        string         `json:"table"`
+	Operation    string         `json:"operation"`
+	Values       map[string]any `json:"values,omitempty"`
+	Where        map[string]any `json:"where,omitempty"`
+	Keys         map[string]any `json:"keys,omitempty"` // primary-key values of the affected row, where known
+	RowsAffected int64          `json:"rows_affected"`
 }
 
 // AbortRequest is sent by coordinator to abort
 type AbortRequest struct {
 	TransactionID string `json:"transaction_id"`
+	Reason        string `json:"reason,omitempty"`      // why the coordinator decided to abort
+	Coordinator   string `json:"coordinator,omitempty"` // must match the coordinator that prepared
+	Takeover      bool   `json:"takeover,omitempty"`    // see CommitRequest.Takeover
 }
 
 // AbortResponse is returned by participants
@@ -36,32 +63,161 @@ type AbortResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// ProtocolVersion identifies the wire protocol spoken by this build.
+// Nodes reporting a different version should not share a cluster.
+const ProtocolVersion = "1"
+
+// Database health values reported in HealthResponse.
+const (
+	DatabaseOK          = "OK"
+	DatabaseUnreachable = "UNREACHABLE"
+	DatabaseNone        = "NONE"
+)
+
 // HealthResponse is returned by health check endpoint
 type HealthResponse struct {
-	Status  string `json:"status"`
+	Status   string    `json:"status"` // OK, or DEGRADED when the database is unreachable
+	Address  string    `json:"address"`
+	Role     string    `json:"role"`
+	Version  string    `json:"version,omitempty"`
+	Database string    `json:"database,omitempty"`
+	Time     time.Time `json:"time"` // server clock, for skew checks
+
+	Maintenance bool `json:"maintenance,omitempty"` // node is in maintenance mode
+
+	// State is the node's lifecycle state; Draining is set while it shuts
+	// down. A draining node is alive but must not be given new work.
+	State    NodeState `json:"state"`
+	Draining bool      `json:"draining,omitempty"`
+}
+
+// NodeState is a node's lifecycle state as reported by /health.
+type NodeState string
+
+const (
+	NodeStateRunning     NodeState = "running"     // taking new work
+	NodeStateDraining    NodeState = "draining"    // shutting down: finishing prepared transactions, refusing new ones
+	NodeStateMaintenance NodeState = "maintenance" // in maintenance mode, see MaintenanceRequest
+)
+
+// ReadyResponse is returned by the readiness endpoint, with 200 when the node
+// can take part in new transactions and 503 when it cannot.
+type ReadyResponse struct {
+	Ready   bool   `json:"ready"`
 	Address string `json:"address"`
-	Role    string `json:"role"`
+	Reason  string `json:"reason,omitempty"` // why the node is not ready
 }
 
+// MaintenanceHeader is set to "true" on /health responses (including HEAD) of a
+// node in maintenance, so heartbeats learn it without reading the body.
+const MaintenanceHeader = "X-2PC-Maintenance"
+
+// StateHeader carries the NodeState on /health responses (including HEAD).
+// Nodes that predate it only send MaintenanceHeader.
+const StateHeader = "X-2PC-State"
+
 // RoleResponse returns the current role of the node
 type RoleResponse struct {
 	Role    string `json:"role"`
 	Address string `json:"address"`
 }
 
+// MasterResponse returns the master according to the answering node's cluster view
+type MasterResponse struct {
+	MasterAddr string `json:"master_addr"`
+	ReportedBy string `json:"reported_by"`
+}
+
 // TransactionRequest is the CLI request to start a 2PC transaction
 type TransactionRequest struct {
 	Payload any `json:"payload"`
+	// Targets limits the transaction to these node addresses (default: all alive nodes).
+	Targets []string `json:"targets,omitempty"`
+	// RecordOnAll writes a best-effort OBSERVED marker on non-target nodes after commit.
+	RecordOnAll bool `json:"record_on_all,omitempty"`
+	// PartitionKey routes the transaction to the coordinator owning the key's
+	// hash range when the keyspace is partitioned (default: the master).
+	PartitionKey string `json:"partition_key,omitempty"`
+	// Forwarded is set by a node that forwarded the request to the partition
+	// owner; a forwarded request is never forwarded again.
+	// TransactionID is assigned by the node that accepted an async request,
+	// so the ID it returned survives forwarding. Neither is part of the JSON
+	// body: they travel in headers signed with the coordinators' forward key.
+	Forwarded     bool   `json:"-"`
+	TransactionID string `json:"-"`
+	// AckPolicy overrides the coordinator's commit acknowledgement policy
+	// for this transaction: "all", "local" or "none" (default: the
+	// coordinator's --commit-ack).
+	AckPolicy string `json:"ack_policy,omitempty"`
+}
+
+// AsyncTransactionResponse acknowledges POST /transaction?async=true. The
+// transaction runs in the background; poll StatusURL for its outcome.
+type AsyncTransactionResponse struct {
+	TransactionID string `json:"transaction_id"`
+	Status        string `json:"status"`     // always RUNNING
+	StatusURL     string `json:"status_url"` // path of GET /transaction/{id} on the accepting node
+}
+
+// KeyspacePartition assigns the partition keys whose hash falls in
+// [Start, End] to one coordinator.
+type KeyspacePartition struct {
+	Start       uint32 `json:"start"`
+	End         uint32 `json:"end"`
+	Coordinator string `json:"coordinator"`
+}
+
+// ObserveRequest asks a non-participant to record an OBSERVED marker for a transaction.
+type ObserveRequest struct {
+	TransactionID string `json:"transaction_id"`
+	Payload       any    `json:"payload"`
+}
+
+// ObserveResponse is returned after recording an OBSERVED marker.
+type ObserveResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
 }
 
 // TransactionResponse is the result of a 2PC transaction
 type TransactionResponse struct {
-	TransactionID string `json:"transaction_id"`
-	Success       bool   `json:"success"`
-	Message       string `json:"message,omitempty"`
-	Error         string `json:"error,omitempty"`
+	TransactionID string           `json:"transaction_id"`
+	Success       bool             `json:"success"`
+	Message       string           `json:"message,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	RowsAffected  map[string]int64 `json:"rows_affected,omitempty"`  // per-node rows modified
+	Durable       bool             `json:"durable"`                  // every participant acknowledged the commit
+	PendingNodes  []string         `json:"pending_nodes,omitempty"`  // decided committed, but the commit ack is missing
+	RetryAfterMs  int64            `json:"retry_after_ms,omitempty"` // set when rejected by the rate limit: retry after this long
+	Excluded      []ExcludedNode   `json:"excluded,omitempty"`       // members left out of the transaction (verbose responses only)
+
+	// Returned holds, per node, the RETURNING values of a committed INSERT
+	// that asked for them, such as generated keys.
+	Returned map[string][]map[string]any `json:"returned,omitempty"`
+
+	// Code classifies a failure (one of the ErrCode* values). Over HTTP a
+	// failed transaction is sent as an ErrorResponse, which carries the code.
+	Code string `json:"-"`
+}
+
+// ExcludedNode is a cluster member the coordinator left out of a transaction.
+type ExcludedNode struct {
+	Address string `json:"address"`
+	Reason  string `json:"reason"` // one of the Exclude* reasons
 }
 
+// Reasons a member is left out of a transaction.
+const (
+	ExcludeDead           = "dead"            // failed its heartbeats
+	ExcludeDisabled       = "disabled"        // disabled by an operator
+	ExcludeMaintenance    = "maintenance"     // in maintenance mode
+	ExcludeDraining       = "draining"        // shutting down
+	ExcludeNotReady       = "not_ready"       // alive but failed its readiness probe
+	ExcludeNotTargeted    = "not_targeted"    // the request named other targets
+	ExcludeNoDatabase     = "no_database"     // local node without a database, skipped by policy
+	ExcludeCoordinateOnly = "coordinate_only" // local node only coordinates
+)
+
 // JoinRequest is sent by a new node to join the cluster
 type JoinRequest struct {
 	Address string `json:"address"` // The address of the node wanting to join
@@ -77,9 +233,61 @@ type JoinResponse struct {
 
 // ClusterInfoResponse returns information about the cluster
 type ClusterInfoResponse struct {
-	MasterAddr string     `json:"master_addr"`
-	Nodes      []NodeInfo `json:"nodes"`
-	Generated  time.Time  `json:"generated_at"`
+	MasterAddr string        `json:"master_addr"`
+	Nodes      []NodeInfo    `json:"nodes"`
+	Elections  ElectionStats `json:"elections"`
+	Generated  time.Time     `json:"generated_at"`
+
+	// SplitBrain is set once the heartbeat has seen two masters at once.
+	SplitBrain *SplitBrainStats `json:"split_brain,omitempty"`
+}
+
+// Overall cluster health reported by /cluster/health, best first.
+const (
+	ClusterHealthy  = "healthy"
+	ClusterDegraded = "degraded"
+	ClusterCritical = "critical"
+)
+
+// ClusterHealthResponse scores the cluster for monitoring: Score runs from
+// 100 (everything fine) down to 0, and Status is the worst status of Checks.
+type ClusterHealthResponse struct {
+	Status    string               `json:"status"`
+	Score     int                  `json:"score"`
+	Checks    []ClusterHealthCheck `json:"checks"`
+	Generated time.Time            `json:"generated_at"`
+}
+
+// ClusterHealthCheck is one input of the health score and the points it cost.
+type ClusterHealthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Penalty int    `json:"penalty"`
+	Detail  string `json:"detail"`
+}
+
+// Reasons recorded for a master election.
+const (
+	ElectionReasonInitial       = "initial"        // first election of this cluster view
+	ElectionReasonMasterDied    = "master_died"    // the master stopped answering heartbeats
+	ElectionReasonMasterRemoved = "master_removed" // the master was removed from the cluster
+	ElectionReasonManual        = "manual"         // an explicit re-election
+	ElectionReasonMaintenance   = "maintenance"    // the master entered maintenance mode
+)
+
+// ElectionStats counts master elections; frequent elections indicate flapping.
+type ElectionStats struct {
+	Count      int        `json:"count"`
+	LastAt     *time.Time `json:"last_at,omitempty"`
+	LastReason string     `json:"last_reason,omitempty"`
+}
+
+// SplitBrainStats reports reachable nodes claiming the MASTER role at the
+// same time, as seen by the heartbeat's /role checks.
+type SplitBrainStats struct {
+	MultipleMastersDetected int        `json:"multiple_masters_detected"` // times the condition began
+	LastAt                  *time.Time `json:"last_at,omitempty"`
+	Masters                 []string   `json:"masters,omitempty"` // nodes claiming MASTER now; empty once resolved
 }
 
 // NodeInfo contains information about a single node
@@ -88,21 +296,52 @@ type NodeInfo struct {
 	Address  string      `json:"address"`
 	Role     string      `json:"role"`
 	Alive    bool        `json:"alive"`
+	Disabled bool        `json:"disabled,omitempty"`
 	Database string      `json:"database,omitempty"`
 	Metrics  NodeMetrics `json:"metrics"`
+
+	Maintenance  bool `json:"maintenance,omitempty"`   // self-reported, see MaintenanceRequest
+	NotReady     bool `json:"not_ready,omitempty"`     // alive but failed its last /ready probe
+	Draining     bool `json:"draining,omitempty"`      // shutting down, see HealthResponse
+	MetricsStale bool `json:"metrics_stale,omitempty"` // metrics did not arrive in time; Metrics is zero-valued
+
+	JoinedAt time.Time `json:"joined_at"`
+	Uptime   int64     `json:"uptime_seconds"` // seconds since the node last became alive
+
+	// Transient lifecycle status for auto-started nodes (STARTING/FAILED).
+	Status      string `json:"status,omitempty"`
+	StatusError string `json:"status_error,omitempty"`
+
+	// Detail is only set by /cluster/nodes?detail=true.
+	Detail *NodeDetail `json:"detail,omitempty"`
+}
+
+// NodeDetail is the extended per-node view for orchestrators. Fields a remote
+// node could not report (e.g. it is down) are left empty.
+type NodeDetail struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Priority    int               `json:"priority"`
+	Version     string            `json:"version,omitempty"`       // protocol version from /health
+	ClockSkewMs *int64            `json:"clock_skew_ms,omitempty"` // node clock minus the answering node's clock
+	InDoubt     *int              `json:"in_doubt,omitempty"`      // prepared transactions awaiting a decision
 }
 
 // AddNodeRequest is sent to add a new node to the cluster
 type AddNodeRequest struct {
-	Address  string `json:"address"`
-	Name     string `json:"name,omitempty"`
-	Database string `json:"database,omitempty"`
+	Address  string            `json:"address"`
+	Name     string            `json:"name,omitempty"`
+	Database string            `json:"database,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
 }
 
 // AddNodeResponse is returned after adding a node
 type AddNodeResponse struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error,omitempty"`
+
+	// Updated is set when the address was already a member: its metadata
+	// was updated in place, keeping its liveness and counters.
+	Updated bool `json:"updated,omitempty"`
 }
 
 // RemoveNodeRequest removes a node from the cluster
@@ -116,6 +355,62 @@ type RemoveNodeResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// SyncClusterRequest is the complete desired membership of the cluster.
+// Members not listed are removed; listed ones are added or have their
+// metadata updated.
+type SyncClusterRequest struct {
+	Nodes []AddNodeRequest `json:"nodes"`
+}
+
+// SyncClusterResponse lists, by address, what a sync changed. All three
+// lists are empty when the cluster already matched.
+type SyncClusterResponse struct {
+	Success bool     `json:"success"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// DisableNodeRequest disables or re-enables a node without changing membership
+type DisableNodeRequest struct {
+	Address string `json:"address"`
+}
+
+// DisableNodeResponse is returned after disabling or enabling a node
+type DisableNodeResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MaintenanceRequest puts a node into or out of maintenance mode. Sent to the
+// master (/cluster/maintenance) it names the node; sent to the node itself
+// (/maintenance) Address may be empty.
+type MaintenanceRequest struct {
+	Address        string `json:"address"`
+	Enabled        bool   `json:"enabled"`
+	DrainTimeoutMs int64  `json:"drain_timeout_ms,omitempty"` // wait for prepared transactions (default 10s)
+}
+
+// MaintenanceResponse reports the node's maintenance state after a change.
+// Drained is false when prepared transactions were still pending at the
+// drain timeout; InFlight is how many.
+type MaintenanceResponse struct {
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Enabled  bool   `json:"enabled"`
+	Drained  bool   `json:"drained"`
+	InFlight int    `json:"in_flight,omitempty"`
+}
+
+// ShutdownResponse acknowledges POST /admin/shutdown. The node drains and stops
+// after sending it.
+type ShutdownResponse struct {
+	Status  string `json:"status"` // always "shutting_down"
+	Address string `json:"address"`
+}
+
 // SetNameRequest sets a display name for a node.
 type SetNameRequest struct {
 	Address string `json:"address"`
@@ -128,6 +423,18 @@ type SetNameResponse struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// HeartbeatIntervalRequest changes the heartbeat interval at runtime.
+type HeartbeatIntervalRequest struct {
+	Interval string `json:"interval"` // Go duration, e.g. "2s"
+}
+
+// HeartbeatIntervalResponse is returned after changing the heartbeat interval.
+type HeartbeatIntervalResponse struct {
+	Success  bool   `json:"success"`
+	Interval string `json:"interval,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
 // NodeMetrics carries lightweight node telemetry for dashboards/automation.
 type NodeMetrics struct {
 	Prepared    uint64    `json:"prepared"`
@@ -138,22 +445,233 @@ type NodeMetrics struct {
 	SuccessRate float64   `json:"success_rate"`
 	LastError   string    `json:"last_error,omitempty"`
 	LastUpdated time.Time `json:"last_updated"`
+
+	// Abort breakdown since process start: this node voted ABORT at prepare
+	// vs. it prepared but the coordinator aborted because another node failed.
+	AbortedSelf          uint64 `json:"aborted_self"`
+	AbortedByCoordinator uint64 `json:"aborted_by_coordinator"`
+
+	// Time transactions spent PREPARED before commit or abort, since process
+	// start. A growing tail points at a coordinator that prepares and stalls.
+	PreparedDurationP95Ms int64              `json:"prepared_duration_p95_ms"`
+	PreparedDuration      *DurationHistogram `json:"prepared_duration,omitempty"`
+
+	// Postgres prepared transactions (PREPARE TRANSACTION) this engine holds on
+	// the node, and how many of them are stale. Stale ones block vacuum and hold
+	// locks until the prepared-transaction reconciler resolves them.
+	PreparedXacts      int `json:"prepared_xacts"`
+	StalePreparedXacts int `json:"stale_prepared_xacts"`
+
+	// Limit on transactions held prepared at once (0 = unlimited), and the
+	// prepares refused because the node was at it since process start.
+	MaxPrepared        int    `json:"max_prepared"`
+	RejectedAtCapacity uint64 `json:"rejected_at_capacity"`
+
+	// Prepares refused since process start because the connection pool
+	// crossed the node's resource thresholds.
+	RejectedForPressure uint64 `json:"rejected_for_pressure"`
+}
+
+// DurationHistogram is a fixed-bucket histogram of durations. Bucket counts are
+// cumulative; Count minus the last bucket's count exceeded every bound.
+type DurationHistogram struct {
+	Buckets []DurationBucket `json:"buckets"`
+	Count   uint64           `json:"count"`
+	SumMs   int64            `json:"sum_ms"`
+	MaxMs   int64            `json:"max_ms"`
+}
+
+// DurationBucket counts observations at or below LeMs milliseconds.
+type DurationBucket struct {
+	LeMs  int64  `json:"le_ms"`
+	Count uint64 `json:"count"`
+}
+
+// MetricsSample summarizes one sampling interval of a node's metrics. The
+// counts are the transactions finished during the interval ending at Time.
+type MetricsSample struct {
+	Time        time.Time `json:"time"`
+	Committed   uint64    `json:"committed"`
+	Aborted     uint64    `json:"aborted"`
+	Failed      uint64    `json:"failed"`
+	SuccessRate *float64  `json:"success_rate"` // of the interval's transactions; null when there were none
+	InFlight    int       `json:"in_flight"`    // at Time
+}
+
+// MetricsHistoryResponse is returned by GET /metrics/history: the node's
+// recent samples, oldest first, at most Window of them.
+type MetricsHistoryResponse struct {
+	Node            string          `json:"node"`
+	IntervalSeconds int64           `json:"interval_seconds"`
+	Window          int             `json:"window"`
+	Samples         []MetricsSample `json:"samples"`
 }
 
 // ClusterDashboardResponse is a richer view for UIs.
 type ClusterDashboardResponse struct {
-	MasterAddr string     `json:"master_addr"`
-	Nodes      []NodeInfo `json:"nodes"`
-	Generated  time.Time  `json:"generated_at"`
+	MasterAddr string        `json:"master_addr"`
+	Nodes      []NodeInfo    `json:"nodes"`
+	Elections  ElectionStats `json:"elections"`
+	Generated  time.Time     `json:"generated_at"`
+
+	SplitBrain *SplitBrainStats `json:"split_brain,omitempty"`
 }
 
 // TransactionRecord represents a stored distributed transaction row.
 type TransactionRecord struct {
-	TxID      string    `json:"tx_id"`
-	Status    string    `json:"status"`
-	Payload   any       `json:"payload,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	TxID            string    `json:"tx_id"`
+	Status          string    `json:"status"`
+	Payload         any       `json:"payload,omitempty"`
+	CoordinatorAddr string    `json:"coordinator_addr,omitempty"` // coordinator that prepared it ("" = unfenced or observed)
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Set by the node that accepted an async transaction: the outcome once it
+	// finished, and the error code when it did not commit.
+	Result    *TransactionResponse `json:"result,omitempty"`
+	ErrorCode string               `json:"error_code,omitempty"`
+}
+
+// TransactionEvent is one state change of a transaction on a node, streamed
+// by GET /transactions/stream as a server-sent event named "transaction".
+type TransactionEvent struct {
+	TxID        string    `json:"tx_id"`
+	Node        string    `json:"node"`
+	Status      string    `json:"status"`                // PREPARED, COMMITTED or ABORTED
+	Coordinator string    `json:"coordinator,omitempty"` // coordinator that prepared it ("" = unfenced)
+	Error       string    `json:"error,omitempty"`       // why the node voted ABORT at prepare
+	Time        time.Time `json:"time"`
+}
+
+// Per-node transaction status values used in TransactionDetailResponse in
+// addition to the stored statuses (PREPARED, COMMITTED, ABORTED).
+const (
+	TxStatusMissing     = "MISSING"     // node has no record of the transaction
+	TxStatusUnreachable = "UNREACHABLE" // node could not be queried
+)
+
+// Stored transaction statuses (the status column of distributed_tx).
+const (
+	TxStatusPrepared  = "PREPARED"
+	TxStatusCommitted = "COMMITTED"
+	TxStatusAborted   = "ABORTED"
+	TxStatusObserved  = "OBSERVED" // recorded by a node that did not participate
+	TxStatusRunning   = "RUNNING"  // async transaction still being coordinated
+	TxStatusFailed    = "FAILED"   // async transaction whose handler failed; outcome unknown
+)
+
+// TransactionNodeStatus is one node's view of a transaction.
+type TransactionNodeStatus struct {
+	Status    string     `json:"status"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// TransactionDetailResponse shows a transaction's status on every cluster member.
+type TransactionDetailResponse struct {
+	TransactionID string                           `json:"transaction_id"`
+	Nodes         map[string]TransactionNodeStatus `json:"nodes"` // keyed by node address
+	Generated     time.Time                        `json:"generated_at"`
+}
+
+// Grounds on which reconcile picks a transaction's outcome.
+const (
+	ReconcileBasisCoordinatorLog = "coordinator_log" // the coordinator still holds an undelivered decision
+	ReconcileBasisMajority       = "majority"        // most nodes in a final state agree
+	ReconcileBasisPresumedAbort  = "presumed_abort"  // no node reached a final state
+)
+
+// Per-node reconcile actions.
+const (
+	ReconcileUnchanged = "unchanged" // already at the outcome, or holds nothing to undo
+	ReconcileApplied   = "applied"   // the outcome was pushed to a prepared node
+	ReconcileFailed    = "failed"    // the push failed or the node cannot reach the outcome
+	ReconcileSkipped   = "skipped"   // the node could not be queried
+)
+
+// ReconcileNodeResult is what reconcile found and did on one node.
+type ReconcileNodeResult struct {
+	Before string `json:"before"` // status before reconcile
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReconcileResponse reports the outcome reconcile chose for a transaction and
+// what it changed on every node.
+type ReconcileResponse struct {
+	TransactionID string                         `json:"transaction_id"`
+	Decision      string                         `json:"decision"` // COMMITTED or ABORTED
+	Basis         string                         `json:"basis"`
+	Nodes         map[string]ReconcileNodeResult `json:"nodes"` // keyed by node address
+	Generated     time.Time                      `json:"generated_at"`
+}
+
+// PreparedXact is a Postgres prepared transaction (pg_prepared_xacts row) the
+// engine created on a node.
+type PreparedXact struct {
+	TransactionID string    `json:"transaction_id"`
+	GID           string    `json:"gid"`
+	PreparedAt    time.Time `json:"prepared_at"`
+	Coordinator   string    `json:"coordinator,omitempty"` // coordinator that prepared it ("" = unfenced)
+	Stale         bool      `json:"stale"`                 // prepared longer than the node's stale threshold
+}
+
+// PreparedXactsResponse lists a node's prepared transactions.
+type PreparedXactsResponse struct {
+	Node  string         `json:"node"`
+	Xacts []PreparedXact `json:"xacts"`
+	Stale int            `json:"stale"`
+}
+
+// RowResponse is one node's copy of a row, read by key for consistency checks
+// (GET /rows).
+type RowResponse struct {
+	Node      string         `json:"node"`
+	Table     string         `json:"table"`
+	KeyColumn string         `json:"key_column"`
+	Key       string         `json:"key"`
+	HasDB     bool           `json:"has_db"`
+	Found     bool           `json:"found"`
+	Row       map[string]any `json:"row,omitempty"`
+}
+
+// Coordinator phases reported for in-flight transactions.
+const (
+	PhasePreparing  = "preparing"
+	PhaseCommitting = "committing"
+	PhaseAborting   = "aborting"
+)
+
+// InflightTransaction describes a transaction the coordinator is currently driving.
+type InflightTransaction struct {
+	TransactionID string    `json:"transaction_id"`
+	StartedAt     time.Time `json:"started_at"`
+	Phase         string    `json:"phase"`
+	Participants  int       `json:"participants"`
+}
+
+// InflightResponse lists the coordinator's active transactions, oldest first.
+type InflightResponse struct {
+	Transactions []InflightTransaction `json:"transactions"`
+	Generated    time.Time             `json:"generated_at"`
+}
+
+// Abort categories counted by the coordinator, one per aborted transaction.
+const (
+	AbortVoteAbort             = "vote_abort"              // a participant voted ABORT
+	AbortPrepareTimeout        = "prepare_timeout"         // a prepare request timed out
+	AbortPrepareTransportError = "prepare_transport_error" // a participant could not be reached
+	AbortNoParticipants        = "no_participants"         // nobody was available to prepare
+	AbortNodeAtCapacity        = "node_at_capacity"        // a participant already held its maximum of prepared transactions
+	AbortResourcePressure      = "resource_pressure"       // a participant's connection pool was saturated
+	AbortCommitLogError        = "commit_log_error"        // the commit decision could not be recorded in the commit log
+	AbortDurabilityUnmet       = "durability_unmet"        // every participant prepared, but too few for the durability rule
+)
+
+// CoordinatorMetricsResponse is the coordinator's abort breakdown by category.
+type CoordinatorMetricsResponse struct {
+	Aborts    map[string]int64 `json:"aborts"`
+	Generated time.Time        `json:"generated_at"`
 }
 
 // TransactionListResponse represents a paginated set of transactions.
@@ -165,3 +683,12 @@ type TransactionListResponse struct {
 	Address      string              `json:"address"`
 	HasDB        bool                `json:"has_db"`
 }
+
+// ChaosConfig describes failures injected by a node running in chaos mode.
+// Counters are decremented as failures are injected.
+type ChaosConfig struct {
+	FailPrepares int   `json:"fail_prepares"`        // reject the next N prepares
+	FailCommits  int   `json:"fail_commits"`         // fail the next N commits
+	LatencyMs    int64 `json:"latency_ms"`           // delay added to every prepare/commit/abort
+	CrashNext    bool  `json:"crash_next,omitempty"` // exit the process on the next prepare/commit
+}
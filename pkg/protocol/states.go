@@ -26,3 +26,13 @@ const (
 	StatusReady PrepareStatus = "READY"
 	StatusAbort PrepareStatus = "ABORT"
 )
+
+// NodeStatus is a lifecycle status reported in the cluster summary
+type NodeStatus string
+
+const (
+	NodeStatusStarting    NodeStatus = "STARTING"
+	NodeStatusFailed      NodeStatus = "FAILED"
+	NodeStatusDisabled    NodeStatus = "DISABLED"
+	NodeStatusMaintenance NodeStatus = "MAINTENANCE"
+)
@@ -0,0 +1,192 @@
+// Package testutil runs an in-process cluster of real HTTP nodes so integration
+// tests can exercise the 2PC engine without hand-rolled stub servers.
+package testutil
+
+import (
+	"database/sql"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+	twophasecommit "github.com/baxromumarov/2pc-engine/pkg/two_phase_commit"
+)
+
+// DefaultTimeout is the coordinator RPC timeout used when Options.Timeout is zero.
+const DefaultTimeout = 2 * time.Second
+
+// Options tunes NewTestClusterWithOptions.
+type Options struct {
+	// Timeout is the coordinator's per-RPC timeout (default DefaultTimeout).
+	Timeout time.Duration
+	// OpenDB returns the database backing node i, e.g. a sqlmock or a
+	// throwaway Postgres. A nil func or nil result keeps the node in memory.
+	OpenDB func(i int) *sql.DB
+}
+
+// TestNode is one member of a TestCluster, served on its own loopback port.
+type TestNode struct {
+	Node   *node.Node
+	Server *transport.HTTPServer
+
+	http *httptest.Server // nil while the node is killed
+}
+
+// Addr returns the node's host:port.
+func (n *TestNode) Addr() string {
+	return n.Node.Addr
+}
+
+// TestCluster is a set of in-process nodes sharing one cluster view. Nodes[0]
+// is the master and runs the coordinator; transactions go through its HTTP API.
+type TestCluster struct {
+	Cluster     *cluster.Cluster
+	Coordinator *twophasecommit.Coordinator
+	Nodes       []*TestNode
+
+	t         testing.TB
+	client    *transport.HTTPClient
+	heartbeat *cluster.HeartbeatManager
+}
+
+// NewTestCluster starts nNodes in-memory nodes with the default options.
+func NewTestCluster(t testing.TB, nNodes int) *TestCluster {
+	return NewTestClusterWithOptions(t, nNodes, Options{})
+}
+
+// NewTestClusterWithOptions starts nNodes nodes on ephemeral loopback ports,
+// all alive, with Nodes[0] as master. Everything is shut down by t.Cleanup.
+func NewTestClusterWithOptions(t testing.TB, nNodes int, opts Options) *TestCluster {
+	t.Helper()
+
+	if nNodes < 1 {
+		t.Fatalf("testutil: need at least one node, got %d", nNodes)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	tc := &TestCluster{
+		Cluster: cluster.NewCluster(),
+		t:       t,
+		client:  transport.NewHTTPClient(opts.Timeout),
+	}
+	// The heartbeat is never started: Kill and Revive probe synchronously so
+	// tests do not depend on timing.
+	tc.heartbeat = cluster.NewHeartbeatManager(tc.Cluster, time.Second)
+
+	for i := range nNodes {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("testutil: listen for node %d: %v", i, err)
+		}
+
+		var db *sql.DB
+		if opts.OpenDB != nil {
+			db = opts.OpenDB(i)
+		}
+
+		addr := ln.Addr().String()
+		n := node.NewNode(addr, protocol.RoleSlave)
+		if db != nil {
+			n = node.NewNodeWithDB(addr, protocol.RoleSlave, db)
+		}
+		n.SetAlive(true)
+
+		tn := &TestNode{Node: n, Server: transport.NewHTTPServer(n)}
+		tn.Server.SetMasterHandler(tc.masterAddr)
+		tn.Server.SetElectionCheck(tc.Cluster.ElectionInProgress)
+		tn.serve(ln)
+
+		tc.Nodes = append(tc.Nodes, tn)
+		tc.Cluster.AddNode(n)
+	}
+
+	master := tc.Nodes[0]
+	tc.Cluster.SetMaster(master.Node)
+	tc.Coordinator = twophasecommit.NewCoordinator(tc.Cluster, master.Node, opts.Timeout)
+	master.Server.SetTransactionHandler(tc.Coordinator.ExecuteRequest)
+	master.Server.SetInflightHandler(tc.Coordinator.InFlight)
+	master.Server.SetCoordinatorMetricsHandler(tc.Coordinator.AbortBreakdown)
+
+	t.Cleanup(func() {
+		for _, n := range tc.Nodes {
+			n.stop()
+		}
+	})
+
+	return tc
+}
+
+// Master returns the node running the coordinator.
+func (tc *TestCluster) Master() *TestNode {
+	return tc.Nodes[0]
+}
+
+// Submit sends a transaction to the master over HTTP, like a client would.
+func (tc *TestCluster) Submit(payload any) (*protocol.TransactionResponse, error) {
+	return tc.SubmitRequest(&protocol.TransactionRequest{Payload: payload})
+}
+
+// SubmitRequest sends a full transaction request (targets, record-on-all) to the master.
+func (tc *TestCluster) SubmitRequest(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+	return tc.client.StartTransaction(tc.Master().Addr(), req)
+}
+
+// Kill stops serving node i and lets the master's view mark it dead, as a
+// heartbeat would. Killing the master (i == 0) makes Submit fail.
+func (tc *TestCluster) Kill(i int) {
+	tc.t.Helper()
+
+	n := tc.Nodes[i]
+	n.stop()
+	if tc.heartbeat.CheckNode(n.Addr()) {
+		tc.t.Fatalf("testutil: node %s still alive after kill", n.Addr())
+	}
+}
+
+// Revive serves node i again on its original address and marks it alive.
+func (tc *TestCluster) Revive(i int) {
+	tc.t.Helper()
+
+	n := tc.Nodes[i]
+	if n.http != nil {
+		return
+	}
+
+	ln, err := net.Listen("tcp", n.Addr())
+	if err != nil {
+		tc.t.Fatalf("testutil: relisten on %s: %v", n.Addr(), err)
+	}
+	n.serve(ln)
+
+	if !tc.heartbeat.CheckNode(n.Addr()) {
+		tc.t.Fatalf("testutil: node %s not alive after revive", n.Addr())
+	}
+}
+
+func (tc *TestCluster) masterAddr() string {
+	if m := tc.Cluster.GetMaster(); m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (n *TestNode) serve(ln net.Listener) {
+	srv := httptest.NewUnstartedServer(n.Server.Handler())
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+	n.http = srv
+}
+
+func (n *TestNode) stop() {
+	if n.http != nil {
+		n.http.Close()
+		n.http = nil
+	}
+}
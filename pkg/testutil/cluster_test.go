@@ -0,0 +1,32 @@
+package testutil
+
+import (
+	"testing"
+)
+
+func TestKillAndReviveNode(t *testing.T) {
+	tc := NewTestCluster(t, 3)
+
+	tc.Kill(2)
+	if tc.Nodes[2].Node.GetAlive() {
+		t.Fatal("Expected killed node to be marked dead")
+	}
+
+	// The dead node is excluded, so the remaining two commit.
+	resp, err := tc.Submit(map[string]any{"table": "users", "values": map[string]any{"id": 1}})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if !resp.Success || resp.Message != "Transaction committed on 2 nodes" {
+		t.Fatalf("Expected commit on 2 nodes, got %+v", resp)
+	}
+
+	tc.Revive(2)
+	resp, err = tc.Submit(map[string]any{"table": "users", "values": map[string]any{"id": 2}})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if !resp.Success || resp.Message != "Transaction committed on 3 nodes" {
+		t.Fatalf("Expected commit on 3 nodes after revive, got %+v", resp)
+	}
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/google/uuid"
+)
+
+const (
+	// asyncRetention is how long the outcome of an async transaction stays
+	// available to GET /transaction/{id} after it finished.
+	asyncRetention = 10 * time.Minute
+	// maxAsyncRunning caps the async transactions a node coordinates at once;
+	// further submissions are refused with 503 until one finishes.
+	maxAsyncRunning = 1024
+)
+
+// asyncJob is one transaction accepted with POST /transaction?async=true.
+type asyncJob struct {
+	submitted time.Time
+	finished  time.Time // zero while running
+	result    *protocol.TransactionResponse
+	err       error
+}
+
+// record folds the job's progress into rec, this node's own record of the
+// transaction, which is nil when the node did not take part.
+func (j asyncJob) record(txID string, rec *protocol.TransactionRecord) *protocol.TransactionRecord {
+	if rec == nil {
+		rec = &protocol.TransactionRecord{TxID: txID}
+	}
+	rec.CreatedAt = j.submitted
+	rec.UpdatedAt = j.submitted
+
+	switch {
+	case j.finished.IsZero():
+		rec.Status = protocol.TxStatusRunning
+		return rec
+	case j.err != nil:
+		rec.Status = protocol.TxStatusFailed
+		rec.ErrorCode = protocol.ErrCodeInternal
+	case j.result.Success:
+		rec.Status = protocol.TxStatusCommitted
+	default:
+		rec.Status = protocol.TxStatusAborted
+		rec.ErrorCode = j.result.Code
+		if rec.ErrorCode == "" {
+			rec.ErrorCode = protocol.ErrCodeTransactionAborted
+		}
+	}
+	rec.UpdatedAt = j.finished
+	rec.Result = j.result
+	return rec
+}
+
+// asyncJobs tracks the async transactions accepted by this node.
+type asyncJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*asyncJob
+	running int
+	wg      sync.WaitGroup // one count per running job; shutdown waits on it
+}
+
+// start registers a new job under txID. It returns false when
+// maxAsyncRunning jobs are already running.
+func (a *asyncJobs) start(txID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running >= maxAsyncRunning {
+		return false
+	}
+	if a.jobs == nil {
+		a.jobs = make(map[string]*asyncJob)
+	}
+
+	now := time.Now()
+	for id, job := range a.jobs {
+		if !job.finished.IsZero() && now.Sub(job.finished) > asyncRetention {
+			delete(a.jobs, id)
+		}
+	}
+
+	a.jobs[txID] = &asyncJob{submitted: now}
+	a.running++
+	a.wg.Add(1)
+	return true
+}
+
+// finish records the outcome of the job under txID.
+func (a *asyncJobs) finish(txID string, result *protocol.TransactionResponse, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	job, ok := a.jobs[txID]
+	if !ok {
+		return
+	}
+	job.finished = time.Now()
+	job.result = result
+	job.err = err
+	a.running--
+	a.wg.Done()
+}
+
+// get returns a snapshot of the job under txID.
+func (a *asyncJobs) get(txID string) (asyncJob, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	job, ok := a.jobs[txID]
+	if !ok {
+		return asyncJob{}, false
+	}
+	return *job, true
+}
+
+// startAsyncTransaction assigns req a transaction ID, runs it in the
+// background and answers 202 with where to poll for the outcome.
+func (s *HTTPServer) startAsyncTransaction(w http.ResponseWriter, req *protocol.TransactionRequest) {
+	txID := uuid.New().String()
+	if !s.async.start(txID) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, protocol.ErrCodeUnavailable, "Too many async transactions in progress", http.StatusServiceUnavailable)
+		return
+	}
+	req.TransactionID = txID
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				logging.Errorf("[Master %s] Async transaction %s panicked: %v", s.node.Addr, txID, p)
+				s.async.finish(txID, nil, fmt.Errorf("transaction handler panicked: %v", p))
+			}
+		}()
+
+		result, err := s.onTransaction(req)
+		if err != nil {
+			logging.Warnf("[Master %s] Async transaction %s failed: %v", s.node.Addr, txID, err)
+		} else if result.TransactionID == "" {
+			result.TransactionID = txID
+		}
+		s.async.finish(txID, result, err)
+	}()
+
+	statusURL := "/transaction/" + url.PathEscape(txID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", statusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(protocol.AsyncTransactionResponse{
+		TransactionID: txID,
+		Status:        protocol.TxStatusRunning,
+		StatusURL:     statusURL,
+	})
+}
+
+// WaitAsync blocks until every accepted async transaction has finished or
+// ctx is done. Call it after BeginShutdown so no new job can start.
+func (s *HTTPServer) WaitAsync(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.async.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
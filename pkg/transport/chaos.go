@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// chaosExit is the process exit hook used for injected crashes (overridden in tests).
+var chaosExit = os.Exit
+
+// chaosInjector holds the runtime-adjustable failure injection state.
+type chaosInjector struct {
+	mu  sync.Mutex
+	cfg protocol.ChaosConfig
+}
+
+// Config returns a snapshot of the current chaos configuration.
+func (c *chaosInjector) Config() protocol.ChaosConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cfg
+}
+
+// Set replaces the chaos configuration.
+func (c *chaosInjector) Set(cfg protocol.ChaosConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cfg.FailPrepares < 0 {
+		cfg.FailPrepares = 0
+	}
+	if cfg.FailCommits < 0 {
+		cfg.FailCommits = 0
+	}
+	if cfg.LatencyMs < 0 {
+		cfg.LatencyMs = 0
+	}
+	c.cfg = cfg
+}
+
+// beforePrepare applies latency/crash and returns an error if the prepare should be rejected.
+func (c *chaosInjector) beforePrepare(addr string) error {
+	if c.apply(addr) {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.FailPrepares > 0 {
+		c.cfg.FailPrepares--
+		logging.Warnf("[Chaos %s] Injecting prepare failure (%d remaining)", addr, c.cfg.FailPrepares)
+		return errors.New("chaos: injected prepare failure")
+	}
+	return nil
+}
+
+// beforeCommit applies latency/crash and returns an error if the commit should fail.
+func (c *chaosInjector) beforeCommit(addr string) error {
+	if c.apply(addr) {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.FailCommits > 0 {
+		c.cfg.FailCommits--
+		logging.Warnf("[Chaos %s] Injecting commit failure (%d remaining)", addr, c.cfg.FailCommits)
+		return errors.New("chaos: injected commit failure")
+	}
+	return nil
+}
+
+// beforeAbort only applies latency; aborts are never failed on purpose.
+func (c *chaosInjector) beforeAbort() {
+	c.mu.Lock()
+	latency := time.Duration(c.cfg.LatencyMs) * time.Millisecond
+	c.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+// apply sleeps for the configured latency and triggers a pending crash.
+// It returns true when the process exit hook was invoked.
+func (c *chaosInjector) apply(addr string) bool {
+	c.mu.Lock()
+	latency := time.Duration(c.cfg.LatencyMs) * time.Millisecond
+	crash := c.cfg.CrashNext
+	c.cfg.CrashNext = false
+	c.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	if crash {
+		logging.Warnf("[Chaos %s] Injecting process crash", addr)
+		chaosExit(1)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestChaosEndpointDisabledByDefault(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8081", protocol.RoleSlave))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/chaos")
+	if err != nil {
+		t.Fatalf("GET /debug/chaos failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 when chaos is disabled, got %d", resp.StatusCode)
+	}
+}
+
+func TestChaosInjectsPrepareFailure(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8081", protocol.RoleSlave))
+	s.EnableChaos()
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	client := NewHTTPClient(5 * time.Second)
+	addr := server.Listener.Addr().String()
+
+	cfg, err := client.SetChaos(addr, &protocol.ChaosConfig{FailPrepares: 1})
+	if err != nil {
+		t.Fatalf("SetChaos failed: %v", err)
+	}
+	if cfg.FailPrepares != 1 {
+		t.Fatalf("Expected fail_prepares=1, got %d", cfg.FailPrepares)
+	}
+
+	// The injected failure surfaces as a 500 from the participant.
+	_, err = client.Prepare(addr, &protocol.PrepareRequest{TransactionID: "chaos-tx-1"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("Expected injected prepare failure, got %v", err)
+	}
+
+	// The counter is consumed, so the next prepare goes through.
+	resp, err := client.Prepare(addr, &protocol.PrepareRequest{TransactionID: "chaos-tx-2"})
+	if err != nil {
+		t.Fatalf("Prepare after chaos exhausted failed: %v", err)
+	}
+	if resp.Status != protocol.StatusReady {
+		t.Errorf("Expected READY after chaos exhausted, got %s", resp.Status)
+	}
+}
+
+func TestChaosCrashUsesExitHook(t *testing.T) {
+	var exitCode int
+	orig := chaosExit
+	chaosExit = func(code int) { exitCode = code }
+	defer func() { chaosExit = orig }()
+
+	c := &chaosInjector{}
+	c.Set(protocol.ChaosConfig{CrashNext: true})
+
+	_ = c.beforeCommit("localhost:8081")
+	if exitCode != 1 {
+		t.Fatalf("Expected crash to invoke exit hook with code 1, got %d", exitCode)
+	}
+	if c.Config().CrashNext {
+		t.Error("Expected crash flag to be consumed")
+	}
+}
@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+const (
+	corsAllowMethods = "GET, POST, OPTIONS"
+	corsAllowHeaders = "Content-Type, Authorization"
+	corsMaxAge       = "600"
+)
+
+// SetCORSOrigins enables CORS on the client-facing JSON routes for the given
+// origins.
+// Use "*" to allow any origin. An empty list disables CORS (the default).
+func (s *HTTPServer) SetCORSOrigins(origins []string) {
+	allowed := make([]string, 0, len(origins))
+	for _, o := range origins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		if o != "" {
+			allowed = append(allowed, o)
+		}
+	}
+	s.corsOrigins = allowed
+}
+
+// allowedOrigin returns the value for Access-Control-Allow-Origin, or "" when
+// the request origin is not on the allowlist.
+func (s *HTTPServer) allowedOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, o := range s.corsOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// withCORS adds CORS headers for allowlisted origins and answers preflight
+// OPTIONS requests before they reach the wrapped handler.
+func (s *HTTPServer) withCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.corsOrigins) == 0 {
+			next(w, r)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		allowOrigin := s.allowedOrigin(origin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if allowOrigin == "" {
+			if preflight {
+				writeError(w, protocol.ErrCodeOriginNotAllowed, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		if allowOrigin != "*" {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCORSPreflightAndActualRequest(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetCORSOrigins([]string{"http://app.example.com"})
+
+	req, _ := http.NewRequest(http.MethodOptions, server.URL+"/transaction", nil)
+	req.Header.Set("Origin", "http://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("preflight failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 for preflight, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "http://app.example.com" {
+		t.Errorf("Expected allowed origin echoed, got %q", got)
+	}
+	if resp.Header.Get("Access-Control-Allow-Methods") == "" {
+		t.Error("Expected Access-Control-Allow-Methods on preflight")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/health", nil)
+	req.Header.Set("Origin", "http://app.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /health failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "http://app.example.com" {
+		t.Errorf("Expected allowed origin on actual request, got %q", got)
+	}
+}
+
+func TestCORSRejectsUnknownOrigin(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetCORSOrigins([]string{"http://app.example.com"})
+
+	req, _ := http.NewRequest(http.MethodOptions, server.URL+"/transaction", nil)
+	req.Header.Set("Origin", "http://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("preflight failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for disallowed origin, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Access-Control-Allow-Origin, got %q", got)
+	}
+}
+
+func TestCORSNotAppliedToDashboard(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetCORSOrigins([]string{"*"})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/dashboard", nil)
+	req.Header.Set("Origin", "http://app.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /dashboard failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS headers on dashboard HTML, got %q", got)
+	}
+}
+
+func TestCORSNotAppliedToInternalRoutes(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetCORSOrigins([]string{"http://app.example.com"})
+
+	for _, path := range []string{"/prepare", "/commit", "/abort", "/prepared", "/debug/chaos", "/cluster/add", "/cluster/sync", "/cluster/maintenance"} {
+		req, _ := http.NewRequest(http.MethodOptions, server.URL+path, nil)
+		req.Header.Set("Origin", "http://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("preflight %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "" ||
+			resp.Header.Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("Expected no CORS on %s, got %d with origin %q", path, resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+		}
+	}
+}
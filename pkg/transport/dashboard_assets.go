@@ -1,6 +1,41 @@
 package transport
 
-import _ "embed"
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"time"
+)
 
 //go:embed web/dashboard.html
 var dashboardPage string
+
+// dashboardRefresh is how often the dashboard re-fetches /cluster/summary.
+const dashboardRefresh = 5 * time.Second
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardPage))
+
+// dashboardData is injected into the embedded dashboard template.
+type dashboardData struct {
+	Title     string
+	Address   string
+	RefreshMs int64
+}
+
+// renderDashboard executes the embedded dashboard template for a node.
+func renderDashboard(title, addr string) ([]byte, error) {
+	if title == "" {
+		title = "Cluster Dashboard"
+	}
+
+	var buf bytes.Buffer
+	if err := dashboardTemplate.Execute(&buf, dashboardData{
+		Title:     title,
+		Address:   addr,
+		RefreshMs: dashboardRefresh.Milliseconds(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
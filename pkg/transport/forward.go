@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// A coordinator forwarding a transaction to another one marks the request
+// with headerForwarded, holding the transaction ID it assigned (empty for a
+// synchronous request), and signs the marker and body with the key shared by
+// the coordinators. The receiving node only honors a marker that verifies, so
+// a client cannot choose its own transaction ID or skip partition routing.
+const (
+	headerForwarded          = "X-2PC-Forwarded"
+	headerForwardedSignature = "X-2PC-Forwarded-Signature"
+)
+
+// errNoForwardKey is returned when a transaction is forwarded without a key to sign it.
+var errNoForwardKey = errors.New("forwarding a transaction needs a forward key")
+
+// SetForwardKey sets the key forwarded transactions must be signed with; every
+// coordinator must share it. Without a key forwarding markers are ignored and
+// each request is handled as if a client had sent it.
+func (s *HTTPServer) SetForwardKey(key []byte) {
+	s.forwardKey = key
+}
+
+// WithForwardKey signs forwarded transactions with key (see HTTPServer.SetForwardKey).
+func (c *HTTPClient) WithForwardKey(key []byte) *HTTPClient {
+	c.forwardKey = key
+	return c
+}
+
+// signForward returns the hex HMAC-SHA256 of a forwarded request's
+// transaction ID and body.
+func signForward(key []byte, txID string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(txID))
+	mac.Write([]byte{'\n'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// forwardedMarker returns the transaction ID carried by a forwarded request
+// and whether the request was forwarded by a coordinator holding the key.
+// Unsigned or badly signed markers are ignored.
+func (s *HTTPServer) forwardedMarker(r *http.Request, body []byte) (string, bool) {
+	txID, marked := r.Header[http.CanonicalHeaderKey(headerForwarded)]
+	if !marked {
+		return "", false
+	}
+	if len(s.forwardKey) == 0 {
+		logging.Debugf("[Node %s] Ignoring forwarding marker from %s: no forward key configured", s.node.Addr, s.clientAddr(r))
+		return "", false
+	}
+
+	want := signForward(s.forwardKey, txID[0], body)
+	if !hmac.Equal([]byte(r.Header.Get(headerForwardedSignature)), []byte(want)) {
+		logging.Warnf("[Node %s] Ignoring forwarding marker from %s: bad signature", s.node.Addr, s.clientAddr(r))
+		return "", false
+	}
+	return txID[0], true
+}
+
+// postForwarded sends a transaction another coordinator forwarded, signed
+// with the client's forward key.
+func (c *HTTPClient) postForwarded(addr string, req *protocol.TransactionRequest) (*http.Response, error) {
+	if len(c.forwardKey) == 0 {
+		return nil, errNoForwardKey
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	signature := signForward(c.forwardKey, req.TransactionID, body)
+
+	return c.doWithRetry(func() (*http.Response, error) {
+		httpReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/transaction", addr), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set(headerForwarded, req.TransactionID)
+		httpReq.Header.Set(headerForwardedSignature, signature)
+		return c.client.Do(httpReq)
+	})
+}
@@ -1,11 +1,15 @@
 package transport
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
@@ -18,6 +22,11 @@ type HTTPClient struct {
 	// retry configuration; kept simple to avoid changing public constructors
 	maxRetries int
 	retryDelay time.Duration
+	// optional basic-auth credentials for protected dashboard endpoints
+	authUser string
+	authPass string
+	// signs forwarded transactions (see WithForwardKey)
+	forwardKey []byte
 }
 
 // NewHTTPClient creates a new HTTP client with timeout
@@ -45,11 +54,78 @@ func (c *HTTPClient) WithRetry(maxRetries int, retryDelay time.Duration) *HTTPCl
 	return c
 }
 
+// WithBasicAuth sets credentials sent to endpoints protected by dashboard auth.
+func (c *HTTPClient) WithBasicAuth(user, pass string) *HTTPClient {
+	c.authUser = user
+	c.authPass = pass
+	return c
+}
+
 // DefaultHTTPClient creates a client with default 5 second timeout
 func DefaultHTTPClient() *HTTPClient {
 	return NewHTTPClient(5 * time.Second)
 }
 
+// Ping checks that a node is reachable with a HEAD /health request, without
+// decoding a body. Use HealthCheck when role or status is needed.
+func (c *HTTPClient) Ping(addr string) error {
+	_, err := c.PingStatus(addr)
+	return err
+}
+
+// PingStatus is what a HEAD /health reports about a live node.
+type PingStatus struct {
+	Maintenance bool
+	Draining    bool
+}
+
+// PingStatus pings a node and reports whether it is in maintenance mode or
+// draining.
+func (c *HTTPClient) PingStatus(addr string) (PingStatus, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Head(fmt.Sprintf("http://%s/health", addr))
+	})
+	if err != nil {
+		return PingStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return PingStatus{}, fmt.Errorf("ping failed with status: %d", resp.StatusCode)
+	}
+
+	return PingStatus{
+		Maintenance: resp.Header.Get(protocol.MaintenanceHeader) == "true",
+		Draining:    resp.Header.Get(protocol.StateHeader) == string(protocol.NodeStateDraining),
+	}, nil
+}
+
+// PingMaintenance pings a node and reports whether it is in maintenance mode.
+func (c *HTTPClient) PingMaintenance(addr string) (bool, error) {
+	status, err := c.PingStatus(addr)
+	return status.Maintenance, err
+}
+
+// Ready probes a node's /ready endpoint. A 503 means the node is up but not
+// ready; a node that predates /ready (404) counts as ready. The probe is not
+// retried, since a 503 is an answer rather than a failure.
+func (c *HTTPClient) Ready(addr string) (bool, error) {
+	resp, err := c.client.Head(fmt.Sprintf("http://%s/ready", addr))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return true, nil
+	case http.StatusServiceUnavailable:
+		return false, nil
+	default:
+		return false, fmt.Errorf("readiness probe failed with status: %d", resp.StatusCode)
+	}
+}
+
 // HealthCheck checks if a node is alive
 func (c *HTTPClient) HealthCheck(addr string) (*protocol.HealthResponse, error) {
 	resp, err := c.doWithRetry(func() (*http.Response, error) {
@@ -61,7 +137,7 @@ func (c *HTTPClient) HealthCheck(addr string) (*protocol.HealthResponse, error)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("health check failed with status: %d", resp.StatusCode)
+		return nil, responseError("health check", resp)
 	}
 
 	var health protocol.HealthResponse
@@ -83,7 +159,7 @@ func (c *HTTPClient) GetRole(addr string) (*protocol.RoleResponse, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get role failed with status: %d", resp.StatusCode)
+		return nil, responseError("get role", resp)
 	}
 
 	var role protocol.RoleResponse
@@ -94,6 +170,31 @@ func (c *HTTPClient) GetRole(addr string) (*protocol.RoleResponse, error) {
 	return &role, nil
 }
 
+// GetMaster asks a node which node it considers the master
+func (c *HTTPClient) GetMaster(addr string) (string, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Get(fmt.Sprintf("http://%s/cluster/master", addr))
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("node %s knows no master", addr)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get master failed with status: %d", resp.StatusCode)
+	}
+
+	var master protocol.MasterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&master); err != nil {
+		return "", err
+	}
+
+	return master.MasterAddr, nil
+}
+
 // GetMetrics fetches metrics from a remote node
 func (c *HTTPClient) GetMetrics(addr string) (*protocol.NodeMetrics, error) {
 	resp, err := c.doWithRetry(func() (*http.Response, error) {
@@ -105,7 +206,7 @@ func (c *HTTPClient) GetMetrics(addr string) (*protocol.NodeMetrics, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get metrics failed with status: %d", resp.StatusCode)
+		return nil, responseError("get metrics", resp)
 	}
 
 	var metrics protocol.NodeMetrics
@@ -151,19 +252,55 @@ func (c *HTTPClient) Abort(addr string, req *protocol.AbortRequest) (*protocol.A
 
 // StartTransaction sends a transaction request to the master
 func (c *HTTPClient) StartTransaction(masterAddr string, req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
-	resp, err := c.postJSON(masterAddr, "transaction", req)
+	var resp *http.Response
+	var err error
+	if req.Forwarded {
+		resp, err = c.postForwarded(masterAddr, req)
+	} else {
+		resp, err = c.postJSON(masterAddr, "transaction", req)
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return decodeTransactionError(resp)
+	}
 	return decodeTransactionResponse(resp.Body)
 }
 
+// StartTransactionAsync submits a transaction to be run in the background.
+// Poll GetTransaction with the returned ID on the same node for its outcome.
+func (c *HTTPClient) StartTransactionAsync(masterAddr string, req *protocol.TransactionRequest) (*protocol.AsyncTransactionResponse, error) {
+	resp, err := c.postJSON(masterAddr, "transaction?async=true", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, responseError("start transaction", resp)
+	}
+
+	var accepted protocol.AsyncTransactionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&accepted); err != nil {
+		return nil, err
+	}
+	return &accepted, nil
+}
+
 // ClusterInfo returns membership and node telemetry for dashboards/automation.
 func (c *HTTPClient) ClusterInfo(addr string) (*protocol.ClusterDashboardResponse, error) {
 	resp, err := c.doWithRetry(func() (*http.Response, error) {
-		return c.client.Get(fmt.Sprintf("http://%s/cluster/summary", addr))
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/cluster/summary", addr), nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.authUser != "" {
+			req.SetBasicAuth(c.authUser, c.authPass)
+		}
+		return c.client.Do(req)
 	})
 	if err != nil {
 		return nil, err
@@ -171,7 +308,7 @@ func (c *HTTPClient) ClusterInfo(addr string) (*protocol.ClusterDashboardRespons
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("cluster info failed with status: %d", resp.StatusCode)
+		return nil, responseError("cluster info", resp)
 	}
 
 	var info protocol.ClusterDashboardResponse
@@ -182,6 +319,48 @@ func (c *HTTPClient) ClusterInfo(addr string) (*protocol.ClusterDashboardRespons
 	return &info, nil
 }
 
+// Join asks the node at addr to add req.Address to its cluster.
+func (c *HTTPClient) Join(addr string, req *protocol.JoinRequest) (*protocol.JoinResponse, error) {
+	resp, err := c.postJSON(addr, "cluster/join", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("join", resp)
+	}
+
+	var joinResp protocol.JoinResponse
+	if err := json.NewDecoder(resp.Body).Decode(&joinResp); err != nil {
+		return nil, err
+	}
+
+	return &joinResp, nil
+}
+
+// ClusterNodes returns the membership as the node at addr sees it.
+func (c *HTTPClient) ClusterNodes(addr string) (*protocol.ClusterInfoResponse, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Get(fmt.Sprintf("http://%s/cluster/nodes", addr))
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("cluster nodes", resp)
+	}
+
+	var info protocol.ClusterInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
 // AddNode registers a new node with the cluster.
 func (c *HTTPClient) AddNode(masterAddr string, req *protocol.AddNodeRequest) (*protocol.AddNodeResponse, error) {
 	resp, err := c.postJSON(masterAddr, "cluster/add", req)
@@ -190,18 +369,15 @@ func (c *HTTPClient) AddNode(masterAddr string, req *protocol.AddNodeRequest) (*
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("add node", resp)
+	}
+
 	var addResp protocol.AddNodeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&addResp); err != nil {
 		return nil, err
 	}
 
-	if !addResp.Success {
-		if addResp.Error != "" {
-			return nil, fmt.Errorf("add node failed: %s", addResp.Error)
-		}
-		return nil, fmt.Errorf("add node failed with status: %d", resp.StatusCode)
-	}
-
 	return &addResp, nil
 }
 
@@ -213,19 +389,95 @@ func (c *HTTPClient) RemoveNode(masterAddr string, req *protocol.RemoveNodeReque
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("remove node", resp)
+	}
+
 	var remResp protocol.RemoveNodeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&remResp); err != nil {
 		return nil, err
 	}
 
-	if !remResp.Success {
-		if remResp.Error != "" {
-			return nil, fmt.Errorf("remove node failed: %s", remResp.Error)
-		}
-		return nil, fmt.Errorf("remove node failed with status: %d", resp.StatusCode)
+	return &remResp, nil
+}
+
+// SyncCluster replaces the cluster's membership with the desired set in req,
+// reporting which nodes were added, removed and updated.
+func (c *HTTPClient) SyncCluster(masterAddr string, req *protocol.SyncClusterRequest) (*protocol.SyncClusterResponse, error) {
+	resp, err := c.postJSON(masterAddr, "cluster/sync", req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return &remResp, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("sync cluster", resp)
+	}
+
+	var syncResp protocol.SyncClusterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&syncResp); err != nil {
+		return nil, err
+	}
+
+	return &syncResp, nil
+}
+
+// DisableNode excludes a node from transactions and election without removing it.
+func (c *HTTPClient) DisableNode(masterAddr string, req *protocol.DisableNodeRequest) (*protocol.DisableNodeResponse, error) {
+	return c.setNodeDisabled(masterAddr, "cluster/disable", req)
+}
+
+// EnableNode re-enables a previously disabled node.
+func (c *HTTPClient) EnableNode(masterAddr string, req *protocol.DisableNodeRequest) (*protocol.DisableNodeResponse, error) {
+	return c.setNodeDisabled(masterAddr, "cluster/enable", req)
+}
+
+func (c *HTTPClient) setNodeDisabled(masterAddr, path string, req *protocol.DisableNodeRequest) (*protocol.DisableNodeResponse, error) {
+	resp, err := c.postJSON(masterAddr, path, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(path, resp)
+	}
+
+	var disResp protocol.DisableNodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&disResp); err != nil {
+		return nil, err
+	}
+
+	return &disResp, nil
+}
+
+// SetMaintenance asks the master to put a node into or out of maintenance mode.
+func (c *HTTPClient) SetMaintenance(masterAddr string, req *protocol.MaintenanceRequest) (*protocol.MaintenanceResponse, error) {
+	return c.postMaintenance(masterAddr, "cluster/maintenance", req)
+}
+
+// SetNodeMaintenance puts the node at addr itself into or out of maintenance mode.
+func (c *HTTPClient) SetNodeMaintenance(addr string, req *protocol.MaintenanceRequest) (*protocol.MaintenanceResponse, error) {
+	return c.postMaintenance(addr, "maintenance", req)
+}
+
+func (c *HTTPClient) postMaintenance(addr, path string, req *protocol.MaintenanceRequest) (*protocol.MaintenanceResponse, error) {
+	resp, err := c.postJSON(addr, path, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(path, resp)
+	}
+
+	var mResp protocol.MaintenanceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&mResp); err != nil {
+		return nil, err
+	}
+
+	return &mResp, nil
 }
 
 // NameNode sets a display name for a node.
@@ -236,19 +488,38 @@ func (c *HTTPClient) NameNode(masterAddr string, req *protocol.SetNameRequest) (
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("set name", resp)
+	}
+
 	var nameResp protocol.SetNameResponse
 	if err := json.NewDecoder(resp.Body).Decode(&nameResp); err != nil {
 		return nil, err
 	}
 
-	if !nameResp.Success {
-		if nameResp.Error != "" {
-			return nil, fmt.Errorf("set name failed: %s", nameResp.Error)
-		}
-		return nil, fmt.Errorf("set name failed with status: %d", resp.StatusCode)
+	return &nameResp, nil
+}
+
+// MetricsHistory fetches a node's recent metric samples.
+func (c *HTTPClient) MetricsHistory(addr string) (*protocol.MetricsHistoryResponse, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Get(fmt.Sprintf("http://%s/metrics/history", addr))
+	})
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return &nameResp, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("metrics history", resp)
+	}
+
+	var history protocol.MetricsHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, err
+	}
+
+	return &history, nil
 }
 
 // Transactions fetches paginated transaction list from a node.
@@ -267,7 +538,7 @@ func (c *HTTPClient) Transactions(addr string, page, limit int, status string) (
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("transactions failed with status: %d", resp.StatusCode)
+		return nil, responseError("transactions", resp)
 	}
 
 	var txResp protocol.TransactionListResponse
@@ -278,6 +549,206 @@ func (c *HTTPClient) Transactions(addr string, page, limit int, status string) (
 	return &txResp, nil
 }
 
+// Observe asks a node to record an OBSERVED marker for a transaction.
+func (c *HTTPClient) Observe(addr string, req *protocol.ObserveRequest) error {
+	resp, err := c.postJSON(addr, "observe", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("observe", resp)
+	}
+
+	return nil
+}
+
+// ReadRow fetches a node's copy of the row of table whose keyCol equals key.
+func (c *HTTPClient) ReadRow(addr, table, keyCol, key string) (*protocol.RowResponse, error) {
+	query := url.Values{"table": {table}, "key_col": {keyCol}, "key": {key}}
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/rows?%s", addr, query.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.authUser != "" {
+			req.SetBasicAuth(c.authUser, c.authPass)
+		}
+		return c.client.Do(req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("read row", resp)
+	}
+
+	var row protocol.RowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&row); err != nil {
+		return nil, err
+	}
+
+	return &row, nil
+}
+
+// GetTransaction fetches a single transaction record from a node.
+// It returns nil without error when the node has no record of txID.
+func (c *HTTPClient) GetTransaction(addr, txID string) (*protocol.TransactionRecord, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Get(fmt.Sprintf("http://%s/transaction/%s", addr, url.PathEscape(txID)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("get transaction", resp)
+	}
+
+	var rec protocol.TransactionRecord
+	if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+// ListPreparedXacts fetches the Postgres prepared transactions a node holds.
+func (c *HTTPClient) ListPreparedXacts(addr string) (*protocol.PreparedXactsResponse, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Get(fmt.Sprintf("http://%s/prepared", addr))
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("list prepared transactions", resp)
+	}
+
+	var out protocol.PreparedXactsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// TransactionDetail fetches a transaction's per-node status from the master.
+func (c *HTTPClient) TransactionDetail(masterAddr, txID string) (*protocol.TransactionDetailResponse, error) {
+	resp, err := c.doWithRetry(func() (*http.Response, error) {
+		return c.client.Get(fmt.Sprintf("http://%s/transaction/%s/detail", masterAddr, url.PathEscape(txID)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("transaction detail", resp)
+	}
+
+	var detail protocol.TransactionDetailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+		return nil, err
+	}
+
+	return &detail, nil
+}
+
+// Reconcile asks the master to force every node to one outcome for txID.
+func (c *HTTPClient) Reconcile(masterAddr, txID string) (*protocol.ReconcileResponse, error) {
+	resp, err := c.postJSON(masterAddr, fmt.Sprintf("transaction/%s/reconcile", url.PathEscape(txID)), struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("reconcile", resp)
+	}
+
+	var result protocol.ReconcileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SetChaos replaces the chaos configuration on a node running in chaos mode.
+func (c *HTTPClient) SetChaos(addr string, cfg *protocol.ChaosConfig) (*protocol.ChaosConfig, error) {
+	resp, err := c.postJSON(addr, "debug/chaos", cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("set chaos", resp)
+	}
+
+	var current protocol.ChaosConfig
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return nil, err
+	}
+
+	return &current, nil
+}
+
+// StreamTransactions follows a node's /transactions/stream and calls fn for
+// each event until ctx is done, the node ends the stream or fn returns an
+// error. The client timeout does not apply to the stream; end it with ctx.
+func (c *HTTPClient) StreamTransactions(ctx context.Context, addr string, fn func(protocol.TransactionEvent) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/transactions/stream", addr), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	stream := &http.Client{Transport: c.client.Transport}
+	resp, err := stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("transaction stream", resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// Only data lines carry events; the event name, blank separators and
+		// keepalive comments are skipped.
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		var ev protocol.TransactionEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			return fmt.Errorf("invalid transaction event: %w", err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
+
 func (c *HTTPClient) postJSON(addr, path string, payload any) (*http.Response, error) {
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -306,7 +777,7 @@ func (c *HTTPClient) doWithRetry(do func() (*http.Response, error)) (*http.Respo
 		if err != nil {
 			lastErr = err
 		} else {
-			lastErr = fmt.Errorf("transient status: %d", resp.StatusCode)
+			lastErr = responseError("request", resp)
 			// Ensure we drain/close to avoid leaking connections
 			if resp.Body != nil {
 				_, _ = io.Copy(io.Discard, resp.Body)
@@ -326,6 +797,17 @@ func (c *HTTPClient) doWithRetry(do func() (*http.Response, error)) (*http.Respo
 	return nil, lastErr
 }
 
+// responseError builds the error for a failed response from its error
+// envelope, so callers can get the code with errors.As. A body that is not an
+// envelope yields an error naming the status.
+func responseError(op string, resp *http.Response) error {
+	var env protocol.ErrorResponse
+	if resp.Body != nil && json.NewDecoder(resp.Body).Decode(&env) == nil && env.Error.Message != "" {
+		return fmt.Errorf("%s failed with status %d: %w", op, resp.StatusCode, &env.Error)
+	}
+	return fmt.Errorf("%s failed with status: %d", op, resp.StatusCode)
+}
+
 func decodePrepareResponse(body io.Reader) (*protocol.PrepareResponse, error) {
 	var prepareResp protocol.PrepareResponse
 	if err := json.NewDecoder(body).Decode(&prepareResp); err != nil {
@@ -350,6 +832,24 @@ func decodeAbortResponse(body io.Reader) (*protocol.AbortResponse, error) {
 	return &abortResp, nil
 }
 
+// decodeTransactionError turns the error envelope of a failed transaction back
+// into a TransactionResponse, so callers see one shape for every outcome.
+func decodeTransactionError(resp *http.Response) (*protocol.TransactionResponse, error) {
+	var env protocol.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil || env.Error.Message == "" {
+		return nil, fmt.Errorf("transaction failed with status: %d", resp.StatusCode)
+	}
+
+	return &protocol.TransactionResponse{
+		TransactionID: env.TransactionID,
+		Success:       false,
+		Error:         env.Error.Message,
+		Code:          env.Error.Code,
+		RetryAfterMs:  env.RetryAfterMs,
+		Excluded:      env.Excluded,
+	}, nil
+}
+
 func decodeTransactionResponse(body io.Reader) (*protocol.TransactionResponse, error) {
 	var txResp protocol.TransactionResponse
 	if err := json.NewDecoder(body).Decode(&txResp); err != nil {
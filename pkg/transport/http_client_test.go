@@ -55,6 +55,29 @@ func TestHTTPClientHealthCheckFails(t *testing.T) {
 	}
 }
 
+func TestHTTPClientPingUsesHead(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(5 * time.Second)
+	if err := client.Ping(server.Listener.Addr().String()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if method != http.MethodHead {
+		t.Errorf("Expected HEAD request, got %s", method)
+	}
+
+	if err := client.Ping("localhost:59999"); err == nil {
+		t.Error("Expected error for non-existent server")
+	}
+}
+
 func TestHTTPClientGetRole(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		resp := protocol.RoleResponse{
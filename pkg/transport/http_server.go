@@ -1,12 +1,19 @@
 package transport
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
@@ -16,27 +23,60 @@ type HTTPServer struct {
 	node           *node.Node
 	mux            *http.ServeMux
 	server         *http.Server
-	onTransaction  func(payload any) (*protocol.TransactionResponse, error) // callback for master
-	onJoin         func(addr string) (*protocol.JoinResponse, error)        // callback for join requests
-	onAddNode      func(addr, name, database string) error                  // callback to add node to cluster
-	onRemoveNode   func(addr string) error                                  // callback to remove node from cluster
-	onSetName      func(addr, name string) error                            // callback to set node name
+	onTransaction  func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) // callback for master
+	onJoin         func(addr string) (*protocol.JoinResponse, error)                             // callback for join requests
+	onAddNode      func(req *protocol.AddNodeRequest) (bool, error)                              // callback to add node to cluster
+	onRemoveNode   func(addr string) error                                                       // callback to remove node from cluster
+	onSetDisabled  func(addr string, disabled bool) error                                        // callback to disable/enable a node
+	onSetName      func(addr, name string) error                                                 // callback to set node name
 	onListTx       func(addr string, page, limit int, status string) (*protocol.TransactionListResponse, error)
-	getClusterInfo func() *protocol.ClusterInfoResponse // callback to get cluster info
+	onTxDetail     func(txID string) (*protocol.TransactionDetailResponse, error)
+	onReconcile    func(txID string) (*protocol.ReconcileResponse, error)
+	onMaintenance  func(req *protocol.MaintenanceRequest) (*protocol.MaintenanceResponse, error)
+	onInflight     func() []protocol.InflightTransaction
+	getAborts      func() map[string]int64
+	getMaster      func() string                          // current master address from the local cluster view ("" if unknown)
+	isReady        func() bool                            // gates /transaction until the cluster view is verified
+	isElecting     func() bool                            // reports an election in progress
+	getClusterInfo func() *protocol.ClusterInfoResponse   // callback to get cluster info
+	getNodeDetail  func(addr string) *protocol.NodeDetail // extended per-node info for /cluster/nodes?detail=true
+	resolveNode    func(ref string) (string, error)       // maps a member's address or display name to its address
+	onHeartbeat    func(interval time.Duration) error     // callback to change heartbeat interval
+	onShutdown     func()                                 // starts the drain-and-stop path, as on SIGTERM
+	shuttingDown   atomic.Bool                            // set once shutdown began; new transactions are refused
+	partitioned    bool                                   // any node may accept /transaction; the handler routes it
+	chaos          *chaosInjector                         // failure injection; nil unless enabled
+	dashboardUser  string                                 // basic-auth user for dashboard routes (optional)
+	dashboardPass  string                                 // basic-auth password for dashboard routes (optional)
+	corsOrigins    []string                               // allowed CORS origins for JSON routes (optional)
+	trustProxy     bool                                   // honor X-Forwarded-* headers when logging clients
+	forwardKey     []byte                                 // verifies transactions forwarded by other coordinators
+	streams        context.Context                        // canceled on shutdown to end /transactions/stream responses
+	closeStreams   context.CancelFunc
+	async          asyncJobs // transactions accepted with POST /transaction?async=true
+
+	getMetricsHistory func(addr string) (*protocol.MetricsHistoryResponse, error) // recent metric samples of a node
+	onSyncCluster     func(req *protocol.SyncClusterRequest) (*protocol.SyncClusterResponse, error)
+	getClusterHealth  func() *protocol.ClusterHealthResponse
 }
 
+// streamKeepalive is how often an idle /transactions/stream sends a comment,
+// so proxies do not close the connection.
+const streamKeepalive = 15 * time.Second
+
 // NewHTTPServer creates a new HTTP server for a node
 func NewHTTPServer(n *node.Node) *HTTPServer {
 	s := &HTTPServer{
 		node: n,
 		mux:  http.NewServeMux(),
 	}
+	s.streams, s.closeStreams = context.WithCancel(context.Background())
 	s.setupRoutes()
 	return s
 }
 
 // SetTransactionHandler sets the callback for handling transaction requests (master only)
-func (s *HTTPServer) SetTransactionHandler(handler func(payload any) (*protocol.TransactionResponse, error)) {
+func (s *HTTPServer) SetTransactionHandler(handler func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error)) {
 	s.onTransaction = handler
 }
 
@@ -45,16 +85,35 @@ func (s *HTTPServer) SetJoinHandler(handler func(addr string) (*protocol.JoinRes
 	s.onJoin = handler
 }
 
-// SetAddNodeHandler sets the callback for adding nodes to the cluster
-func (s *HTTPServer) SetAddNodeHandler(handler func(addr, name, database string) error) {
+// SetAddNodeHandler sets the callback for adding nodes to the cluster. The
+// handler reports whether the address was already a member whose metadata it
+// updated instead.
+func (s *HTTPServer) SetAddNodeHandler(handler func(req *protocol.AddNodeRequest) (bool, error)) {
 	s.onAddNode = handler
 }
 
+// SetSyncClusterHandler sets the callback that converges the membership to
+// the desired set posted to /cluster/sync.
+func (s *HTTPServer) SetSyncClusterHandler(handler func(req *protocol.SyncClusterRequest) (*protocol.SyncClusterResponse, error)) {
+	s.onSyncCluster = handler
+}
+
+// SetClusterHealthHandler sets the callback that scores the cluster for
+// GET /cluster/health.
+func (s *HTTPServer) SetClusterHealthHandler(handler func() *protocol.ClusterHealthResponse) {
+	s.getClusterHealth = handler
+}
+
 // SetRemoveNodeHandler sets the callback for removing nodes from the cluster
 func (s *HTTPServer) SetRemoveNodeHandler(handler func(addr string) error) {
 	s.onRemoveNode = handler
 }
 
+// SetDisableNodeHandler sets the callback for disabling and re-enabling nodes
+func (s *HTTPServer) SetDisableNodeHandler(handler func(addr string, disabled bool) error) {
+	s.onSetDisabled = handler
+}
+
 // SetNameHandler sets the callback for naming nodes.
 func (s *HTTPServer) SetNameHandler(handler func(addr, name string) error) {
 	s.onSetName = handler
@@ -65,42 +124,187 @@ func (s *HTTPServer) SetTransactionsHandler(handler func(addr string, page, limi
 	s.onListTx = handler
 }
 
+// EnableChaos turns on failure injection controlled through /debug/chaos.
+// Chaos mode is off by default and should only be used for testing and drills.
+func (s *HTTPServer) EnableChaos() {
+	if s.chaos == nil {
+		s.chaos = &chaosInjector{}
+	}
+	logging.Warnf("[Node %s] Chaos mode enabled at /debug/chaos", s.node.Addr)
+}
+
+// SetDashboardAuth protects the dashboard and cluster summary with HTTP basic auth.
+// Leaving user empty disables authentication.
+func (s *HTTPServer) SetDashboardAuth(user, pass string) {
+	s.dashboardUser = user
+	s.dashboardPass = pass
+}
+
+// SetHeartbeatIntervalHandler sets the callback for changing the heartbeat interval.
+func (s *HTTPServer) SetHeartbeatIntervalHandler(handler func(interval time.Duration) error) {
+	s.onHeartbeat = handler
+}
+
+// SetMaintenanceHandler sets the callback that moves a cluster member into or
+// out of maintenance mode (/cluster/maintenance). The node's own /maintenance
+// endpoint needs no handler.
+func (s *HTTPServer) SetMaintenanceHandler(handler func(req *protocol.MaintenanceRequest) (*protocol.MaintenanceResponse, error)) {
+	s.onMaintenance = handler
+}
+
+// SetNodeResolver sets the callback that maps a node reference (address or
+// display name) to the member's address. Endpoints that address a node accept
+// either once it is set.
+func (s *HTTPServer) SetNodeResolver(resolver func(ref string) (string, error)) {
+	s.resolveNode = resolver
+}
+
+// SetReconcileHandler sets the callback that forces a transaction's nodes to one outcome.
+func (s *HTTPServer) SetReconcileHandler(handler func(txID string) (*protocol.ReconcileResponse, error)) {
+	s.onReconcile = handler
+}
+
+// SetTransactionDetailHandler sets the callback that aggregates a transaction's status across the cluster.
+func (s *HTTPServer) SetTransactionDetailHandler(handler func(txID string) (*protocol.TransactionDetailResponse, error)) {
+	s.onTxDetail = handler
+}
+
+// SetMasterHandler sets the callback that reports the current master address.
+func (s *HTTPServer) SetMasterHandler(handler func() string) {
+	s.getMaster = handler
+}
+
+// SetInflightHandler sets the callback that lists transactions the coordinator is currently driving.
+func (s *HTTPServer) SetInflightHandler(handler func() []protocol.InflightTransaction) {
+	s.onInflight = handler
+}
+
+// SetCoordinatorMetricsHandler sets the callback that reports the coordinator's abort counts by category.
+func (s *HTTPServer) SetCoordinatorMetricsHandler(handler func() map[string]int64) {
+	s.getAborts = handler
+}
+
+// SetMetricsHistoryHandler sets the callback serving GET /metrics/history.
+// addr is the member asked for with ?address=, empty for this node.
+func (s *HTTPServer) SetMetricsHistoryHandler(handler func(addr string) (*protocol.MetricsHistoryResponse, error)) {
+	s.getMetricsHistory = handler
+}
+
+// SetReadinessCheck makes /transaction answer 503 while check returns false.
+func (s *HTTPServer) SetReadinessCheck(check func() bool) {
+	s.isReady = check
+}
+
+// SetElectionCheck makes /transaction on a non-master answer 503 while check
+// returns true, so clients retry instead of failing during a failover.
+func (s *HTTPServer) SetElectionCheck(check func() bool) {
+	s.isElecting = check
+}
+
+// SetNodeDetailHandler sets the callback that fills the extended node view of /cluster/nodes?detail=true.
+func (s *HTTPServer) SetNodeDetailHandler(handler func(addr string) *protocol.NodeDetail) {
+	s.getNodeDetail = handler
+}
+
+// SetShutdownHandler sets the callback POST /admin/shutdown runs to drain and
+// stop the process. It runs in its own goroutine after the 202 is sent.
+func (s *HTTPServer) SetShutdownHandler(handler func()) {
+	s.onShutdown = handler
+}
+
+// SetPartitionRouting lets a non-master accept POST /transaction when the
+// keyspace is partitioned between coordinators. The transaction handler then
+// decides whether to coordinate, forward or refuse the request.
+func (s *HTTPServer) SetPartitionRouting(enabled bool) {
+	s.partitioned = enabled
+}
+
+// BeginShutdown makes the server refuse new transactions and report the node
+// as draining. The shutdown path calls it first, whether started by a signal
+// or by /admin/shutdown.
+func (s *HTTPServer) BeginShutdown() {
+	s.shuttingDown.Store(true)
+	s.node.SetDraining(true)
+}
+
+// ShuttingDown reports whether shutdown has begun.
+func (s *HTTPServer) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
 // SetClusterInfoHandler sets the callback for getting cluster info
 func (s *HTTPServer) SetClusterInfoHandler(handler func() *protocol.ClusterInfoResponse) {
 	s.getClusterInfo = handler
 }
 
+// setupRoutes registers the handlers. CORS, which lets allowlisted browser
+// origins send credentials, only wraps the read routes clients and dashboards
+// poll and POST /transaction; node-to-node and mutating admin routes never
+// answer cross-origin requests.
 func (s *HTTPServer) setupRoutes() {
-	s.mux.HandleFunc("/health", s.handleHealth)
-	s.mux.HandleFunc("/role", s.handleRole)
-	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/health", s.withCORS(s.handleHealth))
+	s.mux.HandleFunc("/ready", s.withCORS(s.handleReady))
+	s.mux.HandleFunc("/role", s.withCORS(s.handleRole))
+	s.mux.HandleFunc("/metrics", s.withCORS(s.handleMetrics))
+	s.mux.HandleFunc("/metrics/history", s.withCORS(s.handleMetricsHistory))
 	s.mux.HandleFunc("/prepare", s.handlePrepare)
 	s.mux.HandleFunc("/commit", s.handleCommit)
 	s.mux.HandleFunc("/abort", s.handleAbort)
-	s.mux.HandleFunc("/transaction", s.handleTransaction)
+	s.mux.HandleFunc("/observe", s.handleObserve)
+	s.mux.HandleFunc("/transaction", s.withCORS(s.handleTransaction))
+	s.mux.HandleFunc("/transaction/{id}", s.withCORS(s.handleGetTransaction))
+	s.mux.HandleFunc("/transaction/{id}/detail", s.withCORS(s.handleTransactionDetail))
+	s.mux.HandleFunc("/transaction/{id}/reconcile", s.handleReconcile)
+	s.mux.HandleFunc("/coordinator/inflight", s.withCORS(s.handleInflight))
+	s.mux.HandleFunc("/coordinator/metrics", s.withCORS(s.handleCoordinatorMetrics))
 	s.mux.HandleFunc("/cluster/join", s.handleJoin)
-	s.mux.HandleFunc("/cluster/nodes", s.handleClusterNodes)
+	s.mux.HandleFunc("/cluster/master", s.withCORS(s.handleMaster))
+	s.mux.HandleFunc("/cluster/nodes", s.withCORS(s.handleClusterNodes))
 	s.mux.HandleFunc("/cluster/add", s.handleAddNode)
 	s.mux.HandleFunc("/cluster/remove", s.handleRemoveNode)
-	s.mux.HandleFunc("/cluster/summary", s.handleClusterSummary)
+	s.mux.HandleFunc("/cluster/sync", s.handleSyncCluster)
+	s.mux.HandleFunc("/cluster/disable", s.handleSetDisabled(true))
+	s.mux.HandleFunc("/cluster/enable", s.handleSetDisabled(false))
+	s.mux.HandleFunc("/cluster/maintenance", s.handleClusterMaintenance)
+	s.mux.HandleFunc("/maintenance", s.handleMaintenance)
+	s.mux.HandleFunc("/cluster/health", s.withCORS(s.handleClusterHealth))
+	s.mux.HandleFunc("/cluster/summary", s.withCORS(s.requireDashboardAuth(s.handleClusterSummary)))
 	s.mux.HandleFunc("/cluster/name", s.handleSetName)
-	s.mux.HandleFunc("/transactions", s.handleTransactions)
-	s.mux.HandleFunc("/dashboard", s.handleDashboard)
-	s.mux.HandleFunc("/ui", s.handleDashboard)
-	s.mux.HandleFunc("/", s.handleDashboard)
+	s.mux.HandleFunc("/cluster/heartbeat-interval", s.handleHeartbeatInterval)
+	s.mux.HandleFunc("/transactions", s.withCORS(s.handleTransactions))
+	s.mux.HandleFunc("/transactions/stream", s.withCORS(s.handleTransactionStream))
+	s.mux.HandleFunc("/prepared", s.handlePrepared)
+	s.mux.HandleFunc("/rows", s.requireAdminAuth(s.handleReadRow))
+	s.mux.HandleFunc("/debug/chaos", s.handleChaos)
+	s.mux.HandleFunc("/admin/shutdown", s.requireAdminAuth(s.handleShutdown))
+	s.mux.HandleFunc("/dashboard", s.requireDashboardAuth(s.handleDashboard))
+	s.mux.HandleFunc("/ui", s.requireDashboardAuth(s.handleDashboard))
+	s.mux.HandleFunc("/", s.requireDashboardAuth(s.handleDashboard))
 }
 
 // Start starts the HTTP server
 func (s *HTTPServer) Start() error {
 	s.server = &http.Server{
-		Addr:    s.node.Addr,
+		Addr:    s.node.ListenAddr(),
 		Handler: s.mux,
 	}
+	// Shutdown waits for open requests, so end the streams that never finish.
+	s.server.RegisterOnShutdown(s.closeStreams)
 
-	log.Printf("[HTTPServer] Starting server on %s", s.node.Addr)
+	if s.node.ListenAddr() != s.node.Addr {
+		logging.Infof("[HTTPServer] Starting server on %s (advertised as %s)", s.node.ListenAddr(), s.node.Addr)
+	} else {
+		logging.Infof("[HTTPServer] Starting server on %s", s.node.Addr)
+	}
 	return s.server.ListenAndServe()
 }
 
+// Handler returns the server's routes so they can be served on another
+// listener (e.g. an httptest.Server) instead of through Start.
+func (s *HTTPServer) Handler() http.Handler {
+	return s.mux
+}
+
 // Stop stops the HTTP server
 func (s *HTTPServer) Stop() error {
 	if s.server != nil {
@@ -109,27 +313,109 @@ func (s *HTTPServer) Stop() error {
 	return nil
 }
 
-// handleHealth responds to health check requests
+// Shutdown stops the HTTP server gracefully: it stops listening and waits for
+// requests in progress and accepted async transactions to finish until ctx is
+// done.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	if s.server != nil {
+		if err := s.server.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	return s.WaitAsync(ctx)
+}
+
+// handleHealth responds to health check requests. HEAD is a cheap liveness
+// probe: it answers 200 with no body and skips the database ping.
 func (s *HTTPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+
+	maintenance := s.node.GetMaintenance()
+	if maintenance {
+		w.Header().Set(protocol.MaintenanceHeader, "true")
+	}
+	state := s.node.State()
+	w.Header().Set(protocol.StateHeader, string(state))
+
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	resp := protocol.HealthResponse{
-		Status:  "OK",
-		Address: s.node.Addr,
-		Role:    string(s.node.GetRole()),
+		Status:      "OK",
+		Address:     s.node.Addr,
+		Role:        string(s.node.GetRole()),
+		Version:     protocol.ProtocolVersion,
+		Database:    protocol.DatabaseNone,
+		Time:        time.Now(),
+		Maintenance: maintenance,
+
+		State:    state,
+		Draining: state == protocol.NodeStateDraining,
+	}
+
+	if s.node.HasDB() {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		resp.Database = protocol.DatabaseOK
+		if s.node.DBDegraded() || s.node.PingDB(ctx) != nil {
+			// Still 200 so heartbeats keep the node alive; callers inspect Status.
+			resp.Status = "DEGRADED"
+			resp.Database = protocol.DatabaseUnreachable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleReady answers whether the node can take part in new transactions:
+// 200 when it can, 503 while it is shutting down, in maintenance or cannot
+// reach its database. Unlike /health it fails for a node that is up.
+func (s *HTTPServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+
+	resp := protocol.ReadyResponse{Ready: true, Address: s.node.Addr}
+	switch {
+	case s.ShuttingDown():
+		resp.Reason = "shutting down"
+	case s.node.GetMaintenance():
+		resp.Reason = "maintenance"
+	case s.node.DBDegraded():
+		resp.Reason = "database unreachable"
+	case s.node.HasDB():
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := s.node.PingDB(ctx); err != nil {
+			resp.Reason = "database unreachable"
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Reason != "" {
+		resp.Ready = false
+		status = http.StatusServiceUnavailable
+	}
+
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(resp)
 }
 
 // handleRole responds with the node's current role
 func (s *HTTPServer) handleRole(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
@@ -142,10 +428,31 @@ func (s *HTTPServer) handleRole(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleMaster reports the current master from this node's cluster view
+func (s *HTTPServer) handleMaster(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	masterAddr := s.localMaster()
+	if s.getMaster != nil {
+		masterAddr = s.getMaster()
+	}
+	if masterAddr == "" {
+		writeError(w, protocol.ErrCodeNoMaster, "No master known", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protocol.MasterResponse{
+		MasterAddr: masterAddr,
+		ReportedBy: s.node.Addr,
+	})
+}
+
 // handleMetrics returns the local node's metrics from the database
 func (s *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
@@ -154,38 +461,101 @@ func (s *HTTPServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(metrics)
 }
 
+// handleMetricsHistory returns a node's recent metric samples, oldest first:
+// this node's, or with ?address= another member's, fetched from it.
+func (s *HTTPServer) handleMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	if s.getMetricsHistory == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Metrics history is not sampled on this node", http.StatusNotFound)
+		return
+	}
+
+	addr := r.URL.Query().Get("address")
+	if addr != "" {
+		var err error
+		if addr, err = s.nodeAddr(addr); err != nil {
+			writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := s.getMetricsHistory(addr)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
 // handlePrepare handles prepare phase requests
 func (s *HTTPServer) handlePrepare(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodPost) {
 		return
 	}
 
 	var req protocol.PrepareRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendPrepareResponse(w, protocol.StatusAbort, "Invalid request body", http.StatusBadRequest)
+		sendPrepareResponse(w, protocol.StatusAbort, "Invalid request body", "", 0, http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[Node %s] Received prepare request for transaction %s", s.node.Addr, req.TransactionID)
+	logging.Debugf("[Node %s] Received prepare request for transaction %s", s.node.Addr, req.TransactionID)
+
+	if s.chaos != nil {
+		if err := s.chaos.beforePrepare(s.node.Addr); err != nil {
+			sendPrepareResponse(w, protocol.StatusAbort, err.Error(), "", 0, http.StatusInternalServerError)
+			return
+		}
+	}
 
-	ready, err := s.node.Prepare(req.TransactionID, req.Payload)
+	ready, err := s.node.PrepareFor(req.Coordinator, req.TransactionID, req.Payload)
+	if errors.Is(err, node.ErrAtCapacity) {
+		// 429 rather than 500, so the client hands the vote to the coordinator
+		// instead of retrying into a node that is already full.
+		sendPrepareResponse(w, protocol.StatusAbort, err.Error(), protocol.ErrCodeAtCapacity, 0, http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, node.ErrResourcePressure) {
+		sendPrepareResponse(w, protocol.StatusAbort, err.Error(), protocol.ErrCodeResourcePressure, 0, http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, node.ErrTableNotPermitted) {
+		// 403 rather than 500: retrying cannot change the vote.
+		sendPrepareResponse(w, protocol.StatusAbort, err.Error(), protocol.ErrCodeTableNotPermitted, 0, http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, node.ErrCoordinateOnly) {
+		sendPrepareResponse(w, protocol.StatusAbort, err.Error(), protocol.ErrCodeLocalNoDatabase, 0, http.StatusForbidden)
+		return
+	}
 	if !ready || err != nil {
 		errMsg := "Prepare failed"
 		if err != nil {
 			errMsg = err.Error()
 		}
-		sendPrepareResponse(w, protocol.StatusAbort, errMsg, http.StatusInternalServerError)
+		sendPrepareResponse(w, protocol.StatusAbort, errMsg, "", 0, http.StatusInternalServerError)
 		return
 	}
 
-	sendPrepareResponse(w, protocol.StatusReady, "", http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protocol.PrepareResponse{
+		Status:       protocol.StatusReady,
+		RowsAffected: s.node.RowsAffected(req.TransactionID),
+		Returned:     s.node.Returned(req.TransactionID),
+	})
 }
 
-func sendPrepareResponse(w http.ResponseWriter, status protocol.PrepareStatus, errMsg string, httpStatus int) {
+func sendPrepareResponse(w http.ResponseWriter, status protocol.PrepareStatus, errMsg, code string, rowsAffected int64, httpStatus int) {
 	resp := protocol.PrepareResponse{
-		Status: status,
-		Error:  errMsg,
+		Status:       status,
+		Error:        errMsg,
+		RowsAffected: rowsAffected,
+		Code:         code,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus)
@@ -194,8 +564,7 @@ func sendPrepareResponse(w http.ResponseWriter, status protocol.PrepareStatus, e
 
 // handleCommit handles commit requests
 func (s *HTTPServer) handleCommit(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodPost) {
 		return
 	}
 
@@ -205,20 +574,46 @@ func (s *HTTPServer) handleCommit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[Node %s] Received commit request for transaction %s", s.node.Addr, req.TransactionID)
+	logging.Debugf("[Node %s] Received commit request for transaction %s", s.node.Addr, req.TransactionID)
+
+	if s.chaos != nil {
+		if err := s.chaos.beforeCommit(s.node.Addr); err != nil {
+			sendCommitResponse(w, false, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.Takeover {
+		if err := s.node.CommitTakeover(req.Coordinator, req.TransactionID); err != nil {
+			sendCommitResponse(w, false, err.Error(), decisionErrorStatus(err))
+			return
+		}
+		sendCommitResponse(w, true, "", http.StatusOK)
+		return
+	}
 
-	if err := s.node.Commit(req.TransactionID); err != nil {
-		sendCommitResponse(w, false, err.Error(), http.StatusInternalServerError)
+	if !req.IncludeChanges {
+		if err := s.node.CommitFor(req.Coordinator, req.TransactionID); err != nil {
+			sendCommitResponse(w, false, err.Error(), decisionErrorStatus(err))
+			return
+		}
+		sendCommitResponse(w, true, "", http.StatusOK)
 		return
 	}
 
-	sendCommitResponse(w, true, "", http.StatusOK)
+	changes, err := s.node.CommitForChanges(req.Coordinator, req.TransactionID)
+	if err != nil {
+		sendCommitResponse(w, false, err.Error(), decisionErrorStatus(err))
+		return
+	}
+	sendCommitResponse(w, true, "", http.StatusOK, changes...)
 }
 
-func sendCommitResponse(w http.ResponseWriter, success bool, errMsg string, httpStatus int) {
+func sendCommitResponse(w http.ResponseWriter, success bool, errMsg string, httpStatus int, changes ...protocol.AppliedChange) {
 	resp := protocol.CommitResponse{
 		Success: success,
 		Error:   errMsg,
+		Changes: changes,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus)
@@ -227,8 +622,7 @@ func sendCommitResponse(w http.ResponseWriter, success bool, errMsg string, http
 
 // handleAbort handles abort requests
 func (s *HTTPServer) handleAbort(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodPost) {
 		return
 	}
 
@@ -238,16 +632,38 @@ func (s *HTTPServer) handleAbort(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[Node %s] Received abort request for transaction %s", s.node.Addr, req.TransactionID)
+	if req.Reason != "" {
+		logging.Debugf("[Node %s] Received abort request for transaction %s: %s", s.node.Addr, req.TransactionID, req.Reason)
+	} else {
+		logging.Debugf("[Node %s] Received abort request for transaction %s", s.node.Addr, req.TransactionID)
+	}
+
+	if s.chaos != nil {
+		s.chaos.beforeAbort()
+	}
 
-	if err := s.node.Abort(req.TransactionID); err != nil {
-		sendAbortResponse(w, false, err.Error(), http.StatusInternalServerError)
+	abort := s.node.AbortFor
+	if req.Takeover {
+		abort = s.node.AbortTakeover
+	}
+	if err := abort(req.Coordinator, req.TransactionID); err != nil {
+		sendAbortResponse(w, false, err.Error(), decisionErrorStatus(err))
 		return
 	}
 
 	sendAbortResponse(w, true, "", http.StatusOK)
 }
 
+// decisionErrorStatus maps a commit/abort failure to an HTTP status: a
+// decision from a foreign coordinator or a refused takeover is a conflict,
+// anything else a server error.
+func decisionErrorStatus(err error) int {
+	if errors.Is(err, node.ErrForeignCoordinator) || errors.Is(err, node.ErrTakeoverRefused) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 func sendAbortResponse(w http.ResponseWriter, success bool, errMsg string, httpStatus int) {
 	resp := protocol.AbortResponse{
 		Success: success,
@@ -258,290 +674,728 @@ func sendAbortResponse(w http.ResponseWriter, success bool, errMsg string, httpS
 	json.NewEncoder(w).Encode(resp)
 }
 
-// handleTransaction handles 2PC transaction requests (master only)
-func (s *HTTPServer) handleTransaction(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleObserve records an OBSERVED marker for a transaction this node did not take part in
+func (s *HTTPServer) handleObserve(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
 		return
 	}
 
-	// Only master can handle transactions
-	if s.node.GetRole() != protocol.RoleMaster {
-		resp := protocol.TransactionResponse{
-			Success: false,
-			Error:   "This node is not the master",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	var req protocol.ObserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TransactionID == "" {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	var req protocol.TransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		resp := protocol.TransactionResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.node.RecordObserved(ctx, req.TransactionID, req.Payload); err != nil {
+		logging.Warnf("[Node %s] Failed to record observed transaction %s: %v", s.node.Addr, req.TransactionID, err)
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("[Master %s] Received transaction request", s.node.Addr)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protocol.ObserveResponse{Success: true})
+}
 
-	if s.onTransaction == nil {
-		resp := protocol.TransactionResponse{
-			Success: false,
-			Error:   "Transaction handler not configured",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+// handleTransaction handles 2PC transaction requests (master only)
+func (s *HTTPServer) handleTransaction(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
 		return
 	}
 
-	result, err := s.onTransaction(req.Payload)
-	if err != nil {
-		resp := protocol.TransactionResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+	if s.ShuttingDown() {
+		writeError(w, protocol.ErrCodeUnavailable, "Node is shutting down", http.StatusServiceUnavailable)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if result.Success {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-	json.NewEncoder(w).Encode(result)
-}
+	// Only master can handle transactions, unless the handler routes by partition
+	if !s.partitioned && s.node.GetRole() != protocol.RoleMaster {
+		if s.isElecting != nil && s.isElecting() {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, protocol.ErrCodeElectionInProgress, "Master election in progress", http.StatusServiceUnavailable)
+			return
+		}
 
-// handleJoin handles requests from new nodes wanting to join the cluster
-func (s *HTTPServer) handleJoin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, protocol.ErrCodeNotMaster, "This node is not the master", http.StatusBadRequest)
 		return
 	}
 
-	var req protocol.JoinRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		resp := protocol.JoinResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	if s.isReady != nil && !s.isReady() {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, protocol.ErrCodeNotReady, "Master is not ready: waiting for the first heartbeat round", http.StatusServiceUnavailable)
 		return
 	}
 
-	if s.onJoin == nil {
-		resp := protocol.JoinResponse{
-			Success: false,
-			Error:   "Join handler not configured",
+	async := false
+	if v := r.URL.Query().Get("async"); v != "" {
+		var err error
+		if async, err = strconv.ParseBool(v); err != nil {
+			writeError(w, protocol.ErrCodeInvalidRequest, "async must be true or false", http.StatusBadRequest)
+			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
-		return
 	}
 
-	log.Printf("[Node %s] Received join request from %s", s.node.Addr, req.Address)
-
-	result, err := s.onJoin(req.Address)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		resp := protocol.JoinResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if result.Success {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusBadRequest)
-	}
-	json.NewEncoder(w).Encode(result)
-}
-
-// handleClusterNodes returns the current cluster membership
-func (s *HTTPServer) handleClusterNodes(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var req protocol.TransactionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	req.TransactionID, req.Forwarded = s.forwardedMarker(r, body)
 
-	s.writeClusterInfo(w)
-}
+	logging.Debugf("[Master %s] Received transaction request from %s (%s)", s.node.Addr, s.clientAddr(r), s.requestScheme(r))
 
-// handleAddNode handles requests to add a new node to the cluster
-func (s *HTTPServer) handleAddNode(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if s.onTransaction == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Transaction handler not configured", http.StatusInternalServerError)
 		return
 	}
 
-	var req protocol.AddNodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		resp := protocol.AddNodeResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	if async {
+		s.startAsyncTransaction(w, &req)
 		return
 	}
 
-	if req.Address == "" {
-		resp := protocol.AddNodeResponse{
-			Success: false,
-			Error:   "Address is required",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	result, err := s.onTransaction(&req)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
 		return
 	}
 
-	if s.onAddNode == nil {
-		resp := protocol.AddNodeResponse{
-			Success: false,
-			Error:   "Add node handler not configured",
-		}
+	if result.Success {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
 		return
 	}
 
-	log.Printf("[Node %s] Adding new node: %s (db: %s)", s.node.Addr, req.Address, req.Database)
-
-	if err := s.onAddNode(req.Address, req.Name, req.Database); err != nil {
-		resp := protocol.AddNodeResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
-		return
+	code := result.Code
+	if code == "" {
+		code = protocol.ErrCodeTransactionAborted
 	}
-
-	resp := protocol.AddNodeResponse{
-		Success: true,
+	status := http.StatusInternalServerError
+	switch code {
+	case protocol.ErrCodeNotMaster:
+		// Refused by the partition router, as by the role check above.
+		status = http.StatusBadRequest
+	case protocol.ErrCodeWrongCoordinator:
+		status = http.StatusConflict
+	case protocol.ErrCodeNoMaster, protocol.ErrCodeUnavailable:
+		status = http.StatusServiceUnavailable
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+	if result.RetryAfterMs > 0 {
+		// Throttled by the coordinator's rate limit; Retry-After is in whole seconds.
+		w.Header().Set("Retry-After", strconv.FormatInt((result.RetryAfterMs+999)/1000, 10))
+		status = http.StatusTooManyRequests
+	}
+	writeErrorResponse(w, protocol.ErrorResponse{
+		Error:         protocol.APIError{Code: code, Message: result.Error},
+		TransactionID: result.TransactionID,
+		RetryAfterMs:  result.RetryAfterMs,
+		Excluded:      result.Excluded,
+	}, status)
 }
 
-// handleRemoveNode handles requests to remove a node from the cluster
-func (s *HTTPServer) handleRemoveNode(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleGetTransaction returns this node's record of a single transaction,
+// including the progress of an async transaction this node accepted.
+func (s *HTTPServer) handleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
-	var req protocol.RemoveNodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		resp := protocol.RemoveNodeResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	txID := r.PathValue("id")
+	job, async := s.async.get(txID)
+
+	rec, err := s.node.GetTransaction(ctx, txID)
+	if err != nil && !async {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
 		return
 	}
-
-	if req.Address == "" {
-		resp := protocol.RemoveNodeResponse{
-			Success: false,
-			Error:   "Address is required",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+	if async {
+		rec = job.record(txID, rec)
+	}
+	if rec == nil {
+		writeError(w, protocol.ErrCodeTransactionNotFound, "Transaction not found", http.StatusNotFound)
 		return
 	}
 
-	if s.onRemoveNode == nil {
-		resp := protocol.RemoveNodeResponse{
-			Success: false,
-			Error:   "Remove node handler not configured",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// handlePrepared lists the Postgres prepared transactions this node holds
+func (s *HTTPServer) handlePrepared(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
-	log.Printf("[Node %s] Removing node: %s", s.node.Addr, req.Address)
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
 
-	if err := s.onRemoveNode(req.Address); err != nil {
-		resp := protocol.RemoveNodeResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+	xacts, err := s.node.ListPreparedXacts(ctx)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
 		return
 	}
 
-	resp := protocol.RemoveNodeResponse{
-		Success: true,
+	resp := protocol.PreparedXactsResponse{
+		Node:  s.node.Addr,
+		Xacts: []protocol.PreparedXact{},
+	}
+	for _, x := range xacts {
+		resp.Xacts = append(resp.Xacts, x)
+		if x.Stale {
+			resp.Stale++
+		}
 	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
-// handleClusterSummary returns enriched cluster info with metrics
-func (s *HTTPServer) handleClusterSummary(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleReadRow returns this node's copy of one row, looked up by key, so
+// copies can be compared across nodes.
+func (s *HTTPServer) handleReadRow(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	q := r.URL.Query()
+	table, keyCol, key := q.Get("table"), q.Get("key_col"), q.Get("key")
+	if table == "" || keyCol == "" || !q.Has("key") {
+		writeError(w, protocol.ErrCodeInvalidRequest, "table, key_col and key are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	row, hasDB, err := s.node.ReadRow(ctx, table, keyCol, key)
+	switch {
+	case errors.Is(err, node.ErrTableNotPermitted):
+		writeError(w, protocol.ErrCodeTableNotPermitted, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, node.ErrAmbiguousKey):
+		writeError(w, protocol.ErrCodeConflict, err.Error(), http.StatusConflict)
+		return
+	case protocol.ErrorCode(err, "") == protocol.ErrCodeInvalidRequest:
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+		return
+	case err != nil:
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protocol.RowResponse{
+		Node:      s.node.Addr,
+		Table:     table,
+		KeyColumn: keyCol,
+		Key:       key,
+		HasDB:     hasDB,
+		Found:     row != nil,
+		Row:       row,
+	})
+}
+
+// handleTransactionDetail returns a transaction's status on every cluster member (master only)
+func (s *HTTPServer) handleTransactionDetail(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	if s.onTxDetail == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Transaction detail handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	detail, err := s.onTxDetail(r.PathValue("id"))
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// handleReconcile drives a transaction's nodes to one outcome (master only)
+func (s *HTTPServer) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if s.onReconcile == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Reconcile handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := s.onReconcile(r.PathValue("id"))
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeConflict, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleInflight lists the coordinator's active transactions (master only)
+func (s *HTTPServer) handleInflight(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	// A node without a coordinator has nothing in flight.
+	resp := protocol.InflightResponse{
+		Transactions: []protocol.InflightTransaction{},
+		Generated:    time.Now(),
+	}
+	if s.onInflight != nil {
+		resp.Transactions = s.onInflight()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleCoordinatorMetrics reports why the coordinator's transactions aborted (master only)
+func (s *HTTPServer) handleCoordinatorMetrics(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	// A node without a coordinator has aborted nothing.
+	resp := protocol.CoordinatorMetricsResponse{
+		Aborts:    map[string]int64{},
+		Generated: time.Now(),
+	}
+	if s.getAborts != nil {
+		resp.Aborts = s.getAborts()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleJoin handles requests from new nodes wanting to join the cluster
+func (s *HTTPServer) handleJoin(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.JoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if s.onJoin == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Join handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	logging.Infof("[Node %s] Received join request from %s (client %s)", s.node.Addr, req.Address, s.clientAddr(r))
+
+	result, err := s.onJoin(req.Address)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+		return
+	}
+
+	if !result.Success {
+		writeError(w, protocol.ErrCodeInvalidRequest, result.Error, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleClusterNodes returns the current cluster membership
+func (s *HTTPServer) handleClusterNodes(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	detail, _ := strconv.ParseBool(r.URL.Query().Get("detail"))
+	if !detail {
+		s.writeClusterInfo(w)
+		return
+	}
+
+	info := s.clusterInfo()
+	if info == nil {
+		writeError(w, protocol.ErrCodeUnavailable, "Cluster info unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	for i := range info.Nodes {
+		switch {
+		case s.getNodeDetail != nil:
+			info.Nodes[i].Detail = s.getNodeDetail(info.Nodes[i].Address)
+		case info.Nodes[i].Address == s.node.Addr:
+			info.Nodes[i].Detail = s.localNodeDetail()
+		}
+	}
+	if info.Generated.IsZero() {
+		info.Generated = time.Now()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleAddNode handles requests to add a new node to the cluster
+func (s *HTTPServer) handleAddNode(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.AddNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Address == "" {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	if s.onAddNode == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Add node handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	logging.Infof("[Node %s] Adding new node: %s (db: %s)", s.node.Addr, req.Address, req.Database)
+
+	updated, err := s.onAddNode(&req)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+		return
+	}
+
+	resp := protocol.AddNodeResponse{
+		Success: true,
+		Updated: updated,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleRemoveNode handles requests to remove a node from the cluster
+func (s *HTTPServer) handleRemoveNode(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.RemoveNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Address == "" {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	if s.onRemoveNode == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Remove node handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	addr, err := s.nodeAddr(req.Address)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	req.Address = addr
+
+	logging.Infof("[Node %s] Removing node: %s", s.node.Addr, req.Address)
+
+	if err := s.onRemoveNode(req.Address); err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+		return
+	}
+
+	resp := protocol.RemoveNodeResponse{
+		Success: true,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleSyncCluster handles requests to converge the membership to a desired
+// set. Posting the same set again changes nothing.
+func (s *HTTPServer) handleSyncCluster(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.SyncClusterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	for _, n := range req.Nodes {
+		if n.Address == "" {
+			writeError(w, protocol.ErrCodeInvalidRequest, "Every node needs an address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if s.onSyncCluster == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Sync cluster handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	logging.Infof("[Node %s] Syncing membership to %d nodes", s.node.Addr, len(req.Nodes))
+
+	resp, err := s.onSyncCluster(&req)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+		return
+	}
+
+	resp.Success = true
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleSetDisabled returns a handler that disables or re-enables a node while keeping it in the cluster
+func (s *HTTPServer) handleSetDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethods(w, r, http.MethodPost) {
+			return
+		}
+
+		var req protocol.DisableNodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.Address == "" {
+			writeError(w, protocol.ErrCodeInvalidRequest, "Address is required", http.StatusBadRequest)
+			return
+		}
+
+		if s.onSetDisabled == nil {
+			writeError(w, protocol.ErrCodeNotConfigured, "Disable node handler not configured", http.StatusInternalServerError)
+			return
+		}
+
+		addr, err := s.nodeAddr(req.Address)
+		if err != nil {
+			writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		req.Address = addr
+
+		logging.Infof("[Node %s] Setting node %s disabled=%t", s.node.Addr, req.Address, disabled)
+
+		if err := s.onSetDisabled(req.Address, disabled); err != nil {
+			writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(protocol.DisableNodeResponse{Success: true})
+	}
+}
+
+// handleMaintenance puts this node into or out of maintenance mode, draining
+// prepared transactions before answering when it is turned on
+func (s *HTTPServer) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := s.node.ApplyMaintenance(req.Enabled, time.Duration(req.DrainTimeoutMs)*time.Millisecond)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleClusterMaintenance moves a cluster member into or out of maintenance mode (master only)
+func (s *HTTPServer) handleClusterMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Address == "" {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	if s.onMaintenance == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Maintenance handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	addr, err := s.nodeAddr(req.Address)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+		return
+	}
+	req.Address = addr
+
+	logging.Infof("[Node %s] Setting node %s maintenance=%t", s.node.Addr, req.Address, req.Enabled)
+
+	resp, err := s.onMaintenance(&req)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// nodeAddr resolves a node reference from a request to the member's address.
+// Without a resolver the reference is taken as an address.
+func (s *HTTPServer) nodeAddr(ref string) (string, error) {
+	if s.resolveNode == nil {
+		return ref, nil
+	}
+	return s.resolveNode(ref)
+}
+
+// handleClusterSummary returns enriched cluster info with metrics
+func (s *HTTPServer) handleClusterSummary(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
 	s.writeClusterInfo(w)
 }
 
-// handleTransactions returns paginated transactions for a node.
-func (s *HTTPServer) handleTransactions(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleClusterHealth reports the cluster's health score. A critical cluster
+// answers 503 so plain HTTP checks can alert on the status code alone.
+func (s *HTTPServer) handleClusterHealth(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	if s.getClusterHealth == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Cluster health handler not configured", http.StatusNotFound)
+		return
+	}
+
+	resp := s.getClusterHealth()
+	status := http.StatusOK
+	if resp.Status == protocol.ClusterCritical {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleTransactionStream streams this node's transaction state changes as
+// server-sent events until the client disconnects or the server shuts down.
+// Each event is named "transaction" and carries a protocol.TransactionEvent.
+func (s *HTTPServer) handleTransactionStream(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
-	if s.onListTx == nil {
-		http.Error(w, "Transactions handler not configured", http.StatusInternalServerError)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, protocol.ErrCodeInternal, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := s.node.SubscribeTransactions()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.streams.Done():
+			return
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case ev := <-events:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: transaction\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// handleTransactions returns paginated transactions for a node.
+func (s *HTTPServer) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
 	addr := r.URL.Query().Get("address")
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	status := r.URL.Query().Get("status")
-
-	resp, err := s.onListTx(addr, page, limit, status)
+	status, err := node.NormalizeTxStatus(r.URL.Query().Get("status"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
 		return
 	}
+	if addr != "" {
+		if addr, err = s.nodeAddr(addr); err != nil {
+			writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Without a handler (node still starting up) answer with an empty page.
+	var resp *protocol.TransactionListResponse
+	if s.onListTx != nil {
+		resp, err = s.onListTx(addr, page, limit, status)
+		if err != nil {
+			writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
+			return
+		}
+	}
 
 	if resp == nil {
 		resp = &protocol.TransactionListResponse{
@@ -560,53 +1414,34 @@ func (s *HTTPServer) handleTransactions(w http.ResponseWriter, r *http.Request)
 
 // handleSetName sets a display name for a node.
 func (s *HTTPServer) handleSetName(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodPost) {
 		return
 	}
 
 	var req protocol.SetNameRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		resp := protocol.SetNameResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Address == "" {
-		resp := protocol.SetNameResponse{
-			Success: false,
-			Error:   "Address is required",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(resp)
+		writeError(w, protocol.ErrCodeInvalidRequest, "Address is required", http.StatusBadRequest)
 		return
 	}
 
 	if s.onSetName == nil {
-		resp := protocol.SetNameResponse{
-			Success: false,
-			Error:   "Set name handler not configured",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+		writeError(w, protocol.ErrCodeNotConfigured, "Set name handler not configured", http.StatusInternalServerError)
 		return
 	}
 
-	if err := s.onSetName(req.Address, req.Name); err != nil {
-		resp := protocol.SetNameResponse{
-			Success: false,
-			Error:   err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(resp)
+	addr, err := s.nodeAddr(req.Address)
+	if err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.onSetName(addr, req.Name); err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInternal, http.StatusInternalServerError)
 		return
 	}
 
@@ -616,15 +1451,73 @@ func (s *HTTPServer) handleSetName(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *HTTPServer) writeClusterInfo(w http.ResponseWriter) {
-	if s.getClusterInfo == nil {
-		http.Error(w, "Cluster info handler not configured", http.StatusInternalServerError)
+// handleHeartbeatInterval changes the heartbeat interval without a restart.
+func (s *HTTPServer) handleHeartbeatInterval(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req protocol.HeartbeatIntervalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	interval, err := time.ParseDuration(req.Interval)
+	if err != nil || interval <= 0 {
+		writeError(w, protocol.ErrCodeInvalidRequest, "Interval must be a positive duration (e.g. \"2s\")", http.StatusBadRequest)
+		return
+	}
+
+	if s.onHeartbeat == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Heartbeat handler not configured", http.StatusInternalServerError)
 		return
 	}
 
-	info := s.getClusterInfo()
+	if err := s.onHeartbeat(interval); err != nil {
+		writeHandlerError(w, err, protocol.ErrCodeInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	logging.Infof("[Node %s] Heartbeat interval set to %v", s.node.Addr, interval)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(protocol.HeartbeatIntervalResponse{
+		Success:  true,
+		Interval: interval.String(),
+	})
+}
+
+// handleChaos reads (GET) or replaces (POST) the chaos configuration.
+// The endpoint is hidden unless chaos mode was enabled at startup.
+func (s *HTTPServer) handleChaos(w http.ResponseWriter, r *http.Request) {
+	if s.chaos == nil {
+		writeError(w, protocol.ErrCodeNotFound, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var cfg protocol.ChaosConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			writeError(w, protocol.ErrCodeInvalidRequest, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		s.chaos.Set(cfg)
+		logging.Warnf("[Node %s] Chaos config updated: %+v", s.node.Addr, cfg)
+	default:
+		allowMethods(w, r, http.MethodGet, http.MethodPost)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.chaos.Config())
+}
+
+func (s *HTTPServer) writeClusterInfo(w http.ResponseWriter) {
+	info := s.clusterInfo()
 	if info == nil {
-		http.Error(w, "Cluster info unavailable", http.StatusServiceUnavailable)
+		writeError(w, protocol.ErrCodeUnavailable, "Cluster info unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -637,21 +1530,165 @@ func (s *HTTPServer) writeClusterInfo(w http.ResponseWriter) {
 	_ = json.NewEncoder(w).Encode(info)
 }
 
+// clusterInfo returns the configured cluster view or, before one is set, a
+// view holding only this node.
+func (s *HTTPServer) clusterInfo() *protocol.ClusterInfoResponse {
+	if s.getClusterInfo != nil {
+		return s.getClusterInfo()
+	}
+
+	n := s.node
+	info := protocol.NodeInfo{
+		Name:        n.GetName(),
+		Address:     n.Addr,
+		Role:        string(n.GetRole()),
+		Alive:       n.GetAlive(),
+		Disabled:    n.GetDisabled(),
+		Maintenance: n.GetMaintenance(),
+		NotReady:    !n.GetReady(),
+		Draining:    n.GetDraining(),
+		Database:    n.GetDatabase(),
+		Metrics:     n.Metrics(),
+		JoinedAt:    n.GetJoinedAt(),
+		Uptime:      int64(n.Uptime().Seconds()),
+	}
+
+	return &protocol.ClusterInfoResponse{
+		MasterAddr: s.localMaster(),
+		Nodes:      []protocol.NodeInfo{info},
+		Generated:  time.Now(),
+	}
+}
+
+// localMaster is this node's address if it is master, otherwise "".
+func (s *HTTPServer) localMaster() string {
+	if s.node.GetRole() == protocol.RoleMaster {
+		return s.node.Addr
+	}
+	return ""
+}
+
+// localNodeDetail is the extended view of this node, read directly.
+func (s *HTTPServer) localNodeDetail() *protocol.NodeDetail {
+	var skew int64
+	inDoubt := len(s.node.GetPendingTransactions())
+
+	return &protocol.NodeDetail{
+		Labels:      s.node.GetLabels(),
+		Priority:    s.node.GetPriority(),
+		Version:     protocol.ProtocolVersion,
+		ClockSkewMs: &skew,
+		InDoubt:     &inDoubt,
+	}
+}
+
+// allowMethods reports whether r uses one of the given methods. Otherwise it
+// replies 405 with an Allow header and a JSON error body.
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, protocol.ErrCodeMethodNotAllowed, "Method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeError writes the JSON error envelope with the given code and status.
+func writeError(w http.ResponseWriter, code, msg string, httpStatus int) {
+	writeErrorResponse(w, protocol.ErrorResponse{
+		Error: protocol.APIError{Code: code, Message: msg},
+	}, httpStatus)
+}
+
+// writeHandlerError reports err from a handler callback. An APIError in err's
+// chain picks the code; otherwise code is used.
+func writeHandlerError(w http.ResponseWriter, err error, code string, httpStatus int) {
+	writeError(w, protocol.ErrorCode(err, code), err.Error(), httpStatus)
+}
+
+func writeErrorResponse(w http.ResponseWriter, resp protocol.ErrorResponse, httpStatus int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// requireDashboardAuth wraps a handler with basic auth when dashboard credentials are set.
+func (s *HTTPServer) requireDashboardAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.dashboardUser == "" {
+			next(w, r)
+			return
+		}
+
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(s.dashboardUser)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(s.dashboardPass)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="2pc-dashboard"`)
+			writeError(w, protocol.ErrCodeUnauthorized, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// requireAdminAuth guards /admin routes with the dashboard credentials. Unlike
+// the dashboard they are refused outright when no credentials are configured.
+func (s *HTTPServer) requireAdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.dashboardUser == "" {
+			writeError(w, protocol.ErrCodeUnauthorized, "Admin endpoints require --dashboard-user and --dashboard-pass", http.StatusForbidden)
+			return
+		}
+		s.requireDashboardAuth(next)(w, r)
+	}
+}
+
+// handleShutdown starts the same drain-and-stop path as SIGTERM. It answers 202
+// at once; repeating the request while shutting down is harmless.
+func (s *HTTPServer) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if s.onShutdown == nil {
+		writeError(w, protocol.ErrCodeNotConfigured, "Shutdown handler not configured", http.StatusInternalServerError)
+		return
+	}
+
+	first := s.shuttingDown.CompareAndSwap(false, true)
+	s.node.SetDraining(true)
+	logging.Infof("[Node %s] Shutdown requested by %s", s.node.Addr, s.clientAddr(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(protocol.ShutdownResponse{Status: "shutting_down", Address: s.node.Addr})
+
+	if first {
+		go s.onShutdown()
+	}
+}
+
 func (s *HTTPServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !allowMethods(w, r, http.MethodGet) {
 		return
 	}
 
 	switch r.URL.Path {
 	case "/", "/dashboard", "/ui":
-		if dashboardPage == "" {
-			http.Error(w, "Dashboard not available", http.StatusInternalServerError)
+		page, err := renderDashboard(s.node.GetName(), s.node.Addr)
+		if err != nil {
+			logging.Errorf("[Node %s] Failed to render dashboard: %v", s.node.Addr, err)
+			writeError(w, protocol.ErrCodeInternal, "Dashboard not available", http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		_, _ = w.Write([]byte(dashboardPage))
+		_, _ = w.Write(page)
 	default:
-		http.NotFound(w, r)
+		writeError(w, protocol.ErrCodeNotFound, "Not found", http.StatusNotFound)
 	}
 }
@@ -0,0 +1,1365 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func newTestServer(t *testing.T) (*HTTPServer, *httptest.Server) {
+	t.Helper()
+
+	s := NewHTTPServer(node.NewNode("localhost:8081", protocol.RoleSlave))
+	s.SetClusterInfoHandler(func() *protocol.ClusterInfoResponse {
+		return &protocol.ClusterInfoResponse{MasterAddr: "localhost:8081"}
+	})
+
+	server := httptest.NewServer(s.mux)
+	t.Cleanup(server.Close)
+
+	return s, server
+}
+
+func TestReadRowRequiresAdminAuth(t *testing.T) {
+	s, server := newTestServer(t)
+	url := server.URL + "/rows?table=users&key_col=id&key=1"
+
+	// Without configured credentials the endpoint is off.
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET /rows failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 without dashboard credentials, got %d", resp.StatusCode)
+	}
+
+	s.SetDashboardAuth("admin", "secret")
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.SetBasicAuth("admin", "secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /rows failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with credentials, got %d", resp.StatusCode)
+	}
+}
+
+func TestDashboardAuth(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetDashboardAuth("admin", "secret")
+
+	for _, path := range []string{"/dashboard", "/cluster/summary"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without credentials: expected 401, got %d", path, resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("GET %s: expected WWW-Authenticate challenge", path)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.SetBasicAuth("admin", "wrong")
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s with wrong password: expected 401, got %d", path, resp.StatusCode)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.SetBasicAuth("admin", "secret")
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s with credentials: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	// Protocol endpoints stay open for peers.
+	resp, err := http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET /health failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /health to bypass dashboard auth, got %d", resp.StatusCode)
+	}
+
+	client := NewHTTPClient(5*time.Second).WithBasicAuth("admin", "secret")
+	if _, err := client.ClusterInfo(server.Listener.Addr().String()); err != nil {
+		t.Errorf("ClusterInfo with credentials failed: %v", err)
+	}
+}
+
+func TestDashboardOpenWithoutAuth(t *testing.T) {
+	_, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/cluster/summary")
+	if err != nil {
+		t.Fatalf("GET /cluster/summary failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 when dashboard auth is not configured, got %d", resp.StatusCode)
+	}
+}
+
+func TestDashboardServesEmbeddedPageWithNodeAddress(t *testing.T) {
+	s, server := newTestServer(t)
+	s.node.SetName("Shard-<1>")
+
+	resp, err := http.Get(server.URL + "/dashboard")
+	if err != nil {
+		t.Fatalf("GET /dashboard failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected HTML content type, got %q", ct)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+
+	if !strings.Contains(page, `<span class="mono" id="selfAddr">localhost:8081</span>`) {
+		t.Error("Expected node address to be injected into the dashboard")
+	}
+	if !strings.Contains(page, "Shard-&lt;1&gt;") {
+		t.Error("Expected node name to be injected HTML-escaped")
+	}
+	if strings.Contains(page, "{{") {
+		t.Error("Expected no unrendered template actions in the dashboard")
+	}
+	if !strings.Contains(page, "/cluster/summary") {
+		t.Error("Expected dashboard to fetch /cluster/summary client-side")
+	}
+}
+
+func TestMethodNotAllowedSetsAllowHeader(t *testing.T) {
+	_, server := newTestServer(t)
+
+	cases := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodPost, "/health", "GET, HEAD"},
+		{http.MethodGet, "/prepare", "POST"},
+		{http.MethodDelete, "/cluster/summary", "GET"},
+	}
+
+	for _, tc := range cases {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", tc.method, tc.path, err)
+		}
+
+		var body protocol.ErrorResponse
+		decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected 405, got %d", tc.method, tc.path, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", tc.method, tc.path, tc.allow, got)
+		}
+		if decodeErr != nil || body.Error.Code != protocol.ErrCodeMethodNotAllowed || body.Error.Message == "" {
+			t.Errorf("%s %s: expected a method_not_allowed error envelope, got %+v (decode err: %v)", tc.method, tc.path, body, decodeErr)
+		}
+	}
+}
+
+func TestHealthHeadIsBodyless(t *testing.T) {
+	_, server := newTestServer(t)
+
+	resp, err := http.Head(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("HEAD /health failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+	if len(body) != 0 {
+		t.Errorf("Expected empty body, got %q", body)
+	}
+}
+
+func TestHealthReportsLifecycleState(t *testing.T) {
+	s, server := newTestServer(t)
+
+	health := func() (string, protocol.HealthResponse) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/health")
+		if err != nil {
+			t.Fatalf("GET /health failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var health protocol.HealthResponse
+		if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+			t.Fatalf("Failed to decode health: %v", err)
+		}
+		return resp.Header.Get(protocol.StateHeader), health
+	}
+
+	if header, h := health(); h.State != protocol.NodeStateRunning || h.Draining || header != string(protocol.NodeStateRunning) {
+		t.Errorf("Expected running, got state=%q draining=%t header=%q", h.State, h.Draining, header)
+	}
+
+	s.node.SetMaintenance(true)
+	if header, h := health(); h.State != protocol.NodeStateMaintenance || h.Draining || header != string(protocol.NodeStateMaintenance) {
+		t.Errorf("Expected maintenance, got state=%q draining=%t header=%q", h.State, h.Draining, header)
+	}
+
+	// Draining wins over maintenance.
+	s.BeginShutdown()
+	if header, h := health(); h.State != protocol.NodeStateDraining || !h.Draining || header != string(protocol.NodeStateDraining) {
+		t.Errorf("Expected draining, got state=%q draining=%t header=%q", h.State, h.Draining, header)
+	}
+
+	status, err := NewHTTPClient(time.Second).PingStatus(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil || !status.Draining || !status.Maintenance {
+		t.Errorf("PingStatus = %+v, %v; want draining and maintenance", status, err)
+	}
+}
+
+func TestMetricsHistoryEndpoint(t *testing.T) {
+	s, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/metrics/history")
+	if err != nil {
+		t.Fatalf("GET /metrics/history failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without a sampler, got %d", resp.StatusCode)
+	}
+
+	sampler := node.NewMetricsSampler(s.node, time.Hour, 10)
+	sampler.Start()
+	defer sampler.Stop()
+	var asked []string
+	s.SetMetricsHistoryHandler(func(addr string) (*protocol.MetricsHistoryResponse, error) {
+		asked = append(asked, addr)
+		if addr != "" {
+			return nil, protocol.NewAPIError(protocol.ErrCodeNodeNotFound, "unknown node")
+		}
+		return sampler.History(), nil
+	})
+
+	resp, err = http.Get(server.URL + "/metrics/history?address=other:1")
+	if err != nil {
+		t.Fatalf("GET /metrics/history failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || len(asked) != 1 || asked[0] != "other:1" {
+		t.Errorf("Expected the address passed to the handler and its error as 502, got %d, asked %v", resp.StatusCode, asked)
+	}
+
+	resp, err = http.Get(server.URL + "/metrics/history")
+	if err != nil {
+		t.Fatalf("GET /metrics/history failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var history protocol.MetricsHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatalf("Failed to decode history: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || history.Window != 10 || history.Samples == nil {
+		t.Errorf("Unexpected history: %d %+v", resp.StatusCode, history)
+	}
+}
+
+func TestReadyEndpoint(t *testing.T) {
+	s, server := newTestServer(t)
+
+	ready := func() (int, protocol.ReadyResponse) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/ready")
+		if err != nil {
+			t.Fatalf("GET /ready failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body protocol.ReadyResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if status, body := ready(); status != http.StatusOK || !body.Ready {
+		t.Fatalf("Expected a ready node, got %d %+v", status, body)
+	}
+
+	// In maintenance the node is still healthy, but no longer ready.
+	s.node.SetMaintenance(true)
+	if status, body := ready(); status != http.StatusServiceUnavailable || body.Ready || body.Reason != "maintenance" {
+		t.Errorf("Expected 503 in maintenance, got %d %+v", status, body)
+	}
+	if resp, err := http.Head(server.URL + "/health"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /health to stay 200 in maintenance, got %v %v", resp, err)
+	}
+	s.node.SetMaintenance(false)
+
+	s.BeginShutdown()
+	if status, body := ready(); status != http.StatusServiceUnavailable || body.Reason != "shutting down" {
+		t.Errorf("Expected 503 while shutting down, got %d %+v", status, body)
+	}
+
+	client := NewHTTPClient(time.Second)
+	if ok, err := client.Ready(strings.TrimPrefix(server.URL, "http://")); err != nil || ok {
+		t.Errorf("Expected the client to report not ready, got %v, %v", ok, err)
+	}
+}
+
+func TestHeartbeatIntervalEndpoint(t *testing.T) {
+	s, server := newTestServer(t)
+
+	var got time.Duration
+	s.SetHeartbeatIntervalHandler(func(interval time.Duration) error {
+		got = interval
+		return nil
+	})
+
+	resp, err := http.Post(server.URL+"/cluster/heartbeat-interval", "application/json", strings.NewReader(`{"interval":"250ms"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	var body protocol.HeartbeatIntervalResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !body.Success {
+		t.Fatalf("Expected success, got %d %+v", resp.StatusCode, body)
+	}
+	if got != 250*time.Millisecond {
+		t.Errorf("Expected handler to receive 250ms, got %v", got)
+	}
+
+	resp, err = http.Post(server.URL+"/cluster/heartbeat-interval", "application/json", strings.NewReader(`{"interval":"-1s"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for negative interval, got %d", resp.StatusCode)
+	}
+}
+
+func TestDisableEnableNodeEndpoints(t *testing.T) {
+	s, server := newTestServer(t)
+
+	disabled := map[string]bool{}
+	s.SetDisableNodeHandler(func(addr string, d bool) error {
+		if addr == "unknown:1" {
+			return errors.New("node not found")
+		}
+		disabled[addr] = d
+		return nil
+	})
+
+	client := NewHTTPClient(time.Second)
+	target := server.Listener.Addr().String()
+	req := &protocol.DisableNodeRequest{Address: "localhost:8082"}
+
+	if _, err := client.DisableNode(target, req); err != nil {
+		t.Fatalf("DisableNode failed: %v", err)
+	}
+	if !disabled["localhost:8082"] {
+		t.Error("Expected handler to disable localhost:8082")
+	}
+
+	if _, err := client.EnableNode(target, req); err != nil {
+		t.Fatalf("EnableNode failed: %v", err)
+	}
+	if disabled["localhost:8082"] {
+		t.Error("Expected handler to re-enable localhost:8082")
+	}
+
+	_, err := client.DisableNode(target, &protocol.DisableNodeRequest{Address: "unknown:1"})
+	if err == nil || !strings.Contains(err.Error(), "node not found") {
+		t.Errorf("Expected handler error to surface, got %v", err)
+	}
+}
+
+func TestTransactionRejectedUntilReady(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	var ready atomic.Bool
+	calls := 0
+	s.SetReadinessCheck(ready.Load)
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		calls++
+		return &protocol.TransactionResponse{Success: true}, nil
+	})
+
+	post := func() *http.Response {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/transaction", "application/json", strings.NewReader(`{"payload":{}}`))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post()
+	var body protocol.TransactionResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || body.Success || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("Expected 503 with Retry-After before ready, got %d %+v", resp.StatusCode, body)
+	}
+	if calls != 0 {
+		t.Fatal("Transaction handler must not run before ready")
+	}
+
+	ready.Store(true)
+	resp = post()
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 1 {
+		t.Errorf("Expected transaction to run once ready, got %d (calls=%d)", resp.StatusCode, calls)
+	}
+}
+
+func TestTransactionOnSlaveDuringElection(t *testing.T) {
+	s, server := newTestServer(t)
+
+	var electing atomic.Bool
+	s.SetElectionCheck(electing.Load)
+
+	post := func() *http.Response {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/transaction", "application/json", strings.NewReader(`{"payload":{}}`))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		return resp
+	}
+
+	electing.Store(true)
+	resp := post()
+	var body protocol.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" || body.Error.Code != protocol.ErrCodeElectionInProgress {
+		t.Fatalf("Expected 503 with Retry-After during election, got %d %+v", resp.StatusCode, body)
+	}
+
+	electing.Store(false)
+	resp = post()
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 from a slave once the election settled, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerBindsListenAddrAndAdvertisesAddr(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve port: %v", err)
+	}
+	bind := l.Addr().String()
+	l.Close()
+
+	n := node.NewNode("node-1.example.com:8081", protocol.RoleSlave)
+	n.BindAddr = bind
+	s := NewHTTPServer(n)
+	go s.Start()
+	defer s.Stop()
+
+	client := NewHTTPClient(time.Second)
+	var health *protocol.HealthResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		health, err = client.HealthCheck(bind)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Expected server on bind address %s: %v", bind, err)
+	}
+	if health.Address != n.Addr {
+		t.Errorf("Health address = %q, want advertised %q", health.Address, n.Addr)
+	}
+}
+
+func TestServerWildcardBindWithExplicitAdvertise(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve port: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	n := node.NewNode("10.0.0.5:"+port, protocol.RoleSlave)
+	n.BindAddr = "0.0.0.0:" + port
+	s := NewHTTPServer(n)
+	go s.Start()
+	defer s.Stop()
+
+	// The wildcard listener is reachable on loopback, while the node keeps
+	// reporting its advertised identity.
+	client := NewHTTPClient(time.Second)
+	var role *protocol.RoleResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		role, err = client.GetRole("127.0.0.1:" + port)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Expected wildcard listener on port %s: %v", port, err)
+	}
+	if role.Address != n.Addr {
+		t.Errorf("Role address = %q, want advertised %q", role.Address, n.Addr)
+	}
+}
+
+func TestClusterMasterEndpoint(t *testing.T) {
+	s, server := newTestServer(t)
+
+	master := ""
+	s.SetMasterHandler(func() string { return master })
+
+	client := NewHTTPClient(time.Second)
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	if _, err := client.GetMaster(addr); err == nil {
+		t.Error("Expected an error when the node knows no master")
+	}
+	resp, err := http.Get(server.URL + "/cluster/master")
+	if err != nil {
+		t.Fatalf("GET /cluster/master failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without a known master, got %d", resp.StatusCode)
+	}
+
+	master = "localhost:8080"
+	got, err := client.GetMaster(addr)
+	if err != nil {
+		t.Fatalf("GetMaster failed: %v", err)
+	}
+	if got != master {
+		t.Errorf("GetMaster = %q, want %q", got, master)
+	}
+}
+
+func TestCommitFromForeignCoordinatorRejected(t *testing.T) {
+	s, server := newTestServer(t)
+	client := NewHTTPClient(time.Second)
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	prep, err := client.Prepare(addr, &protocol.PrepareRequest{
+		TransactionID: "tx-split",
+		Payload:       map[string]string{"k": "v"},
+		Coordinator:   "master-a:8080",
+	})
+	if err != nil || prep.Status != protocol.StatusReady {
+		t.Fatalf("Prepare failed: %+v, %v", prep, err)
+	}
+
+	body := strings.NewReader(`{"transaction_id":"tx-split","coordinator":"master-b:8080"}`)
+	resp, err := http.Post(server.URL+"/commit", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST /commit failed: %v", err)
+	}
+	var commit protocol.CommitResponse
+	json.NewDecoder(resp.Body).Decode(&commit)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict || commit.Success {
+		t.Fatalf("Expected 409 for a foreign coordinator, got %d %+v", resp.StatusCode, commit)
+	}
+	if !s.node.HasPendingTransaction("tx-split") {
+		t.Fatal("Expected the transaction to stay prepared")
+	}
+
+	ack, err := client.Commit(addr, &protocol.CommitRequest{TransactionID: "tx-split", Coordinator: "master-a:8080"})
+	if err != nil || !ack.Success {
+		t.Errorf("Expected the owning coordinator to commit, got %+v, %v", ack, err)
+	}
+}
+
+func TestAdminShutdown(t *testing.T) {
+	s, server := newTestServer(t)
+	s.node.SetRole(protocol.RoleMaster)
+	s.SetTransactionHandler(func(*protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		return &protocol.TransactionResponse{Success: true}, nil
+	})
+	calls := make(chan struct{}, 2)
+	s.SetShutdownHandler(func() { calls <- struct{}{} })
+
+	shutdown := func(user, pass string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/admin/shutdown", nil)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /admin/shutdown failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Without configured credentials the endpoint is off.
+	if resp := shutdown("", ""); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 without dashboard credentials, got %d", resp.StatusCode)
+	}
+
+	s.SetDashboardAuth("admin", "secret")
+	if resp := shutdown("admin", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for wrong credentials, got %d", resp.StatusCode)
+	}
+	if s.ShuttingDown() {
+		t.Fatal("Expected a refused request not to start shutdown")
+	}
+
+	if resp := shutdown("admin", "secret"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the shutdown handler to run")
+	}
+
+	// A repeated request is accepted but does not start a second shutdown.
+	if resp := shutdown("admin", "secret"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202 for a repeated request, got %d", resp.StatusCode)
+	}
+	select {
+	case <-calls:
+		t.Error("Expected the shutdown handler to run once")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resp, err := http.Post(server.URL+"/transaction", "application/json", strings.NewReader(`{"payload":{}}`))
+	if err != nil {
+		t.Fatalf("POST /transaction failed: %v", err)
+	}
+	var errResp protocol.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&errResp)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || errResp.Error.Code != protocol.ErrCodeUnavailable {
+		t.Errorf("Expected new transactions to be refused while shutting down, got %d %+v", resp.StatusCode, errResp)
+	}
+}
+
+func TestPrepareAtCapacityVotesAbortWithCode(t *testing.T) {
+	s, server := newTestServer(t)
+	s.node.SetMaxPrepared(1)
+	client := NewHTTPClient(time.Second)
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	if prep, err := client.Prepare(addr, &protocol.PrepareRequest{TransactionID: "tx-held", Payload: map[string]any{"k": "v"}}); err != nil || prep.Status != protocol.StatusReady {
+		t.Fatalf("Prepare failed: %+v, %v", prep, err)
+	}
+
+	prep, err := client.Prepare(addr, &protocol.PrepareRequest{TransactionID: "tx-over", Payload: map[string]any{"k": "v"}})
+	if err != nil {
+		t.Fatalf("Expected the capacity vote to reach the caller, got %v", err)
+	}
+	if prep.Status != protocol.StatusAbort || prep.Code != protocol.ErrCodeAtCapacity {
+		t.Errorf("Expected an at_capacity ABORT vote, got %+v", prep)
+	}
+}
+
+func TestPrepareOfDeniedTableVotesAbortWithCode(t *testing.T) {
+	s, server := newTestServer(t)
+	if err := s.node.SetAllowedTables([]string{"users"}); err != nil {
+		t.Fatalf("SetAllowedTables failed: %v", err)
+	}
+	client := NewHTTPClient(time.Second)
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	prep, err := client.Prepare(addr, &protocol.PrepareRequest{
+		TransactionID: "tx-denied",
+		Payload:       map[string]any{"table": "payments", "values": map[string]any{"amount": 10}},
+	})
+	if err != nil {
+		t.Fatalf("Expected the vote to reach the caller, got %v", err)
+	}
+	if prep.Status != protocol.StatusAbort || prep.Code != protocol.ErrCodeTableNotPermitted {
+		t.Errorf("Expected a table_not_permitted ABORT vote, got %+v", prep)
+	}
+}
+
+func TestCommitEchoesChangesOnlyWhenRequested(t *testing.T) {
+	_, server := newTestServer(t)
+	client := NewHTTPClient(time.Second)
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	payload := map[string]any{"table": "users", "operation": "update", "values": map[string]any{"name": "Bob"}, "where": map[string]any{"id": 7}}
+	for _, txID := range []string{"tx-plain", "tx-cdc"} {
+		if prep, err := client.Prepare(addr, &protocol.PrepareRequest{TransactionID: txID, Payload: payload}); err != nil || prep.Status != protocol.StatusReady {
+			t.Fatalf("Prepare %s failed: %+v, %v", txID, prep, err)
+		}
+	}
+
+	plain, err := client.Commit(addr, &protocol.CommitRequest{TransactionID: "tx-plain"})
+	if err != nil || !plain.Success || plain.Changes != nil {
+		t.Fatalf("Expected a plain commit without changes, got %+v, %v", plain, err)
+	}
+
+	ack, err := client.Commit(addr, &protocol.CommitRequest{TransactionID: "tx-cdc", IncludeChanges: true})
+	if err != nil || !ack.Success {
+		t.Fatalf("Commit failed: %+v, %v", ack, err)
+	}
+	got, _ := json.Marshal(ack.Changes)
+	want := `[{"table":"users","operation":"UPDATE","values":{"name":"Bob"},"where":{"id":7},"keys":{"id":7},"rows_affected":0}]`
+	if string(got) != want {
+		t.Errorf("changes = %s, want %s", got, want)
+	}
+}
+
+func TestClusterNodesDetailOnlyWhenRequested(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetClusterInfoHandler(func() *protocol.ClusterInfoResponse {
+		return &protocol.ClusterInfoResponse{
+			MasterAddr: "localhost:8081",
+			Nodes:      []protocol.NodeInfo{{Address: "localhost:8081", Role: "MASTER", Alive: true}},
+		}
+	})
+	s.SetNodeDetailHandler(func(addr string) *protocol.NodeDetail {
+		inDoubt := 2
+		return &protocol.NodeDetail{
+			Labels:   map[string]string{"zone": "a"},
+			Priority: 5,
+			Version:  protocol.ProtocolVersion,
+			InDoubt:  &inDoubt,
+		}
+	})
+
+	fetch := func(query string) map[string]any {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/cluster/nodes" + query)
+		if err != nil {
+			t.Fatalf("GET /cluster/nodes%s failed: %v", query, err)
+		}
+		defer resp.Body.Close()
+
+		var body struct {
+			Nodes []map[string]any `json:"nodes"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(body.Nodes) != 1 {
+			t.Fatalf("Expected one node, got %v", body.Nodes)
+		}
+		return body.Nodes[0]
+	}
+
+	if n := fetch(""); n["detail"] != nil {
+		t.Errorf("Expected no detail by default, got %v", n["detail"])
+	}
+
+	detail, ok := fetch("?detail=true")["detail"].(map[string]any)
+	if !ok {
+		t.Fatal("Expected detail with ?detail=true")
+	}
+	if detail["priority"] != float64(5) || detail["in_doubt"] != float64(2) || detail["version"] != protocol.ProtocolVersion {
+		t.Errorf("Unexpected detail: %v", detail)
+	}
+	if labels, _ := detail["labels"].(map[string]any); labels["zone"] != "a" {
+		t.Errorf("Expected labels in detail, got %v", detail["labels"])
+	}
+}
+
+func TestTransactionsStatusFilterValidated(t *testing.T) {
+	s, server := newTestServer(t)
+
+	var got []string
+	s.SetTransactionsHandler(func(addr string, page, limit int, status string) (*protocol.TransactionListResponse, error) {
+		got = append(got, status)
+		return nil, nil
+	})
+
+	for _, status := range []string{"committed", "PREPARED", ""} {
+		resp, err := http.Get(server.URL + "/transactions?status=" + status)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status=%q: got HTTP %d, want 200", status, resp.StatusCode)
+		}
+	}
+	if want := []string{"COMMITTED", "PREPARED", ""}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Handler saw statuses %q, want %q", got, want)
+	}
+
+	resp, err := http.Get(server.URL + "/transactions?status=dropped")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Bogus status: got HTTP %d, want 400", resp.StatusCode)
+	}
+	if len(got) != 3 {
+		t.Errorf("Handler must not be called for a bogus status, saw %q", got)
+	}
+}
+
+func TestReadEndpointsFallBackWithoutHandlers(t *testing.T) {
+	n := node.NewNode("localhost:8081", protocol.RoleMaster)
+	server := httptest.NewServer(NewHTTPServer(n).Handler())
+	t.Cleanup(server.Close)
+
+	get := func(path string, out any) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("GET %s: HTTP %d, want 200: %s", path, resp.StatusCode, body)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("GET %s: decode: %v", path, err)
+		}
+	}
+
+	for _, path := range []string{"/cluster/nodes", "/cluster/summary", "/cluster/nodes?detail=true"} {
+		var info protocol.ClusterInfoResponse
+		get(path, &info)
+		if info.MasterAddr != n.Addr || len(info.Nodes) != 1 || info.Nodes[0].Address != n.Addr {
+			t.Errorf("%s: expected a local-only view, got %+v", path, info)
+		}
+	}
+
+	var detailed protocol.ClusterInfoResponse
+	get("/cluster/nodes?detail=true", &detailed)
+	if d := detailed.Nodes[0].Detail; d == nil || d.Version != protocol.ProtocolVersion {
+		t.Errorf("Expected local node detail, got %+v", d)
+	}
+
+	var master protocol.MasterResponse
+	get("/cluster/master", &master)
+	if master.MasterAddr != n.Addr {
+		t.Errorf("Expected the master node to report itself, got %+v", master)
+	}
+
+	var txs protocol.TransactionListResponse
+	get("/transactions", &txs)
+	if txs.Transactions == nil || len(txs.Transactions) != 0 || txs.HasDB {
+		t.Errorf("Expected an empty transaction page without a DB, got %+v", txs)
+	}
+
+	var inflight protocol.InflightResponse
+	get("/coordinator/inflight", &inflight)
+	if inflight.Transactions == nil || len(inflight.Transactions) != 0 {
+		t.Errorf("Expected no in-flight transactions, got %+v", inflight)
+	}
+
+	var aborts protocol.CoordinatorMetricsResponse
+	get("/coordinator/metrics", &aborts)
+	if aborts.Aborts == nil {
+		t.Error("Expected an empty abort breakdown")
+	}
+
+	// A slave without a cluster view knows no master.
+	n.SetRole(protocol.RoleSlave)
+	resp, err := http.Get(server.URL + "/cluster/master")
+	if err != nil {
+		t.Fatalf("GET /cluster/master failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 from a slave without a view, got %d", resp.StatusCode)
+	}
+}
+
+func TestNodeEndpointsResolveNames(t *testing.T) {
+	s, server := newTestServer(t)
+
+	s.SetNodeResolver(func(ref string) (string, error) {
+		switch ref {
+		case "localhost:8082", "replica-a":
+			return "localhost:8082", nil
+		case "replica":
+			return "", errors.New("node name is ambiguous: \"replica\" is used by localhost:8082, localhost:8083; use an address")
+		}
+		return "", errors.New("unknown node \"" + ref + "\"")
+	})
+
+	var disabledAddr, listedAddr string
+	s.SetDisableNodeHandler(func(addr string, d bool) error {
+		disabledAddr = addr
+		return nil
+	})
+	s.SetTransactionsHandler(func(addr string, page, limit int, status string) (*protocol.TransactionListResponse, error) {
+		listedAddr = addr
+		return nil, nil
+	})
+
+	client := NewHTTPClient(time.Second)
+	target := server.Listener.Addr().String()
+
+	if _, err := client.DisableNode(target, &protocol.DisableNodeRequest{Address: "replica-a"}); err != nil {
+		t.Fatalf("DisableNode by name failed: %v", err)
+	}
+	if disabledAddr != "localhost:8082" {
+		t.Errorf("Expected the name to reach the handler as localhost:8082, got %q", disabledAddr)
+	}
+
+	_, err := client.DisableNode(target, &protocol.DisableNodeRequest{Address: "replica"})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected an ambiguous-name error, got %v", err)
+	}
+
+	resp, err := http.Get(server.URL + "/transactions?address=replica-a")
+	if err != nil {
+		t.Fatalf("GET /transactions failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || listedAddr != "localhost:8082" {
+		t.Errorf("Expected /transactions to resolve the name, got HTTP %d and address %q", resp.StatusCode, listedAddr)
+	}
+
+	resp, err = http.Get(server.URL + "/transactions?address=nobody")
+	if err != nil {
+		t.Fatalf("GET /transactions failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), `unknown node`) {
+		t.Errorf("Expected 400 for an unknown node, got HTTP %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestThrottledTransactionReturns429(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		return &protocol.TransactionResponse{
+			TransactionID: "tx-throttled",
+			Error:         "Rate limit exceeded: at most 5 transactions/s",
+			Code:          protocol.ErrCodeRateLimited,
+			RetryAfterMs:  1200,
+		}, nil
+	})
+
+	resp, err := http.Post(server.URL+"/transaction", "application/json", strings.NewReader(`{"payload":{}}`))
+	if err != nil {
+		t.Fatalf("POST /transaction failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2 (rounded up)", got)
+	}
+
+	var body protocol.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.RetryAfterMs != 1200 || body.Error.Code != protocol.ErrCodeRateLimited || body.TransactionID != "tx-throttled" {
+		t.Errorf("Unexpected body: %#v", body)
+	}
+}
+
+func TestFailuresUseErrorEnvelope(t *testing.T) {
+	s, server := newTestServer(t)
+	s.SetNodeResolver(func(ref string) (string, error) {
+		return "", protocol.NewAPIError(protocol.ErrCodeNodeNotFound, "unknown node "+ref)
+	})
+	s.SetRemoveNodeHandler(func(addr string) error { return nil })
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodPost, "/transaction", `{"payload":{}}`, http.StatusBadRequest, protocol.ErrCodeNotMaster},
+		{http.MethodPost, "/cluster/remove", `{`, http.StatusBadRequest, protocol.ErrCodeInvalidRequest},
+		{http.MethodPost, "/cluster/remove", `{"address":"ghost"}`, http.StatusBadRequest, protocol.ErrCodeNodeNotFound},
+		{http.MethodPost, "/cluster/join", `{"address":"localhost:8083"}`, http.StatusInternalServerError, protocol.ErrCodeNotConfigured},
+		{http.MethodGet, "/transaction/tx-missing", "", http.StatusNotFound, protocol.ErrCodeTransactionNotFound},
+		{http.MethodGet, "/transactions?status=bogus", "", http.StatusBadRequest, protocol.ErrCodeInvalidRequest},
+		{http.MethodPost, "/cluster/heartbeat-interval", `{"interval":"-1s"}`, http.StatusBadRequest, protocol.ErrCodeInvalidRequest},
+		{http.MethodGet, "/no-such-page", "", http.StatusNotFound, protocol.ErrCodeNotFound},
+	} {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", tc.method, tc.path, err)
+		}
+
+		// Decode loosely so stray top-level fields would show up.
+		var body map[string]json.RawMessage
+		decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s %s: expected HTTP %d, got %d", tc.method, tc.path, tc.status, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: expected a JSON body, got %q", tc.method, tc.path, ct)
+		}
+		if decodeErr != nil || len(body) != 1 {
+			t.Errorf("%s %s: expected only an \"error\" field, got %v (decode err: %v)", tc.method, tc.path, body, decodeErr)
+			continue
+		}
+
+		var apiErr protocol.APIError
+		if err := json.Unmarshal(body["error"], &apiErr); err != nil || apiErr.Code != tc.code || apiErr.Message == "" {
+			t.Errorf("%s %s: expected code %q with a message, got %s", tc.method, tc.path, tc.code, body["error"])
+		}
+	}
+}
+
+func TestFailedTransactionEnvelopeRoundTrips(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		return &protocol.TransactionResponse{
+			TransactionID: "tx-bad",
+			Error:         "Payload rejected: too many columns",
+			Code:          protocol.ErrCodeInvalidPayload,
+			RetryAfterMs:  1,
+		}, nil
+	})
+
+	resp, err := http.Post(server.URL+"/transaction", "application/json", strings.NewReader(`{"payload":{}}`))
+	if err != nil {
+		t.Fatalf("POST /transaction failed: %v", err)
+	}
+	var body protocol.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if body.Error.Code != protocol.ErrCodeInvalidPayload || body.TransactionID != "tx-bad" {
+		t.Fatalf("Expected an invalid_payload envelope for tx-bad, got %+v", body)
+	}
+
+	// The client turns the envelope back into a failed TransactionResponse.
+	client := NewHTTPClient(time.Second)
+	got, err := client.StartTransaction(strings.TrimPrefix(server.URL, "http://"), &protocol.TransactionRequest{Payload: map[string]any{}})
+	if err != nil {
+		t.Fatalf("StartTransaction failed: %v", err)
+	}
+	if got.Success || got.Code != protocol.ErrCodeInvalidPayload || got.TransactionID != "tx-bad" || got.Error == "" {
+		t.Errorf("Unexpected response: %#v", got)
+	}
+
+	// Other client calls surface the envelope as an APIError.
+	_, err = client.RemoveNode(strings.TrimPrefix(server.URL, "http://"), &protocol.RemoveNodeRequest{})
+	var apiErr *protocol.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != protocol.ErrCodeInvalidRequest {
+		t.Errorf("Expected an invalid_request APIError, got %v", err)
+	}
+}
+
+func TestTransactionStreamReportsCommit(t *testing.T) {
+	s, server := newTestServer(t)
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan protocol.TransactionEvent, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- NewHTTPClient(time.Second).StreamTransactions(ctx, addr, func(ev protocol.TransactionEvent) error {
+			events <- ev
+			return nil
+		})
+	}()
+
+	// The subscription starts asynchronously, so keep committing transactions
+	// until one shows up in the stream.
+	committed := make(map[string]bool)
+	deadline := time.After(5 * time.Second)
+	for i := 0; ; i++ {
+		txID := "tx-stream-" + strconv.Itoa(i)
+		if _, err := s.node.Prepare(txID, map[string]any{"table": "users", "values": map[string]any{"name": "Alice"}}); err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if err := s.node.Commit(txID); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		committed[txID] = true
+
+		select {
+		case ev := <-events:
+			for ev.Status == "PREPARED" {
+				ev = <-events
+			}
+			if !committed[ev.TxID] || ev.Status != "COMMITTED" || ev.Node != "localhost:8081" {
+				t.Fatalf("Expected a COMMITTED event for a committed transaction, got %+v", ev)
+			}
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected the stream to end with the context, got %v", err)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("No event received from /transactions/stream")
+		}
+	}
+}
+
+func TestAsyncTransactionPolling(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	release := make(chan struct{})
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		<-release
+		if req.Payload.(map[string]any)["fail"] == true {
+			return &protocol.TransactionResponse{TransactionID: req.TransactionID, Error: "prepare failed"}, nil
+		}
+		return &protocol.TransactionResponse{TransactionID: req.TransactionID, Success: true}, nil
+	})
+
+	client := NewHTTPClient(time.Second)
+	accepted, err := client.StartTransactionAsync(addr, &protocol.TransactionRequest{Payload: map[string]any{}})
+	if err != nil {
+		t.Fatalf("StartTransactionAsync failed: %v", err)
+	}
+	if accepted.TransactionID == "" || accepted.Status != protocol.TxStatusRunning || accepted.StatusURL != "/transaction/"+accepted.TransactionID {
+		t.Fatalf("Unexpected acceptance: %+v", accepted)
+	}
+	failing, err := client.StartTransactionAsync(addr, &protocol.TransactionRequest{Payload: map[string]any{"fail": true}})
+	if err != nil {
+		t.Fatalf("StartTransactionAsync failed: %v", err)
+	}
+
+	rec, err := client.GetTransaction(addr, accepted.TransactionID)
+	if err != nil || rec == nil || rec.Status != protocol.TxStatusRunning || rec.Result != nil {
+		t.Fatalf("Expected a RUNNING record before the handler returns, got %+v, %v", rec, err)
+	}
+
+	close(release)
+	wait := func(txID string) *protocol.TransactionRecord {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			rec, err := client.GetTransaction(addr, txID)
+			if err != nil {
+				t.Fatalf("GetTransaction failed: %v", err)
+			}
+			if rec.Status != protocol.TxStatusRunning {
+				return rec
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Transaction %s still running", txID)
+		return nil
+	}
+
+	if rec := wait(accepted.TransactionID); rec.Status != protocol.TxStatusCommitted || rec.Result == nil ||
+		!rec.Result.Success || rec.Result.TransactionID != accepted.TransactionID {
+		t.Errorf("Expected a COMMITTED record with the result, got %+v", rec)
+	}
+	if rec := wait(failing.TransactionID); rec.Status != protocol.TxStatusAborted ||
+		rec.ErrorCode != protocol.ErrCodeTransactionAborted || rec.Result == nil || rec.Result.Error != "prepare failed" {
+		t.Errorf("Expected an ABORTED record with the error, got %+v", rec)
+	}
+}
+
+func TestAsyncTransactionRejectsBadRequests(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		return &protocol.TransactionResponse{Success: true}, nil
+	})
+
+	resp, err := http.Post(server.URL+"/transaction?async=bogus", "application/json", strings.NewReader(`{"payload":{}}`))
+	if err != nil {
+		t.Fatalf("POST /transaction failed: %v", err)
+	}
+	var body protocol.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || body.Error.Code != protocol.ErrCodeInvalidRequest {
+		t.Errorf("Expected 400 invalid_request for a bad async flag, got %d %+v", resp.StatusCode, body)
+	}
+
+	// A client cannot choose the transaction ID or pose as a forwarding node.
+	resp, err = http.Post(server.URL+"/transaction?async=true", "application/json",
+		strings.NewReader(`{"payload":{},"transaction_id":"chosen-by-client","forwarded":true}`))
+	if err != nil {
+		t.Fatalf("POST /transaction failed: %v", err)
+	}
+	var accepted protocol.AsyncTransactionResponse
+	json.NewDecoder(resp.Body).Decode(&accepted)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || accepted.TransactionID == "" || accepted.TransactionID == "chosen-by-client" {
+		t.Errorf("Expected the client's transaction_id to be ignored, got %d %+v", resp.StatusCode, accepted)
+	}
+}
+
+func TestAsyncTransactionPanicFinishesJob(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+
+	release := make(chan struct{})
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		<-release
+		panic("boom")
+	})
+
+	resp, err := http.Post(server.URL+"/transaction?async=true", "application/json", strings.NewReader(`{"payload":{}}`))
+	if err != nil {
+		t.Fatalf("POST /transaction failed: %v", err)
+	}
+	var accepted protocol.AsyncTransactionResponse
+	json.NewDecoder(resp.Body).Decode(&accepted)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+
+	// Shutdown waits for the running job.
+	s.BeginShutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	err = s.WaitAsync(ctx)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected WaitAsync to wait for the running job, got %v", err)
+	}
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown did not see the panicking job finish: %v", err)
+	}
+
+	job, ok := s.async.get(accepted.TransactionID)
+	if !ok || job.finished.IsZero() || job.err == nil {
+		t.Fatalf("Expected the panic to finish the job with an error, got %+v", job)
+	}
+	if rec := job.record(accepted.TransactionID, nil); rec.Status != protocol.TxStatusFailed {
+		t.Errorf("Expected status %s, got %s", protocol.TxStatusFailed, rec.Status)
+	}
+}
+
+func TestForwardedTransactionsMustBeSigned(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8080", protocol.RoleMaster))
+	server := httptest.NewServer(s.mux)
+	defer server.Close()
+	s.SetForwardKey([]byte("cluster-secret"))
+
+	seen := make(chan protocol.TransactionRequest, 1)
+	s.SetTransactionHandler(func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		seen <- *req
+		return &protocol.TransactionResponse{Success: true, TransactionID: req.TransactionID}, nil
+	})
+	addr := server.Listener.Addr().String()
+	fwd := &protocol.TransactionRequest{Payload: map[string]any{"k": "v"}, Forwarded: true, TransactionID: "tx-fwd"}
+
+	if _, err := NewHTTPClient(time.Second).StartTransaction(addr, fwd); err == nil {
+		t.Fatal("Expected forwarding without a key to fail")
+	}
+
+	for _, tc := range []struct {
+		key       string
+		forwarded bool
+	}{
+		{"cluster-secret", true},
+		{"wrong-secret", false},
+	} {
+		if _, err := NewHTTPClient(time.Second).WithForwardKey([]byte(tc.key)).StartTransaction(addr, fwd); err != nil {
+			t.Fatalf("StartTransaction with key %q failed: %v", tc.key, err)
+		}
+		got := <-seen
+		if got.Forwarded != tc.forwarded || (got.TransactionID == "tx-fwd") != tc.forwarded {
+			t.Errorf("Key %q: handler saw forwarded=%v transaction_id=%q, want forwarded=%v", tc.key, got.Forwarded, got.TransactionID, tc.forwarded)
+		}
+	}
+}
+
+func TestClusterHealthEndpoint(t *testing.T) {
+	s, server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/cluster/health")
+	if err != nil {
+		t.Fatalf("GET /cluster/health failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without a handler, got %d", resp.StatusCode)
+	}
+
+	status := protocol.ClusterDegraded
+	s.SetClusterHealthHandler(func() *protocol.ClusterHealthResponse {
+		return &protocol.ClusterHealthResponse{Status: status, Score: 70}
+	})
+
+	resp, err = http.Get(server.URL + "/cluster/health")
+	if err != nil {
+		t.Fatalf("GET /cluster/health failed: %v", err)
+	}
+	var health protocol.ClusterHealthResponse
+	err = json.NewDecoder(resp.Body).Decode(&health)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || health.Score != 70 {
+		t.Errorf("Expected 200 with the score for a degraded cluster, got %d %+v (%v)", resp.StatusCode, health, err)
+	}
+
+	status = protocol.ClusterCritical
+	resp, err = http.Get(server.URL + "/cluster/health")
+	if err != nil {
+		t.Fatalf("GET /cluster/health failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a critical cluster, got %d", resp.StatusCode)
+	}
+}
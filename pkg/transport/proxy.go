@@ -0,0 +1,47 @@
+package transport
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetTrustProxyHeaders makes request logging honor X-Forwarded-For and
+// X-Forwarded-Proto. Enable it only behind a proxy that sets these headers;
+// otherwise clients can spoof them. Off by default.
+func (s *HTTPServer) SetTrustProxyHeaders(trust bool) {
+	s.trustProxy = trust
+}
+
+// clientAddr returns the client IP for logging: the first X-Forwarded-For hop
+// when proxy headers are trusted, otherwise the connection's remote host.
+func (s *HTTPServer) clientAddr(r *http.Request) string {
+	if s.trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			if first = strings.TrimSpace(first); first != "" {
+				return first
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestScheme returns the scheme the client used: X-Forwarded-Proto when
+// proxy headers are trusted, otherwise derived from the connection.
+func (s *HTTPServer) requestScheme(r *http.Request) string {
+	if s.trustProxy {
+		if proto := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto"))); proto != "" {
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
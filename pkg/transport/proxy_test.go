@@ -0,0 +1,44 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestClientAddrAndScheme(t *testing.T) {
+	s := NewHTTPServer(node.NewNode("localhost:8081", protocol.RoleSlave))
+
+	r := httptest.NewRequest("GET", "/health", nil)
+	r.RemoteAddr = "10.0.0.9:51234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.9")
+	r.Header.Set("X-Forwarded-Proto", "HTTPS")
+
+	if got := s.clientAddr(r); got != "10.0.0.9" {
+		t.Errorf("clientAddr = %q, want the remote host when proxy headers are untrusted", got)
+	}
+	if got := s.requestScheme(r); got != "http" {
+		t.Errorf("requestScheme = %q, want http when proxy headers are untrusted", got)
+	}
+
+	s.SetTrustProxyHeaders(true)
+	if got := s.clientAddr(r); got != "203.0.113.7" {
+		t.Errorf("clientAddr = %q, want the first forwarded hop", got)
+	}
+	if got := s.requestScheme(r); got != "https" {
+		t.Errorf("requestScheme = %q, want forwarded https", got)
+	}
+
+	direct := httptest.NewRequest("GET", "/health", nil)
+	direct.RemoteAddr = "192.0.2.1:4000"
+	direct.TLS = &tls.ConnectionState{}
+	if got := s.clientAddr(direct); got != "192.0.2.1" {
+		t.Errorf("clientAddr without headers = %q, want remote host", got)
+	}
+	if got := s.requestScheme(direct); got != "https" {
+		t.Errorf("requestScheme over TLS = %q, want https", got)
+	}
+}
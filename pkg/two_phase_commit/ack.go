@@ -0,0 +1,62 @@
+package twophasecommit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+)
+
+// CommitAckPolicy is how many commit acknowledgements the coordinator waits
+// for before answering a committed transaction. The decision is the same
+// under every policy; the weaker ones answer sooner and leave the remaining
+// commits to run in the background, where a node that fails to acknowledge
+// is handed to the recovery queue.
+type CommitAckPolicy string
+
+const (
+	// CommitAckAll waits for every participant to acknowledge the commit.
+	CommitAckAll CommitAckPolicy = "all"
+	// CommitAckLocal waits for the local node only.
+	CommitAckLocal CommitAckPolicy = "local"
+	// CommitAckNone answers as soon as the decision is made ("fire and recover").
+	CommitAckNone CommitAckPolicy = "none"
+)
+
+// ParseCommitAckPolicy validates a commit acknowledgement policy name.
+func ParseCommitAckPolicy(s string) (CommitAckPolicy, error) {
+	switch policy := CommitAckPolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case CommitAckAll, CommitAckLocal, CommitAckNone:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown commit ack policy %q (want %s, %s or %s)", s, CommitAckAll, CommitAckLocal, CommitAckNone)
+	}
+}
+
+// WithCommitAckPolicy sets the policy for transactions that do not choose
+// one. The default is CommitAckAll.
+func (c *Coordinator) WithCommitAckPolicy(policy CommitAckPolicy) *Coordinator {
+	c.ack = policy
+	return c
+}
+
+// ackPolicy resolves the policy of one transaction: requested, if set,
+// overrides the coordinator's default. Without a recovery queue a commit that
+// is not awaited could be lost, so every policy is clamped to CommitAckAll.
+func (c *Coordinator) ackPolicy(requested string) (CommitAckPolicy, error) {
+	policy := c.ack
+	if requested != "" {
+		var err error
+		if policy, err = ParseCommitAckPolicy(requested); err != nil {
+			return "", err
+		}
+	}
+	if policy == "" {
+		policy = CommitAckAll
+	}
+	if policy != CommitAckAll && c.recovery == nil {
+		logging.Debugf("[Coordinator] Commit ack policy %s needs a recovery queue, waiting for all acknowledgements", policy)
+		policy = CommitAckAll
+	}
+	return policy, nil
+}
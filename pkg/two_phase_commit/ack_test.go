@@ -0,0 +1,148 @@
+package twophasecommit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestCoordinator_PerRequestCommitAckPolicy(t *testing.T) {
+	slowCommit := commitSuccess()
+	slowCommit.delay = 300 * time.Millisecond
+	stub := newStubNodeServer(readyPrepare(0), slowCommit, abortSuccess())
+	defer stub.Close()
+
+	recovery := NewRecoveryQueue(time.Second, time.Hour)
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).
+		WithRecoveryQueue(recovery)
+
+	// The default waits for the slow acknowledgement.
+	start := time.Now()
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload()})
+	if err != nil || !resp.Success || !resp.Durable {
+		t.Fatalf("Expected a durable commit, got %#v, %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected the default policy to wait for the commit, returned after %v", elapsed)
+	}
+
+	// "none" answers once the decision is made and commits in the background.
+	start = time.Now()
+	resp, err = coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload(), AckPolicy: "none"})
+	if err != nil || !resp.Success || resp.Durable {
+		t.Fatalf("Expected a committed but unacknowledged transaction, got %#v, %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 300*time.Millisecond {
+		t.Errorf("Expected ack policy none not to wait for the commit, returned after %v", elapsed)
+	}
+	if len(resp.PendingNodes) != 1 || resp.PendingNodes[0] != stub.Addr() {
+		t.Errorf("Expected the unawaited node listed as pending, got %v", resp.PendingNodes)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for stub.callCounts().commit < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := stub.callCounts().commit; got != 2 {
+		t.Errorf("Expected the background commit delivered, got %d commits", got)
+	}
+	if got := len(recovery.Pending()); got != 0 {
+		t.Errorf("Expected nothing queued for recovery after an acknowledged commit, got %d", got)
+	}
+}
+
+func TestCoordinator_CommitAckPolicyClampedWithoutRecovery(t *testing.T) {
+	stub := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer stub.Close()
+
+	// Without a recovery queue an unawaited commit could be lost.
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).
+		WithCommitAckPolicy(CommitAckNone)
+
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload()})
+	if err != nil || !resp.Success || !resp.Durable {
+		t.Fatalf("Expected the policy clamped to all, got %#v, %v", resp, err)
+	}
+
+	resp, err = coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload(), AckPolicy: "some"})
+	if err != nil || resp.Success || resp.Code != protocol.ErrCodeInvalidRequest {
+		t.Fatalf("Expected an unknown policy rejected, got %#v, %v", resp, err)
+	}
+	if got := stub.callCounts().prepare; got != 1 {
+		t.Errorf("Expected the rejected transaction never to reach prepare, got %d prepares", got)
+	}
+}
+
+func TestCoordinator_CompletionHookWaitsForBackgroundCommits(t *testing.T) {
+	slowCommit := commitSuccess()
+	slowCommit.delay = 100 * time.Millisecond
+	stub := newStubNodeServer(readyPrepare(0), slowCommit, abortSuccess())
+	defer stub.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).
+		WithRecoveryQueue(NewRecoveryQueue(time.Second, time.Hour)).
+		WithCommitAckPolicy(CommitAckNone)
+	outcomes := make(chan TransactionOutcome, 1)
+	coordinator.SetOnComplete(func(o TransactionOutcome) { outcomes <- o })
+
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload()})
+	if err != nil || !resp.Success || resp.Durable {
+		t.Fatalf("Expected a committed but unacknowledged transaction, got %#v, %v", resp, err)
+	}
+
+	select {
+	case o := <-outcomes:
+		if !o.Durable || len(o.PendingNodes) != 0 {
+			t.Errorf("Expected the hook to report the acknowledged background commit as durable, got %#v", o)
+		}
+		if got := stub.callCounts().commit; got != 1 {
+			t.Errorf("Expected the hook to fire after the commit was delivered, got %d commits", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Completion hook was not called")
+	}
+}
+
+func TestCoordinator_WaitBackgroundCoversUnawaitedCommits(t *testing.T) {
+	slowCommit := commitSuccess()
+	slowCommit.delay = 300 * time.Millisecond
+	stub := newStubNodeServer(readyPrepare(0), slowCommit, abortSuccess())
+	defer stub.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).
+		WithRecoveryQueue(NewRecoveryQueue(time.Second, time.Hour)).
+		WithCommitAckPolicy(CommitAckNone)
+
+	reported := make(chan bool, 1)
+	coordinator.SetOnComplete(func(o TransactionOutcome) { reported <- o.Durable })
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil || !resp.Success {
+		t.Fatalf("Expected a commit, got %#v, %v", resp, err)
+	}
+
+	// Shutdown must not get past a commit the client was already told about.
+	short, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := coordinator.WaitBackground(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitBackground() = %v while the commit is in flight, want a deadline error", err)
+	}
+
+	if err := coordinator.WaitBackground(context.Background()); err != nil {
+		t.Fatalf("WaitBackground() = %v, want nil", err)
+	}
+	if got := stub.callCounts().commit; got != 1 {
+		t.Errorf("Expected the background commit delivered before WaitBackground returned, got %d commits", got)
+	}
+	select {
+	case durable := <-reported:
+		if !durable {
+			t.Error("Expected the hook to report the commit durable")
+		}
+	default:
+		t.Error("Expected the completion hook to run before WaitBackground returned")
+	}
+}
@@ -0,0 +1,171 @@
+package twophasecommit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// ErrCommitLogTampered is returned when a commit log entry does not verify.
+var ErrCommitLogTampered = errors.New("commit log tampered")
+
+// CommitPoint is one line of the commit log: the decision the coordinator
+// took for a transaction, when, and over which participants.
+type CommitPoint struct {
+	Seq          uint64           `json:"seq"`
+	TxID         string           `json:"tx_id"`
+	Decision     protocol.TxState `json:"decision"` // COMMIT or ABORT
+	Time         time.Time        `json:"time"`
+	Participants []string         `json:"participants"`
+	Prev         string           `json:"prev,omitempty"` // MAC of the previous entry
+	MAC          string           `json:"mac,omitempty"`
+}
+
+// sign returns the HMAC-SHA256 of the entry with its MAC field left out.
+func (p CommitPoint) sign(key []byte) (string, error) {
+	p.MAC = ""
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(raw)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CommitLog is an append-only file recording each decision the coordinator
+// takes, written and synced before the decision is sent to participants. Every
+// line is signed with an HMAC over its content and the previous line's MAC, so
+// an edited, removed or reordered entry fails VerifyCommitLog. Cutting entries
+// off the end of the file cannot be detected from the file alone.
+type CommitLog struct {
+	mu   sync.Mutex
+	file *os.File
+	key  []byte
+	seq  uint64
+	prev string
+}
+
+// OpenCommitLog opens or creates the commit log at path. An existing log must
+// verify with key, so the coordinator never extends a log it cannot vouch for.
+func OpenCommitLog(path string, key []byte) (*CommitLog, error) {
+	if len(key) == 0 {
+		return nil, errors.New("commit log key is required")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	last, _, err := verifyCommitLog(file, key)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("commit log %s: %w", path, err)
+	}
+
+	return &CommitLog{
+		file: file,
+		key:  key,
+		seq:  last.Seq,
+		prev: last.MAC,
+	}, nil
+}
+
+// Append records a decision for txID and syncs it to disk.
+func (l *CommitLog) Append(txID string, decision protocol.TxState, participants []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := CommitPoint{
+		Seq:          l.seq + 1,
+		TxID:         txID,
+		Decision:     decision,
+		Time:         time.Now().UTC(),
+		Participants: participants,
+		Prev:         l.prev,
+	}
+	mac, err := entry.sign(l.key)
+	if err != nil {
+		return err
+	}
+	entry.MAC = mac
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+
+	l.seq = entry.Seq
+	l.prev = entry.MAC
+	return nil
+}
+
+// Close closes the log file.
+func (l *CommitLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// VerifyCommitLog checks every entry read from r against key and returns how
+// many verified. The error wraps ErrCommitLogTampered and names the first line
+// that does not verify.
+func VerifyCommitLog(r io.Reader, key []byte) (int, error) {
+	_, n, err := verifyCommitLog(r, key)
+	return n, err
+}
+
+// verifyCommitLog returns the last verified entry and the number of entries.
+func verifyCommitLog(r io.Reader, key []byte) (CommitPoint, int, error) {
+	var last CommitPoint
+	n := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var entry CommitPoint
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return last, n, fmt.Errorf("%w: line %d is not an entry: %v", ErrCommitLogTampered, line, err)
+		}
+
+		want, err := entry.sign(key)
+		if err != nil {
+			return last, n, err
+		}
+		switch {
+		case !hmac.Equal([]byte(entry.MAC), []byte(want)):
+			return last, n, fmt.Errorf("%w: line %d (tx %s) has a bad signature", ErrCommitLogTampered, line, entry.TxID)
+		case entry.Seq != last.Seq+1 || entry.Prev != last.MAC:
+			return last, n, fmt.Errorf("%w: line %d (tx %s) does not follow seq %d", ErrCommitLogTampered, line, entry.TxID, last.Seq)
+		}
+
+		last = entry
+		n++
+	}
+
+	return last, n, scanner.Err()
+}
@@ -0,0 +1,197 @@
+package twophasecommit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+var testCommitLogKey = []byte("audit-key")
+
+func writeCommitLog(t *testing.T, entries int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "commit.log")
+	l, err := OpenCommitLog(path, testCommitLogKey)
+	if err != nil {
+		t.Fatalf("OpenCommitLog failed: %v", err)
+	}
+	defer l.Close()
+
+	for i := range entries {
+		decision := protocol.StateCommit
+		if i%2 == 1 {
+			decision = protocol.StateAbort
+		}
+		if err := l.Append("tx-"+string(rune('a'+i)), decision, []string{"node-a:8081", "node-b:8082"}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	return path
+}
+
+func TestCommitLogVerifies(t *testing.T) {
+	path := writeCommitLog(t, 3)
+
+	// Reopening continues the chain instead of starting a new one.
+	l, err := OpenCommitLog(path, testCommitLogKey)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	if err := l.Append("tx-d", protocol.StateCommit, nil); err != nil {
+		t.Fatalf("Append after reopen failed: %v", err)
+	}
+	l.Close()
+
+	raw, _ := os.ReadFile(path)
+	if n, err := VerifyCommitLog(bytes.NewReader(raw), testCommitLogKey); err != nil || n != 4 {
+		t.Fatalf("VerifyCommitLog = %d, %v; want 4 verified entries", n, err)
+	}
+
+	if _, err := VerifyCommitLog(bytes.NewReader(raw), []byte("other-key")); !errors.Is(err, ErrCommitLogTampered) {
+		t.Errorf("Expected the wrong key to fail verification, got %v", err)
+	}
+	if _, err := OpenCommitLog(path, []byte("other-key")); err == nil {
+		t.Error("Expected OpenCommitLog to refuse a log it cannot verify")
+	}
+}
+
+func TestCommitLogDetectsTampering(t *testing.T) {
+	raw, _ := os.ReadFile(writeCommitLog(t, 3))
+	lines := strings.SplitAfter(strings.TrimSuffix(string(raw), "\n"), "\n")
+
+	tests := map[string]struct {
+		log  string
+		line string
+	}{
+		"edited decision": {
+			log:  lines[0] + strings.Replace(lines[1], `"decision":"ABORT"`, `"decision":"COMMIT"`, 1) + lines[2],
+			line: "line 2",
+		},
+		"removed entry": {
+			log:  lines[0] + lines[2],
+			line: "line 2",
+		},
+		"reordered entries": {
+			log:  lines[1] + lines[0] + lines[2],
+			line: "line 1",
+		},
+		"garbage line": {
+			log:  lines[0] + "not json\n" + lines[1],
+			line: "line 2",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := VerifyCommitLog(strings.NewReader(tt.log), testCommitLogKey)
+			if !errors.Is(err, ErrCommitLogTampered) || !strings.Contains(err.Error(), tt.line) {
+				t.Fatalf("Expected tampering reported at %s, got %v", tt.line, err)
+			}
+		})
+	}
+}
+
+func TestCoordinator_CommitLogRecordsDecisions(t *testing.T) {
+	ready := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer ready.Close()
+	refusing := newStubNodeServer(stubEndpoint{
+		response: protocol.PrepareResponse{Status: protocol.StatusAbort, Error: "constraint violation"},
+	}, commitSuccess(), abortSuccess())
+	defer refusing.Close()
+
+	path := filepath.Join(t.TempDir(), "commit.log")
+	l, err := OpenCommitLog(path, testCommitLogKey)
+	if err != nil {
+		t.Fatalf("OpenCommitLog failed: %v", err)
+	}
+	defer l.Close()
+
+	committed, err := NewCoordinator(testClusterWithSlaves(ready.Addr()), nil, time.Second).WithCommitLog(l).Execute(samplePayload())
+	if err != nil || !committed.Success {
+		t.Fatalf("Expected a commit, got %+v, %v", committed, err)
+	}
+	aborted, err := NewCoordinator(testClusterWithSlaves(ready.Addr(), refusing.Addr()), nil, time.Second).WithCommitLog(l).Execute(samplePayload())
+	if err != nil || aborted.Success {
+		t.Fatalf("Expected an abort, got %+v, %v", aborted, err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if n, err := VerifyCommitLog(bytes.NewReader(raw), testCommitLogKey); err != nil || n != 2 {
+		t.Fatalf("VerifyCommitLog = %d, %v; want 2 entries", n, err)
+	}
+	if !strings.Contains(string(raw), `"tx_id":"`+committed.TransactionID+`","decision":"COMMIT"`) ||
+		!strings.Contains(string(raw), `"tx_id":"`+aborted.TransactionID+`","decision":"ABORT"`) {
+		t.Errorf("Expected both decisions in the log, got:\n%s", raw)
+	}
+}
+
+func TestCoordinator_CommitLogFailureAbortsCommit(t *testing.T) {
+	stub := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer stub.Close()
+
+	l, err := OpenCommitLog(filepath.Join(t.TempDir(), "commit.log"), testCommitLogKey)
+	if err != nil {
+		t.Fatalf("OpenCommitLog failed: %v", err)
+	}
+	l.Close() // every append now fails
+
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).WithCommitLog(l)
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success || resp.Code != protocol.ErrCodeTransactionAborted {
+		t.Fatalf("Expected the unrecorded commit to abort, got %+v", resp)
+	}
+	if got := stub.callCounts(); got.commit != 0 || got.abort != 1 {
+		t.Errorf("Expected the participant to be aborted, not committed, got %+v", got)
+	}
+
+	want := map[string]int64{protocol.AbortCommitLogError: 1}
+	for category, n := range coordinator.AbortBreakdown() {
+		if n != want[category] {
+			t.Errorf("AbortBreakdown[%s] = %d, want %d", category, n, want[category])
+		}
+	}
+}
+
+func TestCoordinator_CommitLogRecordsReconcileDecisions(t *testing.T) {
+	committed, _ := reconcileStubNode(t, protocol.TxStatusCommitted)
+	prepared, received := reconcileStubNode(t, protocol.TxStatusPrepared)
+
+	path := filepath.Join(t.TempDir(), "commit.log")
+	l, err := OpenCommitLog(path, testCommitLogKey)
+	if err != nil {
+		t.Fatalf("OpenCommitLog failed: %v", err)
+	}
+	defer l.Close()
+
+	coordinator := NewCoordinatorForAddrs([]string{committed, prepared}, nil, time.Second).WithCommitLog(l)
+	if _, err := coordinator.Reconcile("tx-reconciled"); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if n, err := VerifyCommitLog(bytes.NewReader(raw), testCommitLogKey); err != nil || n != 1 {
+		t.Fatalf("VerifyCommitLog = %d, %v; want 1 entry", n, err)
+	}
+	if !strings.Contains(string(raw), `"tx_id":"tx-reconciled","decision":"COMMIT"`) {
+		t.Errorf("Expected the reconcile decision in the log, got:\n%s", raw)
+	}
+
+	// A decision that cannot be recorded is not sent.
+	l.Close()
+	if _, err := coordinator.Reconcile("tx-unrecorded"); err == nil {
+		t.Error("Expected Reconcile to fail when the commit log cannot record the decision")
+	}
+	if got := received(); len(got) != 1 {
+		t.Errorf("Expected only the recorded decision to reach the prepared node, got %v", got)
+	}
+}
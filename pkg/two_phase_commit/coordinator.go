@@ -1,36 +1,415 @@
 package twophasecommit
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"math"
+	"net"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 	"github.com/baxromumarov/2pc-engine/pkg/transport"
 	"github.com/google/uuid"
 )
 
+// LocalNoDBPolicy controls how the coordinator treats a local node without a database.
+type LocalNoDBPolicy int
+
+const (
+	// LocalNoDBParticipate lets the local node participate in-memory only (logged as a warning).
+	LocalNoDBParticipate LocalNoDBPolicy = iota
+	// LocalNoDBSkip excludes the local node from transactions.
+	LocalNoDBSkip
+	// LocalNoDBRefuse rejects transactions while the local node has no database.
+	LocalNoDBRefuse
+)
+
+// CommitOrder controls the order in which the local node and remote participants
+// receive the commit decision.
+type CommitOrder string
+
+const (
+	// CommitParallel commits the local node and remote participants concurrently.
+	CommitParallel CommitOrder = "parallel"
+	// CommitLocalFirst commits the local node before any remote participant.
+	CommitLocalFirst CommitOrder = "local-first"
+	// CommitRemoteFirst commits remote participants before the local node.
+	CommitRemoteFirst CommitOrder = "remote-first"
+)
+
+// ParseCommitOrder validates a commit order name.
+func ParseCommitOrder(s string) (CommitOrder, error) {
+	switch order := CommitOrder(s); order {
+	case CommitParallel, CommitLocalFirst, CommitRemoteFirst:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown commit order %q (want %s, %s or %s)", s, CommitParallel, CommitLocalFirst, CommitRemoteFirst)
+	}
+}
+
+// PrepareOrder controls how prepare requests reach the participants.
+type PrepareOrder string
+
+const (
+	// PrepareParallel prepares the local node, then every remote participant
+	// concurrently. Lowest latency, but nodes acquire row locks in no fixed order.
+	PrepareParallel PrepareOrder = "parallel"
+	// PrepareSequential prepares the participants one at a time in canonical
+	// address order, the local node included, and stops at the first one that
+	// does not vote READY. Concurrent transactions over the same rows then lock
+	// them node by node in the same order, which avoids cross-node deadlocks
+	// at the cost of one round trip per participant.
+	PrepareSequential PrepareOrder = "sequential"
+)
+
+// ParsePrepareOrder validates a prepare order name.
+func ParsePrepareOrder(s string) (PrepareOrder, error) {
+	switch order := PrepareOrder(s); order {
+	case PrepareParallel, PrepareSequential:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown prepare order %q (want %s or %s)", s, PrepareParallel, PrepareSequential)
+	}
+}
+
 // Coordinator manages the 2PC protocol from the master's perspective
 type Coordinator struct {
-	cluster   *cluster.Cluster
-	localNode *node.Node // The local (master) node that also participates
-	client    *transport.HTTPClient
-	timeout   time.Duration
-	mu        sync.Mutex
+	participants Participants
+	localNode    *node.Node // The local (master) node that also participates
+	client       *transport.HTTPClient
+	timeout      time.Duration
+	localNoDB    LocalNoDBPolicy
+	coordOnly    bool // the local node coordinates but never participates
+	order        CommitOrder
+	prepareOrder PrepareOrder
+	ack          CommitAckPolicy // commit acknowledgements awaited by default
+	limits       node.PayloadLimits
+	maxParts     int            // refuse transactions fanning out to more participants; 0 = unlimited
+	recovery     *RecoveryQueue // retries unacknowledged decisions; nil disables recovery
+	commitLog    *CommitLog     // signed record of each decision; nil disables it
+	durability   DurabilityRule // participants that must prepare before a commit
+	limiter      *tokenBucket   // caps the transaction rate; nil = unlimited
+	throttle     ThrottleMode   // what happens to transactions over the rate
+	verbose      bool           // list excluded members in transaction responses
+	mu           sync.Mutex
+
+	// inflight tracks transactions between Execute start and return. It has its
+	// own lock so it can be read while mu is held for a running round.
+	inflightMu sync.Mutex
+	inflight   map[string]*protocol.InflightTransaction
+
+	// aborts counts aborted transactions by category (protocol.Abort*).
+	abortsMu sync.Mutex
+	aborts   map[string]int64
+
+	// onComplete is called after each decided transaction, see SetOnComplete.
+	hookMu     sync.Mutex
+	onComplete func(TransactionOutcome)
+
+	// background tracks commits delivered after Execute returned, see WaitBackground.
+	background sync.WaitGroup
 }
 
 // NewCoordinator creates a new 2PC coordinator
 func NewCoordinator(c *cluster.Cluster, localNode *node.Node, timeout time.Duration) *Coordinator {
+	return NewCoordinatorWithParticipants(c, localNode, timeout)
+}
+
+// NewCoordinatorForAddrs creates a coordinator for an ad-hoc 2PC over a fixed
+// list of participant addresses, without cluster membership or heartbeats.
+// localNode may be nil when the caller does not participate itself.
+func NewCoordinatorForAddrs(addrs []string, localNode *node.Node, timeout time.Duration) *Coordinator {
+	return NewCoordinatorWithParticipants(NewStaticParticipants(addrs), localNode, timeout)
+}
+
+// NewCoordinatorWithParticipants creates a coordinator that takes its
+// participants from p.
+func NewCoordinatorWithParticipants(p Participants, localNode *node.Node, timeout time.Duration) *Coordinator {
 	return &Coordinator{
-		cluster:   c,
-		localNode: localNode,
-		client:    transport.NewHTTPClient(timeout),
-		timeout:   timeout,
+		participants: p,
+		localNode:    localNode,
+		client:       transport.NewHTTPClient(timeout),
+		timeout:      timeout,
+		order:        CommitParallel,
+		prepareOrder: PrepareParallel,
+		ack:          CommitAckAll,
+		limits:       node.DefaultPayloadLimits,
+		inflight:     make(map[string]*protocol.InflightTransaction),
+		aborts:       make(map[string]int64),
+	}
+}
+
+// WithHTTPClient replaces the client used to reach participants, e.g. one
+// configured with retries or basic auth. The client's own timeout applies in
+// place of the one given to the constructor. A nil client is ignored.
+func (c *Coordinator) WithHTTPClient(client *transport.HTTPClient) *Coordinator {
+	if client != nil {
+		c.client = client
+	}
+	return c
+}
+
+// WithLocalNoDBPolicy configures how a local node without a database is handled.
+// The default (LocalNoDBParticipate) preserves the in-memory participation behavior.
+func (c *Coordinator) WithLocalNoDBPolicy(policy LocalNoDBPolicy) *Coordinator {
+	c.localNoDB = policy
+	return c
+}
+
+// WithLocalParticipation controls whether the local node takes part in
+// transactions. With false it only coordinates: it is never prepared, so it
+// needs no database, and naming it in targets rejects the transaction.
+func (c *Coordinator) WithLocalParticipation(enabled bool) *Coordinator {
+	c.coordOnly = !enabled
+	return c
+}
+
+// WithCommitOrder sets the order in which commit is sent to the local node and
+// remote participants. The default is CommitParallel.
+func (c *Coordinator) WithCommitOrder(order CommitOrder) *Coordinator {
+	c.order = order
+	return c
+}
+
+// WithPrepareOrder sets how prepare requests reach the participants.
+func (c *Coordinator) WithPrepareOrder(order PrepareOrder) *Coordinator {
+	c.prepareOrder = order
+	return c
+}
+
+// WithPayloadLimits overrides the payload size limits enforced before prepare.
+func (c *Coordinator) WithPayloadLimits(limits node.PayloadLimits) *Coordinator {
+	c.limits = limits
+	return c
+}
+
+// WithMaxParticipants refuses transactions that would prepare on more than n
+// nodes (local node included), guarding against an accidental fan-out to a
+// huge or corrupted membership. Zero, the default, means unlimited.
+func (c *Coordinator) WithMaxParticipants(n int) *Coordinator {
+	c.maxParts = n
+	return c
+}
+
+// WithRateLimit caps the rate at which transactions start to tps per second,
+// allowing bursts of up to burst transactions (burst < 1 means one second's
+// worth). Transactions over the rate are rejected or delayed according to mode.
+// A tps of zero or less, the default, means unlimited.
+func (c *Coordinator) WithRateLimit(tps float64, burst int, mode ThrottleMode) *Coordinator {
+	c.limiter = nil
+	if tps > 0 {
+		c.limiter = newTokenBucket(tps, burst)
+	}
+	c.throttle = mode
+	return c
+}
+
+// WithVerboseResponses lists the members left out of each transaction, with
+// the reason, in TransactionResponse.Excluded. Exclusions are always logged at
+// debug level.
+func (c *Coordinator) WithVerboseResponses(enabled bool) *Coordinator {
+	c.verbose = enabled
+	return c
+}
+
+// WithRecoveryQueue hands remote participants that fail to acknowledge a commit
+// or abort decision to q, which keeps retrying until they confirm.
+func (c *Coordinator) WithRecoveryQueue(q *RecoveryQueue) *Coordinator {
+	c.recovery = q
+	return c
+}
+
+// WithCommitLog records every commit or abort decision in l before it is sent
+// to participants. A commit whose decision cannot be recorded is aborted.
+func (c *Coordinator) WithCommitLog(l *CommitLog) *Coordinator {
+	c.commitLog = l
+	return c
+}
+
+// WithDurabilityRule refuses to commit unless rule holds after prepare, e.g.
+// local+1 requires the local node and at least one remote to have prepared.
+// Transactions that fall short are aborted. The default, DurabilityAll, adds
+// no requirement.
+func (c *Coordinator) WithDurabilityRule(rule DurabilityRule) *Coordinator {
+	c.durability = rule
+	return c
+}
+
+// identity is the coordinator address sent with prepare/commit/abort so
+// participants can fence decisions from another coordinator.
+func (c *Coordinator) identity() string {
+	if c.localNode == nil {
+		return ""
+	}
+	return c.localNode.Addr
+}
+
+// WaitBackground waits for the commits still being delivered in the
+// background, and the completion hooks that report them, or until ctx is
+// done. With a commit acknowledgement policy other than "all" the client is
+// told a transaction committed before every participant acknowledged it, so
+// shutdown calls this before stopping the recovery queue; otherwise a commit
+// in flight would be dropped and later presumed aborted.
+func (c *Coordinator) WaitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AbortBreakdown returns how many transactions aborted in each category.
+// Every category is present, with zero when it never occurred.
+func (c *Coordinator) AbortBreakdown() map[string]int64 {
+	c.abortsMu.Lock()
+	defer c.abortsMu.Unlock()
+
+	out := map[string]int64{
+		protocol.AbortVoteAbort:             0,
+		protocol.AbortPrepareTimeout:        0,
+		protocol.AbortPrepareTransportError: 0,
+		protocol.AbortNoParticipants:        0,
+		protocol.AbortNodeAtCapacity:        0,
+		protocol.AbortResourcePressure:      0,
+		protocol.AbortCommitLogError:        0,
+		protocol.AbortDurabilityUnmet:       0,
+	}
+	for category, n := range c.aborts {
+		out[category] = n
+	}
+	return out
+}
+
+func (c *Coordinator) recordAbort(category string) {
+	c.abortsMu.Lock()
+	c.aborts[category]++
+	c.abortsMu.Unlock()
+}
+
+// InFlight returns a snapshot of the transactions currently being coordinated, oldest first.
+func (c *Coordinator) InFlight() []protocol.InflightTransaction {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	out := make([]protocol.InflightTransaction, 0, len(c.inflight))
+	for _, tx := range c.inflight {
+		out = append(out, *tx)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].StartedAt.Before(out[j].StartedAt)
+	})
+
+	return out
+}
+
+func (c *Coordinator) trackInflight(txID string) {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	c.inflight[txID] = &protocol.InflightTransaction{
+		TransactionID: txID,
+		StartedAt:     time.Now(),
+		Phase:         protocol.PhasePreparing,
+	}
+}
+
+func (c *Coordinator) updateInflight(txID, phase string, participants int) {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	if tx, ok := c.inflight[txID]; ok {
+		tx.Phase = phase
+		tx.Participants = participants
+	}
+}
+
+func (c *Coordinator) isInflight(txID string) bool {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	_, ok := c.inflight[txID]
+	return ok
+}
+
+func (c *Coordinator) untrackInflight(txID string) {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	delete(c.inflight, txID)
+}
+
+// TransactionDetail asks every cluster member for its record of txID and
+// assembles a per-node status map. Nodes without a record are reported as
+// MISSING and nodes that cannot be queried as UNREACHABLE.
+func (c *Coordinator) TransactionDetail(txID string) *protocol.TransactionDetailResponse {
+	members := c.participants.GetNodes()
+	statuses := make([]protocol.TransactionNodeStatus, len(members))
+
+	var wg sync.WaitGroup
+	wg.Add(len(members))
+
+	for i, m := range members {
+		idx := i
+		addr := m.Addr
+		go func() {
+			defer wg.Done()
+
+			var rec *protocol.TransactionRecord
+			var err error
+			if c.localNode != nil && cluster.SameAddr(addr, c.localNode.Addr) {
+				ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+				defer cancel()
+				rec, err = c.localNode.GetTransaction(ctx, txID)
+			} else {
+				rec, err = c.client.GetTransaction(addr, txID)
+			}
+
+			switch {
+			case err != nil:
+				statuses[idx] = protocol.TransactionNodeStatus{
+					Status: protocol.TxStatusUnreachable,
+					Error:  err.Error(),
+				}
+			case rec == nil:
+				statuses[idx] = protocol.TransactionNodeStatus{Status: protocol.TxStatusMissing}
+			default:
+				status := protocol.TransactionNodeStatus{Status: rec.Status}
+				if !rec.UpdatedAt.IsZero() {
+					updated := rec.UpdatedAt
+					status.UpdatedAt = &updated
+				}
+				statuses[idx] = status
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	detail := &protocol.TransactionDetailResponse{
+		TransactionID: txID,
+		Nodes:         make(map[string]protocol.TransactionNodeStatus, len(members)),
+		Generated:     time.Now(),
+	}
+	for i, m := range members {
+		detail.Nodes[m.Addr] = statuses[i]
 	}
+
+	return detail
 }
 
 // PrepareResult holds the result of a prepare request
@@ -52,79 +431,567 @@ type prepareOutcome struct {
 	includeLocal    bool
 	localPrepared   bool
 	preparedRemotes []string
+	unsureRemotes   []string // prepare returned no vote; they may still hold a prepared transaction
 	failedNodes     []string
+	rowsAffected    map[string]int64
+	abortCategory   string // why prepare failed (protocol.Abort*), empty when every node voted READY
+	ruleErr         error  // why the durability rule refused a commit every node voted for
+
+	// RETURNING values per node, reported only once the decision is COMMIT
+	returned map[string][]map[string]any
+}
+
+// addReturned keeps the RETURNING values a participant read back at prepare.
+func (o *prepareOutcome) addReturned(addr string, rows []map[string]any) {
+	if len(rows) == 0 {
+		return
+	}
+	if o.returned == nil {
+		o.returned = make(map[string][]map[string]any)
+	}
+	o.returned[addr] = rows
 }
 
-// Execute runs the 2PC protocol for a transaction
+// Execute runs the 2PC protocol for a transaction across all alive nodes
 func (c *Coordinator) Execute(payload any) (*protocol.TransactionResponse, error) {
+	return c.ExecuteRequest(&protocol.TransactionRequest{Payload: payload})
+}
+
+// ExecuteRequest runs the 2PC protocol honoring the request's target subset and
+// RecordOnAll option.
+func (c *Coordinator) ExecuteRequest(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+	// Wait for the rate limiter before taking the lock, so a throttled
+	// transaction does not hold up the one in progress.
+	if resp := c.applyRateLimit(); resp != nil {
+		return resp, nil
+	}
+
+	resp, done, err := c.execute(req)
+	c.notifyComplete(done)
+	return resp, err
+}
+
+// execute runs one transaction under the coordinator lock. done describes
+// the decision, or is nil when the transaction was rejected before prepare.
+func (c *Coordinator) execute(req *protocol.TransactionRequest) (resp *protocol.TransactionResponse, done *TransactionOutcome, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	txID := uuid.New().String()
-	log.Printf("[Coordinator] Starting 2PC for transaction %s", txID)
+	payload := req.Payload
+	txID := req.TransactionID // assigned by the node that accepted an async request
+	if txID == "" {
+		txID = uuid.New().String()
+	}
+	logging.Debugf("[Coordinator] Starting 2PC for transaction %s", txID)
+
+	c.trackInflight(txID)
+	defer c.untrackInflight(txID)
+	started := time.Now()
+
+	if err := node.ValidatePayload(payload, c.limits); err != nil {
+		logging.Warnf("[Coordinator] Rejecting transaction %s: %v", txID, err)
+		return &protocol.TransactionResponse{
+			TransactionID: txID,
+			Success:       false,
+			Error:         fmt.Sprintf("Payload rejected: %v", err),
+			Code:          protocol.ErrCodeInvalidPayload,
+		}, nil, nil
+	}
+
+	ack, err := c.ackPolicy(req.AckPolicy)
+	if err != nil {
+		logging.Warnf("[Coordinator] Rejecting transaction %s: %v", txID, err)
+		return &protocol.TransactionResponse{
+			TransactionID: txID,
+			Success:       false,
+			Error:         err.Error(),
+			Code:          protocol.ErrCodeInvalidRequest,
+		}, nil, nil
+	}
 
 	// Get all alive participant nodes (slaves)
-	remoteParticipants := c.cluster.GetSlaveNodes()
+	remoteParticipants := c.dedupeParticipants(c.remoteCandidates())
+	includeLocal := c.localNode != nil && !c.coordOnly
+	localReason := ""
+	if c.coordOnly {
+		localReason = protocol.ExcludeCoordinateOnly
+	}
+
+	// Once participants are chosen, explain who was left out and why.
+	var excluded []protocol.ExcludedNode
+	defer func() {
+		if c.verbose && resp != nil {
+			resp.Excluded = excluded
+		}
+	}()
+
+	if len(req.Targets) > 0 {
+		remoteParticipants, includeLocal, err = c.selectTargets(req.Targets, remoteParticipants)
+		if err != nil {
+			logging.Warnf("[Coordinator] Rejecting transaction %s: %v", txID, err)
+			return &protocol.TransactionResponse{
+				TransactionID: txID,
+				Success:       false,
+				Error:         err.Error(),
+				Code:          protocol.ErrorCode(err, protocol.ErrCodeTargetsUnavailable),
+			}, nil, nil
+		}
+		if c.localNode != nil && !includeLocal && localReason == "" {
+			localReason = protocol.ExcludeNotTargeted
+		}
+	}
+
+	sortParticipants(remoteParticipants)
+
+	// A local node marked dead is excluded just like dead remotes.
+	if includeLocal && !c.localNode.GetAlive() {
+		logging.Warnf("[Coordinator] Local node %s is not alive, excluding it from transaction %s", c.localNode.Addr, txID)
+		includeLocal = false
+		localReason = protocol.ExcludeDead
+	}
+	if includeLocal && !c.localDBHealthy() {
+		logging.Warnf("[Coordinator] Local node %s cannot reach its database, excluding it from transaction %s", c.localNode.Addr, txID)
+		includeLocal = false
+		localReason = protocol.ExcludeNotReady
+	}
+	if includeLocal && c.localNode.GetMaintenance() {
+		logging.Debugf("[Coordinator] Local node %s is in maintenance, excluding it from transaction %s", c.localNode.Addr, txID)
+		includeLocal = false
+		localReason = protocol.ExcludeMaintenance
+	}
+	if includeLocal && c.localNode.GetDraining() {
+		logging.Debugf("[Coordinator] Local node %s is draining, excluding it from transaction %s", c.localNode.Addr, txID)
+		includeLocal = false
+		localReason = protocol.ExcludeDraining
+	}
 
 	// Calculate total participants (remote slaves + local master if it has a DB)
 	totalParticipants := len(remoteParticipants)
-	includeLocal := c.localNode != nil
+	if includeLocal && !c.localNode.HasDB() {
+		switch c.localNoDB {
+		case LocalNoDBSkip:
+			logging.Debugf("[Coordinator] Local node %s has no database, excluding it from transaction %s", c.localNode.Addr, txID)
+			includeLocal = false
+			localReason = protocol.ExcludeNoDatabase
+		case LocalNoDBRefuse:
+			logging.Warnf("[Coordinator] Local node %s has no database, refusing transaction %s", c.localNode.Addr, txID)
+			return &protocol.TransactionResponse{
+				TransactionID: txID,
+				Success:       false,
+				Error:         "Local node has no database; refusing non-durable participation",
+				Code:          protocol.ErrCodeLocalNoDatabase,
+			}, nil, nil
+		default:
+			logging.Warnf("[Coordinator] local node %s has no database, its participation in transaction %s is not durable", c.localNode.Addr, txID)
+		}
+	}
 	if includeLocal {
 		totalParticipants++
 	}
+	excluded = c.excludedNodes(remoteParticipants, localReason, len(req.Targets) > 0)
+	if len(excluded) > 0 {
+		logging.Debugf("[Coordinator] Transaction %s excludes %s", txID, formatExcluded(excluded))
+	}
+
+	// With no remotes, an in-memory local node is the only copy of the commit.
+	localOnlyNonDurable := includeLocal && len(remoteParticipants) == 0 && !c.localNode.HasDB()
+
+	if c.maxParts > 0 && totalParticipants > c.maxParts {
+		logging.Errorf("[Coordinator] Refusing transaction %s: %d participants exceed the limit of %d", txID, totalParticipants, c.maxParts)
+		return &protocol.TransactionResponse{
+			TransactionID: txID,
+			Success:       false,
+			Error:         fmt.Sprintf("Transaction would involve %d participants, above the limit of %d", totalParticipants, c.maxParts),
+			Code:          protocol.ErrCodeTooManyParticipants,
+		}, nil, nil
+	}
 
 	if totalParticipants == 0 {
+		c.recordAbort(protocol.AbortNoParticipants)
 		return &protocol.TransactionResponse{
 			TransactionID: txID,
 			Success:       false,
 			Error:         "No participants available",
-		}, nil
+			Code:          protocol.ErrCodeNoParticipants,
+		}, nil, nil
 	}
 
-	log.Printf("[Coordinator] Found %d participants for transaction %s (including local: %v)", totalParticipants, txID, includeLocal)
+	logging.Debugf("[Coordinator] Found %d participants for transaction %s (including local: %v)", totalParticipants, txID, includeLocal)
 
-	participantAddrs := make([]string, 0, len(remoteParticipants))
-	for _, p := range remoteParticipants {
-		participantAddrs = append(participantAddrs, p.Addr)
+	c.updateInflight(txID, protocol.PhasePreparing, totalParticipants)
+	outcome := c.prepareTransaction(txID, payload, includeLocal, remoteParticipants)
+	if len(outcome.failedNodes) == 0 {
+		if err := c.durability.check(outcome, c.localNode != nil && c.localNode.HasDB()); err != nil {
+			logging.Warnf("[Coordinator] Refusing to commit transaction %s: %v", txID, err)
+			outcome.failedNodes = append(outcome.failedNodes, "durability rule")
+			outcome.abortCategory = protocol.AbortDurabilityUnmet
+			outcome.ruleErr = err
+		}
+	}
+	if len(outcome.failedNodes) == 0 {
+		if err := c.logDecision(txID, protocol.StateCommit, includeLocal, remoteParticipants); err != nil {
+			logging.Errorf("[Coordinator] Failed to record the commit point of %s, aborting: %v", txID, err)
+			outcome.failedNodes = append(outcome.failedNodes, "commit log")
+			outcome.abortCategory = protocol.AbortCommitLogError
+		}
+	} else if err := c.logDecision(txID, protocol.StateAbort, includeLocal, remoteParticipants); err != nil {
+		// Nothing was committed, so a missing abort record loses no outcome.
+		logging.Errorf("[Coordinator] Failed to record the abort of %s: %v", txID, err)
 	}
 
-	outcome := c.prepareTransaction(txID, payload, includeLocal, remoteParticipants)
 	if len(outcome.failedNodes) > 0 {
-		abortErr := c.abortTransaction(txID, outcome, participantAddrs)
+		c.recordAbort(outcome.abortCategory)
+		c.updateInflight(txID, protocol.PhaseAborting, totalParticipants)
+		failedAborts, abortErr := c.abortTransaction(txID, outcome)
 		errMsg := fmt.Sprintf("Prepare failed for nodes: %v", outcome.failedNodes)
+		switch outcome.abortCategory {
+		case protocol.AbortCommitLogError:
+			errMsg = "Commit point could not be recorded"
+		case protocol.AbortDurabilityUnmet:
+			errMsg = "Aborted: " + outcome.ruleErr.Error()
+		}
+		if len(failedAborts) > 0 {
+			errMsg = fmt.Sprintf("%s; abort failed for nodes: %v", errMsg, failedAborts)
+		}
 		if abortErr != nil {
 			errMsg = fmt.Sprintf("%s; abort errors: %v", errMsg, abortErr)
 		}
 
+		done = &TransactionOutcome{
+			TransactionID: txID,
+			Decision:      protocol.StateAbort,
+			Participants:  c.participantAddrs(includeLocal, remoteParticipants),
+			Duration:      time.Since(started),
+			AbortCategory: outcome.abortCategory,
+		}
 		return &protocol.TransactionResponse{
 			TransactionID: txID,
 			Success:       false,
 			Error:         errMsg,
-		}, nil
+			Code:          protocol.ErrCodeTransactionAborted,
+		}, done, nil
 	}
 
-	commitSuccess, totalCommitted, failedCommitNodes, commitErr := c.commitTransaction(txID, outcome)
+	// Every participant voted READY, so the decision is COMMIT. Nodes that fail to
+	// acknowledge still hold a prepared transaction and must be finalized later.
+	c.updateInflight(txID, protocol.PhaseCommitting, totalParticipants)
+	awaitedOK, totalCommitted, pendingNodes, settle, commitErr := c.commitTransaction(txID, outcome, ack)
+	commitSuccess := awaitedOK && settle == nil
+	if req.RecordOnAll {
+		c.recordObserved(txID, payload, includeLocal, remoteParticipants)
+	}
+	done = &TransactionOutcome{
+		TransactionID: txID,
+		Decision:      protocol.StateCommit,
+		Participants:  c.participantAddrs(includeLocal, remoteParticipants),
+		Duration:      time.Since(started),
+		Durable:       commitSuccess && !localOnlyNonDurable,
+		PendingNodes:  pendingNodes,
+	}
+	if settle != nil {
+		done.settle = func() {
+			done.Durable, done.PendingNodes = settle()
+			done.Duration = time.Since(started)
+		}
+	}
 	if commitSuccess {
+		msg := fmt.Sprintf("Transaction committed on %d nodes", totalCommitted)
+		if localOnlyNonDurable {
+			msg = "Transaction committed on 1 node (no durability)"
+			logging.Warnf("[Coordinator] Transaction %s committed only in memory on local node %s", txID, c.localNode.Addr)
+		}
 		return &protocol.TransactionResponse{
 			TransactionID: txID,
 			Success:       true,
-			Message:       fmt.Sprintf("Transaction committed on %d nodes", totalCommitted),
-		}, nil
+			Message:       msg,
+			RowsAffected:  outcome.rowsAffected,
+			Returned:      outcome.returned,
+			Durable:       !localOnlyNonDurable,
+		}, done, nil
 	}
 
-	errMsg := "Some commits failed"
-	if len(failedCommitNodes) > 0 {
-		errMsg = fmt.Sprintf("Commit failed for nodes: %v", failedCommitNodes)
+	msg := fmt.Sprintf("Transaction committed on %d nodes; commit pending on %v", totalCommitted, pendingNodes)
+	if c.recovery != nil {
+		msg += " (queued for recovery)"
+	}
+	if ack != CommitAckAll {
+		msg += fmt.Sprintf(" (ack policy %s)", ack)
 	}
 	if commitErr != nil {
-		errMsg = fmt.Sprintf("%s; details: %v", errMsg, commitErr)
+		msg = fmt.Sprintf("%s; details: %v", msg, commitErr)
+	}
+	if awaitedOK {
+		// Only the commits ack chose not to wait for are outstanding.
+		logging.Debugf("[Coordinator] Transaction %s decided COMMIT, awaiting background acknowledgements: %s", txID, msg)
+	} else {
+		logging.Warnf("[Coordinator] Transaction %s decided COMMIT but is not durable yet: %s", txID, msg)
 	}
 
 	return &protocol.TransactionResponse{
 		TransactionID: txID,
-		Success:       false,
-		Error:         errMsg,
-	}, nil
+		Success:       true,
+		Message:       msg,
+		RowsAffected:  outcome.rowsAffected,
+		Returned:      outcome.returned,
+		Durable:       false,
+		PendingNodes:  pendingNodes,
+	}, done, nil
+}
+
+// applyRateLimit takes a token from the rate limiter, sleeping first in wait
+// mode. It returns the response for a throttled transaction, or nil to proceed.
+func (c *Coordinator) applyRateLimit() *protocol.TransactionResponse {
+	if c.limiter == nil {
+		return nil
+	}
+
+	var maxWait time.Duration
+	if c.throttle == ThrottleWait {
+		maxWait = c.timeout
+	}
+
+	wait, ok := c.limiter.reserve(maxWait)
+	if !ok {
+		logging.Debugf("[Coordinator] Throttling transaction: over %g transactions/s", c.limiter.rate)
+		return &protocol.TransactionResponse{
+			Success:      false,
+			Error:        fmt.Sprintf("Rate limit exceeded: at most %g transactions/s", c.limiter.rate),
+			RetryAfterMs: int64(math.Ceil(float64(wait) / float64(time.Millisecond))),
+			Code:         protocol.ErrCodeRateLimited,
+		}
+	}
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return nil
+}
+
+// selectTargets narrows the alive participants to the requested addresses or
+// display names. Every target, including the local node, must be a known, alive
+// member of the cluster, and a name shared by several members is rejected.
+func (c *Coordinator) selectTargets(targets []string, alive []*node.Node) ([]*node.Node, bool, error) {
+	aliveByAddr := make(map[string]*node.Node, len(alive))
+	for _, n := range alive {
+		aliveByAddr[cluster.CanonicalAddr(n.Addr)] = n
+	}
+
+	// Targets may name nodes by display name; dead members count too so that
+	// naming one reports it as unavailable rather than unknown.
+	members := c.participants.GetNodes()
+	if c.localNode != nil {
+		members = append(members, c.localNode)
+	}
+
+	var selected []*node.Node
+	var unavailable []string
+	includeLocal := false
+	seen := make(map[string]bool, len(targets))
+
+	for _, addr := range targets {
+		n, err := cluster.ResolveNodeRef(addr, members)
+		switch {
+		case err == nil:
+			addr = n.Addr
+		case errors.Is(err, cluster.ErrAmbiguousNode):
+			return nil, false, err
+		}
+
+		key := cluster.CanonicalAddr(addr)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if c.localNode != nil && key == cluster.CanonicalAddr(c.localNode.Addr) {
+			if c.coordOnly || !c.localNode.GetAlive() || !c.localDBHealthy() || c.localNode.GetMaintenance() || c.localNode.GetDraining() {
+				unavailable = append(unavailable, addr)
+				continue
+			}
+			includeLocal = true
+			continue
+		}
+		if n, ok := aliveByAddr[key]; ok {
+			selected = append(selected, n)
+			continue
+		}
+		unavailable = append(unavailable, addr)
+	}
+
+	if len(unavailable) > 0 {
+		return nil, false, fmt.Errorf("Target nodes unavailable: %v", unavailable)
+	}
+
+	return selected, includeLocal, nil
+}
+
+// excludedNodes lists the members that are not among participants, with the
+// reason each was left out. localReason is why the local node was excluded, or
+// empty when it participates. Members whose role is master are the coordinator,
+// not participants, and are not listed.
+func (c *Coordinator) excludedNodes(participants []*node.Node, localReason string, targeted bool) []protocol.ExcludedNode {
+	seen := make(map[string]bool, len(participants)+1)
+	for _, p := range participants {
+		seen[cluster.CanonicalAddr(p.Addr)] = true
+	}
+
+	var excluded []protocol.ExcludedNode
+	if c.localNode != nil {
+		seen[cluster.CanonicalAddr(c.localNode.Addr)] = true
+		if localReason != "" {
+			excluded = append(excluded, protocol.ExcludedNode{Address: c.localNode.Addr, Reason: localReason})
+		}
+	}
+
+	for _, n := range c.participants.GetNodes() {
+		key := cluster.CanonicalAddr(n.Addr)
+		if seen[key] || n.GetRole() == protocol.RoleMaster {
+			continue
+		}
+		seen[key] = true
+
+		var reason string
+		switch {
+		case n.GetDisabled():
+			reason = protocol.ExcludeDisabled
+		case n.GetMaintenance():
+			reason = protocol.ExcludeMaintenance
+		case !n.GetAlive():
+			reason = protocol.ExcludeDead
+		case n.GetDraining():
+			reason = protocol.ExcludeDraining
+		case !n.GetReady():
+			reason = protocol.ExcludeNotReady
+		case targeted:
+			reason = protocol.ExcludeNotTargeted
+		default:
+			continue
+		}
+		excluded = append(excluded, protocol.ExcludedNode{Address: n.Addr, Reason: reason})
+	}
+
+	sort.Slice(excluded, func(i, j int) bool { return excluded[i].Address < excluded[j].Address })
+	return excluded
+}
+
+// localDBHealthy reports whether the local node's database answers. The local
+// heartbeat never touches the database, so GetAlive alone misses an outage.
+// A node without a database is healthy.
+func (c *Coordinator) localDBHealthy() bool {
+	if !c.localNode.HasDB() {
+		return true
+	}
+	if c.localNode.DBDegraded() {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), min(c.timeout, 2*time.Second))
+	defer cancel()
+	return c.localNode.PingDB(ctx) == nil
+}
+
+// formatExcluded renders exclusions as "addr (reason), ..." for logs.
+func formatExcluded(excluded []protocol.ExcludedNode) string {
+	parts := make([]string, len(excluded))
+	for i, e := range excluded {
+		parts[i] = fmt.Sprintf("%s (%s)", e.Address, e.Reason)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// remoteCandidates returns the slaves, plus the master when this coordinator is not it.
+func (c *Coordinator) remoteCandidates() []*node.Node {
+	nodes := c.participants.GetSlaveNodes()
+	if c.localNode == nil || c.localNode.GetRole() == protocol.RoleMaster {
+		return nodes
+	}
+
+	for _, n := range c.participants.GetNodes() {
+		if n.GetRole() == protocol.RoleMaster && n.Selectable() {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// dedupeParticipants drops remote participants whose address is equivalent to
+// the local node or to an earlier participant, so no node is prepared twice.
+func (c *Coordinator) dedupeParticipants(participants []*node.Node) []*node.Node {
+	seen := make(map[string]bool, len(participants)+1)
+	if c.localNode != nil {
+		seen[cluster.CanonicalAddr(c.localNode.Addr)] = true
+	}
+
+	unique := make([]*node.Node, 0, len(participants))
+	for _, p := range participants {
+		key := cluster.CanonicalAddr(p.Addr)
+		if seen[key] {
+			logging.Debugf("[Coordinator] Skipping duplicate participant %s", p.Addr)
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, p)
+	}
+
+	return unique
+}
+
+// sortParticipants orders participants by canonical address. Cluster
+// membership is a map, so without this the order would change from one
+// transaction to the next; a fixed order makes results, logs and commit-log
+// entries list nodes the same way for every transaction, and is the order
+// PrepareSequential prepares them in.
+func sortParticipants(participants []*node.Node) {
+	sort.SliceStable(participants, func(i, j int) bool {
+		return cluster.CanonicalAddr(participants[i].Addr) < cluster.CanonicalAddr(participants[j].Addr)
+	})
+}
+
+// recordObserved writes OBSERVED markers on every member that did not take part
+// in txID. It runs in the background and never affects the transaction outcome.
+func (c *Coordinator) recordObserved(txID string, payload any, includeLocal bool, participants []*node.Node) {
+	participated := make(map[string]bool, len(participants)+1)
+	for _, p := range participants {
+		participated[cluster.CanonicalAddr(p.Addr)] = true
+	}
+
+	localObserver := c.localNode != nil && !includeLocal
+	var remotes []string
+	for _, n := range c.participants.GetNodes() {
+		if participated[cluster.CanonicalAddr(n.Addr)] || (c.localNode != nil && cluster.SameAddr(n.Addr, c.localNode.Addr)) {
+			continue
+		}
+		remotes = append(remotes, n.Addr)
+	}
+
+	if !localObserver && len(remotes) == 0 {
+		return
+	}
+
+	req := &protocol.ObserveRequest{TransactionID: txID, Payload: payload}
+
+	go func() {
+		if localObserver {
+			ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			if err := c.localNode.RecordObserved(ctx, txID, payload); err != nil {
+				logging.Warnf("[Coordinator] Failed to record observed marker for %s locally: %v", txID, err)
+			}
+			cancel()
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(len(remotes))
+		for _, addr := range remotes {
+			nodeAddr := addr
+			go func() {
+				defer wg.Done()
+				if err := c.client.Observe(nodeAddr, req); err != nil {
+					logging.Warnf("[Coordinator] Failed to record observed marker for %s on %s: %v", txID, nodeAddr, err)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
 }
 
 func (c *Coordinator) prepareTransaction(
@@ -135,57 +1002,232 @@ func (c *Coordinator) prepareTransaction(
 ) prepareOutcome {
 	outcome := prepareOutcome{
 		includeLocal: includeLocal,
+		rowsAffected: make(map[string]int64),
 	}
 
-	if includeLocal {
+	failures := make(map[string]bool)
+
+	prepareLocal := func() bool {
 		ready, err := c.localNode.Prepare(txID, payload)
 		if ready && err == nil {
 			outcome.localPrepared = true
-			log.Printf("[Coordinator] Local node prepared for transaction %s", txID)
-		} else {
-			outcome.failedNodes = append(outcome.failedNodes, c.localNode.Addr+" (local)")
-			log.Printf("[Coordinator] Local node prepare failed for transaction %s: %v", txID, err)
+			outcome.rowsAffected[c.localNode.Addr] = c.localNode.RowsAffected(txID)
+			outcome.addReturned(c.localNode.Addr, c.localNode.Returned(txID))
+			logging.Debugf("[Coordinator] Local node prepared for transaction %s", txID)
+			return true
+		}
+
+		outcome.failedNodes = append(outcome.failedNodes, c.localNode.Addr+" (local)")
+		switch {
+		case errors.Is(err, node.ErrAtCapacity):
+			failures[protocol.AbortNodeAtCapacity] = true
+		case errors.Is(err, node.ErrResourcePressure):
+			failures[protocol.AbortResourcePressure] = true
+		default:
+			failures[protocol.AbortVoteAbort] = true
 		}
+		logging.Warnf("[Coordinator] Local node prepare failed for transaction %s: %v", txID, err)
+		return false
 	}
 
-	prepareResults := c.preparePhase(txID, payload, remoteParticipants)
-	for _, result := range prepareResults {
+	recordRemote := func(result PrepareResult) bool {
 		if result.Success {
 			outcome.preparedRemotes = append(outcome.preparedRemotes, result.Addr)
-			continue
+			outcome.rowsAffected[result.Addr] = result.Response.RowsAffected
+			outcome.addReturned(result.Addr, result.Response.Returned)
+			return true
 		}
 
 		outcome.failedNodes = append(outcome.failedNodes, result.Addr)
+		failures[classifyPrepareFailure(result.Response, result.Error)] = true
 		if result.Error != nil {
-			log.Printf("[Coordinator] Prepare failed for %s: %v", result.Addr, result.Error)
+			outcome.unsureRemotes = append(outcome.unsureRemotes, result.Addr)
+			logging.Warnf("[Coordinator] Prepare failed for %s: %v", result.Addr, result.Error)
+		}
+		return false
+	}
+
+	if c.prepareOrder == PrepareSequential {
+		// One participant at a time in address order; the rest are never
+		// asked once a node refuses, as the transaction aborts anyway.
+		type step struct {
+			addr  string
+			local bool
+		}
+		steps := make([]step, 0, len(remoteParticipants)+1)
+		if includeLocal {
+			steps = append(steps, step{addr: c.localNode.Addr, local: true})
+		}
+		for _, p := range remoteParticipants {
+			steps = append(steps, step{addr: p.Addr})
+		}
+		sort.SliceStable(steps, func(i, j int) bool {
+			return cluster.CanonicalAddr(steps[i].addr) < cluster.CanonicalAddr(steps[j].addr)
+		})
+
+		for _, st := range steps {
+			var ok bool
+			if st.local {
+				ok = prepareLocal()
+			} else {
+				ok = recordRemote(c.prepareOne(txID, payload, st.addr))
+			}
+			if !ok {
+				break
+			}
+		}
+	} else {
+		if includeLocal {
+			prepareLocal()
+		}
+		for _, result := range c.preparePhase(txID, payload, remoteParticipants) {
+			recordRemote(result)
+		}
+	}
+
+	for _, category := range abortPrecedence {
+		if failures[category] {
+			outcome.abortCategory = category
+			break
 		}
 	}
 
 	return outcome
 }
 
-func (c *Coordinator) commitTransaction(txID string, outcome prepareOutcome) (bool, int, []string, error) {
-	log.Printf("[Coordinator] All participants ready, committing transaction %s", txID)
+// logDecision appends the decision for txID over its participants to the
+// commit log, if there is one.
+func (c *Coordinator) logDecision(txID string, decision protocol.TxState, includeLocal bool, remotes []*node.Node) error {
+	if c.commitLog == nil {
+		return nil
+	}
+
+	return c.commitLog.Append(txID, decision, c.participantAddrs(includeLocal, remotes))
+}
+
+// abortPrecedence decides the category of a transaction whose participants
+// failed for different reasons: an unreachable node outranks a vote.
+var abortPrecedence = []string{
+	protocol.AbortPrepareTimeout,
+	protocol.AbortPrepareTransportError,
+	protocol.AbortNodeAtCapacity,
+	protocol.AbortResourcePressure,
+	protocol.AbortVoteAbort,
+}
 
+// classifyPrepareFailure maps the error of a failed remote prepare to an abort
+// category. A nil error means the participant answered and voted ABORT, which
+// counts separately when it did so because it was at capacity.
+func classifyPrepareFailure(resp *protocol.PrepareResponse, err error) string {
+	if err == nil {
+		switch {
+		case resp != nil && resp.Code == protocol.ErrCodeAtCapacity:
+			return protocol.AbortNodeAtCapacity
+		case resp != nil && resp.Code == protocol.ErrCodeResourcePressure:
+			return protocol.AbortResourcePressure
+		}
+		return protocol.AbortVoteAbort
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return protocol.AbortPrepareTimeout
+	}
+	return protocol.AbortPrepareTransportError
+}
+
+// commitTransaction sends the commit decision to every prepared participant.
+// It waits for the acknowledgements ack asks for and delivers the other
+// commits in the background, reporting those nodes as pending. The first
+// result is whether every awaited node acknowledged. When commits were left to
+// the background, settle waits for them and returns whether the transaction
+// ended up durable and which nodes still did not acknowledge; otherwise it is nil.
+func (c *Coordinator) commitTransaction(txID string, outcome prepareOutcome, ack CommitAckPolicy) (awaitedOK bool, totalCommitted int, pendingNodes []string, settle func() (bool, []string), err error) {
+	logging.Debugf("[Coordinator] All participants ready, committing transaction %s (order: %s, ack: %s)", txID, c.order, ack)
+
+	var background prepareOutcome
+	switch ack {
+	case CommitAckNone:
+		background, outcome = outcome, prepareOutcome{}
+	case CommitAckLocal:
+		background = outcome
+		background.includeLocal = false
+		outcome.preparedRemotes = nil
+	}
+
+	var notAwaited []string
+	if background.includeLocal && background.localPrepared {
+		notAwaited = append(notAwaited, c.localNode.Addr+" (local)")
+	}
+	notAwaited = append(notAwaited, background.preparedRemotes...)
+	var backgroundFailed chan []string
+	if len(notAwaited) > 0 {
+		backgroundFailed = make(chan []string, 1)
+		c.background.Add(1)
+		go func() {
+			defer c.background.Done()
+			_, _, failed, _ := c.deliverCommit(txID, background, true)
+			backgroundFailed <- failed
+		}()
+	}
+
+	awaitedOK, totalCommitted, failedNodes, err := c.deliverCommit(txID, outcome, false)
+	if backgroundFailed != nil {
+		awaitedFailed := slices.Clone(failedNodes)
+		settle = func() (bool, []string) {
+			pending := append(awaitedFailed, <-backgroundFailed...)
+			return len(pending) == 0, pending
+		}
+	}
+	return awaitedOK, totalCommitted, append(failedNodes, notAwaited...), settle, err
+}
+
+// deliverCommit commits txID on the participants of outcome in the configured
+// order and hands remotes that fail to acknowledge to the recovery queue. A
+// background delivery has no caller to report to, so a failed local commit is
+// queued too and failures are logged.
+func (c *Coordinator) deliverCommit(txID string, outcome prepareOutcome, background bool) (bool, int, []string, error) {
 	var failedNodes []string
 	var errs []error
 	totalCommitted := 0
 
+	commitLocal := func() error { return nil }
+	if outcome.includeLocal && outcome.localPrepared {
+		commitLocal = func() error { return c.localNode.Commit(txID) }
+	}
+
+	var localErr error
+	var commitResults []CommitResult
+	switch c.order {
+	case CommitLocalFirst:
+		localErr = commitLocal()
+		commitResults = c.commitPhase(txID, outcome.preparedRemotes)
+	case CommitRemoteFirst:
+		commitResults = c.commitPhase(txID, outcome.preparedRemotes)
+		localErr = commitLocal()
+	default:
+		localDone := make(chan error, 1)
+		go func() { localDone <- commitLocal() }()
+		commitResults = c.commitPhase(txID, outcome.preparedRemotes)
+		localErr = <-localDone
+	}
+
 	localCommitSuccess := true
 	if outcome.includeLocal && outcome.localPrepared {
-		if err := c.localNode.Commit(txID); err != nil {
+		if localErr != nil {
 			localCommitSuccess = false
 			failedNodes = append(failedNodes, c.localNode.Addr+" (local)")
-			errs = append(errs, fmt.Errorf("local commit: %w", err))
-			log.Printf("[Coordinator] Local node commit failed for %s: %v", txID, err)
+			errs = append(errs, fmt.Errorf("local commit: %w", localErr))
+			logging.Errorf("[Coordinator] Local node commit failed for %s: %v", txID, localErr)
+			if background && c.recovery != nil {
+				c.recovery.Enqueue(txID, c.localNode.Addr, protocol.StateCommit, c.identity())
+			}
 		} else {
 			totalCommitted++
-			log.Printf("[Coordinator] Local node committed transaction %s", txID)
+			logging.Debugf("[Coordinator] Local node committed transaction %s", txID)
 		}
 	}
 
-	commitResults := c.commitPhase(txID, outcome.preparedRemotes)
-
 	commitSuccess := localCommitSuccess
 	for _, result := range commitResults {
 		if !result.Success {
@@ -194,37 +1236,73 @@ func (c *Coordinator) commitTransaction(txID string, outcome prepareOutcome) (bo
 			if result.Error != nil {
 				errs = append(errs, fmt.Errorf("%s: %w", result.Addr, result.Error))
 			}
-			log.Printf("[Coordinator] Commit failed for %s: %v", result.Addr, result.Error)
+			logging.Errorf("[Coordinator] Commit failed for %s: %v", result.Addr, result.Error)
+			if c.recovery != nil {
+				c.recovery.Enqueue(txID, result.Addr, protocol.StateCommit, c.identity())
+			}
 		} else {
 			totalCommitted++
 		}
 	}
 
+	if background && !commitSuccess {
+		logging.Warnf("[Coordinator] Background commit of %s not acknowledged by %v, queued for recovery", txID, failedNodes)
+	}
 	return commitSuccess, totalCommitted, failedNodes, errors.Join(errs...)
 }
 
-func (c *Coordinator) abortTransaction(txID string, outcome prepareOutcome, participantAddrs []string) error {
-	log.Printf("[Coordinator] Prepare failed for nodes %v, aborting transaction %s", outcome.failedNodes, txID)
+// abortTransaction rolls back every participant that did not vote ABORT: the
+// nodes that voted READY, including the local node, and the remotes whose
+// prepare returned no vote (a timeout, a transport error or an error status),
+// since they may have prepared after all. Abort is idempotent, so a node that
+// had rolled back just confirms. Nodes that voted ABORT are skipped. A node
+// whose abort fails may still hold its prepared transaction, so the abort is
+// handed to the recovery queue, which retries it until the node confirms.
+// It returns the nodes whose abort failed.
+func (c *Coordinator) abortTransaction(txID string, outcome prepareOutcome) ([]string, error) {
+	reason := fmt.Sprintf("prepare failed on %v", outcome.failedNodes)
+	switch outcome.abortCategory {
+	case protocol.AbortCommitLogError:
+		reason = "commit point could not be recorded"
+	case protocol.AbortDurabilityUnmet:
+		reason = outcome.ruleErr.Error()
+	}
+	logging.Warnf("[Coordinator] Aborting transaction %s: %s", txID, reason)
 
+	var failedNodes []string
 	var abortErrs []error
 
 	if outcome.includeLocal && outcome.localPrepared {
 		if err := c.localNode.Abort(txID); err != nil {
-			log.Printf("[Coordinator] Local node abort failed for %s: %v", txID, err)
+			logging.Errorf("[Coordinator] Local node abort failed for %s: %v", txID, err)
+			failedNodes = append(failedNodes, c.localNode.Addr+" (local)")
 			abortErrs = append(abortErrs, fmt.Errorf("local abort: %w", err))
+			if c.recovery != nil {
+				// Retried over HTTP like any other participant's.
+				c.recovery.Enqueue(txID, c.localNode.Addr, protocol.StateAbort, c.identity())
+			}
 		}
 	}
 
-	for _, result := range c.abortPhase(txID, participantAddrs) {
-		if !result.Success && result.Error != nil {
+	targets := append(slices.Clone(outcome.preparedRemotes), outcome.unsureRemotes...)
+	for _, result := range c.abortPhase(txID, reason, targets) {
+		if result.Success {
+			continue
+		}
+
+		failedNodes = append(failedNodes, result.Addr)
+		if result.Error != nil {
 			abortErrs = append(abortErrs, fmt.Errorf("%s: %w", result.Addr, result.Error))
 		}
+		if c.recovery != nil {
+			c.recovery.Enqueue(txID, result.Addr, protocol.StateAbort, c.identity())
+		}
 	}
 
-	return errors.Join(abortErrs...)
+	return failedNodes, errors.Join(abortErrs...)
 }
 
-// preparePhase sends prepare requests to all participants
+// preparePhase sends prepare requests to all participants concurrently.
 func (c *Coordinator) preparePhase(
 	txID string,
 	payload any,
@@ -240,19 +1318,7 @@ func (c *Coordinator) preparePhase(
 		participant := p
 		go func() {
 			defer wg.Done()
-
-			req := &protocol.PrepareRequest{
-				TransactionID: txID,
-				Payload:       payload,
-			}
-
-			resp, err := c.client.Prepare(participant.Addr, req)
-			results[idx] = PrepareResult{
-				Addr:     participant.Addr,
-				Success:  err == nil && resp != nil && resp.Status == protocol.StatusReady,
-				Response: resp,
-				Error:    err,
-			}
+			results[idx] = c.prepareOne(txID, payload, participant.Addr)
 		}()
 	}
 
@@ -261,6 +1327,23 @@ func (c *Coordinator) preparePhase(
 	return results
 }
 
+// prepareOne sends the prepare request for txID to addr.
+func (c *Coordinator) prepareOne(txID string, payload any, addr string) PrepareResult {
+	req := &protocol.PrepareRequest{
+		TransactionID: txID,
+		Payload:       payload,
+		Coordinator:   c.identity(),
+	}
+
+	resp, err := c.client.Prepare(addr, req)
+	return PrepareResult{
+		Addr:     addr,
+		Success:  err == nil && resp != nil && resp.Status == protocol.StatusReady,
+		Response: resp,
+		Error:    err,
+	}
+}
+
 // commitPhase sends commit requests to all prepared participants
 func (c *Coordinator) commitPhase(txID string, preparedAddrs []string) []CommitResult {
 	results := make([]CommitResult, len(preparedAddrs))
@@ -276,6 +1359,7 @@ func (c *Coordinator) commitPhase(txID string, preparedAddrs []string) []CommitR
 
 			req := &protocol.CommitRequest{
 				TransactionID: txID,
+				Coordinator:   c.identity(),
 			}
 
 			resp, err := c.client.Commit(nodeAddr, req)
@@ -295,8 +1379,8 @@ func (c *Coordinator) commitPhase(txID string, preparedAddrs []string) []CommitR
 	return results
 }
 
-// abortPhase sends abort requests to all participants that were part of the prepare phase.
-func (c *Coordinator) abortPhase(txID string, participantAddrs []string) []CommitResult {
+// abortPhase sends abort requests to the given participants.
+func (c *Coordinator) abortPhase(txID, reason string, participantAddrs []string) []CommitResult {
 	if len(participantAddrs) == 0 {
 		return nil
 	}
@@ -313,9 +1397,14 @@ func (c *Coordinator) abortPhase(txID string, participantAddrs []string) []Commi
 
 			req := &protocol.AbortRequest{
 				TransactionID: txID,
+				Reason:        reason,
+				Coordinator:   c.identity(),
 			}
 
 			resp, err := c.client.Abort(nodeAddr, req)
+			if err == nil && resp != nil && !resp.Success && resp.Error != "" {
+				err = errors.New(resp.Error)
+			}
 			results[idx] = CommitResult{
 				Addr:    nodeAddr,
 				Success: err == nil && resp != nil && resp.Success,
@@ -323,7 +1412,7 @@ func (c *Coordinator) abortPhase(txID string, participantAddrs []string) []Commi
 			}
 
 			if err != nil {
-				log.Printf("[Coordinator] Abort failed for %s: %v", nodeAddr, err)
+				logging.Warnf("[Coordinator] Abort failed for %s: %v", nodeAddr, err)
 			}
 		}()
 	}
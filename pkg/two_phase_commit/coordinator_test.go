@@ -1,61 +1,29 @@
 package twophasecommit
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
+
 	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
 )
 
-// TestSuccessful2PC tests the happy path where all nodes prepare and commit successfully
-func TestSuccessful2PC(t *testing.T) {
-	// Create mock nodes
-	node1 := createMockNode(t, true, true) // prepare success, commit success
-	node2 := createMockNode(t, true, true)
-	defer node1.Close()
-	defer node2.Close()
-
-	// Create cluster with mock nodes
-	c := cluster.NewCluster()
-	master := node.NewNode("localhost:8080", protocol.RoleMaster)
-	master.SetAlive(true)
-	c.AddNode(master)
-	c.SetMaster(master)
-
-	// Add slave nodes pointing to mock servers
-	slave1 := node.NewNode(node1.Listener.Addr().String(), protocol.RoleSlave)
-	slave2 := node.NewNode(node2.Listener.Addr().String(), protocol.RoleSlave)
-	slave1.SetAlive(true)
-	slave2.SetAlive(true)
-	c.AddNode(slave1)
-	c.AddNode(slave2)
-
-	// Create coordinator and execute (nil localNode = master doesn't participate)
-	coordinator := NewCoordinator(c, nil, 5*time.Second)
-	resp, err := coordinator.Execute(map[string]string{"test": "data"})
-
-	if err != nil {
-		t.Fatalf("Execute failed: %v", err)
-	}
-
-	if !resp.Success {
-		t.Errorf("Expected success, got failure: %s", resp.Error)
-	}
-
-	if resp.TransactionID == "" {
-		t.Error("Expected transaction ID to be set")
-	}
-}
-
-// TestPrepareFails tests when one node fails prepare
 func TestPrepareFails(t *testing.T) {
 	// Create mock nodes - one fails prepare
 	node1 := createMockNode(t, true, true)  // prepare success
@@ -203,8 +171,11 @@ func TestCoordinator_ExecuteFullFlowWithFailures(t *testing.T) {
 		if !resp.Success {
 			t.Fatalf("Execute() failed unexpectedly: %#v", resp)
 		}
-		if resp.Message != "Transaction committed on 1 nodes" {
-			t.Fatalf("Commit message = %q, want %q", resp.Message, "Transaction committed on 1 nodes")
+		if want := "Transaction committed on 1 node (no durability)"; resp.Message != want {
+			t.Fatalf("Commit message = %q, want %q", resp.Message, want)
+		}
+		if resp.Durable {
+			t.Fatal("Expected Durable=false for an in-memory local-only commit")
 		}
 		if local.TxState != protocol.StateCommit {
 			t.Fatalf("Local node state = %s, want COMMIT", local.TxState)
@@ -447,3 +418,1638 @@ func abortSuccess() stubEndpoint {
 		},
 	}
 }
+
+func TestCoordinator_LocalNodeWithoutDB(t *testing.T) {
+	payload := samplePayload()
+
+	t.Run("ParticipateByDefault", func(t *testing.T) {
+		local := node.NewNode("local:0", protocol.RoleMaster)
+		coordinator := NewCoordinator(testClusterWithSlaves(), local, 100*time.Millisecond)
+
+		resp, err := coordinator.Execute(payload)
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+		if !resp.Success {
+			t.Fatalf("Execute() failed unexpectedly: %#v", resp)
+		}
+		if local.TxState != protocol.StateCommit {
+			t.Fatalf("Local node state = %s, want COMMIT", local.TxState)
+		}
+	})
+
+	t.Run("SkipExcludesLocal", func(t *testing.T) {
+		remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+		defer remote.Close()
+
+		local := node.NewNode("local:0", protocol.RoleMaster)
+		coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), local, 200*time.Millisecond).
+			WithLocalNoDBPolicy(LocalNoDBSkip)
+
+		resp, err := coordinator.Execute(payload)
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+		if !resp.Success {
+			t.Fatalf("Execute() failed unexpectedly: %#v", resp)
+		}
+		if resp.Message != "Transaction committed on 1 nodes" {
+			t.Fatalf("Commit message = %q, want only the remote node", resp.Message)
+		}
+		if local.TxState != protocol.StateInit {
+			t.Fatalf("Local node state = %s, want INIT (not participating)", local.TxState)
+		}
+	})
+
+	t.Run("RefuseWithoutRemotes", func(t *testing.T) {
+		local := node.NewNode("local:0", protocol.RoleMaster)
+		coordinator := NewCoordinator(testClusterWithSlaves(), local, 100*time.Millisecond).
+			WithLocalNoDBPolicy(LocalNoDBRefuse)
+
+		resp, err := coordinator.Execute(payload)
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+		if resp.Success {
+			t.Fatalf("Expected a non-durable local-only commit to be refused, got %#v", resp)
+		}
+		if local.GetTxState() != protocol.StateInit {
+			t.Fatalf("Local node state = %s, want INIT (never prepared)", local.GetTxState())
+		}
+	})
+
+	t.Run("SkipWithoutRemotesHasNoParticipants", func(t *testing.T) {
+		local := node.NewNode("local:0", protocol.RoleMaster)
+		coordinator := NewCoordinator(testClusterWithSlaves(), local, 100*time.Millisecond).
+			WithLocalNoDBPolicy(LocalNoDBSkip)
+
+		resp, err := coordinator.Execute(payload)
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+		if resp.Success || resp.Error != "No participants available" {
+			t.Fatalf("Expected no participants failure, got %#v", resp)
+		}
+	})
+
+	t.Run("RefuseRejectsTransaction", func(t *testing.T) {
+		remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+		defer remote.Close()
+
+		local := node.NewNode("local:0", protocol.RoleMaster)
+		coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), local, 200*time.Millisecond).
+			WithLocalNoDBPolicy(LocalNoDBRefuse)
+
+		resp, err := coordinator.Execute(payload)
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+		if resp.Success {
+			t.Fatal("Expected refusal when local node has no database")
+		}
+		if calls := remote.callCounts(); calls.prepare != 0 {
+			t.Fatalf("Remote should not be contacted on refusal, got %+v", calls)
+		}
+	})
+}
+
+func TestCoordinator_ReportsRowsAffected(t *testing.T) {
+	matched := newStubNodeServer(stubEndpoint{
+		status:   http.StatusOK,
+		response: protocol.PrepareResponse{Status: protocol.StatusReady, RowsAffected: 2},
+	}, commitSuccess(), abortSuccess())
+	noMatch := newStubNodeServer(stubEndpoint{
+		status:   http.StatusOK,
+		response: protocol.PrepareResponse{Status: protocol.StatusReady, RowsAffected: 0},
+	}, commitSuccess(), abortSuccess())
+	defer matched.Close()
+	defer noMatch.Close()
+
+	c := testClusterWithSlaves(matched.Addr(), noMatch.Addr())
+	coordinator := NewCoordinator(c, nil, 200*time.Millisecond)
+
+	update := node.SQLAction{
+		Table:     "users",
+		Operation: "UPDATE",
+		Values:    map[string]any{"name": "Alice"},
+		Where:     map[string]any{"id": 42},
+	}
+
+	resp, err := coordinator.Execute(update)
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Execute() failed unexpectedly: %#v", resp)
+	}
+
+	if got, ok := resp.RowsAffected[matched.Addr()]; !ok || got != 2 {
+		t.Errorf("Rows affected on %s = %d (present=%v), want 2", matched.Addr(), got, ok)
+	}
+	if got, ok := resp.RowsAffected[noMatch.Addr()]; !ok || got != 0 {
+		t.Errorf("Rows affected on %s = %d (present=%v), want 0 for no-op UPDATE", noMatch.Addr(), got, ok)
+	}
+}
+
+func TestCoordinator_ZeroRowsUpdateAbortsTransaction(t *testing.T) {
+	matched := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	noMatch := newStubNodeServer(stubEndpoint{
+		status: http.StatusInternalServerError,
+		response: protocol.PrepareResponse{
+			Status: protocol.StatusAbort,
+			Error:  "rows affected 0 does not satisfy expectation >=1",
+		},
+	}, commitSuccess(), abortSuccess())
+	defer matched.Close()
+	defer noMatch.Close()
+
+	c := testClusterWithSlaves(matched.Addr(), noMatch.Addr())
+	local := node.NewNode("local:0", protocol.RoleMaster)
+	coordinator := NewCoordinator(c, local, 200*time.Millisecond)
+
+	resp, err := coordinator.Execute(node.SQLAction{
+		Table:              "users",
+		Operation:          "UPDATE",
+		Values:             map[string]any{"name": "Alice"},
+		Where:              map[string]any{"id": 42},
+		ExpectRowsAffected: ">=1",
+	})
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("Expected transaction to abort when an update matches zero rows")
+	}
+	if !strings.Contains(resp.Error, noMatch.Addr()) {
+		t.Errorf("Expected error to mention %s, got %q", noMatch.Addr(), resp.Error)
+	}
+	if local.TxState != protocol.StateAbort {
+		t.Errorf("Local node state = %s, want ABORT", local.TxState)
+	}
+	if calls := matched.callCounts(); calls.commit != 0 || calls.abort != 1 {
+		t.Errorf("Matched node calls: %+v, expected abort without commit", calls)
+	}
+}
+
+func TestCoordinator_AbortSkipsNodesThatVotedAbort(t *testing.T) {
+	abortFailure := stubEndpoint{
+		status:   http.StatusInternalServerError,
+		response: protocol.AbortResponse{Success: false, Error: "rollback failed"},
+	}
+	// A vote the client decodes; a 500 is retried and surfaces as an error,
+	// which the coordinator cannot tell apart from a lost request.
+	voteAbort := stubEndpoint{
+		status:   http.StatusForbidden,
+		response: protocol.PrepareResponse{Status: protocol.StatusAbort, Error: "table not permitted", Code: protocol.ErrCodeTableNotPermitted},
+	}
+
+	readyOK := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	readyBroken := newStubNodeServer(readyPrepare(0), commitSuccess(), abortFailure)
+	refused := newStubNodeServer(voteAbort, commitSuccess(), abortSuccess())
+	defer readyOK.Close()
+	defer readyBroken.Close()
+	defer refused.Close()
+
+	c := testClusterWithSlaves(readyOK.Addr(), readyBroken.Addr(), refused.Addr())
+	local := node.NewNode("local:0", protocol.RoleMaster)
+	coordinator := NewCoordinator(c, local, 200*time.Millisecond)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("Expected transaction to abort")
+	}
+
+	if calls := readyOK.callCounts(); calls.abort != 1 || calls.commit != 0 {
+		t.Errorf("Prepared node calls: %+v, expected exactly one abort", calls)
+	}
+	if calls := readyBroken.callCounts(); calls.abort != 1 {
+		t.Errorf("Prepared node with failing abort calls: %+v, expected exactly one abort", calls)
+	}
+	if calls := refused.callCounts(); calls.abort != 0 {
+		t.Errorf("Node that voted ABORT calls: %+v, expected no abort", calls)
+	}
+	if local.TxState != protocol.StateAbort {
+		t.Errorf("Local node state = %s, want ABORT", local.TxState)
+	}
+
+	if !strings.Contains(resp.Error, "abort failed for nodes: ["+readyBroken.Addr()+"]") {
+		t.Errorf("Expected error to list failed abort on %s, got %q", readyBroken.Addr(), resp.Error)
+	}
+}
+
+func TestCoordinator_MissingCommitAckIsNotDurable(t *testing.T) {
+	timeout := 75 * time.Millisecond
+	acked := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	lostAck := newStubNodeServer(readyPrepare(0), stubEndpoint{
+		delay:    2 * timeout, // commit ack never arrives in time
+		status:   http.StatusOK,
+		response: protocol.CommitResponse{Success: true},
+	}, abortSuccess())
+	defer acked.Close()
+	defer lostAck.Close()
+
+	c := testClusterWithSlaves(acked.Addr(), lostAck.Addr())
+	coordinator := NewCoordinator(c, nil, timeout)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Expected decision to be committed, got %#v", resp)
+	}
+	if resp.Durable {
+		t.Error("Expected Durable=false when a commit ack is missing")
+	}
+	if len(resp.PendingNodes) != 1 || resp.PendingNodes[0] != lostAck.Addr() {
+		t.Errorf("PendingNodes = %v, want [%s]", resp.PendingNodes, lostAck.Addr())
+	}
+	if calls := lostAck.callCounts(); calls.abort != 0 {
+		t.Errorf("Committed transaction must not be aborted, got %+v", calls)
+	}
+}
+
+func TestCoordinator_AllCommitAcksAreDurable(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	c := testClusterWithSlaves(remote.Addr())
+	coordinator := NewCoordinator(c, nil, 200*time.Millisecond)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success || !resp.Durable || len(resp.PendingNodes) != 0 {
+		t.Errorf("Expected durable commit with no pending nodes, got %#v", resp)
+	}
+}
+
+func TestCoordinator_RejectsOversizedPayloadBeforePrepare(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	c := testClusterWithSlaves(remote.Addr())
+	coordinator := NewCoordinator(c, nil, 200*time.Millisecond).
+		WithPayloadLimits(node.PayloadLimits{MaxColumns: 1})
+
+	resp, err := coordinator.Execute(node.SQLAction{
+		Table:  "users",
+		Values: map[string]any{"name": "Alice", "email": "a@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("Expected oversized payload to be rejected")
+	}
+	if !strings.Contains(resp.Error, "exceeds limit of 1") {
+		t.Errorf("Expected limit error, got %q", resp.Error)
+	}
+	if calls := remote.callCounts(); calls.prepare != 0 {
+		t.Errorf("Expected no prepare for rejected payload, got %+v", calls)
+	}
+}
+
+func TestCoordinator_CoordinateOnlyRunsOverSlaves(t *testing.T) {
+	remotes := []*stubNodeServer{
+		newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess()),
+		newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess()),
+	}
+	for _, r := range remotes {
+		defer r.Close()
+	}
+
+	c := testClusterWithSlaves(remotes[0].Addr(), remotes[1].Addr())
+	// The master has no database at all and is not a participant.
+	local := c.GetMaster()
+	coordinator := NewCoordinator(c, local, 200*time.Millisecond).
+		WithLocalNoDBPolicy(LocalNoDBRefuse).
+		WithLocalParticipation(false)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success || !resp.Durable {
+		t.Fatalf("Expected a durable commit over the slaves, got %#v", resp)
+	}
+	for _, r := range remotes {
+		if calls := r.callCounts(); calls.prepare != 1 || calls.commit != 1 {
+			t.Errorf("Slave %s calls: %+v, expected one prepare and one commit", r.Addr(), calls)
+		}
+	}
+	if _, ok := resp.RowsAffected[local.Addr]; ok || local.GetTxState() != protocol.StateInit {
+		t.Errorf("Expected the coordinate-only master never to be prepared, state %s", local.GetTxState())
+	}
+
+	resp, err = coordinator.ExecuteRequest(&protocol.TransactionRequest{
+		Payload: samplePayload(),
+		Targets: []string{local.Addr},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, local.Addr) {
+		t.Errorf("Expected targeting the coordinate-only master to be rejected, got %#v", resp)
+	}
+}
+
+func TestCoordinator_CoordinateOnlyNodeRefusesOtherCoordinatorsPrepare(t *testing.T) {
+	// A coordinate-only master has no database; a second coordinator that
+	// still lists it as a participant must not get a READY it cannot keep.
+	master := node.NewNode("master:0", protocol.RoleMaster)
+	master.SetCoordinateOnly(true)
+	srv := httptest.NewServer(transport.NewHTTPServer(master).Handler())
+	defer srv.Close()
+
+	other := node.NewNode("other-coordinator:0", protocol.RoleSlave)
+	coordinator := NewCoordinatorForAddrs([]string{srv.Listener.Addr().String()}, other, time.Second).
+		WithLocalParticipation(false)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success || resp.Code != protocol.ErrCodeTransactionAborted {
+		t.Fatalf("Expected the coordinate-only node to vote ABORT, got %#v", resp)
+	}
+	if n := len(master.GetPendingTransactions()); n != 0 {
+		t.Errorf("Coordinate-only node holds %d prepared transactions, want none", n)
+	}
+}
+
+func TestCoordinator_RefusesTooManyParticipantsBeforePrepare(t *testing.T) {
+	remotes := make([]*stubNodeServer, 3)
+	addrs := make([]string, len(remotes))
+	for i := range remotes {
+		remotes[i] = newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+		defer remotes[i].Close()
+		addrs[i] = remotes[i].Addr()
+	}
+
+	c := testClusterWithSlaves(addrs...)
+	coordinator := NewCoordinator(c, nil, 200*time.Millisecond).WithMaxParticipants(2)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("Expected transaction over the participant limit to be refused")
+	}
+	if !strings.Contains(resp.Error, "3 participants, above the limit of 2") {
+		t.Errorf("Expected participant limit error, got %q", resp.Error)
+	}
+	for _, r := range remotes {
+		if calls := r.callCounts(); calls.prepare != 0 {
+			t.Errorf("Expected no prepare on %s, got %+v", r.Addr(), calls)
+		}
+	}
+
+	// Targeting a subset within the limit still goes through.
+	resp, err = coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload(), Targets: addrs[:2]})
+	if err != nil || !resp.Success {
+		t.Fatalf("Expected targeted transaction within the limit to commit, got %#v (err %v)", resp, err)
+	}
+}
+
+func txRecordServer(t *testing.T, status int, rec *protocol.TransactionRecord) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if rec != nil {
+			_ = json.NewEncoder(w).Encode(rec)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.Listener.Addr().String()
+}
+
+func TestCoordinator_TransactionDetailMixedStatuses(t *testing.T) {
+	txID := "tx-partial"
+	committed := txRecordServer(t, http.StatusOK, &protocol.TransactionRecord{TxID: txID, Status: "COMMITTED", UpdatedAt: time.Now()})
+	missing := txRecordServer(t, http.StatusNotFound, nil)
+	broken := txRecordServer(t, http.StatusInternalServerError, nil)
+
+	local := node.NewNode("local:0", protocol.RoleMaster)
+	if ready, err := local.Prepare(txID, samplePayload()); !ready || err != nil {
+		t.Fatalf("Local prepare failed: ready=%v err=%v", ready, err)
+	}
+
+	c := cluster.NewCluster()
+	c.AddNode(local)
+	for _, addr := range []string{committed, missing, broken} {
+		c.AddNode(node.NewNode(addr, protocol.RoleSlave))
+	}
+	coordinator := NewCoordinator(c, local, 200*time.Millisecond)
+
+	detail := coordinator.TransactionDetail(txID)
+	if detail.TransactionID != txID {
+		t.Errorf("TransactionID = %q, want %q", detail.TransactionID, txID)
+	}
+
+	want := map[string]string{
+		committed:  "COMMITTED",
+		missing:    protocol.TxStatusMissing,
+		broken:     protocol.TxStatusUnreachable,
+		local.Addr: "PREPARED",
+	}
+	if len(detail.Nodes) != len(want) {
+		t.Fatalf("Expected %d nodes, got %d: %+v", len(want), len(detail.Nodes), detail.Nodes)
+	}
+	for addr, status := range want {
+		if got := detail.Nodes[addr].Status; got != status {
+			t.Errorf("Node %s status = %q, want %q", addr, got, status)
+		}
+	}
+	if detail.Nodes[committed].UpdatedAt == nil {
+		t.Error("Expected updated_at for committed node")
+	}
+	if detail.Nodes[broken].Error == "" {
+		t.Error("Expected error detail for unreachable node")
+	}
+}
+
+func TestCoordinator_RecordOnAllMarksNonTargetsAsync(t *testing.T) {
+	target := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	bystander := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer target.Close()
+	defer bystander.Close()
+
+	observed := make(chan protocol.ObserveRequest, 1)
+	release := make(chan struct{})
+	observer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/observe" {
+			http.NotFound(w, r)
+			return
+		}
+		var req protocol.ObserveRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		<-release // hold the marker write until the transaction has returned
+		observed <- req
+		_ = json.NewEncoder(w).Encode(protocol.ObserveResponse{Success: true})
+	}))
+	defer observer.Close()
+	defer close(release)
+
+	c := testClusterWithSlaves(target.Addr(), bystander.Addr())
+	// The observer is a member that is down, so it is not a 2PC candidate at all.
+	c.AddNode(node.NewNode(observer.Listener.Addr().String(), protocol.RoleSlave))
+	coordinator := NewCoordinator(c, nil, 200*time.Millisecond)
+
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{
+		Payload:     samplePayload(),
+		Targets:     []string{target.Addr()},
+		RecordOnAll: true,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if !resp.Success || !resp.Durable {
+		t.Fatalf("Expected durable commit while markers are still pending, got %#v", resp)
+	}
+
+	if calls := target.callCounts(); calls.prepare != 1 || calls.commit != 1 {
+		t.Errorf("Target calls: %+v, expected one prepare and one commit", calls)
+	}
+	if calls := bystander.callCounts(); calls.prepare != 0 || calls.commit != 0 {
+		t.Errorf("Non-target calls: %+v, expected no 2PC traffic", calls)
+	}
+
+	release <- struct{}{}
+	select {
+	case req := <-observed:
+		if req.TransactionID != resp.TransactionID {
+			t.Errorf("Observed tx = %q, want %q", req.TransactionID, resp.TransactionID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected non-target node to receive an OBSERVED marker")
+	}
+}
+
+func TestCoordinator_RejectsUnavailableTargets(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), nil, 200*time.Millisecond)
+
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{
+		Payload: samplePayload(),
+		Targets: []string{remote.Addr(), "missing:1"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "missing:1") {
+		t.Errorf("Expected rejection naming missing:1, got %#v", resp)
+	}
+	if calls := remote.callCounts(); calls.prepare != 0 {
+		t.Errorf("Expected no prepare on rejected transaction, got %+v", calls)
+	}
+}
+
+func TestCoordinator_TargetsByName(t *testing.T) {
+	orders := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer orders.Close()
+	replicaA := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer replicaA.Close()
+	replicaB := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer replicaB.Close()
+
+	c := testClusterWithSlaves(orders.Addr(), replicaA.Addr(), replicaB.Addr())
+	c.GetNode(orders.Addr()).SetName("orders")
+	c.GetNode(replicaA.Addr()).SetName("replica")
+	c.GetNode(replicaB.Addr()).SetName("replica")
+	coordinator := NewCoordinator(c, nil, 200*time.Millisecond)
+
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{
+		Payload: samplePayload(),
+		Targets: []string{"orders"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Expected commit on the named node, got %#v", resp)
+	}
+	if calls := orders.callCounts(); calls.prepare != 1 || calls.commit != 1 {
+		t.Errorf("Named target calls: %+v, expected one prepare and one commit", calls)
+	}
+
+	resp, err = coordinator.ExecuteRequest(&protocol.TransactionRequest{
+		Payload: samplePayload(),
+		Targets: []string{"replica"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "ambiguous") {
+		t.Errorf("Expected rejection of an ambiguous name, got %#v", resp)
+	}
+	if a, b := replicaA.callCounts(), replicaB.callCounts(); a.prepare+b.prepare != 0 {
+		t.Errorf("Expected no prepare on the ambiguous name, got %+v and %+v", a, b)
+	}
+}
+
+func TestCoordinator_InFlightTracksActiveTransaction(t *testing.T) {
+	slow := newStubNodeServer(readyPrepare(300*time.Millisecond), commitSuccess(), abortSuccess())
+	defer slow.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(slow.Addr()), nil, time.Second)
+	if got := coordinator.InFlight(); len(got) != 0 {
+		t.Fatalf("Expected no in-flight transactions initially, got %v", got)
+	}
+
+	done := make(chan *protocol.TransactionResponse, 1)
+	go func() {
+		resp, _ := coordinator.Execute(samplePayload())
+		done <- resp
+	}()
+
+	var active []protocol.InflightTransaction
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if active = coordinator.InFlight(); len(active) > 0 && active[0].Participants > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if len(active) != 1 {
+		t.Fatalf("Expected one in-flight transaction during prepare, got %v", active)
+	}
+	if active[0].Phase != protocol.PhasePreparing || active[0].Participants != 1 || active[0].StartedAt.IsZero() {
+		t.Errorf("Unexpected in-flight entry: %+v", active[0])
+	}
+
+	resp := <-done
+	if resp == nil || !resp.Success {
+		t.Fatalf("Expected transaction to commit, got %#v", resp)
+	}
+	if active[0].TransactionID != resp.TransactionID {
+		t.Errorf("In-flight ID = %s, want %s", active[0].TransactionID, resp.TransactionID)
+	}
+	if got := coordinator.InFlight(); len(got) != 0 {
+		t.Errorf("Expected in-flight list to be cleared after Execute, got %v", got)
+	}
+}
+
+func TestCoordinator_CommitOrder(t *testing.T) {
+	cases := []struct {
+		order           CommitOrder
+		localAtRemoteTx protocol.TxState // local node state when the remote receives commit
+	}{
+		{CommitRemoteFirst, protocol.StateReady},
+		{CommitLocalFirst, protocol.StateCommit},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.order), func(t *testing.T) {
+			local := node.NewNode("local:0", protocol.RoleMaster)
+
+			var mu sync.Mutex
+			var seen protocol.TxState
+			mux := http.NewServeMux()
+			mux.HandleFunc("/prepare", func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(protocol.PrepareResponse{Status: protocol.StatusReady})
+			})
+			mux.HandleFunc("/commit", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				seen = local.GetTxState()
+				mu.Unlock()
+				_ = json.NewEncoder(w).Encode(protocol.CommitResponse{Success: true})
+			})
+			remote := httptest.NewServer(mux)
+			defer remote.Close()
+
+			c := testClusterWithSlaves(remote.Listener.Addr().String())
+			coordinator := NewCoordinator(c, local, 200*time.Millisecond).WithCommitOrder(tc.order)
+
+			resp, err := coordinator.Execute(samplePayload())
+			if err != nil {
+				t.Fatalf("Execute() returned error: %v", err)
+			}
+			if !resp.Success || !resp.Durable {
+				t.Fatalf("Expected durable commit, got %#v", resp)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if seen != tc.localAtRemoteTx {
+				t.Errorf("Local state when remote committed = %s, want %s", seen, tc.localAtRemoteTx)
+			}
+			if local.GetTxState() != protocol.StateCommit {
+				t.Errorf("Local state after Execute = %s, want COMMIT", local.GetTxState())
+			}
+		})
+	}
+}
+
+func TestParseCommitOrder(t *testing.T) {
+	for _, valid := range []string{"parallel", "local-first", "remote-first"} {
+		if order, err := ParseCommitOrder(valid); err != nil || string(order) != valid {
+			t.Errorf("ParseCommitOrder(%q) = %q, %v", valid, order, err)
+		}
+	}
+	if _, err := ParseCommitOrder("random"); err == nil {
+		t.Error("Expected error for unknown commit order")
+	}
+}
+
+func TestCoordinator_DedupesEquivalentParticipantAddresses(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	_, port, _ := strings.Cut(remote.Addr(), ":")
+	loopbackAlias := "localhost:" + port
+
+	t.Run("RemoteEquivalentToLocal", func(t *testing.T) {
+		// The local node is registered under another spelling of the remote's address.
+		local := node.NewNode(loopbackAlias, protocol.RoleMaster)
+		coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), local, 200*time.Millisecond)
+
+		before := remote.callCounts()
+		resp, err := coordinator.Execute(samplePayload())
+		if err != nil || !resp.Success {
+			t.Fatalf("Expected success, got %#v (err=%v)", resp, err)
+		}
+		if calls := remote.callCounts(); calls.prepare != before.prepare {
+			t.Errorf("Expected duplicate of local node not to be prepared remotely, got %+v", calls)
+		}
+	})
+
+	t.Run("TargetsMatchEquivalentForms", func(t *testing.T) {
+		coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), nil, 200*time.Millisecond)
+
+		before := remote.callCounts()
+		resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{
+			Payload: samplePayload(),
+			Targets: []string{loopbackAlias, remote.Addr()},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("Expected success, got %#v (err=%v)", resp, err)
+		}
+		if calls := remote.callCounts(); calls.prepare != before.prepare+1 {
+			t.Errorf("Expected exactly one prepare for equivalent targets, got %d", calls.prepare-before.prepare)
+		}
+	})
+}
+
+func TestCoordinator_ExcludesDeadLocalNode(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	local := node.NewNode("local:0", protocol.RoleMaster)
+	local.SetAlive(false) // e.g. its database went down
+	coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), local, 200*time.Millisecond)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success || resp.Message != "Transaction committed on 1 nodes" {
+		t.Fatalf("Expected commit on the remote only, got %#v", resp)
+	}
+	if local.GetTxState() != protocol.StateInit {
+		t.Errorf("Expected dead local node not to be prepared, state = %s", local.GetTxState())
+	}
+
+	t.Run("ExplicitTargetRejected", func(t *testing.T) {
+		resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{
+			Payload: samplePayload(),
+			Targets: []string{local.Addr},
+		})
+		if err != nil {
+			t.Fatalf("ExecuteRequest() returned error: %v", err)
+		}
+		if resp.Success || !strings.Contains(resp.Error, local.Addr) {
+			t.Errorf("Expected rejection naming %s, got %#v", local.Addr, resp)
+		}
+	})
+
+	t.Run("NoOtherParticipants", func(t *testing.T) {
+		resp, err := NewCoordinator(testClusterWithSlaves(), local, 200*time.Millisecond).Execute(samplePayload())
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+		if resp.Success || resp.Error != "No participants available" {
+			t.Errorf("Expected no participants, got %#v", resp)
+		}
+	})
+}
+
+func TestCoordinator_ExcludesLocalNodeWithDeadDB(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	// Nothing listens on port 1, so every ping fails while the node stays alive.
+	db, err := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/postgres?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	defer db.Close()
+
+	local := node.NewNodeWithDB("local:0", protocol.RoleMaster, db)
+	local.SetAlive(true)
+	coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), local, 500*time.Millisecond).
+		WithVerboseResponses(true)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success || resp.Message != "Transaction committed on 1 nodes" {
+		t.Fatalf("Expected commit on the remote only, got %#v", resp)
+	}
+	if local.GetTxState() != protocol.StateInit {
+		t.Errorf("Expected local node with a dead database not to be prepared, state = %s", local.GetTxState())
+	}
+	found := false
+	for _, e := range resp.Excluded {
+		if e.Address == local.Addr {
+			found = e.Reason == protocol.ExcludeNotReady
+		}
+	}
+	if !found {
+		t.Errorf("Expected local node excluded as %q, got %#v", protocol.ExcludeNotReady, resp.Excluded)
+	}
+
+	t.Run("ExplicitTargetRejected", func(t *testing.T) {
+		resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{
+			Payload: samplePayload(),
+			Targets: []string{local.Addr},
+		})
+		if err != nil {
+			t.Fatalf("ExecuteRequest() returned error: %v", err)
+		}
+		if resp.Success || !strings.Contains(resp.Error, local.Addr) {
+			t.Errorf("Expected rejection naming %s, got %#v", local.Addr, resp)
+		}
+	})
+}
+
+func TestCoordinator_AbortBreakdownClassifiesPrepareTimeout(t *testing.T) {
+	slow := newStubNodeServer(readyPrepare(300*time.Millisecond), commitSuccess(), abortSuccess())
+	defer slow.Close()
+	fast := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer fast.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(slow.Addr(), fast.Addr()), nil, 100*time.Millisecond)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("Expected abort when a participant times out in prepare")
+	}
+
+	want := map[string]int64{
+		protocol.AbortVoteAbort:             0,
+		protocol.AbortPrepareTimeout:        1,
+		protocol.AbortPrepareTransportError: 0,
+		protocol.AbortNoParticipants:        0,
+		protocol.AbortNodeAtCapacity:        0,
+		protocol.AbortResourcePressure:      0,
+		protocol.AbortCommitLogError:        0,
+		protocol.AbortDurabilityUnmet:       0,
+	}
+	if got := coordinator.AbortBreakdown(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AbortBreakdown = %v, want %v", got, want)
+	}
+}
+
+func TestCoordinator_AbortBreakdownCategories(t *testing.T) {
+	voter := newStubNodeServer(stubEndpoint{response: protocol.PrepareResponse{Status: protocol.StatusAbort}}, commitSuccess(), abortSuccess())
+	defer voter.Close()
+
+	full := newStubNodeServer(stubEndpoint{
+		status: http.StatusTooManyRequests,
+		response: protocol.PrepareResponse{
+			Status: protocol.StatusAbort,
+			Error:  "node at capacity",
+			Code:   protocol.ErrCodeAtCapacity,
+		},
+	}, commitSuccess(), abortSuccess())
+	defer full.Close()
+
+	pressed := newStubNodeServer(stubEndpoint{
+		status: http.StatusTooManyRequests,
+		response: protocol.PrepareResponse{
+			Status: protocol.StatusAbort,
+			Error:  "resource pressure",
+			Code:   protocol.ErrCodeResourcePressure,
+		},
+	}, commitSuccess(), abortSuccess())
+	defer pressed.Close()
+
+	down := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	downAddr := down.Addr()
+	down.Close()
+
+	for _, addr := range []string{voter.Addr(), full.Addr(), pressed.Addr(), downAddr} {
+		coordinator := NewCoordinator(testClusterWithSlaves(addr), nil, 200*time.Millisecond)
+		if resp, _ := coordinator.Execute(samplePayload()); resp.Success {
+			t.Fatalf("Expected abort for %s", addr)
+		}
+
+		want := protocol.AbortVoteAbort
+		switch addr {
+		case full.Addr():
+			want = protocol.AbortNodeAtCapacity
+		case pressed.Addr():
+			want = protocol.AbortResourcePressure
+		case downAddr:
+			want = protocol.AbortPrepareTransportError
+		}
+		if got := coordinator.AbortBreakdown()[want]; got != 1 {
+			t.Errorf("%s: AbortBreakdown()[%s] = %d, want 1 (%v)", addr, want, got, coordinator.AbortBreakdown())
+		}
+	}
+
+	coordinator := NewCoordinator(testClusterWithSlaves(), nil, 200*time.Millisecond)
+	if resp, _ := coordinator.Execute(samplePayload()); resp.Success {
+		t.Fatal("Expected failure without participants")
+	}
+	if got := coordinator.AbortBreakdown()[protocol.AbortNoParticipants]; got != 1 {
+		t.Errorf("AbortBreakdown()[no_participants] = %d, want 1", got)
+	}
+}
+
+func TestCoordinatorForAddrsWithoutCluster(t *testing.T) {
+	remoteA := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	remoteB := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remoteA.Close()
+	defer remoteB.Close()
+
+	coordinator := NewCoordinatorForAddrs([]string{remoteA.Addr(), remoteB.Addr()}, nil, 200*time.Millisecond)
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !resp.Success || resp.Message != "Transaction committed on 2 nodes" {
+		t.Fatalf("Expected commit on both addresses, got %#v", resp)
+	}
+	for _, remote := range []*stubNodeServer{remoteA, remoteB} {
+		if got := remote.callCounts(); got.prepare != 1 || got.commit != 1 {
+			t.Errorf("Expected one prepare and one commit on %s, got %+v", remote.Addr(), got)
+		}
+	}
+
+	t.Run("UnreachableAddressAborts", func(t *testing.T) {
+		down := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+		downAddr := down.Addr()
+		down.Close()
+
+		coordinator := NewCoordinatorForAddrs([]string{remoteA.Addr(), downAddr}, nil, 200*time.Millisecond)
+		resp, err := coordinator.Execute(samplePayload())
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if resp.Success {
+			t.Fatalf("Expected abort when a static participant is unreachable, got %#v", resp)
+		}
+		if got := remoteA.callCounts().abort; got != 1 {
+			t.Errorf("Expected the prepared participant to be aborted once, got %d", got)
+		}
+	})
+
+	t.Run("DisabledParticipantSkipped", func(t *testing.T) {
+		static := NewStaticParticipants([]string{remoteA.Addr(), remoteB.Addr()})
+		static.GetNodes()[1].SetDisabled(true)
+
+		resp, err := NewCoordinatorWithParticipants(static, nil, 200*time.Millisecond).Execute(samplePayload())
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if !resp.Success || resp.Message != "Transaction committed on 1 nodes" {
+			t.Fatalf("Expected commit on the enabled address only, got %#v", resp)
+		}
+	})
+}
+
+// reconcileStubNode serves status for any transaction (404 when status is
+// empty) and records the commit and abort decisions it receives.
+func reconcileStubNode(t *testing.T, status string) (string, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/transaction/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if status == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&protocol.TransactionRecord{TxID: r.PathValue("id"), Status: status})
+	})
+	for _, path := range []string{"/commit", "/abort"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			received = append(received, r.URL.Path)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(&protocol.CommitResponse{Success: true})
+		})
+	}
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv.Listener.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+func TestCoordinator_ReconcileDivergentNodes(t *testing.T) {
+	committedA, receivedA := reconcileStubNode(t, protocol.TxStatusCommitted)
+	committedB, _ := reconcileStubNode(t, protocol.TxStatusCommitted)
+	prepared, receivedPrepared := reconcileStubNode(t, protocol.TxStatusPrepared)
+	aborted, receivedAborted := reconcileStubNode(t, protocol.TxStatusAborted)
+
+	coordinator := NewCoordinatorForAddrs([]string{committedA, committedB, prepared, aborted}, nil, time.Second)
+
+	resp, err := coordinator.Reconcile("tx-divergent")
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if resp.Decision != protocol.TxStatusCommitted || resp.Basis != protocol.ReconcileBasisMajority {
+		t.Errorf("Decision = %s (%s), want COMMITTED by majority", resp.Decision, resp.Basis)
+	}
+
+	want := map[string]string{
+		committedA: protocol.ReconcileUnchanged,
+		committedB: protocol.ReconcileUnchanged,
+		prepared:   protocol.ReconcileApplied,
+		aborted:    protocol.ReconcileFailed,
+	}
+	for addr, action := range want {
+		if got := resp.Nodes[addr].Action; got != action {
+			t.Errorf("Node %s (%s) action = %q, want %q", addr, resp.Nodes[addr].Before, got, action)
+		}
+	}
+
+	if got := receivedPrepared(); !reflect.DeepEqual(got, []string{"/commit"}) {
+		t.Errorf("Prepared node received %v, want a single commit", got)
+	}
+	if got := append(receivedA(), receivedAborted()...); len(got) != 0 {
+		t.Errorf("Nodes in a final state must not be touched, got %v", got)
+	}
+}
+
+func TestCoordinator_ReconcileTakesOverAfterFailover(t *testing.T) {
+	// The old master prepared the transaction on a slave that has since been
+	// promoted and on a peer, then failed before delivering the decision.
+	local := node.NewNode("new-master:0", protocol.RoleMaster)
+	local.SetAlive(true)
+	peer := node.NewNode("peer:0", protocol.RoleSlave)
+	srv := httptest.NewServer(transport.NewHTTPServer(peer).Handler())
+	defer srv.Close()
+
+	view := failoverView(local, "old-master:8080")
+	for _, n := range []*node.Node{local, peer} {
+		n.SetTakeoverGuard(view.AuthorizeTakeover)
+		if ready, err := n.PrepareFor("old-master:8080", "tx-failover", samplePayload()); !ready || err != nil {
+			t.Fatalf("PrepareFor on %s failed: ready=%v err=%v", n.Addr, ready, err)
+		}
+	}
+	committed, _ := reconcileStubNode(t, protocol.TxStatusCommitted)
+
+	coordinator := NewCoordinatorForAddrs([]string{local.Addr, srv.Listener.Addr().String(), committed}, local, time.Second)
+	resp, err := coordinator.Reconcile("tx-failover")
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if resp.Decision != protocol.TxStatusCommitted {
+		t.Fatalf("Decision = %s, want COMMITTED", resp.Decision)
+	}
+	for addr, result := range resp.Nodes {
+		if addr != committed && result.Action != protocol.ReconcileApplied {
+			t.Errorf("Node %s action = %q (%s), want %q", addr, result.Action, result.Error, protocol.ReconcileApplied)
+		}
+	}
+	for _, n := range []*node.Node{local, peer} {
+		if n.HasPendingTransaction("tx-failover") {
+			t.Errorf("Expected %s to commit the transaction the old master prepared", n.Addr)
+		}
+	}
+}
+
+// failoverView is a cluster view after master failed over to newMaster: the
+// old master is still a member but down.
+func failoverView(newMaster *node.Node, oldMaster string) *cluster.Cluster {
+	view := cluster.NewCluster()
+	old := node.NewNode(oldMaster, protocol.RoleSlave)
+	view.AddNode(old)
+	view.AddNode(newMaster)
+	view.SetMaster(newMaster)
+	return view
+}
+
+func TestCoordinator_ReconcileTakeoverRefusedForStaleMaster(t *testing.T) {
+	// The peer already follows a newer master, so a stale master that still
+	// believes it leads cannot take the transaction over.
+	stale := node.NewNode("stale-master:0", protocol.RoleMaster)
+	stale.SetAlive(true)
+	peer := node.NewNode("peer:0", protocol.RoleSlave)
+	newer := node.NewNode("newer-master:0", protocol.RoleMaster)
+	newer.SetAlive(true)
+	peer.SetTakeoverGuard(failoverView(newer, "old-master:8080").AuthorizeTakeover)
+	srv := httptest.NewServer(transport.NewHTTPServer(peer).Handler())
+	defer srv.Close()
+
+	if ready, err := peer.PrepareFor("old-master:8080", "tx-split", samplePayload()); !ready || err != nil {
+		t.Fatalf("PrepareFor failed: ready=%v err=%v", ready, err)
+	}
+	peerAddr := srv.Listener.Addr().String()
+
+	coordinator := NewCoordinatorForAddrs([]string{peerAddr}, stale, time.Second)
+	resp, err := coordinator.Reconcile("tx-split")
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if got := resp.Nodes[peerAddr]; got.Action != protocol.ReconcileFailed || !strings.Contains(got.Error, "takeover refused") {
+		t.Errorf("Peer result = %+v, want a refused takeover", got)
+	}
+	if !peer.HasPendingTransaction("tx-split") {
+		t.Error("A refused takeover must leave the transaction prepared")
+	}
+}
+
+func TestDecideOutcome(t *testing.T) {
+	nodes := func(statuses ...string) map[string]protocol.TransactionNodeStatus {
+		out := make(map[string]protocol.TransactionNodeStatus, len(statuses))
+		for i, s := range statuses {
+			out[fmt.Sprintf("n%d", i)] = protocol.TransactionNodeStatus{Status: s}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		nodes    map[string]protocol.TransactionNodeStatus
+		logged   protocol.TxState
+		decision string
+		basis    string
+		wantErr  bool
+	}{
+		{"coordinator log wins", nodes(protocol.TxStatusAborted, protocol.TxStatusPrepared), protocol.StateCommit, protocol.TxStatusCommitted, protocol.ReconcileBasisCoordinatorLog, false},
+		{"majority abort", nodes(protocol.TxStatusAborted, protocol.TxStatusAborted, protocol.TxStatusCommitted), "", protocol.TxStatusAborted, protocol.ReconcileBasisMajority, false},
+		{"only prepared", nodes(protocol.TxStatusPrepared, protocol.TxStatusMissing), "", protocol.TxStatusAborted, protocol.ReconcileBasisPresumedAbort, false},
+		{"tie", nodes(protocol.TxStatusCommitted, protocol.TxStatusAborted), "", "", "", true},
+		{"unknown everywhere", nodes(protocol.TxStatusMissing, protocol.TxStatusUnreachable), "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, basis, err := decideOutcome(tt.nodes, tt.logged)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if decision != tt.decision || basis != tt.basis {
+				t.Errorf("got %s (%s), want %s (%s)", decision, basis, tt.decision, tt.basis)
+			}
+		})
+	}
+}
+
+// preparedStubNode serves /prepared with xacts, reports those transactions as
+// PREPARED and records the decisions it receives.
+func preparedStubNode(t *testing.T, xacts ...protocol.PreparedXact) (string, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prepared", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&protocol.PreparedXactsResponse{Xacts: xacts})
+	})
+	mux.HandleFunc("/transaction/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for _, x := range xacts {
+			if x.TransactionID == r.PathValue("id") {
+				_ = json.NewEncoder(w).Encode(&protocol.TransactionRecord{TxID: x.TransactionID, Status: protocol.TxStatusPrepared})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	for _, path := range []string{"/commit", "/abort"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			var req protocol.CommitRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			received = append(received, r.URL.Path+" "+req.TransactionID)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(&protocol.CommitResponse{Success: true})
+		})
+	}
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv.Listener.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+func TestCoordinator_ReconcilePreparedResolvesStaleOnly(t *testing.T) {
+	holder, received := preparedStubNode(t,
+		protocol.PreparedXact{TransactionID: "tx-orphan", GID: "2pc-engine:tx-orphan", Stale: true},
+		protocol.PreparedXact{TransactionID: "tx-logged", GID: "2pc-engine:tx-logged", Stale: true},
+		protocol.PreparedXact{TransactionID: "tx-fresh", GID: "2pc-engine:tx-fresh"},
+	)
+	empty, receivedEmpty := preparedStubNode(t)
+
+	recovery := NewRecoveryQueue(time.Second, time.Hour)
+	recovery.Enqueue("tx-logged", holder, protocol.StateCommit, "")
+	coordinator := NewCoordinatorForAddrs([]string{holder, empty}, nil, time.Second).WithRecoveryQueue(recovery)
+
+	resolved, err := coordinator.ReconcilePrepared()
+	if err != nil {
+		t.Fatalf("ReconcilePrepared failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, resp := range resolved {
+		got[resp.TransactionID] = resp.Decision + " (" + resp.Basis + ")"
+	}
+	want := map[string]string{
+		"tx-orphan": protocol.TxStatusAborted + " (" + protocol.ReconcileBasisPresumedAbort + ")",
+		"tx-logged": protocol.TxStatusCommitted + " (" + protocol.ReconcileBasisCoordinatorLog + ")",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolved %v, want %v", got, want)
+	}
+
+	if got, want := received(), []string{"/commit tx-logged", "/abort tx-orphan"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Holder received %v, want %v", got, want)
+	}
+	if got := receivedEmpty(); len(got) != 0 {
+		t.Errorf("Node without prepared transactions received %v", got)
+	}
+}
+
+func TestCoordinator_VerboseResponsesListExclusions(t *testing.T) {
+	remotes := []*stubNodeServer{
+		newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess()),
+		newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess()),
+	}
+	for _, r := range remotes {
+		defer r.Close()
+	}
+
+	c := testClusterWithSlaves(remotes[0].Addr(), remotes[1].Addr(), "dead:1", "disabled:1", "maint:1")
+	c.GetNode("dead:1").SetAlive(false)
+	c.GetNode("disabled:1").SetDisabled(true)
+	c.GetNode("maint:1").SetMaintenance(true)
+	local := node.NewNode("local:0", protocol.RoleMaster)
+	local.SetAlive(true)
+	local.SetMaintenance(true)
+
+	coordinator := NewCoordinator(c, local, time.Second)
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success || resp.Excluded != nil {
+		t.Fatalf("Expected a commit without exclusions listed by default, got %#v", resp)
+	}
+
+	coordinator.WithVerboseResponses(true)
+	resp, err = coordinator.ExecuteRequest(&protocol.TransactionRequest{
+		Payload: samplePayload(),
+		Targets: []string{remotes[0].Addr()},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Expected the targeted transaction to commit, got %#v", resp)
+	}
+
+	want := map[string]string{
+		"dead:1":          protocol.ExcludeDead,
+		"disabled:1":      protocol.ExcludeDisabled,
+		"maint:1":         protocol.ExcludeMaintenance,
+		"local:0":         protocol.ExcludeNotTargeted,
+		remotes[1].Addr(): protocol.ExcludeNotTargeted,
+	}
+	got := make(map[string]string, len(resp.Excluded))
+	for _, e := range resp.Excluded {
+		got[e.Address] = e.Reason
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Excluded = %v, want %v", got, want)
+	}
+
+	// Without targets, the local node is reported for its own state.
+	resp, err = coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	for _, e := range resp.Excluded {
+		if e.Address == local.Addr && e.Reason != protocol.ExcludeMaintenance {
+			t.Errorf("Expected the local node excluded for maintenance, got %q", e.Reason)
+		}
+		if e.Address == remotes[1].Addr() {
+			t.Errorf("Expected %s to participate without targets, got %#v", e.Address, e)
+		}
+	}
+}
+
+func TestCoordinator_SkipsDrainingNodes(t *testing.T) {
+	live := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer live.Close()
+	draining := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer draining.Close()
+
+	c := testClusterWithSlaves(live.Addr(), draining.Addr())
+	c.GetNode(draining.Addr()).SetDraining(true)
+	local := node.NewNode("local:0", protocol.RoleMaster)
+	local.SetAlive(true)
+	local.SetDraining(true)
+
+	coordinator := NewCoordinator(c, local, time.Second).WithVerboseResponses(true)
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Expected commit on the remaining node, got %#v", resp)
+	}
+
+	if got := draining.callCounts().prepare; got != 0 {
+		t.Errorf("Expected no prepare on the draining node, got %d", got)
+	}
+	if got := live.callCounts().prepare; got != 1 {
+		t.Errorf("Expected one prepare on the live node, got %d", got)
+	}
+
+	want := map[string]string{
+		draining.Addr(): protocol.ExcludeDraining,
+		local.Addr:      protocol.ExcludeDraining,
+	}
+	got := make(map[string]string, len(resp.Excluded))
+	for _, e := range resp.Excluded {
+		got[e.Address] = e.Reason
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Excluded = %v, want %v", got, want)
+	}
+}
+
+func TestCoordinator_UsesInjectedHTTPClient(t *testing.T) {
+	// The participant fails its first commit with 503, then acknowledges.
+	var commits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prepare", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(protocol.PrepareResponse{Status: protocol.StatusReady})
+	})
+	mux.HandleFunc("/commit", func(w http.ResponseWriter, r *http.Request) {
+		if commits.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(protocol.CommitResponse{Success: true})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	client := transport.NewHTTPClient(time.Second).WithRetry(1, time.Millisecond)
+	coordinator := NewCoordinator(testClusterWithSlaves(addr), nil, time.Second).WithHTTPClient(client)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success || !resp.Durable {
+		t.Fatalf("Expected the retried commit to be acknowledged, got %#v", resp)
+	}
+	if got := commits.Load(); got != 2 {
+		t.Errorf("Expected the injected client to retry the commit once, got %d commits", got)
+	}
+}
+
+// benchmarkCoordinator builds a coordinate-only master over n stub slaves that
+// vote READY and acknowledge at once, so the numbers measure the coordinator
+// and its HTTP fan-out rather than a database.
+func benchmarkCoordinator(b *testing.B, n int) (*Coordinator, []*node.Node) {
+	b.Helper()
+
+	prevLevel := logging.GetLevel()
+	logging.SetLevel(logging.LevelError)
+	b.Cleanup(func() { logging.SetLevel(prevLevel) })
+
+	addrs := make([]string, n)
+	for i := range addrs {
+		srv := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+		b.Cleanup(srv.Close)
+		addrs[i] = srv.Addr()
+	}
+
+	c := testClusterWithSlaves(addrs...)
+	coordinator := NewCoordinator(c, c.GetMaster(), 5*time.Second).WithLocalParticipation(false)
+	return coordinator, c.GetSlaveNodes()
+}
+
+func reportThroughput(b *testing.B) {
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "tx/s")
+}
+
+// BenchmarkExecute measures full transactions over 1, 3 and 10 participants,
+// issued one at a time and from concurrent callers. The concurrent variant
+// shows what the coordinator lock costs: it runs transactions one by one.
+func BenchmarkExecute(b *testing.B) {
+	for _, n := range []int{1, 3, 10} {
+		b.Run(fmt.Sprintf("participants=%d/serial", n), func(b *testing.B) {
+			coordinator, _ := benchmarkCoordinator(b, n)
+			for b.Loop() {
+				if resp, err := coordinator.Execute(samplePayload()); err != nil || !resp.Success {
+					b.Fatalf("Execute failed: %+v, %v", resp, err)
+				}
+			}
+			reportThroughput(b)
+		})
+
+		b.Run(fmt.Sprintf("participants=%d/concurrent", n), func(b *testing.B) {
+			coordinator, _ := benchmarkCoordinator(b, n)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if resp, err := coordinator.Execute(samplePayload()); err != nil || !resp.Success {
+						b.Errorf("Execute failed: %+v, %v", resp, err)
+						return
+					}
+				}
+			})
+			reportThroughput(b)
+		})
+	}
+}
+
+// BenchmarkPreparePhase measures the prepare fan-out alone. Nothing commits,
+// so the stubs just vote READY again.
+func BenchmarkPreparePhase(b *testing.B) {
+	for _, n := range []int{1, 3, 10} {
+		b.Run(fmt.Sprintf("participants=%d", n), func(b *testing.B) {
+			coordinator, participants := benchmarkCoordinator(b, n)
+			payload := samplePayload()
+			for b.Loop() {
+				for _, res := range coordinator.preparePhase("tx-bench", payload, participants) {
+					if !res.Success {
+						b.Fatalf("Prepare on %s failed: %+v", res.Addr, res)
+					}
+				}
+			}
+			reportThroughput(b)
+		})
+	}
+}
+
+func TestCoordinator_KeepsAssignedTransactionID(t *testing.T) {
+	stub := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer stub.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second)
+	resp, err := coordinator.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload(), TransactionID: "tx-async-1"})
+	if err != nil {
+		t.Fatalf("ExecuteRequest() returned error: %v", err)
+	}
+	if !resp.Success || resp.TransactionID != "tx-async-1" {
+		t.Fatalf("Expected tx-async-1 to commit under its assigned ID, got %#v", resp)
+	}
+}
+
+func TestCoordinator_ReturnsGeneratedKeysOnCommit(t *testing.T) {
+	inserter := newStubNodeServer(stubEndpoint{
+		status: http.StatusOK,
+		response: protocol.PrepareResponse{
+			Status:   protocol.StatusReady,
+			Returned: []map[string]any{{"id": float64(42)}},
+		},
+	}, commitSuccess(), abortSuccess())
+	defer inserter.Close()
+	plain := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer plain.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(inserter.Addr(), plain.Addr()), nil, time.Second)
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil || !resp.Success {
+		t.Fatalf("Expected a commit, got %+v, %v", resp, err)
+	}
+	want := map[string][]map[string]any{inserter.Addr(): {{"id": float64(42)}}}
+	if !reflect.DeepEqual(resp.Returned, want) {
+		t.Errorf("Returned = %v, want %v", resp.Returned, want)
+	}
+
+	refusing := newStubNodeServer(stubEndpoint{
+		response: protocol.PrepareResponse{Status: protocol.StatusAbort, Error: "constraint violation"},
+	}, commitSuccess(), abortSuccess())
+	defer refusing.Close()
+
+	coordinator = NewCoordinator(testClusterWithSlaves(inserter.Addr(), refusing.Addr()), nil, time.Second)
+	resp, err = coordinator.Execute(samplePayload())
+	if err != nil || resp.Success || resp.Returned != nil {
+		t.Errorf("Expected an abort without returned values, got %+v, %v", resp, err)
+	}
+}
+
+func TestCoordinator_ListsParticipantsInAddressOrder(t *testing.T) {
+	var addrs []string
+	for range 5 {
+		stub := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+		defer stub.Close()
+		addrs = append(addrs, stub.Addr())
+	}
+	want := append([]string(nil), addrs...)
+	sort.Slice(want, func(i, j int) bool {
+		return cluster.CanonicalAddr(want[i]) < cluster.CanonicalAddr(want[j])
+	})
+
+	coordinator := NewCoordinator(testClusterWithSlaves(addrs...), nil, time.Second)
+	var outcomes []TransactionOutcome
+	coordinator.SetOnComplete(func(o TransactionOutcome) { outcomes = append(outcomes, o) })
+
+	// Membership is a map; repeated runs would surface a random order.
+	for range 10 {
+		if resp, err := coordinator.Execute(samplePayload()); err != nil || !resp.Success {
+			t.Fatalf("Expected commit, got %#v, %v", resp, err)
+		}
+	}
+	for i, o := range outcomes {
+		if !reflect.DeepEqual(o.Participants, want) {
+			t.Fatalf("Run %d listed %v, want %v", i, o.Participants, want)
+		}
+	}
+}
+
+// arrivalRecorder serves participants that log the order prepares reach them in.
+type arrivalRecorder struct {
+	mu      sync.Mutex
+	arrived []string
+	refuse  map[string]bool // addresses that vote ABORT
+}
+
+func (r *arrivalRecorder) serve(t *testing.T) string {
+	t.Helper()
+	var addr string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/prepare":
+			r.mu.Lock()
+			r.arrived = append(r.arrived, addr)
+			refuse := r.refuse[addr]
+			r.mu.Unlock()
+			// Give a concurrent fan-out time to interleave.
+			time.Sleep(10 * time.Millisecond)
+			if refuse {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(protocol.PrepareResponse{Status: protocol.StatusAbort, Error: "refused"})
+				return
+			}
+			json.NewEncoder(w).Encode(protocol.PrepareResponse{Status: protocol.StatusReady})
+		case "/commit":
+			json.NewEncoder(w).Encode(protocol.CommitResponse{Success: true})
+		default:
+			json.NewEncoder(w).Encode(protocol.AbortResponse{Success: true})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	addr = srv.Listener.Addr().String()
+	return addr
+}
+
+func (r *arrivalRecorder) order() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.arrived)
+}
+
+func TestCoordinator_SequentialPrepareArrivesInAddressOrder(t *testing.T) {
+	rec := &arrivalRecorder{refuse: make(map[string]bool)}
+	var addrs []string
+	for range 5 {
+		addrs = append(addrs, rec.serve(t))
+	}
+	want := slices.Clone(addrs)
+	sort.Slice(want, func(i, j int) bool {
+		return cluster.CanonicalAddr(want[i]) < cluster.CanonicalAddr(want[j])
+	})
+
+	coordinator := NewCoordinator(testClusterWithSlaves(addrs...), nil, time.Second).
+		WithPrepareOrder(PrepareSequential)
+	for run := range 3 {
+		rec.mu.Lock()
+		rec.arrived = nil
+		rec.mu.Unlock()
+		if resp, err := coordinator.Execute(samplePayload()); err != nil || !resp.Success {
+			t.Fatalf("Expected commit, got %#v, %v", resp, err)
+		}
+		if got := rec.order(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Run %d: prepares arrived in %v, want %v", run, got, want)
+		}
+	}
+
+	// A refusal ends the sequence: later nodes are never asked to prepare.
+	rec.mu.Lock()
+	rec.arrived = nil
+	rec.refuse[want[2]] = true
+	rec.mu.Unlock()
+	if resp, err := coordinator.Execute(samplePayload()); err != nil || resp.Success {
+		t.Fatalf("Expected an abort, got %#v, %v", resp, err)
+	}
+	if got := rec.order(); !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("Prepares arrived in %v, want %v and no further", got, want[:3])
+	}
+}
+
+func TestParsePrepareOrder(t *testing.T) {
+	for _, s := range []string{"parallel", "sequential"} {
+		if got, err := ParsePrepareOrder(s); err != nil || string(got) != s {
+			t.Errorf("ParsePrepareOrder(%q) = %q, %v", s, got, err)
+		}
+	}
+	if _, err := ParsePrepareOrder("random"); err == nil {
+		t.Error("Expected an unknown prepare order to be rejected")
+	}
+}
+
+func TestCoordinator_AbortQueuesUnreachableParticipant(t *testing.T) {
+	ready := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer ready.Close()
+	gone := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	goneAddr := gone.Addr()
+	gone.Close() // prepare and abort both fail in transport
+
+	recovery := NewRecoveryQueue(time.Second, time.Hour)
+	coordinator := NewCoordinator(testClusterWithSlaves(ready.Addr(), goneAddr), node.NewNode("local:0", protocol.RoleMaster), 200*time.Millisecond).
+		WithRecoveryQueue(recovery)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("Expected transaction to abort")
+	}
+
+	// The unreachable node may have prepared before its connection dropped,
+	// so its abort is retried until it confirms.
+	pending := recovery.Pending()
+	if len(pending) != 1 || pending[0].Addr != goneAddr || pending[0].Action != protocol.StateAbort {
+		t.Fatalf("Recovery queue = %+v, want one ABORT for %s", pending, goneAddr)
+	}
+}
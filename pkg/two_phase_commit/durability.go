@@ -0,0 +1,74 @@
+package twophasecommit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DurabilityRule is the minimum set of participants that must hold the
+// prepared transaction before the coordinator commits it. Every participant
+// must still vote READY; the rule additionally refuses to commit when too few
+// took part, e.g. while every remote replica is down. The zero value, "all",
+// adds no requirement.
+type DurabilityRule struct {
+	Local   bool // the local node must have prepared, with a database
+	Remotes int  // at least this many remote participants must have prepared
+}
+
+// DurabilityAll adds no requirement beyond every participant voting READY.
+var DurabilityAll = DurabilityRule{}
+
+// ParseDurabilityRule parses "all", "local", "local+N" or "N" (remotes only).
+func ParseDurabilityRule(s string) (DurabilityRule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "all" {
+		return DurabilityAll, nil
+	}
+
+	var rule DurabilityRule
+	count := s
+	if rest, ok := strings.CutPrefix(s, "local"); ok {
+		rule.Local = true
+		if rest == "" {
+			return rule, nil
+		}
+		if count, ok = strings.CutPrefix(rest, "+"); !ok {
+			return DurabilityRule{}, fmt.Errorf("unknown durability rule %q (want all, local, local+N or N)", s)
+		}
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return DurabilityRule{}, fmt.Errorf("unknown durability rule %q (want all, local, local+N or N)", s)
+	}
+	rule.Remotes = n
+	return rule, nil
+}
+
+// String renders the rule in the form ParseDurabilityRule accepts.
+func (r DurabilityRule) String() string {
+	switch {
+	case r == DurabilityAll:
+		return "all"
+	case !r.Local:
+		return strconv.Itoa(r.Remotes)
+	case r.Remotes == 0:
+		return "local"
+	default:
+		return "local+" + strconv.Itoa(r.Remotes)
+	}
+}
+
+// check returns why outcome, in which every participant voted READY, does
+// not satisfy the rule, or nil if it does. localDurable reports whether the
+// local node prepared against a database.
+func (r DurabilityRule) check(outcome prepareOutcome, localDurable bool) error {
+	if got := len(outcome.preparedRemotes); got < r.Remotes {
+		return fmt.Errorf("durability rule %s not met: %d of %d required remote participants prepared", r, got, r.Remotes)
+	}
+	if r.Local && !(outcome.localPrepared && localDurable) {
+		return fmt.Errorf("durability rule %s not met: the local node did not prepare against a database", r)
+	}
+	return nil
+}
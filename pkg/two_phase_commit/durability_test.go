@@ -0,0 +1,89 @@
+package twophasecommit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestParseDurabilityRule(t *testing.T) {
+	tests := map[string]DurabilityRule{
+		"":        DurabilityAll,
+		"all":     DurabilityAll,
+		"local":   {Local: true},
+		"local+1": {Local: true, Remotes: 1},
+		"LOCAL+2": {Local: true, Remotes: 2},
+		"2":       {Remotes: 2},
+	}
+	for in, want := range tests {
+		got, err := ParseDurabilityRule(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDurabilityRule(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"majority", "local+", "local-1", "local+x", "-1"} {
+		if _, err := ParseDurabilityRule(in); err == nil {
+			t.Errorf("Expected %q to be rejected", in)
+		}
+	}
+}
+
+func TestDurabilityRuleCheck(t *testing.T) {
+	rule := DurabilityRule{Local: true, Remotes: 1}
+	prepared := prepareOutcome{includeLocal: true, localPrepared: true, preparedRemotes: []string{"node-b:8081"}}
+
+	if err := rule.check(prepared, true); err != nil {
+		t.Errorf("Expected local+1 to hold with a durable local node and one remote, got %v", err)
+	}
+	if err := rule.check(prepared, false); err == nil {
+		t.Error("Expected local+1 to fail when the local node has no database")
+	}
+	if err := rule.check(prepareOutcome{includeLocal: true, localPrepared: true}, true); err == nil {
+		t.Error("Expected local+1 to fail without a remote")
+	}
+	if err := DurabilityAll.check(prepareOutcome{}, false); err != nil {
+		t.Errorf("Expected the default rule to add no requirement, got %v", err)
+	}
+}
+
+func TestCoordinator_DurabilityRuleAbortsWithoutRemote(t *testing.T) {
+	local := node.NewNode("localhost:8080", protocol.RoleMaster)
+	coordinator := NewCoordinator(testClusterWithSlaves(), local, time.Second).
+		WithDurabilityRule(DurabilityRule{Local: true, Remotes: 1})
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success || resp.Code != protocol.ErrCodeTransactionAborted || !strings.Contains(resp.Error, "0 of 1 required remote") {
+		t.Fatalf("Expected local+1 to abort without a remote, got %+v", resp)
+	}
+	if local.HasPendingTransaction(resp.TransactionID) {
+		t.Error("Expected the local prepare to be rolled back")
+	}
+	if got := coordinator.AbortBreakdown()[protocol.AbortDurabilityUnmet]; got != 1 {
+		t.Errorf("AbortBreakdown[%s] = %d, want 1", protocol.AbortDurabilityUnmet, got)
+	}
+
+	// One remote is not enough for a rule that asks for two.
+	stub := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer stub.Close()
+	coordinator = NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).
+		WithDurabilityRule(DurabilityRule{Remotes: 2})
+	if resp, _ := coordinator.Execute(samplePayload()); resp.Success {
+		t.Fatalf("Expected a rule of 2 remotes to abort with one, got %+v", resp)
+	}
+	if got := stub.callCounts(); got.commit != 0 || got.abort != 1 {
+		t.Errorf("Expected the prepared remote to be aborted, got %+v", got)
+	}
+
+	coordinator = NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second).
+		WithDurabilityRule(DurabilityRule{Remotes: 1})
+	if resp, _ := coordinator.Execute(samplePayload()); !resp.Success {
+		t.Errorf("Expected a rule of 1 remote to commit with one, got %+v", resp)
+	}
+}
@@ -0,0 +1,117 @@
+package twophasecommit_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/testutil"
+)
+
+// TestSuccessful2PC tests the happy path where all nodes prepare and commit successfully
+func TestSuccessful2PC(t *testing.T) {
+	tc := testutil.NewTestCluster(t, 3)
+
+	resp, err := tc.Submit(map[string]string{"test": "data"})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	if !resp.Success {
+		t.Errorf("Expected success, got failure: %s", resp.Error)
+	}
+
+	if resp.TransactionID == "" {
+		t.Error("Expected transaction ID to be set")
+	}
+
+	for _, n := range tc.Nodes {
+		if n.Node.HasPendingTransaction(resp.TransactionID) {
+			t.Errorf("Node %s still holds transaction %s", n.Addr(), resp.TransactionID)
+		}
+		if n.Node.GetTxState() != protocol.StateCommit {
+			t.Errorf("Node %s state = %s, want COMMIT", n.Addr(), n.Node.GetTxState())
+		}
+	}
+}
+
+// TestStalePreparedTransactionIsRolledBack runs against a real Postgres with
+// max_prepared_transactions > 0. Set TWOPC_TEST_POSTGRES_DSN to enable it.
+func TestStalePreparedTransactionIsRolledBack(t *testing.T) {
+	dsn := os.Getenv("TWOPC_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TWOPC_TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS stale_prepared_test (name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `ROLLBACK PREPARED '2pc-engine:tx-stale'`)
+		_, _ = db.ExecContext(ctx, `DROP TABLE IF EXISTS stale_prepared_test`)
+	})
+
+	tc := testutil.NewTestClusterWithOptions(t, 2, testutil.Options{
+		OpenDB: func(i int) *sql.DB {
+			if i == 1 {
+				return db
+			}
+			return nil
+		},
+	})
+	participant := tc.Nodes[1].Node
+	participant.SetPreparedTransactions(true)
+	participant.SetStalePreparedAfter(time.Millisecond)
+
+	// Prepared by a coordinator that never came back with a decision.
+	payload := map[string]any{"table": "stale_prepared_test", "values": map[string]any{"name": "orphan"}}
+	if ready, err := participant.Prepare("tx-stale", payload); err != nil || !ready {
+		t.Fatalf("Prepare failed: ready=%v err=%v", ready, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if m := participant.Metrics(); m.PreparedXacts != 1 || m.StalePreparedXacts != 1 {
+		t.Fatalf("Expected one stale prepared transaction in metrics, got %d (%d stale)", m.PreparedXacts, m.StalePreparedXacts)
+	}
+
+	resolved, err := tc.Coordinator.ReconcilePrepared()
+	if err != nil {
+		t.Fatalf("ReconcilePrepared failed: %v", err)
+	}
+	if len(resolved) != 1 || resolved[0].Decision != protocol.TxStatusAborted {
+		t.Fatalf("Expected tx-stale to be aborted, got %+v", resolved)
+	}
+
+	xacts, err := participant.ListPreparedXacts(ctx)
+	if err != nil {
+		t.Fatalf("ListPreparedXacts failed: %v", err)
+	}
+	for _, x := range xacts {
+		if x.TransactionID == "tx-stale" {
+			t.Errorf("Expected the stale gid to be rolled back, still prepared since %v", x.PreparedAt)
+		}
+	}
+	if participant.HasPendingTransaction("tx-stale") {
+		t.Error("Expected tx-stale to be dropped from the node's pending set")
+	}
+
+	var rows int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM stale_prepared_test`).Scan(&rows); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("Expected the rolled back insert to be gone, got %d rows", rows)
+	}
+}
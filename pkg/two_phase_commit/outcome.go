@@ -0,0 +1,94 @@
+package twophasecommit
+
+import (
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// TransactionOutcome describes a transaction the coordinator decided. It is
+// passed to the SetOnComplete hook once the decision has been sent to every
+// participant.
+type TransactionOutcome struct {
+	TransactionID string
+	Decision      protocol.TxState // StateCommit or StateAbort
+	Participants  []string         // prepared nodes, the local node first when it took part
+	Duration      time.Duration    // from the start of the round to the decision being applied
+
+	// Durable reports that every participant acknowledged a commit;
+	// PendingNodes lists those that did not.
+	Durable      bool
+	PendingNodes []string
+
+	// AbortCategory is why an aborted transaction failed (one of the
+	// protocol.Abort* values); empty on commit.
+	AbortCategory string
+
+	// settle waits for commits delivered in the background and updates
+	// Durable, PendingNodes and Duration; nil when every commit was awaited.
+	settle func()
+}
+
+// SetOnComplete installs fn to be called after each transaction that reached
+// a decision, committed or aborted. Transactions rejected before prepare (an
+// invalid payload, no participants, throttled) do not reach a decision and
+// are not reported. fn runs on the caller's goroutine after the coordinator
+// lock is released, so it delays the caller of Execute but not other
+// transactions; it must not block for long. A commit whose acknowledgements
+// were not all awaited (see CommitAckPolicy) is reported from the background
+// delivery once it finishes, so Durable reflects every participant. A nil fn
+// removes the hook.
+func (c *Coordinator) SetOnComplete(fn func(TransactionOutcome)) {
+	c.hookMu.Lock()
+	defer c.hookMu.Unlock()
+	c.onComplete = fn
+}
+
+// notifyComplete passes done to the completion hook, if one is set. A
+// panicking hook is logged and does not affect the transaction's response.
+func (c *Coordinator) notifyComplete(done *TransactionOutcome) {
+	if done == nil {
+		return
+	}
+
+	c.hookMu.Lock()
+	fn := c.onComplete
+	c.hookMu.Unlock()
+	if fn == nil {
+		return
+	}
+
+	if done.settle != nil {
+		settle := done.settle
+		done.settle = nil
+		c.background.Add(1)
+		go func() {
+			defer c.background.Done()
+			settle()
+			c.notifyComplete(done)
+		}()
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Errorf("[Coordinator] Completion hook panicked for transaction %s: %v", done.TransactionID, r)
+		}
+	}()
+	fn(*done)
+}
+
+// participantAddrs lists the addresses of a transaction's participants, the
+// local node first when it takes part.
+func (c *Coordinator) participantAddrs(includeLocal bool, remotes []*node.Node) []string {
+	addrs := make([]string, 0, len(remotes)+1)
+	if includeLocal {
+		addrs = append(addrs, c.localNode.Addr)
+	}
+	for _, n := range remotes {
+		addrs = append(addrs, n.Addr)
+	}
+	return addrs
+}
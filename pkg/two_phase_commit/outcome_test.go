@@ -0,0 +1,107 @@
+package twophasecommit
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestCoordinator_OnCompleteReportsCommitAndAbort(t *testing.T) {
+	ready := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer ready.Close()
+	voteAbort := newStubNodeServer(stubEndpoint{
+		status:   http.StatusOK,
+		response: protocol.PrepareResponse{Status: protocol.StatusAbort, Error: "constraint violation"},
+	}, commitSuccess(), abortSuccess())
+	defer voteAbort.Close()
+
+	var outcomes []TransactionOutcome
+	committing := NewCoordinator(testClusterWithSlaves(ready.Addr()), nil, time.Second)
+	committing.SetOnComplete(func(o TransactionOutcome) { outcomes = append(outcomes, o) })
+
+	resp, err := committing.Execute(samplePayload())
+	if err != nil || !resp.Success {
+		t.Fatalf("Expected commit, got %#v, %v", resp, err)
+	}
+	if len(outcomes) != 1 {
+		t.Fatalf("Expected one outcome after commit, got %d", len(outcomes))
+	}
+	got := outcomes[0]
+	if got.TransactionID != resp.TransactionID || got.Decision != protocol.StateCommit || !got.Durable || got.AbortCategory != "" {
+		t.Errorf("Unexpected commit outcome: %#v", got)
+	}
+	if !reflect.DeepEqual(got.Participants, []string{ready.Addr()}) {
+		t.Errorf("Participants = %v, want [%s]", got.Participants, ready.Addr())
+	}
+	if got.Duration <= 0 {
+		t.Errorf("Expected a positive duration, got %v", got.Duration)
+	}
+
+	aborting := NewCoordinator(testClusterWithSlaves(ready.Addr(), voteAbort.Addr()), nil, time.Second)
+	aborting.SetOnComplete(func(o TransactionOutcome) { outcomes = append(outcomes, o) })
+
+	resp, err = aborting.Execute(samplePayload())
+	if err != nil || resp.Success {
+		t.Fatalf("Expected abort, got %#v, %v", resp, err)
+	}
+	if len(outcomes) != 2 {
+		t.Fatalf("Expected a second outcome after abort, got %d", len(outcomes))
+	}
+	got = outcomes[1]
+	if got.TransactionID != resp.TransactionID || got.Decision != protocol.StateAbort || got.AbortCategory != protocol.AbortVoteAbort {
+		t.Errorf("Unexpected abort outcome: %#v", got)
+	}
+	if len(got.Participants) != 2 {
+		t.Errorf("Expected both prepared nodes as participants, got %v", got.Participants)
+	}
+}
+
+func TestCoordinator_OnCompleteSkipsRejectedTransactions(t *testing.T) {
+	calls := 0
+	coordinator := NewCoordinator(testClusterWithSlaves(), nil, time.Second)
+	coordinator.SetOnComplete(func(TransactionOutcome) { calls++ })
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil || resp.Code != protocol.ErrCodeNoParticipants {
+		t.Fatalf("Expected no_participants, got %#v, %v", resp, err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no outcome for a transaction rejected before prepare, got %d", calls)
+	}
+}
+
+func TestCoordinator_OnCompleteRunsOutsideLock(t *testing.T) {
+	stub := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer stub.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(stub.Addr()), nil, time.Second)
+	hooked := make(chan struct{})
+	coordinator.SetOnComplete(func(TransactionOutcome) {
+		// Running another transaction from the hook would deadlock if the
+		// coordinator lock were still held. Only the first one recurses.
+		select {
+		case <-hooked:
+			return
+		default:
+			close(hooked)
+		}
+		if _, err := coordinator.Execute(samplePayload()); err != nil {
+			t.Errorf("Nested Execute() returned error: %v", err)
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		coordinator.Execute(samplePayload())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Execute did not return: the hook ran under the coordinator lock")
+	}
+}
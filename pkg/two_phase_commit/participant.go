@@ -1,9 +1,9 @@
 package twophasecommit
 
 import (
-	"log"
 	"sync"
 
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
 	"github.com/baxromumarov/2pc-engine/pkg/node"
 	"github.com/baxromumarov/2pc-engine/pkg/protocol"
 )
@@ -35,18 +35,8 @@ func (p *Participant) Prepare(txID string, payload any) *protocol.PrepareRespons
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Check if transaction already exists
-	if _, exists := p.transactions[txID]; exists {
-		
-		log.Printf("[Participant %s] Transaction %s already exists", p.node.Addr, txID)
-		
-		return &protocol.PrepareResponse{
-			Status: protocol.StatusAbort,
-			Error:  "Transaction already in progress",
-		}
-	}
-
-	// Try to prepare the transaction on the node
+	// Try to prepare the transaction on the node. A repeated prepare is
+	// READY again if it carries the same payload and refused otherwise.
 	ready, err := p.node.Prepare(txID, payload)
 	if !ready || err != nil {
 		errMsg := "Prepare failed"
@@ -54,7 +44,7 @@ func (p *Participant) Prepare(txID string, payload any) *protocol.PrepareRespons
 			errMsg = err.Error()
 		}
 
-		log.Printf("[Participant %s] Failed to prepare transaction %s: %s", p.node.Addr, txID, errMsg)
+		logging.Warnf("[Participant %s] Failed to prepare transaction %s: %s", p.node.Addr, txID, errMsg)
 		
 		return &protocol.PrepareResponse{
 			Status: protocol.StatusAbort,
@@ -69,10 +59,11 @@ func (p *Participant) Prepare(txID string, payload any) *protocol.PrepareRespons
 		Payload: payload,
 	}
 
-	log.Printf("[Participant %s] Prepared transaction %s", p.node.Addr, txID)
+	logging.Debugf("[Participant %s] Prepared transaction %s", p.node.Addr, txID)
 	
 	return &protocol.PrepareResponse{
-		Status: protocol.StatusReady,
+		Status:       protocol.StatusReady,
+		RowsAffected: p.node.RowsAffected(txID),
 	}
 }
 
@@ -84,7 +75,7 @@ func (p *Participant) Commit(txID string) *protocol.CommitResponse {
 	txState, exists := p.transactions[txID]
 	if !exists {
 		
-		log.Printf("[Participant %s] Transaction %s not found for commit", p.node.Addr, txID)
+		logging.Warnf("[Participant %s] Transaction %s not found for commit", p.node.Addr, txID)
 		
 		return &protocol.CommitResponse{
 			Success: false,
@@ -94,7 +85,7 @@ func (p *Participant) Commit(txID string) *protocol.CommitResponse {
 
 	if txState.State != protocol.StateReady {
 	
-		log.Printf("[Participant %s] Transaction %s not in READY state", p.node.Addr, txID)
+		logging.Warnf("[Participant %s] Transaction %s not in READY state", p.node.Addr, txID)
 	
 		return &protocol.CommitResponse{
 			Success: false,
@@ -105,7 +96,7 @@ func (p *Participant) Commit(txID string) *protocol.CommitResponse {
 	// Commit on the node
 	if err := p.node.Commit(txID); err != nil {
 		
-		log.Printf("[Participant %s] Failed to commit transaction %s: %v", p.node.Addr, txID, err)
+		logging.Errorf("[Participant %s] Failed to commit transaction %s: %v", p.node.Addr, txID, err)
 		
 		return &protocol.CommitResponse{
 			Success: false,
@@ -117,7 +108,7 @@ func (p *Participant) Commit(txID string) *protocol.CommitResponse {
 	txState.State = protocol.StateCommit
 	delete(p.transactions, txID)
 
-	log.Printf("[Participant %s] Committed transaction %s", p.node.Addr, txID)
+	logging.Debugf("[Participant %s] Committed transaction %s", p.node.Addr, txID)
 
 	return &protocol.CommitResponse{
 		Success: true,
@@ -132,7 +123,7 @@ func (p *Participant) Abort(txID string) *protocol.AbortResponse {
 	txState, exists := p.transactions[txID]
 	if !exists {
 		// Transaction might not exist if prepare failed
-		log.Printf("[Participant %s] Transaction %s not found for abort (may not have been prepared)", p.node.Addr, txID)
+		logging.Debugf("[Participant %s] Transaction %s not found for abort (may not have been prepared)", p.node.Addr, txID)
 		
 		return &protocol.AbortResponse{
 			Success: true,
@@ -142,7 +133,7 @@ func (p *Participant) Abort(txID string) *protocol.AbortResponse {
 	// Abort on the node
 	if err := p.node.Abort(txID); err != nil {
 	
-		log.Printf("[Participant %s] Failed to abort transaction %s: %v", p.node.Addr, txID, err)
+		logging.Errorf("[Participant %s] Failed to abort transaction %s: %v", p.node.Addr, txID, err)
 	
 		return &protocol.AbortResponse{
 			Success: false,
@@ -154,7 +145,7 @@ func (p *Participant) Abort(txID string) *protocol.AbortResponse {
 	txState.State = protocol.StateAbort
 	delete(p.transactions, txID)
 
-	log.Printf("[Participant %s] Aborted transaction %s", p.node.Addr, txID)
+	logging.Debugf("[Participant %s] Aborted transaction %s", p.node.Addr, txID)
 	
 	return &protocol.AbortResponse{
 		Success: true,
@@ -0,0 +1,53 @@
+package twophasecommit
+
+import (
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// Participants supplies the nodes a coordinator drives. *cluster.Cluster
+// implements it from live membership; StaticParticipants serves a fixed list.
+type Participants interface {
+	// GetSlaveNodes returns the remote nodes that take part in a new transaction.
+	GetSlaveNodes() []*node.Node
+	// GetNodes returns every known node, including ones not currently eligible.
+	GetNodes() []*node.Node
+}
+
+var _ Participants = (*cluster.Cluster)(nil)
+
+// StaticParticipants is a fixed set of participant nodes with no membership
+// or heartbeat behind it. Nodes stay eligible unless marked dead, disabled or
+// in maintenance.
+type StaticParticipants struct {
+	nodes []*node.Node
+}
+
+// NewStaticParticipants creates an alive slave node for every address.
+func NewStaticParticipants(addrs []string) *StaticParticipants {
+	nodes := make([]*node.Node, 0, len(addrs))
+	for _, addr := range addrs {
+		n := node.NewNode(addr, protocol.RoleSlave)
+		n.SetAlive(true)
+		nodes = append(nodes, n)
+	}
+
+	return &StaticParticipants{nodes: nodes}
+}
+
+// GetSlaveNodes returns the selectable nodes.
+func (p *StaticParticipants) GetSlaveNodes() []*node.Node {
+	out := make([]*node.Node, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		if n.Selectable() {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// GetNodes returns every node in the set.
+func (p *StaticParticipants) GetNodes() []*node.Node {
+	return append([]*node.Node(nil), p.nodes...)
+}
@@ -0,0 +1,104 @@
+package twophasecommit
+
+import (
+	"fmt"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// transactionForwarder sends a transaction to another coordinator;
+// *transport.HTTPClient implements it.
+type transactionForwarder interface {
+	StartTransaction(addr string, req *protocol.TransactionRequest) (*protocol.TransactionResponse, error)
+}
+
+// PartitionRouter decides which coordinator runs a transaction. With a
+// keyspace on the cluster, a transaction carrying a partition key runs on the
+// coordinator owning the key's range and is forwarded there from any other
+// node. Everything else, and the partitions of a coordinator that is down, is
+// left to the master as without partitioning.
+type PartitionRouter struct {
+	cluster *cluster.Cluster
+	self    *node.Node
+	client  transactionForwarder
+	execute func(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error)
+}
+
+// NewPartitionRouter routes transactions for the node running coordinator,
+// forwarding them to other coordinators through client.
+func NewPartitionRouter(clstr *cluster.Cluster, coordinator *Coordinator, client transactionForwarder) *PartitionRouter {
+	return &PartitionRouter{
+		cluster: clstr,
+		self:    coordinator.localNode,
+		client:  client,
+		execute: coordinator.ExecuteRequest,
+	}
+}
+
+// Handle runs req here, forwards it to its partition's owner, or refuses it.
+// It has the signature of the HTTP server's transaction handler.
+func (r *PartitionRouter) Handle(req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+	ks := r.cluster.GetKeyspace()
+	if ks == nil || req.PartitionKey == "" {
+		return r.handleAsMaster(req, false)
+	}
+
+	owner := ks.Owner(req.PartitionKey)
+	if cluster.SameAddr(owner, r.self.Addr) {
+		logging.Debugf("[Router] Coordinating partition key %q here", req.PartitionKey)
+		return r.execute(req)
+	}
+
+	if n := r.cluster.GetNode(owner); n == nil || !n.Eligible() {
+		logging.Warnf("[Router] Coordinator %s of partition key %q is unavailable; falling back to the master", owner, req.PartitionKey)
+		return r.handleAsMaster(req, true)
+	}
+
+	if req.Forwarded {
+		return routingFailure(protocol.ErrCodeWrongCoordinator,
+			fmt.Sprintf("Partition key %q is owned by %s, not %s", req.PartitionKey, owner, r.self.Addr)), nil
+	}
+	return r.forward(owner, req)
+}
+
+// handleAsMaster runs req if this node is the master. Otherwise a transaction
+// whose partition owner is down is forwarded to the master, and any other one
+// is refused with not_master as it always was.
+func (r *PartitionRouter) handleAsMaster(req *protocol.TransactionRequest, forward bool) (*protocol.TransactionResponse, error) {
+	if r.self.GetRole() == protocol.RoleMaster {
+		return r.execute(req)
+	}
+
+	if !forward || req.Forwarded {
+		return routingFailure(protocol.ErrCodeNotMaster, "This node is not the master"), nil
+	}
+
+	master := r.cluster.GetMaster()
+	if master == nil {
+		return routingFailure(protocol.ErrCodeNoMaster, "No master available"), nil
+	}
+	return r.forward(master.Addr, req)
+}
+
+func (r *PartitionRouter) forward(addr string, req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+	logging.Debugf("[Router] Forwarding partition key %q to %s", req.PartitionKey, addr)
+
+	fwd := *req
+	fwd.Forwarded = true
+	resp, err := r.client.StartTransaction(addr, &fwd)
+	if err != nil {
+		return routingFailure(protocol.ErrCodeUnavailable, fmt.Sprintf("Forwarding to coordinator %s failed: %v", addr, err)), nil
+	}
+	return resp, nil
+}
+
+func routingFailure(code, msg string) *protocol.TransactionResponse {
+	return &protocol.TransactionResponse{
+		Success: false,
+		Error:   msg,
+		Code:    code,
+	}
+}
@@ -0,0 +1,154 @@
+package twophasecommit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/node"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+type recordingForwarder struct {
+	addrs []string
+	reqs  []protocol.TransactionRequest
+}
+
+func (f *recordingForwarder) StartTransaction(addr string, req *protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+	f.addrs = append(f.addrs, addr)
+	f.reqs = append(f.reqs, *req)
+	return &protocol.TransactionResponse{Success: true, Message: "forwarded"}, nil
+}
+
+// newTestRouter builds a router for slave "node-a:8081" in a cluster whose
+// keyspace is split between node-a and node-b, with "master:0" as master.
+func newTestRouter(t *testing.T) (*PartitionRouter, *cluster.Cluster, *recordingForwarder, *int) {
+	t.Helper()
+
+	clstr := testClusterWithSlaves("node-b:8081")
+	self := node.NewNode("node-a:8081", protocol.RoleSlave)
+	self.SetAlive(true)
+	clstr.AddNode(self)
+
+	ks, err := cluster.NewKeyspace([]string{"node-a:8081", "node-b:8081"})
+	if err != nil {
+		t.Fatalf("NewKeyspace failed: %v", err)
+	}
+	clstr.SetKeyspace(ks)
+
+	fwd := &recordingForwarder{}
+	executed := 0
+	r := NewPartitionRouter(clstr, NewCoordinator(clstr, self, 0), fwd)
+	r.execute = func(*protocol.TransactionRequest) (*protocol.TransactionResponse, error) {
+		executed++
+		return &protocol.TransactionResponse{Success: true, Message: "local"}, nil
+	}
+	return r, clstr, fwd, &executed
+}
+
+// keyOwnedBy returns a partition key whose range belongs to owner.
+func keyOwnedBy(t *testing.T, ks *cluster.Keyspace, owner string) string {
+	t.Helper()
+	for i := range 1000 {
+		if key := fmt.Sprintf("key-%d", i); ks.Owner(key) == owner {
+			return key
+		}
+	}
+	t.Fatalf("No key owned by %s", owner)
+	return ""
+}
+
+func TestPartitionRouterRoutesByKey(t *testing.T) {
+	r, clstr, fwd, executed := newTestRouter(t)
+	ks := clstr.GetKeyspace()
+
+	ownKey := keyOwnedBy(t, ks, "node-a:8081")
+	resp, _ := r.Handle(&protocol.TransactionRequest{PartitionKey: ownKey})
+	if !resp.Success || resp.Message != "local" || *executed != 1 || len(fwd.addrs) != 0 {
+		t.Fatalf("Expected a key of this node's partition to run here, got %+v (executed %d, forwarded %v)", resp, *executed, fwd.addrs)
+	}
+
+	otherKey := keyOwnedBy(t, ks, "node-b:8081")
+	resp, _ = r.Handle(&protocol.TransactionRequest{PartitionKey: otherKey})
+	if !resp.Success || resp.Message != "forwarded" || *executed != 1 {
+		t.Fatalf("Expected another partition's key to be forwarded, got %+v", resp)
+	}
+	if len(fwd.addrs) != 1 || fwd.addrs[0] != "node-b:8081" || !fwd.reqs[0].Forwarded || fwd.reqs[0].PartitionKey != otherKey {
+		t.Errorf("Expected one forwarded request to node-b:8081, got %v %+v", fwd.addrs, fwd.reqs)
+	}
+
+	// A forwarded request is never forwarded again: the maps disagree.
+	resp, _ = r.Handle(&protocol.TransactionRequest{PartitionKey: otherKey, Forwarded: true})
+	if resp.Success || resp.Code != protocol.ErrCodeWrongCoordinator || len(fwd.addrs) != 1 {
+		t.Errorf("Expected wrong_coordinator for a forwarded request we do not own, got %+v", resp)
+	}
+
+	// Without a partition key only the master coordinates, as before.
+	resp, _ = r.Handle(&protocol.TransactionRequest{})
+	if resp.Success || resp.Code != protocol.ErrCodeNotMaster || *executed != 1 {
+		t.Errorf("Expected not_master for a request without a partition key, got %+v", resp)
+	}
+}
+
+func TestPartitionRouterFallsBackToMasterWhenOwnerIsDown(t *testing.T) {
+	r, clstr, fwd, executed := newTestRouter(t)
+	otherKey := keyOwnedBy(t, clstr.GetKeyspace(), "node-b:8081")
+	clstr.GetNode("node-b:8081").SetAlive(false)
+
+	resp, _ := r.Handle(&protocol.TransactionRequest{PartitionKey: otherKey})
+	if !resp.Success || len(fwd.addrs) != 1 || fwd.addrs[0] != "master:0" {
+		t.Fatalf("Expected the partition of a dead owner to go to the master, got %+v via %v", resp, fwd.addrs)
+	}
+
+	// On the master it runs locally.
+	r.self.SetRole(protocol.RoleMaster)
+	if resp, _ := r.Handle(&protocol.TransactionRequest{PartitionKey: otherKey}); !resp.Success || *executed != 1 || len(fwd.addrs) != 1 {
+		t.Errorf("Expected the master to coordinate the orphaned partition, got %+v", resp)
+	}
+}
+
+func TestPartitionRouterWithoutKeyspaceLeavesTransactionsToMaster(t *testing.T) {
+	r, clstr, fwd, executed := newTestRouter(t)
+	clstr.SetKeyspace(nil)
+
+	if resp, _ := r.Handle(&protocol.TransactionRequest{PartitionKey: "anything"}); resp.Code != protocol.ErrCodeNotMaster {
+		t.Errorf("Expected not_master on a slave without a keyspace, got %+v", resp)
+	}
+
+	r.self.SetRole(protocol.RoleMaster)
+	if resp, _ := r.Handle(&protocol.TransactionRequest{PartitionKey: "anything"}); !resp.Success || *executed != 1 || len(fwd.addrs) != 0 {
+		t.Errorf("Expected the master to coordinate, got %+v", resp)
+	}
+}
+
+func TestCoordinatorOwningPartitionPreparesOnMaster(t *testing.T) {
+	masterSrv := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer masterSrv.Close()
+	slaveSrv := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer slaveSrv.Close()
+
+	clstr := cluster.NewCluster()
+	master := node.NewNode(masterSrv.Addr(), protocol.RoleMaster)
+	master.SetAlive(true)
+	clstr.AddNode(master)
+	clstr.SetMaster(master)
+	slave := node.NewNode(slaveSrv.Addr(), protocol.RoleSlave)
+	slave.SetAlive(true)
+	clstr.AddNode(slave)
+
+	// A non-master coordinator with no database of its own.
+	self := node.NewNode("node-a:8081", protocol.RoleSlave)
+	self.SetAlive(true)
+	coordinator := NewCoordinator(clstr, self, 0).WithLocalParticipation(false)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil || !resp.Success {
+		t.Fatalf("Execute failed: %+v, %v", resp, err)
+	}
+	if got := masterSrv.callCounts(); got.prepare != 1 || got.commit != 1 {
+		t.Errorf("Expected the master to take part, got %+v", got)
+	}
+	if got := slaveSrv.callCounts(); got.prepare != 1 || got.commit != 1 {
+		t.Errorf("Expected the slave to take part, got %+v", got)
+	}
+}
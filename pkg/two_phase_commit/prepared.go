@@ -0,0 +1,165 @@
+package twophasecommit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// ReconcilePrepared resolves the stale Postgres prepared transactions held by
+// participants. Each stale transaction goes through Reconcile, so the outcome
+// comes from the recovery queue's decision first, then from the nodes that
+// already finished it, and is abort otherwise. Nodes that are down or cannot
+// list their prepared transactions are skipped until the next run.
+func (c *Coordinator) ReconcilePrepared() ([]*protocol.ReconcileResponse, error) {
+	stale, listErr := c.stalePreparedTxIDs()
+
+	var (
+		resolved []*protocol.ReconcileResponse
+		errs     []error
+	)
+	if listErr != nil {
+		errs = append(errs, listErr)
+	}
+
+	for _, txID := range stale {
+		if c.isInflight(txID) {
+			continue
+		}
+
+		resp, err := c.Reconcile(txID)
+		if err != nil {
+			logging.Warnf("[Coordinator] Could not resolve prepared transaction %s: %v", txID, err)
+			errs = append(errs, fmt.Errorf("%s: %w", txID, err))
+			continue
+		}
+		resolved = append(resolved, resp)
+	}
+
+	return resolved, errors.Join(errs...)
+}
+
+// stalePreparedTxIDs collects, sorted, the IDs of transactions some alive
+// participant reports as stale prepared transactions.
+func (c *Coordinator) stalePreparedTxIDs() ([]string, error) {
+	members := c.participants.GetNodes()
+	found := make([][]protocol.PreparedXact, len(members))
+	errs := make([]error, len(members))
+
+	var wg sync.WaitGroup
+	for i, m := range members {
+		if !m.GetAlive() {
+			continue
+		}
+
+		wg.Add(1)
+		idx := i
+		addr := m.Addr
+		go func() {
+			defer wg.Done()
+
+			if c.localNode != nil && cluster.SameAddr(addr, c.localNode.Addr) {
+				ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+				defer cancel()
+				found[idx], errs[idx] = c.localNode.ListPreparedXacts(ctx)
+				return
+			}
+
+			resp, err := c.client.ListPreparedXacts(addr)
+			if err != nil {
+				errs[idx] = fmt.Errorf("list prepared transactions on %s: %w", addr, err)
+				return
+			}
+			found[idx] = resp.Xacts
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	var txIDs []string
+	for _, xacts := range found {
+		for _, x := range xacts {
+			if x.Stale && !seen[x.TransactionID] {
+				seen[x.TransactionID] = true
+				txIDs = append(txIDs, x.TransactionID)
+			}
+		}
+	}
+	sort.Strings(txIDs)
+
+	return txIDs, errors.Join(errs...)
+}
+
+// PreparedReconciler runs Coordinator.ReconcilePrepared periodically while the
+// local node is master, so prepared transactions whose coordinator went away do
+// not pile up in Postgres holding locks and blocking vacuum.
+type PreparedReconciler struct {
+	coord    *Coordinator
+	interval time.Duration
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewPreparedReconciler creates a reconciler that runs every interval.
+func NewPreparedReconciler(coord *Coordinator, interval time.Duration) *PreparedReconciler {
+	return &PreparedReconciler{
+		coord:    coord,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the background reconcile loop
+func (r *PreparedReconciler) Start() {
+	r.wg.Add(1)
+	go r.run()
+	logging.Infof("[PreparedReconciler] Started with interval %v", r.interval)
+}
+
+// Stop stops the reconcile loop
+func (r *PreparedReconciler) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+	logging.Infof("[PreparedReconciler] Stopped")
+}
+
+func (r *PreparedReconciler) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.runOnce()
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// runOnce reconciles once if the local node is master. Only the master holds
+// the recovery queue's decisions; a slave deciding on its own could abort a
+// transaction the master is still committing.
+func (r *PreparedReconciler) runOnce() {
+	local := r.coord.localNode
+	if local != nil && local.GetRole() != protocol.RoleMaster {
+		return
+	}
+
+	resolved, err := r.coord.ReconcilePrepared()
+	for _, resp := range resolved {
+		logging.Warnf("[PreparedReconciler] Resolved stale prepared transaction %s to %s (%s)", resp.TransactionID, resp.Decision, resp.Basis)
+	}
+	if err != nil {
+		logging.Warnf("[PreparedReconciler] %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package twophasecommit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ThrottleMode controls what happens to a transaction that exceeds the
+// coordinator's rate limit.
+type ThrottleMode string
+
+const (
+	// ThrottleReject fails the transaction at once; the HTTP API answers 429.
+	ThrottleReject ThrottleMode = "reject"
+	// ThrottleWait delays the transaction until the rate allows it, up to the
+	// coordinator timeout, and rejects it only if the wait would be longer.
+	ThrottleWait ThrottleMode = "wait"
+)
+
+// ParseThrottleMode validates a throttle mode name.
+func ParseThrottleMode(s string) (ThrottleMode, error) {
+	switch mode := ThrottleMode(s); mode {
+	case ThrottleReject, ThrottleWait:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown throttle mode %q (want %s or %s)", s, ThrottleReject, ThrottleWait)
+	}
+}
+
+// tokenBucket is a token-bucket rate limiter: it refills at rate tokens per
+// second up to burst, and every transaction takes one token.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newTokenBucket creates a full bucket. A burst below one allows one second's
+// worth of transactions (at least one) at once.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst < 1 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token if one is available within maxWait and returns how long
+// the caller must wait before using it. Otherwise nothing is taken, ok is false
+// and wait is how long until a token would be available.
+func (b *tokenBucket) reserve(maxWait time.Duration) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// Tokens can go negative: reservations already handed out queue up behind
+	// each other, so concurrent waiters are spaced at the configured rate.
+	missing := 1 - b.tokens
+	if missing > 0 {
+		wait = time.Duration(missing / b.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+
+	b.tokens--
+	return wait, true
+}
@@ -0,0 +1,141 @@
+package twophasecommit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestTokenBucketRefillsAtRate(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(10, 2)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	for i := range 2 {
+		if wait, ok := b.reserve(0); !ok || wait != 0 {
+			t.Fatalf("burst token %d: wait=%v ok=%v, want immediate", i, wait, ok)
+		}
+	}
+
+	wait, ok := b.reserve(0)
+	if ok || wait != 100*time.Millisecond {
+		t.Fatalf("Expected rejection with a 100ms wait once the burst is spent, got wait=%v ok=%v", wait, ok)
+	}
+
+	// A caller willing to wait gets the next token, and the one after queues behind it.
+	if wait, ok := b.reserve(time.Second); !ok || wait != 100*time.Millisecond {
+		t.Errorf("First waiter: wait=%v ok=%v, want 100ms", wait, ok)
+	}
+	if wait, ok := b.reserve(time.Second); !ok || wait != 200*time.Millisecond {
+		t.Errorf("Second waiter: wait=%v ok=%v, want 200ms", wait, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if wait, ok := b.reserve(0); !ok || wait != 0 {
+		t.Errorf("Expected a refilled bucket after an idle hour, got wait=%v ok=%v", wait, ok)
+	}
+}
+
+func TestParseThrottleMode(t *testing.T) {
+	for _, s := range []string{"reject", "wait"} {
+		if _, err := ParseThrottleMode(s); err != nil {
+			t.Errorf("ParseThrottleMode(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseThrottleMode("drop"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+// submitBurst sends n transactions at once and returns their responses.
+func submitBurst(t *testing.T, c *Coordinator, n int) []*protocol.TransactionResponse {
+	t.Helper()
+
+	resps := make([]*protocol.TransactionResponse, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.ExecuteRequest(&protocol.TransactionRequest{Payload: samplePayload()})
+			if err != nil {
+				t.Errorf("ExecuteRequest() returned error: %v", err)
+				return
+			}
+			resps[i] = resp
+		}()
+	}
+	wg.Wait()
+
+	return resps
+}
+
+func TestCoordinator_RateLimitRejectsBurst(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), nil, time.Second).
+		WithRateLimit(5, 2, ThrottleReject)
+
+	committed, throttled := 0, 0
+	for _, resp := range submitBurst(t, coordinator, 6) {
+		switch {
+		case resp == nil:
+		case resp.Success:
+			committed++
+		case resp.RetryAfterMs > 0:
+			throttled++
+		default:
+			t.Errorf("Unexpected failure: %#v", resp)
+		}
+	}
+
+	if committed != 2 || throttled != 4 {
+		t.Errorf("Expected the burst of 2 to commit and 4 to be throttled, got %d committed and %d throttled", committed, throttled)
+	}
+	if calls := remote.callCounts(); calls.prepare != 2 {
+		t.Errorf("Expected throttled transactions never to reach prepare, got %+v", calls)
+	}
+}
+
+func TestCoordinator_RateLimitWaitsOutBurst(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), nil, time.Second).
+		WithRateLimit(20, 1, ThrottleWait)
+
+	start := time.Now()
+	resps := submitBurst(t, coordinator, 4)
+	elapsed := time.Since(start)
+
+	for _, resp := range resps {
+		if resp != nil && !resp.Success {
+			t.Errorf("Expected every waiting transaction to commit, got %#v", resp)
+		}
+	}
+	// One goes at once, the other three are spaced 50ms apart.
+	if elapsed < 140*time.Millisecond {
+		t.Errorf("Expected the burst to be spread over ~150ms, took %v", elapsed)
+	}
+	if calls := remote.callCounts(); calls.prepare != 4 {
+		t.Errorf("Expected all 4 transactions to prepare, got %+v", calls)
+	}
+
+	// A wait longer than the coordinator timeout is rejected instead.
+	slow := NewCoordinator(testClusterWithSlaves(remote.Addr()), nil, 100*time.Millisecond).
+		WithRateLimit(1, 1, ThrottleWait)
+	resps = submitBurst(t, slow, 2)
+	throttled := 0
+	for _, resp := range resps {
+		if resp != nil && resp.RetryAfterMs > 0 {
+			throttled++
+		}
+	}
+	if throttled != 1 {
+		t.Errorf("Expected one transaction rejected for exceeding the wait bound, got %d", throttled)
+	}
+}
@@ -0,0 +1,205 @@
+package twophasecommit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+// Reconcile drives every node to one outcome for txID after an incident left
+// them divergent. The outcome is, in order: the decision the recovery queue
+// still holds for txID; the majority among nodes in a final state; or abort
+// when no node got past PREPARED. Prepared nodes then receive the decision.
+// Final states are never rewritten, so a node that committed while the
+// decision is abort (or vice versa) is reported as failed. The decision is
+// appended to the commit log before it is sent.
+func (c *Coordinator) Reconcile(txID string) (*protocol.ReconcileResponse, error) {
+	if c.isInflight(txID) {
+		return nil, fmt.Errorf("transaction %s is still in flight", txID)
+	}
+
+	detail := c.TransactionDetail(txID)
+
+	var logged protocol.TxState
+	if c.recovery != nil {
+		logged, _ = c.recovery.Decision(txID)
+	}
+
+	decision, basis, err := decideOutcome(detail.Nodes, logged)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Warnf("[Coordinator] Reconciling transaction %s to %s (%s)", txID, decision, basis)
+
+	addrs := make([]string, 0, len(detail.Nodes))
+	for addr := range detail.Nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	if err := c.logReconcileDecision(txID, decision, addrs, detail.Nodes); err != nil {
+		return nil, fmt.Errorf("record decision for %s: %w", txID, err)
+	}
+
+	results := make([]protocol.ReconcileNodeResult, len(addrs))
+	var wg sync.WaitGroup
+	wg.Add(len(addrs))
+
+	for i, addr := range addrs {
+		idx := i
+		nodeAddr := addr
+		before := detail.Nodes[addr].Status
+		go func() {
+			defer wg.Done()
+			results[idx] = c.reconcileNode(txID, nodeAddr, before, decision)
+		}()
+	}
+
+	wg.Wait()
+
+	resp := &protocol.ReconcileResponse{
+		TransactionID: txID,
+		Decision:      decision,
+		Basis:         basis,
+		Nodes:         make(map[string]protocol.ReconcileNodeResult, len(addrs)),
+		Generated:     time.Now(),
+	}
+	for i, addr := range addrs {
+		resp.Nodes[addr] = results[i]
+	}
+
+	return resp, nil
+}
+
+// logReconcileDecision appends decision to the commit log before any prepared
+// node receives it, so a decision made by reconcile leaves the same record as
+// one made by Execute. Nothing is logged when no node is left to move.
+func (c *Coordinator) logReconcileDecision(txID, decision string, addrs []string, nodes map[string]protocol.TransactionNodeStatus) error {
+	if c.commitLog == nil {
+		return nil
+	}
+
+	pushes := false
+	for _, st := range nodes {
+		if st.Status == protocol.TxStatusPrepared {
+			pushes = true
+			break
+		}
+	}
+	if !pushes {
+		return nil
+	}
+
+	state := protocol.StateAbort
+	if decision == protocol.TxStatusCommitted {
+		state = protocol.StateCommit
+	}
+	return c.commitLog.Append(txID, state, addrs)
+}
+
+// decideOutcome picks COMMITTED or ABORTED from the per-node statuses and the
+// decision the coordinator still holds (empty if none).
+func decideOutcome(nodes map[string]protocol.TransactionNodeStatus, logged protocol.TxState) (string, string, error) {
+	switch logged {
+	case protocol.StateCommit:
+		return protocol.TxStatusCommitted, protocol.ReconcileBasisCoordinatorLog, nil
+	case protocol.StateAbort:
+		return protocol.TxStatusAborted, protocol.ReconcileBasisCoordinatorLog, nil
+	}
+
+	committed, aborted, prepared := 0, 0, 0
+	for _, st := range nodes {
+		switch st.Status {
+		case protocol.TxStatusCommitted:
+			committed++
+		case protocol.TxStatusAborted:
+			aborted++
+		case protocol.TxStatusPrepared:
+			prepared++
+		}
+	}
+
+	switch {
+	case committed > aborted:
+		return protocol.TxStatusCommitted, protocol.ReconcileBasisMajority, nil
+	case aborted > committed:
+		return protocol.TxStatusAborted, protocol.ReconcileBasisMajority, nil
+	case committed > 0:
+		return "", "", fmt.Errorf("%d nodes committed and %d aborted; resolve manually", committed, aborted)
+	case prepared == 0:
+		return "", "", errors.New("no node holds a record of the transaction")
+	default:
+		// Nobody committed, so aborting the prepared nodes keeps the outcome atomic.
+		return protocol.TxStatusAborted, protocol.ReconcileBasisPresumedAbort, nil
+	}
+}
+
+// reconcileNode moves one node from before to decision where that is possible.
+func (c *Coordinator) reconcileNode(txID, addr, before, decision string) protocol.ReconcileNodeResult {
+	result := protocol.ReconcileNodeResult{Before: before}
+
+	switch before {
+	case decision:
+		result.Action = protocol.ReconcileUnchanged
+	case protocol.TxStatusUnreachable:
+		result.Action = protocol.ReconcileSkipped
+	case protocol.TxStatusPrepared:
+		if err := c.pushDecision(txID, addr, decision); err != nil {
+			result.Action = protocol.ReconcileFailed
+			result.Error = err.Error()
+		} else {
+			result.Action = protocol.ReconcileApplied
+		}
+	case protocol.TxStatusMissing, protocol.TxStatusObserved:
+		if decision == protocol.TxStatusAborted {
+			result.Action = protocol.ReconcileUnchanged
+		} else {
+			result.Action = protocol.ReconcileFailed
+			result.Error = "node never prepared the transaction; nothing to commit"
+		}
+	default:
+		result.Action = protocol.ReconcileFailed
+		result.Error = fmt.Sprintf("node is already %s; a final outcome cannot be changed", before)
+	}
+
+	return result
+}
+
+// pushDecision sends the decision to a prepared node, locally or over HTTP.
+// The node may have been prepared by a coordinator that has since failed
+// over, so the decision is sent as a takeover.
+func (c *Coordinator) pushDecision(txID, addr, decision string) error {
+	local := c.localNode != nil && cluster.SameAddr(addr, c.localNode.Addr)
+	if decision == protocol.TxStatusCommitted {
+		if local {
+			return c.localNode.CommitTakeover(c.identity(), txID)
+		}
+		resp, err := c.client.Commit(addr, &protocol.CommitRequest{TransactionID: txID, Coordinator: c.identity(), Takeover: true})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("commit not acknowledged: %s", resp.Error)
+		}
+		return nil
+	}
+
+	if local {
+		return c.localNode.AbortTakeover(c.identity(), txID)
+	}
+	resp, err := c.client.Abort(addr, &protocol.AbortRequest{TransactionID: txID, Reason: "reconcile", Coordinator: c.identity(), Takeover: true})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("abort not acknowledged: %s", resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package twophasecommit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/logging"
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+	"github.com/baxromumarov/2pc-engine/pkg/transport"
+)
+
+// RecoveryEntry is a decided outcome a participant has not acknowledged yet.
+type RecoveryEntry struct {
+	TransactionID string
+	Addr          string
+	Action        protocol.TxState // StateCommit or StateAbort
+	Coordinator   string           // identity the decision is sent under
+	Attempts      int
+	LastError     string
+	EnqueuedAt    time.Time
+}
+
+type recoveryKey struct {
+	txID string
+	addr string
+}
+
+// RecoveryQueue keeps retrying commit/abort decisions against participants that
+// failed to acknowledge them, until each node confirms. Commit and abort are
+// idempotent on the participant, so repeated delivery is safe.
+type RecoveryQueue struct {
+	client   *transport.HTTPClient
+	interval time.Duration
+	entries  map[recoveryKey]*RecoveryEntry
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// NewRecoveryQueue creates a recovery queue that retries pending decisions every interval.
+func NewRecoveryQueue(timeout, interval time.Duration) *RecoveryQueue {
+	return &RecoveryQueue{
+		client:   transport.NewHTTPClient(timeout),
+		interval: interval,
+		entries:  make(map[recoveryKey]*RecoveryEntry),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the background retry loop
+func (q *RecoveryQueue) Start() {
+	q.wg.Add(1)
+	go q.run()
+	logging.Infof("[Recovery] Started with interval %v", q.interval)
+}
+
+// Stop stops the retry loop. Pending entries are kept but no longer retried.
+func (q *RecoveryQueue) Stop() {
+	close(q.stopCh)
+	q.wg.Wait()
+	logging.Infof("[Recovery] Stopped")
+}
+
+// Enqueue records that addr must still receive action for txID from
+// coordinator. Enqueueing the same transaction and node again replaces the
+// pending action.
+func (q *RecoveryQueue) Enqueue(txID, addr string, action protocol.TxState, coordinator string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.entries[recoveryKey{txID: txID, addr: addr}] = &RecoveryEntry{
+		TransactionID: txID,
+		Addr:          addr,
+		Action:        action,
+		Coordinator:   coordinator,
+		EnqueuedAt:    time.Now(),
+	}
+	logging.Warnf("[Recovery] Queued %s of transaction %s on %s", action, txID, addr)
+}
+
+// Decision returns the outcome still queued for txID on any node, if any.
+func (q *RecoveryQueue) Decision(txID string) (protocol.TxState, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for key, e := range q.entries {
+		if key.txID == txID {
+			return e.Action, true
+		}
+	}
+	return "", false
+}
+
+// InDoubt returns how many transactions have a decision that some
+// participant has not acknowledged yet.
+func (q *RecoveryQueue) InDoubt() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	txIDs := make(map[string]bool, len(q.entries))
+	for key := range q.entries {
+		txIDs[key.txID] = true
+	}
+	return len(txIDs)
+}
+
+// Pending returns a snapshot of unacknowledged decisions, oldest first.
+func (q *RecoveryQueue) Pending() []RecoveryEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]RecoveryEntry, 0, len(q.entries))
+	for _, e := range q.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].EnqueuedAt.Before(out[j].EnqueuedAt)
+	})
+
+	return out
+}
+
+func (q *RecoveryQueue) run() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.retryPending()
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+// retryPending delivers every pending decision once, in parallel, and drops the
+// entries the participant acknowledged.
+func (q *RecoveryQueue) retryPending() {
+	pending := q.Pending()
+	if len(pending) == 0 {
+		return
+	}
+
+	errs := make([]error, len(pending))
+	var wg sync.WaitGroup
+	wg.Add(len(pending))
+
+	for i, e := range pending {
+		idx := i
+		entry := e
+		go func() {
+			defer wg.Done()
+			errs[idx] = q.deliver(entry)
+		}()
+	}
+
+	wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, e := range pending {
+		key := recoveryKey{txID: e.TransactionID, addr: e.Addr}
+		current, ok := q.entries[key]
+		if !ok || current.Action != e.Action {
+			continue // removed or replaced while we were delivering
+		}
+
+		if errs[i] == nil {
+			delete(q.entries, key)
+			logging.Infof("[Recovery] %s of transaction %s confirmed by %s after %d attempts", e.Action, e.TransactionID, e.Addr, current.Attempts+1)
+			continue
+		}
+
+		current.Attempts++
+		current.LastError = errs[i].Error()
+	}
+}
+
+func (q *RecoveryQueue) deliver(e RecoveryEntry) error {
+	switch e.Action {
+	case protocol.StateCommit:
+		resp, err := q.client.Commit(e.Addr, &protocol.CommitRequest{
+			TransactionID: e.TransactionID,
+			Coordinator:   e.Coordinator,
+		})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("commit not acknowledged: %s", resp.Error)
+		}
+	case protocol.StateAbort:
+		resp, err := q.client.Abort(e.Addr, &protocol.AbortRequest{
+			TransactionID: e.TransactionID,
+			Reason:        "recovery",
+			Coordinator:   e.Coordinator,
+		})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("abort not acknowledged: %s", resp.Error)
+		}
+	}
+
+	return nil
+}
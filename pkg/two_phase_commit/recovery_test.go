@@ -0,0 +1,173 @@
+package twophasecommit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/protocol"
+)
+
+func TestRecoveryQueue_CommitsNodeThatDiedAfterReady(t *testing.T) {
+	var down atomic.Bool
+	var commits atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prepare", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(protocol.PrepareResponse{Status: protocol.StatusReady})
+		down.Store(true) // crash right after voting READY
+	})
+	mux.HandleFunc("/commit", func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(protocol.CommitResponse{Success: false, Error: "node down"})
+			return
+		}
+		commits.Add(1)
+		_ = json.NewEncoder(w).Encode(protocol.CommitResponse{Success: true})
+	})
+	flaky := httptest.NewServer(mux)
+	defer flaky.Close()
+	flakyAddr := flaky.Listener.Addr().String()
+
+	healthy := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer healthy.Close()
+
+	queue := NewRecoveryQueue(200*time.Millisecond, 20*time.Millisecond)
+	coordinator := NewCoordinator(testClusterWithSlaves(flakyAddr, healthy.Addr()), nil, 200*time.Millisecond).
+		WithRecoveryQueue(queue)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Expected COMMIT decision to be reported as success, got %#v", resp)
+	}
+	if resp.Durable || len(resp.PendingNodes) != 1 || resp.PendingNodes[0] != flakyAddr {
+		t.Fatalf("Expected %s pending, got durable=%v pending=%v", flakyAddr, resp.Durable, resp.PendingNodes)
+	}
+
+	pending := queue.Pending()
+	if len(pending) != 1 || pending[0].Addr != flakyAddr || pending[0].TransactionID != resp.TransactionID || pending[0].Action != protocol.StateCommit {
+		t.Fatalf("Expected queued COMMIT for %s, got %+v", flakyAddr, pending)
+	}
+
+	queue.Start()
+	defer queue.Stop()
+
+	// While the node is down the entry stays queued and accumulates attempts.
+	time.Sleep(100 * time.Millisecond)
+	pending = queue.Pending()
+	if len(pending) != 1 || pending[0].Attempts == 0 || pending[0].LastError == "" {
+		t.Fatalf("Expected failed retries to be recorded, got %+v", pending)
+	}
+
+	down.Store(false)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(queue.Pending()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if left := queue.Pending(); len(left) != 0 {
+		t.Fatalf("Expected recovered node to drain the queue, still pending: %+v", left)
+	}
+	if commits.Load() != 1 {
+		t.Errorf("Expected exactly one acknowledged commit on the recovered node, got %d", commits.Load())
+	}
+}
+
+func TestRecoveryQueue_AbortsNodeThatWasDownAtAbort(t *testing.T) {
+	var down atomic.Bool
+	var aborts atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prepare", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(protocol.PrepareResponse{Status: protocol.StatusReady})
+		down.Store(true) // crash right after voting READY
+	})
+	mux.HandleFunc("/abort", func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(protocol.AbortResponse{Success: false, Error: "node down"})
+			return
+		}
+		aborts.Add(1)
+		_ = json.NewEncoder(w).Encode(protocol.AbortResponse{Success: true})
+	})
+	flaky := httptest.NewServer(mux)
+	defer flaky.Close()
+	flakyAddr := flaky.Listener.Addr().String()
+
+	// The other participant votes ABORT, so the transaction is rolled back.
+	refusing := newStubNodeServer(stubEndpoint{
+		status:   http.StatusOK,
+		response: protocol.PrepareResponse{Status: protocol.StatusAbort, Error: "constraint violation"},
+	}, commitSuccess(), abortSuccess())
+	defer refusing.Close()
+
+	queue := NewRecoveryQueue(200*time.Millisecond, 20*time.Millisecond)
+	coordinator := NewCoordinator(testClusterWithSlaves(flakyAddr, refusing.Addr()), nil, 200*time.Millisecond).
+		WithRecoveryQueue(queue)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "abort failed for nodes: ["+flakyAddr+"]") {
+		t.Fatalf("Expected an aborted transaction reporting the failed abort on %s, got %#v", flakyAddr, resp)
+	}
+
+	pending := queue.Pending()
+	if len(pending) != 1 || pending[0].Addr != flakyAddr || pending[0].TransactionID != resp.TransactionID || pending[0].Action != protocol.StateAbort {
+		t.Fatalf("Expected queued ABORT for %s, got %+v", flakyAddr, pending)
+	}
+	if got := refusing.callCounts(); got.abort != 0 {
+		t.Errorf("Expected no abort for the node that voted ABORT, got %+v", got)
+	}
+
+	queue.Start()
+	defer queue.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if pending = queue.Pending(); len(pending) != 1 || pending[0].Attempts == 0 {
+		t.Fatalf("Expected the abort to keep failing while the node is down, got %+v", pending)
+	}
+
+	down.Store(false)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(queue.Pending()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if left := queue.Pending(); len(left) != 0 {
+		t.Fatalf("Expected the returned node to confirm the abort, still pending: %+v", left)
+	}
+	if aborts.Load() != 1 {
+		t.Errorf("Expected exactly one acknowledged abort on the returned node, got %d", aborts.Load())
+	}
+}
+
+func TestRecoveryQueue_NotUsedWhenCommitsSucceed(t *testing.T) {
+	remote := newStubNodeServer(readyPrepare(0), commitSuccess(), abortSuccess())
+	defer remote.Close()
+
+	queue := NewRecoveryQueue(200*time.Millisecond, time.Hour)
+	coordinator := NewCoordinator(testClusterWithSlaves(remote.Addr()), nil, 200*time.Millisecond).
+		WithRecoveryQueue(queue)
+
+	resp, err := coordinator.Execute(samplePayload())
+	if err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	if !resp.Durable {
+		t.Fatalf("Expected durable commit, got %#v", resp)
+	}
+	if pending := queue.Pending(); len(pending) != 0 {
+		t.Errorf("Expected empty recovery queue, got %+v", pending)
+	}
+}
@@ -0,0 +1,49 @@
+package twophasecommit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/baxromumarov/2pc-engine/pkg/cluster"
+)
+
+// Timings are the durations a master or node is started with.
+type Timings struct {
+	Heartbeat        time.Duration // interval between liveness probes
+	CoordTimeout     time.Duration // per-RPC timeout of the 2PC coordinator
+	RecoveryInterval time.Duration // retry interval of the recovery queue
+}
+
+// Bounds accepted by ValidateTimings. Below the minimums the heartbeat spins or
+// every RPC times out; above the maximums failures go unnoticed for too long.
+const (
+	MinHeartbeat        = cluster.MinHeartbeatInterval
+	MaxHeartbeat        = cluster.MaxHeartbeatInterval
+	MinCoordTimeout     = 100 * time.Millisecond
+	MaxCoordTimeout     = 10 * time.Minute
+	MinRecoveryInterval = 100 * time.Millisecond
+	MaxRecoveryInterval = 10 * time.Minute
+)
+
+// ValidateTimings rejects durations that are non-positive or outside the
+// supported range. All violations are reported together.
+func ValidateTimings(t Timings) error {
+	return errors.Join(
+		checkDuration("--heartbeat", t.Heartbeat, MinHeartbeat, MaxHeartbeat),
+		checkDuration("--coord-timeout", t.CoordTimeout, MinCoordTimeout, MaxCoordTimeout),
+		checkDuration("--recovery-interval", t.RecoveryInterval, MinRecoveryInterval, MaxRecoveryInterval),
+	)
+}
+
+func checkDuration(name string, d, lo, hi time.Duration) error {
+	switch {
+	case d <= 0:
+		return fmt.Errorf("%s must be positive, got %v", name, d)
+	case d < lo:
+		return fmt.Errorf("%s must be at least %v, got %v", name, lo, d)
+	case d > hi:
+		return fmt.Errorf("%s must be at most %v, got %v", name, hi, d)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package twophasecommit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTimings(t *testing.T) {
+	valid := Timings{
+		Heartbeat:        5 * time.Second,
+		CoordTimeout:     10 * time.Second,
+		RecoveryInterval: 2 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Timings)
+		wantErr string
+	}{
+		{name: "defaults", mutate: func(*Timings) {}},
+		{name: "minimums inclusive", mutate: func(t *Timings) {
+			t.Heartbeat, t.CoordTimeout, t.RecoveryInterval = MinHeartbeat, MinCoordTimeout, MinRecoveryInterval
+		}},
+		{name: "maximums inclusive", mutate: func(t *Timings) {
+			t.Heartbeat, t.CoordTimeout, t.RecoveryInterval = MaxHeartbeat, MaxCoordTimeout, MaxRecoveryInterval
+		}},
+		{name: "zero heartbeat", mutate: func(t *Timings) { t.Heartbeat = 0 }, wantErr: "--heartbeat must be positive"},
+		{name: "negative coord timeout", mutate: func(t *Timings) { t.CoordTimeout = -time.Second }, wantErr: "--coord-timeout must be positive"},
+		{name: "coord timeout below minimum", mutate: func(t *Timings) { t.CoordTimeout = time.Millisecond }, wantErr: "--coord-timeout must be at least 100ms"},
+		{name: "heartbeat just below minimum", mutate: func(t *Timings) { t.Heartbeat = MinHeartbeat - time.Nanosecond }, wantErr: "--heartbeat must be at least"},
+		{name: "heartbeat above maximum", mutate: func(t *Timings) { t.Heartbeat = MaxHeartbeat + time.Second }, wantErr: "--heartbeat must be at most 5m0s"},
+		{name: "recovery interval above maximum", mutate: func(t *Timings) { t.RecoveryInterval = time.Hour }, wantErr: "--recovery-interval must be at most"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			timings := valid
+			tc.mutate(&timings)
+
+			err := ValidateTimings(timings)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected valid timings, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateTimingsReportsAllViolations(t *testing.T) {
+	err := ValidateTimings(Timings{})
+	if err == nil {
+		t.Fatal("Expected error for zero timings")
+	}
+	for _, flag := range []string{"--heartbeat", "--coord-timeout", "--recovery-interval"} {
+		if !strings.Contains(err.Error(), flag) {
+			t.Errorf("Expected %s in error, got %v", flag, err)
+		}
+	}
+}